@@ -0,0 +1,190 @@
+// Package actor is a small actor framework. An actor owns its state and
+// handles the messages in its mailbox one at a time, so the state needs no
+// locking; other goroutines only talk to it through its Ref, either
+// telling it something or asking and waiting for a reply. Actors are
+// supervised: a handler that panics is replaced by a fresh one.
+package actor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrStopped is returned when sending to an actor that has stopped.
+var ErrStopped = errors.New("actor: stopped")
+
+// ErrPanicked is the error of an Ask whose message made the handler
+// panic; the error also holds what it panicked with.
+var ErrPanicked = errors.New("actor: handler panicked")
+
+// Handler handles one message. It is only ever called from the actor's
+// goroutine.
+type Handler[M any] func(ctx context.Context, msg M)
+
+// Options configure an actor.
+type Options struct {
+	// MailboxSize is how many messages can wait before Tell blocks.
+	MailboxSize int
+	// MaxRestarts is how many times the handler may panic and be
+	// replaced before the actor gives up and stops.
+	MaxRestarts int
+}
+
+// envelope is a message in the mailbox. failed, if set, is told when
+// the message makes the handler panic, so an Ask doesn't wait for a
+// reply that will never come.
+type envelope[M any] struct {
+	msg    M
+	failed chan<- error
+}
+
+// Ref is the address of an actor of messages M.
+type Ref[M any] struct {
+	mailbox chan envelope[M]
+	stop    chan struct{}
+	done    chan struct{}
+	once    sync.Once
+
+	mu       sync.Mutex
+	restarts int
+	err      error
+}
+
+// Spawn starts an actor whose handler is made by newHandler, which is
+// called again to restart it after a panic, so each restart begins from
+// fresh state. The actor stops when ctx is done, on Stop, or after too
+// many restarts.
+func Spawn[M any](ctx context.Context, newHandler func() Handler[M], opts Options) *Ref[M] {
+	r := &Ref[M]{
+		mailbox: make(chan envelope[M], opts.MailboxSize),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go r.run(ctx, newHandler, opts.MaxRestarts)
+	return r
+}
+
+func (r *Ref[M]) run(ctx context.Context, newHandler func() Handler[M], maxRestarts int) {
+	defer close(r.done)
+	handle := newHandler()
+	for {
+		select {
+		case <-ctx.Done():
+			r.setErr(ctx.Err())
+			return
+		case <-r.stop:
+			return
+		case env := <-r.mailbox:
+			p := safely(ctx, handle, env.msg)
+			if p == nil {
+				continue
+			}
+			if env.failed != nil {
+				env.failed <- fmt.Errorf("%w: %v", ErrPanicked, p)
+			}
+			r.mu.Lock()
+			r.restarts++
+			restarts := r.restarts
+			r.mu.Unlock()
+			if restarts > maxRestarts {
+				r.setErr(fmt.Errorf("actor: gave up after %d restarts: %v", maxRestarts, p))
+				return
+			}
+			handle = newHandler()
+		}
+	}
+}
+
+// safely calls handle, returning what it panicked with, if anything.
+func safely[M any](ctx context.Context, handle Handler[M], msg M) (p any) {
+	defer func() {
+		p = recover()
+	}()
+	handle(ctx, msg)
+	return nil
+}
+
+func (r *Ref[M]) setErr(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.err = err
+}
+
+// Tell puts msg in the actor's mailbox without waiting for it to be
+// handled. It blocks while the mailbox is full, until ctx is done.
+func (r *Ref[M]) Tell(ctx context.Context, msg M) error {
+	return r.send(ctx, envelope[M]{msg: msg})
+}
+
+func (r *Ref[M]) send(ctx context.Context, env envelope[M]) error {
+	select {
+	case <-r.done:
+		return ErrStopped
+	default:
+	}
+	select {
+	case r.mailbox <- env:
+		return nil
+	case <-r.done:
+		return ErrStopped
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Ask tells the actor the message made by newMsg, which should carry the
+// reply channel it is given, and waits for the reply. If handling the
+// message panics, Ask fails with ErrPanicked.
+func Ask[M, R any](ctx context.Context, r *Ref[M], newMsg func(reply chan<- R) M) (R, error) {
+	var zero R
+	reply := make(chan R, 1)
+	failed := make(chan error, 1)
+	if err := r.send(ctx, envelope[M]{msg: newMsg(reply), failed: failed}); err != nil {
+		return zero, err
+	}
+	select {
+	case v := <-reply:
+		return v, nil
+	case err := <-failed:
+		// a handler that replied before it panicked still answered
+		select {
+		case v := <-reply:
+			return v, nil
+		default:
+		}
+		return zero, err
+	case <-r.done:
+		return zero, ErrStopped
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
+}
+
+// Stop stops the actor once it has handled the current message, and
+// waits for it. Messages still in the mailbox are dropped.
+func (r *Ref[M]) Stop() {
+	r.once.Do(func() { close(r.stop) })
+	<-r.done
+}
+
+// Done is closed when the actor has stopped.
+func (r *Ref[M]) Done() <-chan struct{} {
+	return r.done
+}
+
+// Err says why the actor stopped on its own: its context ended or it
+// gave up restarting. It is nil while running or after Stop.
+func (r *Ref[M]) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// Restarts returns how many times the handler has been replaced.
+func (r *Ref[M]) Restarts() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.restarts
+}
@@ -0,0 +1,90 @@
+package actor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// msg asks a counter to add n and reply with its total, or to panic.
+type msg struct {
+	n     int
+	panic bool
+	reply chan<- int
+}
+
+func counter() Handler[msg] {
+	total := 0
+	return func(ctx context.Context, m msg) {
+		if m.panic {
+			panic("boom")
+		}
+		total += m.n
+		if m.reply != nil {
+			m.reply <- total
+		}
+	}
+}
+
+func add(n int) func(chan<- int) msg {
+	return func(reply chan<- int) msg { return msg{n: n, reply: reply} }
+}
+
+func crash(reply chan<- int) msg { return msg{panic: true, reply: reply} }
+
+func TestAsk(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	r := Spawn(ctx, counter, Options{MaxRestarts: 1})
+	defer r.Stop()
+
+	if got, err := Ask(ctx, r, add(2)); err != nil || got != 2 {
+		t.Fatalf("got %d, %v, want 2", got, err)
+	}
+	if got, err := Ask(ctx, r, add(3)); err != nil || got != 5 {
+		t.Fatalf("got %d, %v, want 5", got, err)
+	}
+	if _, err := Ask(ctx, r, crash); !errors.Is(err, ErrPanicked) {
+		t.Fatalf("got error %v, want %v", err, ErrPanicked)
+	}
+	// the restarted handler starts from fresh state
+	if got, err := Ask(ctx, r, add(1)); err != nil || got != 1 {
+		t.Fatalf("got %d, %v, want 1", got, err)
+	}
+	if n := r.Restarts(); n != 1 {
+		t.Errorf("got %d restarts, want 1", n)
+	}
+}
+
+func TestGivesUp(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	r := Spawn(ctx, counter, Options{MaxRestarts: 1})
+
+	for range 2 {
+		if _, err := Ask(ctx, r, crash); !errors.Is(err, ErrPanicked) {
+			t.Fatalf("got error %v, want %v", err, ErrPanicked)
+		}
+	}
+	<-r.Done()
+	if r.Err() == nil {
+		t.Error("no error after giving up")
+	}
+	if err := r.Tell(ctx, msg{n: 1}); !errors.Is(err, ErrStopped) {
+		t.Errorf("Tell got error %v, want %v", err, ErrStopped)
+	}
+	if _, err := Ask(ctx, r, add(1)); !errors.Is(err, ErrStopped) {
+		t.Errorf("Ask got error %v, want %v", err, ErrStopped)
+	}
+}
+
+func TestStoppedByContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := Spawn(ctx, counter, Options{})
+	cancel()
+	<-r.Done()
+	if err := r.Err(); !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,275 @@
+// Package breaker is a circuit breaker. While closed it lets calls
+// through and watches their failure rate over a window of recent calls;
+// when the rate gets too high it opens and fails calls at once, giving
+// the server time to recover. After a while it goes half-open and lets a
+// few probe calls through: if they succeed it closes again, if one fails
+// it opens again.
+package breaker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/aawadall/go-concurrency-patterns/config"
+	"github.com/aawadall/go-concurrency-patterns/shared"
+)
+
+// ErrOpen is returned for calls the breaker turns away.
+var ErrOpen = errors.New("breaker: open")
+
+// State is the state of a breaker.
+type State int
+
+const (
+	Closed State = iota
+	Open
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// Settings configure a breaker. Zero fields take the defaults below.
+type Settings struct {
+	// Window is how many recent calls the failure rate is taken over,
+	// and MinCalls how many of them there must be before it counts.
+	Window   int
+	MinCalls int
+	// FailureRate is the rate, from 0 to 1, at which the breaker opens.
+	FailureRate float64
+	// OpenFor is how long the breaker stays open before probing.
+	OpenFor time.Duration
+	// Probes is how many calls in a row must succeed while half-open for
+	// the breaker to close.
+	Probes int
+	// OnStateChange, if set, is called on every change of state. It is
+	// called with the breaker locked, so it must not call back into it.
+	OnStateChange func(from, to State)
+}
+
+func (s Settings) withDefaults() Settings {
+	if s.Window == 0 {
+		s.Window = 100
+	}
+	if s.MinCalls == 0 {
+		s.MinCalls = min(20, s.Window)
+	}
+	if s.FailureRate == 0 {
+		s.FailureRate = 0.5
+	}
+	if s.OpenFor == 0 {
+		s.OpenFor = 5 * time.Second
+	}
+	if s.Probes == 0 {
+		s.Probes = 5
+	}
+	return s
+}
+
+// Metrics count what a breaker has done.
+type Metrics struct {
+	State     State
+	Successes int64
+	Failures  int64
+	// Rejected counts calls turned away while open or while half-open
+	// and out of probes.
+	Rejected int64
+	// Opened counts how many times the breaker has opened.
+	Opened int64
+}
+
+// Breaker is a circuit breaker. It is safe for concurrent use.
+type Breaker struct {
+	settings Settings
+
+	mu    sync.Mutex
+	state State
+	// generation counts changes of state, so that a call let through
+	// before one, even into the same state again, isn't counted after it
+	generation uint64
+	openedAt   time.Time
+	// window holds the outcomes of the latest calls while closed, true
+	// for a failure, with next the slot to overwrite.
+	window   []bool
+	next     int
+	failures int
+	// probes counts probe calls let through while half-open and passed
+	// those that succeeded.
+	probes  int
+	passed  int
+	metrics Metrics
+}
+
+func New(s Settings) *Breaker {
+	s = s.withDefaults()
+	return &Breaker{settings: s, window: make([]bool, 0, s.Window)}
+}
+
+// Allow asks to make a call. If the breaker lets it through, done must be
+// called with the call's outcome; otherwise Allow returns ErrOpen.
+func (b *Breaker) Allow() (done func(failed bool), err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == Open && b.stateLocked() == HalfOpen {
+		b.setState(HalfOpen)
+	}
+	switch b.state {
+	case Open:
+		b.metrics.Rejected++
+		return nil, ErrOpen
+	case HalfOpen:
+		if b.probes >= b.settings.Probes {
+			b.metrics.Rejected++
+			return nil, ErrOpen
+		}
+		b.probes++
+	}
+	// remember the generation the call was let through in, so the
+	// outcome of a call from before the last change of state is only
+	// counted
+	gen := b.generation
+	return func(failed bool) { b.done(gen, failed) }, nil
+}
+
+func (b *Breaker) done(gen uint64, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if failed {
+		b.metrics.Failures++
+	} else {
+		b.metrics.Successes++
+	}
+	if gen != b.generation {
+		return
+	}
+	switch b.state {
+	case Closed:
+		b.record(failed)
+		if len(b.window) >= b.settings.MinCalls &&
+			float64(b.failures) >= b.settings.FailureRate*float64(len(b.window)) {
+			b.setState(Open)
+		}
+	case HalfOpen:
+		if failed {
+			b.setState(Open)
+			return
+		}
+		b.passed++
+		if b.passed >= b.settings.Probes {
+			b.setState(Closed)
+		}
+	}
+}
+
+// record adds an outcome to the window, replacing the oldest once full.
+func (b *Breaker) record(failed bool) {
+	if len(b.window) < b.settings.Window {
+		b.window = append(b.window, failed)
+	} else {
+		if b.window[b.next] {
+			b.failures--
+		}
+		b.window[b.next] = failed
+		b.next = (b.next + 1) % b.settings.Window
+	}
+	if failed {
+		b.failures++
+	}
+}
+
+func (b *Breaker) setState(to State) {
+	from := b.state
+	b.state = to
+	b.generation++
+	switch to {
+	case Closed:
+		b.window, b.next, b.failures = b.window[:0], 0, 0
+	case Open:
+		b.openedAt = time.Now()
+		b.metrics.Opened++
+	case HalfOpen:
+		b.probes, b.passed = 0, 0
+	}
+	if b.settings.OnStateChange != nil {
+		b.settings.OnStateChange(from, to)
+	}
+}
+
+// Do calls fn if the breaker allows it, counting an error as a failure.
+func (b *Breaker) Do(fn func() error) error {
+	done, err := b.Allow()
+	if err != nil {
+		return err
+	}
+	err = fn()
+	done(err != nil)
+	return err
+}
+
+// State returns the breaker's state.
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.stateLocked()
+}
+
+// stateLocked returns the state the next call would find the breaker in:
+// open only until OpenFor has passed, half-open after. b.mu must be held.
+func (b *Breaker) stateLocked() State {
+	if b.state == Open && time.Since(b.openedAt) >= b.settings.OpenFor {
+		return HalfOpen
+	}
+	return b.state
+}
+
+// Metrics returns what the breaker has done so far.
+func (b *Breaker) Metrics() Metrics {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	m := b.metrics
+	m.State = b.stateLocked()
+	return m
+}
+
+// Target wraps t so its calls go through b. Any failed request, including
+// a non-2xx response, counts as a failure; requests turned away are
+// recorded as rejected.
+func Target(b *Breaker, t shared.Target) shared.Target {
+	return shared.TargetFunc(func(ctx context.Context) (shared.Result, error) {
+		done, err := b.Allow()
+		if err != nil {
+			r := shared.Rejected(ctx)
+			r.Err = err
+			return r, err
+		}
+		r, err := t.Do(ctx)
+		done(r.ErrorClass != shared.ClassNone)
+		return r, err
+	})
+}
+
+// ConsumeServer is shared.ConsumeServer through b. Calls turned away
+// return ErrOpen without reaching the server.
+func ConsumeServer(b *Breaker, cfg *config.Config) (latency time.Duration, status int, err error) {
+	done, err := b.Allow()
+	if err != nil {
+		return 0, 0, err
+	}
+	latency, status = shared.ConsumeServer(cfg)
+	done(status < 200 || status > 299)
+	return latency, status, nil
+}
@@ -0,0 +1,89 @@
+package breaker
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+var errCall = errors.New("call failed")
+
+func settings() Settings {
+	return Settings{Window: 4, MinCalls: 2, FailureRate: 0.5, OpenFor: 10 * time.Millisecond, Probes: 1}
+}
+
+// trip opens b with failed calls, waits for it to go half-open and
+// closes it again with a probe.
+func trip(t *testing.T, b *Breaker) {
+	t.Helper()
+	for b.State() == Closed {
+		_ = b.Do(func() error { return errCall })
+	}
+	if err := b.Do(func() error { return nil }); !errors.Is(err, ErrOpen) {
+		t.Fatalf("got %v while open, want %v", err, ErrOpen)
+	}
+	time.Sleep(settings().OpenFor)
+	if s := b.State(); s != HalfOpen {
+		t.Fatalf("got state %v after OpenFor, want %v", s, HalfOpen)
+	}
+	if err := b.Do(func() error { return nil }); err != nil {
+		t.Fatalf("probe got %v", err)
+	}
+	if s := b.State(); s != Closed {
+		t.Fatalf("got state %v after the probes passed, want %v", s, Closed)
+	}
+}
+
+func TestCycle(t *testing.T) {
+	b := New(settings())
+	trip(t, b)
+	m := b.Metrics()
+	if m.Opened != 1 || m.Rejected != 1 {
+		t.Errorf("got %+v, want opened and rejected once", m)
+	}
+}
+
+func TestFailedProbeReopens(t *testing.T) {
+	b := New(settings())
+	for b.State() == Closed {
+		_ = b.Do(func() error { return errCall })
+	}
+	time.Sleep(settings().OpenFor)
+	_ = b.Do(func() error { return errCall })
+	if s := b.Metrics().State; s != Open {
+		t.Errorf("got state %v after a failed probe, want %v", s, Open)
+	}
+}
+
+// once OpenFor has passed, the metrics report the breaker half-open just
+// as State does, before any call moves it there
+func TestMetricsStateAfterOpenFor(t *testing.T) {
+	b := New(settings())
+	for b.State() == Closed {
+		_ = b.Do(func() error { return errCall })
+	}
+	if s := b.Metrics().State; s != Open {
+		t.Fatalf("got state %v while open, want %v", s, Open)
+	}
+	time.Sleep(settings().OpenFor)
+	if s, m := b.State(), b.Metrics().State; s != HalfOpen || m != HalfOpen {
+		t.Errorf("got State %v and Metrics().State %v after OpenFor, want %v", s, m, HalfOpen)
+	}
+}
+
+// a call let through while closed that ends after the breaker has opened
+// and closed again belongs to the old window, not the new one
+func TestStaleOutcome(t *testing.T) {
+	b := New(settings())
+	done, err := b.Allow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	trip(t, b)
+
+	done(true)
+	_ = b.Do(func() error { return errCall })
+	if s := b.State(); s != Closed {
+		t.Errorf("got state %v, want %v: the stale failure was counted", s, Closed)
+	}
+}
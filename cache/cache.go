@@ -0,0 +1,249 @@
+// Package cache is an LRU cache with expiry, safe for concurrent use.
+// Keys are spread over shards, each with its own lock and its own share
+// of the capacity, so goroutines using different keys rarely wait for
+// each other. Load fills the cache through a single-flight group, so a
+// key that is missing or has just expired is fetched once however many
+// goroutines ask for it at the same time.
+package cache
+
+import (
+	"container/list"
+	"hash/maphash"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aawadall/go-concurrency-patterns/singleflight"
+)
+
+// Reason is why an entry left the cache.
+type Reason int
+
+const (
+	// Evicted entries made room for newer ones.
+	Evicted Reason = iota
+	// Expired entries outlived the TTL.
+	Expired
+	// Deleted entries were removed with Delete.
+	Deleted
+)
+
+func (r Reason) String() string {
+	switch r {
+	case Evicted:
+		return "evicted"
+	case Expired:
+		return "expired"
+	case Deleted:
+		return "deleted"
+	}
+	return "unknown"
+}
+
+// Options configures a Cache.
+type Options[K comparable, V any] struct {
+	// Capacity is how many entries the cache holds in all.
+	Capacity int
+	// TTL is how long an entry lasts, or forever if zero.
+	TTL time.Duration
+	// Shards is how many shards the keys are spread over, by default
+	// one per CPU.
+	Shards int
+	// OnEvict, if set, is called with every entry that leaves the
+	// cache, outside the shard's lock.
+	OnEvict func(key K, val V, why Reason)
+}
+
+// Stats counts what the cache has seen so far.
+type Stats struct {
+	Hits        int64
+	Misses      int64
+	Evictions   int64
+	Expirations int64
+}
+
+// entry is a cached value.
+type entry[K comparable, V any] struct {
+	key     K
+	val     V
+	expires time.Time
+}
+
+// shard is part of the cache: a map to the entries, and the entries in a
+// list from most to least recently used.
+type shard[K comparable, V any] struct {
+	mu      sync.Mutex
+	entries map[K]*list.Element
+	lru     *list.List
+	cap     int
+}
+
+// Cache is an LRU cache with expiry.
+type Cache[K comparable, V any] struct {
+	opts   Options[K, V]
+	seed   maphash.Seed
+	shards []*shard[K, V]
+	flight singleflight.Group[K, V]
+
+	hits, misses, evictions, expirations atomic.Int64
+}
+
+// New returns an empty cache.
+func New[K comparable, V any](opts Options[K, V]) *Cache[K, V] {
+	if opts.Shards <= 0 {
+		opts.Shards = runtime.GOMAXPROCS(0)
+	}
+	opts.Shards = max(min(opts.Shards, opts.Capacity), 1)
+	c := &Cache[K, V]{opts: opts, seed: maphash.MakeSeed()}
+	for i := range opts.Shards {
+		// share the capacity out, the first shards taking the remainder
+		n := opts.Capacity/opts.Shards + min(max(opts.Capacity%opts.Shards-i, 0), 1)
+		c.shards = append(c.shards, &shard[K, V]{
+			entries: make(map[K]*list.Element, n),
+			lru:     list.New(),
+			cap:     max(n, 1),
+		})
+	}
+	return c
+}
+
+func (c *Cache[K, V]) shard(key K) *shard[K, V] {
+	return c.shards[maphash.Comparable(c.seed, key)%uint64(len(c.shards))]
+}
+
+// Get returns key's value, if it is cached and hasn't expired, making it
+// the most recently used.
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	v, ok := c.get(key)
+	if ok {
+		c.hits.Add(1)
+	} else {
+		c.misses.Add(1)
+	}
+	return v, ok
+}
+
+// get is Get without counting the hit or miss.
+func (c *Cache[K, V]) get(key K) (V, bool) {
+	var zero V
+	s := c.shard(key)
+	s.mu.Lock()
+	el, ok := s.entries[key]
+	if !ok {
+		s.mu.Unlock()
+		return zero, false
+	}
+	e := el.Value.(*entry[K, V])
+	if !e.expires.IsZero() && time.Now().After(e.expires) {
+		s.remove(el)
+		s.mu.Unlock()
+		c.expirations.Add(1)
+		c.evicted(e, Expired)
+		return zero, false
+	}
+	s.lru.MoveToFront(el)
+	s.mu.Unlock()
+	return e.val, true
+}
+
+// Set caches val for key, evicting the least recently used entry of the
+// key's shard if it is full.
+func (c *Cache[K, V]) Set(key K, val V) {
+	var expires time.Time
+	if c.opts.TTL > 0 {
+		expires = time.Now().Add(c.opts.TTL)
+	}
+	s := c.shard(key)
+	s.mu.Lock()
+	if el, ok := s.entries[key]; ok {
+		e := el.Value.(*entry[K, V])
+		e.val, e.expires = val, expires
+		s.lru.MoveToFront(el)
+		s.mu.Unlock()
+		return
+	}
+	s.entries[key] = s.lru.PushFront(&entry[K, V]{key: key, val: val, expires: expires})
+	var evicted *entry[K, V]
+	if s.lru.Len() > s.cap {
+		oldest := s.lru.Back()
+		evicted = oldest.Value.(*entry[K, V])
+		s.remove(oldest)
+	}
+	s.mu.Unlock()
+	if evicted != nil {
+		c.evictions.Add(1)
+		c.evicted(evicted, Evicted)
+	}
+}
+
+// Load returns key's value from the cache or, if it isn't there, from
+// fetch, caching it unless fetch fails. Only one fetch for a key runs at
+// a time; callers missing the key meanwhile wait for it and share its
+// result.
+func (c *Cache[K, V]) Load(key K, fetch func() (V, error)) (V, error) {
+	if v, ok := c.Get(key); ok {
+		return v, nil
+	}
+	v, err, _ := c.flight.Do(key, func() (V, error) {
+		// a fetch that finished since the cache was checked has cached
+		// the value
+		if v, ok := c.get(key); ok {
+			return v, nil
+		}
+		v, err := fetch()
+		if err == nil {
+			c.Set(key, v)
+		}
+		return v, err
+	})
+	return v, err
+}
+
+// Delete removes key from the cache.
+func (c *Cache[K, V]) Delete(key K) {
+	s := c.shard(key)
+	s.mu.Lock()
+	el, ok := s.entries[key]
+	if ok {
+		s.remove(el)
+	}
+	s.mu.Unlock()
+	if ok {
+		c.evicted(el.Value.(*entry[K, V]), Deleted)
+	}
+}
+
+// Len returns the number of entries, counting any that have expired but
+// not been noticed yet.
+func (c *Cache[K, V]) Len() int {
+	n := 0
+	for _, s := range c.shards {
+		s.mu.Lock()
+		n += s.lru.Len()
+		s.mu.Unlock()
+	}
+	return n
+}
+
+// Stats returns what the cache has seen so far.
+func (c *Cache[K, V]) Stats() Stats {
+	return Stats{
+		Hits:        c.hits.Load(),
+		Misses:      c.misses.Load(),
+		Evictions:   c.evictions.Load(),
+		Expirations: c.expirations.Load(),
+	}
+}
+
+func (c *Cache[K, V]) evicted(e *entry[K, V], why Reason) {
+	if c.opts.OnEvict != nil {
+		c.opts.OnEvict(e.key, e.val, why)
+	}
+}
+
+// remove drops an entry; the shard must be locked.
+func (s *shard[K, V]) remove(el *list.Element) {
+	s.lru.Remove(el)
+	delete(s.entries, el.Value.(*entry[K, V]).key)
+}
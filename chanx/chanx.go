@@ -0,0 +1,227 @@
+// Package chanx is the textbook channel helpers, generic over the element
+// type. Every helper that takes a context stops its goroutine when the
+// context is done, closing the channels it returned, so none of them leak.
+package chanx
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// OrDone returns a channel with in's values that is closed when in is
+// closed or ctx is done, whichever comes first, so ranging over it never
+// outlives ctx.
+func OrDone[T any](ctx context.Context, in <-chan T) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+				select {
+				case out <- v:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// Bridge flattens a channel of channels into one channel, reading each
+// inner channel to the end in turn.
+func Bridge[T any](ctx context.Context, chans <-chan (<-chan T)) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for in := range OrDone(ctx, chans) {
+			for v := range OrDone(ctx, in) {
+				select {
+				case out <- v:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// Take returns a channel with the first n values of in.
+func Take[T any](ctx context.Context, in <-chan T, n int) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for i := 0; i < n; i++ {
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+				select {
+				case out <- v:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// Repeat returns a channel that yields vals over and over until ctx is
+// done.
+func Repeat[T any](ctx context.Context, vals ...T) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		if len(vals) == 0 {
+			return
+		}
+		for {
+			for _, v := range vals {
+				select {
+				case out <- v:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// Tee returns n channels that each get every value of in. A value is sent
+// to all of them before the next is read, so the slowest reader sets the
+// pace.
+func Tee[T any](ctx context.Context, in <-chan T, n int) []<-chan T {
+	outs := make([]chan T, n)
+	ros := make([]<-chan T, n)
+	for i := range outs {
+		outs[i] = make(chan T)
+		ros[i] = outs[i]
+	}
+	go func() {
+		defer func() {
+			for _, out := range outs {
+				close(out)
+			}
+		}()
+		for v := range OrDone(ctx, in) {
+			for _, out := range outs {
+				select {
+				case out <- v:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return ros
+}
+
+// FanIn merges ins into one channel, closed once every one of ins is
+// closed. It has no context: to stop early, close ins or wrap them in
+// OrDone.
+func FanIn[T any](ins ...<-chan T) <-chan T {
+	out := make(chan T)
+	var wg sync.WaitGroup
+	for _, in := range ins {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for v := range in {
+				out <- v
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
+
+// Debounce passes on a value of in only once in has been quiet for quiet
+// after it, dropping the values a later one arrives in time to replace.
+// Each burst of values comes out as its last one. A value still waiting
+// when in is closed is passed on at once.
+func Debounce[T any](ctx context.Context, in <-chan T, quiet time.Duration) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		timer := time.NewTimer(quiet)
+		timer.Stop()
+		defer timer.Stop()
+		var latest T
+		waiting := false
+		for {
+			select {
+			case v, ok := <-in:
+				if !ok {
+					if waiting {
+						select {
+						case out <- latest:
+						case <-ctx.Done():
+						}
+					}
+					return
+				}
+				latest, waiting = v, true
+				timer.Reset(quiet)
+			case <-timer.C:
+				waiting = false
+				select {
+				case out <- latest:
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// Throttle passes on at most one value of in per interval: the first value
+// goes through, and the values arriving within interval of the last one
+// passed on are dropped.
+func Throttle[T any](ctx context.Context, in <-chan T, interval time.Duration) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		var last time.Time
+		for v := range OrDone(ctx, in) {
+			if !last.IsZero() && time.Since(last) < interval {
+				continue
+			}
+			last = time.Now()
+			select {
+			case out <- v:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// Drain reads in until it is closed, discarding the values so whoever
+// sends on it is not left blocked, and returns how many it read.
+func Drain[T any](in <-chan T) int {
+	n := 0
+	for range in {
+		n++
+	}
+	return n
+}
@@ -0,0 +1,251 @@
+package chanx
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aawadall/go-concurrency-patterns/shared/leakcheck"
+)
+
+// from returns a closed channel holding vals, with no goroutine behind it.
+func from(vals ...int) <-chan int {
+	ch := make(chan int, len(vals))
+	for _, v := range vals {
+		ch <- v
+	}
+	close(ch)
+	return ch
+}
+
+// collect reads out until it is closed, failing t if that takes too long.
+func collect(t *testing.T, out <-chan int) []int {
+	t.Helper()
+	got := []int{}
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case v, ok := <-out:
+			if !ok {
+				return got
+			}
+			got = append(got, v)
+		case <-timeout:
+			t.Errorf("channel not closed; got %v so far", got)
+			return got
+		}
+	}
+}
+
+// noLeaks fails t if a goroutine it started is still running once it
+// and its cleanups are done.
+func noLeaks(t *testing.T) {
+	before := leakcheck.Take()
+	t.Cleanup(func() {
+		for _, l := range leakcheck.Check(before, time.Second) {
+			t.Errorf("%d goroutines leaked [%s]:\n%s", l.Count, l.State, l.Stack)
+		}
+	})
+}
+
+func TestHelpers(t *testing.T) {
+	soon := 10 * time.Millisecond
+	tests := []struct {
+		name string
+		// out builds the channel under test; cancel cancels ctx
+		out func(ctx context.Context, cancel context.CancelFunc) <-chan int
+		// want nil only checks that out is closed
+		want   []int
+		sorted bool // compare regardless of order
+	}{
+		{
+			name: "OrDone/closed input",
+			out: func(ctx context.Context, _ context.CancelFunc) <-chan int {
+				return OrDone(ctx, from(1, 2, 3))
+			},
+			want: []int{1, 2, 3},
+		},
+		{
+			name: "OrDone/canceled",
+			out: func(ctx context.Context, cancel context.CancelFunc) <-chan int {
+				time.AfterFunc(soon, cancel)
+				return OrDone(ctx, make(chan int))
+			},
+			want: []int{},
+		},
+		{
+			name: "Bridge/closed inputs",
+			out: func(ctx context.Context, _ context.CancelFunc) <-chan int {
+				chans := make(chan (<-chan int), 3)
+				chans <- from(1, 2)
+				chans <- from()
+				chans <- from(3)
+				close(chans)
+				return Bridge(ctx, chans)
+			},
+			want: []int{1, 2, 3},
+		},
+		{
+			name: "Bridge/canceled on the outer channel",
+			out: func(ctx context.Context, cancel context.CancelFunc) <-chan int {
+				time.AfterFunc(soon, cancel)
+				return Bridge(ctx, make(chan (<-chan int)))
+			},
+			want: []int{},
+		},
+		{
+			name: "Bridge/canceled on an inner channel",
+			out: func(ctx context.Context, cancel context.CancelFunc) <-chan int {
+				chans := make(chan (<-chan int), 2)
+				chans <- from(1)
+				chans <- make(chan int)
+				time.AfterFunc(soon, cancel)
+				return Bridge(ctx, chans)
+			},
+			want: []int{1},
+		},
+		{
+			name: "Take/fewer than in",
+			out: func(ctx context.Context, _ context.CancelFunc) <-chan int {
+				return Take(ctx, from(1, 2, 3), 2)
+			},
+			want: []int{1, 2},
+		},
+		{
+			name: "Take/closed input",
+			out: func(ctx context.Context, _ context.CancelFunc) <-chan int {
+				return Take(ctx, from(1, 2), 5)
+			},
+			want: []int{1, 2},
+		},
+		{
+			name: "Take/canceled",
+			out: func(ctx context.Context, cancel context.CancelFunc) <-chan int {
+				time.AfterFunc(soon, cancel)
+				return Take(ctx, make(chan int), 5)
+			},
+			want: []int{},
+		},
+		{
+			name: "Repeat/cycles",
+			out: func(ctx context.Context, _ context.CancelFunc) <-chan int {
+				return Take(ctx, Repeat(ctx, 1, 2), 5)
+			},
+			want: []int{1, 2, 1, 2, 1},
+		},
+		{
+			name: "Repeat/no values",
+			out: func(ctx context.Context, _ context.CancelFunc) <-chan int {
+				return Repeat[int](ctx)
+			},
+			want: []int{},
+		},
+		{
+			name: "Repeat/canceled",
+			out: func(ctx context.Context, cancel context.CancelFunc) <-chan int {
+				time.AfterFunc(soon, cancel)
+				return Repeat(ctx, 7)
+			},
+		},
+		{
+			name: "FanIn/closed inputs",
+			out: func(ctx context.Context, _ context.CancelFunc) <-chan int {
+				return FanIn(from(1, 2), from(), from(3))
+			},
+			want:   []int{1, 2, 3},
+			sorted: true,
+		},
+		{
+			name: "FanIn/no inputs",
+			out: func(ctx context.Context, _ context.CancelFunc) <-chan int {
+				return FanIn[int]()
+			},
+			want: []int{},
+		},
+		{
+			name: "FanIn/canceled through OrDone",
+			out: func(ctx context.Context, cancel context.CancelFunc) <-chan int {
+				time.AfterFunc(soon, cancel)
+				return FanIn(OrDone(ctx, make(chan int)), from(1))
+			},
+			want: []int{1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			noLeaks(t)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			got := collect(t, tt.out(ctx, cancel))
+			if tt.sorted {
+				slices.Sort(got)
+			}
+			if tt.want != nil && !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTee(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      func(cancel context.CancelFunc) <-chan int
+		want    []int
+		readers int
+	}{
+		{"closed input", func(context.CancelFunc) <-chan int { return from(1, 2, 3) }, []int{1, 2, 3}, 3},
+		{"canceled", func(cancel context.CancelFunc) <-chan int {
+			time.AfterFunc(10*time.Millisecond, cancel)
+			return make(chan int)
+		}, []int{}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			noLeaks(t)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			outs := Tee(ctx, tt.in(cancel), tt.readers)
+			if len(outs) != tt.readers {
+				t.Fatalf("got %d channels, want %d", len(outs), tt.readers)
+			}
+			// every reader at once: Tee sends a value to all before the
+			// next
+			got := make([][]int, len(outs))
+			var wg sync.WaitGroup
+			for i, out := range outs {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					got[i] = collect(t, out)
+				}()
+			}
+			wg.Wait()
+			for i := range got {
+				if !slices.Equal(got[i], tt.want) {
+					t.Errorf("channel %d got %v, want %v", i, got[i], tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestDrain(t *testing.T) {
+	tests := []struct {
+		name string
+		in   <-chan int
+		want int
+	}{
+		{"values", from(1, 2, 3), 3},
+		{"empty", from(), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Drain(tt.in); got != tt.want {
+				t.Errorf("got %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
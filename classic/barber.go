@@ -0,0 +1,74 @@
+package classic
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/aawadall/go-concurrency-patterns/shared"
+)
+
+const (
+	chairs      = 3
+	arrivalTime = time.Millisecond // on average, exponentially distributed
+	cutTime     = 1200 * time.Microsecond
+)
+
+// Barber is the sleeping barber: customers arrive at random and wait in
+// a room of three chairs for the barber, who sleeps while the room is
+// empty; a customer finding every chair taken leaves. Customers come a
+// little faster than one barber cuts hair, so the room fills up and some
+// are turned away, recorded as rejected by a worker past the last barber;
+// with Barbers set to 2, the room rarely does. A haircut's latency is how
+// long the customer waited in the room, and its worker the barber who
+// cut, so the per-worker breakdown shows how busy each barber was.
+type Barber struct {
+	Barbers int
+}
+
+func (b Barber) Name() string {
+	if b.Barbers > 1 {
+		return fmt.Sprintf("sleeping-barber-%d", b.Barbers)
+	}
+	return "sleeping-barber"
+}
+
+// customer is a customer waiting in the room since arrived.
+type customer struct {
+	arrived time.Time
+}
+
+func (b Barber) Run(ctx context.Context, collector *shared.Collector) error {
+	barbers := max(b.Barbers, 1)
+	room := make(chan customer, chairs)
+
+	var wg sync.WaitGroup
+	for i := range barbers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// the barber sleeps on the empty room, and finishes the
+			// customers still waiting when the shop closes
+			for c := range room {
+				record(collector, i, "haircut", c.arrived, nil)
+				time.Sleep(cutTime)
+			}
+		}()
+	}
+
+	// the door: a worker of its own, past the barbers
+	for ctx.Err() == nil {
+		sleep(ctx, time.Duration(rand.ExpFloat64()*float64(arrivalTime)))
+		c := customer{arrived: time.Now()}
+		select {
+		case room <- c:
+		default:
+			record(collector, barbers, "turned away", c.arrived, shared.ErrRejected)
+		}
+	}
+	close(room)
+	wg.Wait()
+	return nil
+}
@@ -0,0 +1,165 @@
+package classic
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/aawadall/go-concurrency-patterns/shared"
+)
+
+const (
+	producers   = 4
+	consumers   = 2
+	bufferSize  = 8
+	produceTime = time.Millisecond // at most
+	consumeTime = time.Millisecond
+)
+
+// BoundedBuffer is the producer-consumer problem: producers put items in
+// a buffer of eight and consumers take them out, producers waiting while
+// it is full and consumers while it is empty. The consumers are slower,
+// so the buffer stays full and the producers' waits are what the run
+// measures. As it is, the buffer is a buffered channel; with Cond set it
+// is the textbook monitor, a mutex and two sync.Conds, one for room and
+// one for items. An operation's latency is how long it waited.
+type BoundedBuffer struct {
+	Cond bool
+}
+
+func (b BoundedBuffer) Name() string {
+	if b.Cond {
+		return "bounded-buffer-cond"
+	}
+	return "bounded-buffer"
+}
+
+// buffer is the bounded buffer producers and consumers share. Waits end
+// with an error once the run is over.
+type buffer interface {
+	put(v int) error
+	get() (int, error)
+}
+
+func (b BoundedBuffer) Run(ctx context.Context, collector *shared.Collector) error {
+	var buf buffer = chanBuffer{ctx: ctx, ch: make(chan int, bufferSize)}
+	if b.Cond {
+		buf = newCondBuffer(ctx, bufferSize)
+	}
+
+	// waits cut short by the end of the run aren't recorded: here they
+	// say nothing about anyone's fairness
+	var wg sync.WaitGroup
+	for i := range producers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := 0; ; n++ {
+				pause(ctx, produceTime)
+				start := time.Now()
+				if buf.put(n) != nil {
+					return
+				}
+				record(collector, i, "put", start, nil)
+			}
+		}()
+	}
+	for i := range consumers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				start := time.Now()
+				if _, err := buf.get(); err != nil {
+					return
+				}
+				record(collector, producers+i, "get", start, nil)
+				sleep(ctx, consumeTime)
+			}
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+// chanBuffer is a buffered channel as a buffer.
+type chanBuffer struct {
+	ctx context.Context
+	ch  chan int
+}
+
+func (b chanBuffer) put(v int) error {
+	select {
+	case b.ch <- v:
+		return nil
+	case <-b.ctx.Done():
+		return b.ctx.Err()
+	}
+}
+
+func (b chanBuffer) get() (int, error) {
+	select {
+	case v := <-b.ch:
+		return v, nil
+	case <-b.ctx.Done():
+		return 0, b.ctx.Err()
+	}
+}
+
+// condBuffer is a ring buffer guarded by a mutex, with a sync.Cond each
+// for waiting for room and for items.
+type condBuffer struct {
+	ctx      context.Context
+	mu       sync.Mutex
+	notFull  *sync.Cond
+	notEmpty *sync.Cond
+	items    []int
+	head, n  int
+}
+
+// newCondBuffer returns a buffer of size items whose waits end when ctx
+// is done.
+func newCondBuffer(ctx context.Context, size int) *condBuffer {
+	b := &condBuffer{ctx: ctx, items: make([]int, size)}
+	b.notFull = sync.NewCond(&b.mu)
+	b.notEmpty = sync.NewCond(&b.mu)
+	// a Cond can't wait on a context, so wake everyone to check it
+	context.AfterFunc(ctx, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.notFull.Broadcast()
+		b.notEmpty.Broadcast()
+	})
+	return b
+}
+
+func (b *condBuffer) put(v int) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for b.n == len(b.items) {
+		if err := b.ctx.Err(); err != nil {
+			return err
+		}
+		b.notFull.Wait()
+	}
+	b.items[(b.head+b.n)%len(b.items)] = v
+	b.n++
+	b.notEmpty.Signal()
+	return nil
+}
+
+func (b *condBuffer) get() (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for b.n == 0 {
+		if err := b.ctx.Err(); err != nil {
+			return 0, err
+		}
+		b.notEmpty.Wait()
+	}
+	v := b.items[b.head]
+	b.head = (b.head + 1) % len(b.items)
+	b.n--
+	b.notFull.Signal()
+	return v, nil
+}
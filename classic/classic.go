@@ -0,0 +1,117 @@
+// Package classic is the classic synchronization problems: the dining
+// philosophers, readers and writers, the sleeping barber and the bounded
+// buffer, each with the variant that goes wrong and the ones that don't.
+// They record every operation in a shared.Collector, a meal or a read or
+// a haircut, with the time the worker waited to do it as its latency and
+// its role as its endpoint, so that the usual report measures what the
+// textbooks only describe: the per-worker breakdown shows who starved,
+// and the failures who was still waiting when the run ended, or was
+// caught in a deadlock. A problem can't see its own deadlock: whoever
+// runs it watches for one, with a shared/watchdog say, and cancels the
+// run's context with ErrDeadlock as the cause.
+package classic
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"github.com/aawadall/go-concurrency-patterns/shared"
+)
+
+var (
+	// ErrDeadlock is the cause to cancel a run that deadlocked with,
+	// the error of the operations caught in it and of the run.
+	ErrDeadlock = errors.New("classic: deadlock")
+	// ErrStarved is the error of the operations still waiting when the
+	// run ended.
+	ErrStarved = errors.New("classic: still waiting at the end")
+)
+
+// Problem is a classic synchronization problem.
+type Problem interface {
+	Name() string
+	// Run runs the problem until ctx is done, recording every operation
+	// in collector.
+	Run(ctx context.Context, collector *shared.Collector) error
+}
+
+// All lists every problem and variant.
+var All = []Problem{
+	Philosophers{},
+	Philosophers{Ordered: true},
+	Philosophers{Waiter: true},
+	ReadersWriters{},
+	ReadersWriters{RWMutex: true},
+	Barber{},
+	Barber{Barbers: 2},
+	BoundedBuffer{},
+	BoundedBuffer{Cond: true},
+}
+
+// Lookup returns the problem with the given name.
+func Lookup(name string) (Problem, bool) {
+	for _, p := range All {
+		if p.Name() == name {
+			return p, true
+		}
+	}
+	return nil, false
+}
+
+// record records an operation of worker as role, which waited from
+// start until now, and failed with err if it isn't nil.
+func record(c *shared.Collector, worker int, role string, start time.Time, err error) {
+	end := time.Now()
+	r := shared.Result{
+		Latency:  end.Sub(start),
+		Start:    start,
+		End:      end,
+		WorkerID: worker,
+		Endpoint: role,
+		Err:      err,
+	}
+	switch {
+	case errors.Is(err, shared.ErrRejected):
+		r.ErrorClass = shared.ClassRejected
+	case err != nil:
+		r.ErrorClass = shared.ClassTimeout
+	}
+	c.Record(r)
+}
+
+// stuck is the error of an operation still waiting when ctx was done:
+// ErrDeadlock if that was the cause, ErrStarved otherwise.
+func stuck(ctx context.Context) error {
+	if errors.Is(context.Cause(ctx), ErrDeadlock) {
+		return ErrDeadlock
+	}
+	return ErrStarved
+}
+
+// acquire takes a token from sem, or fails with ctx's error if ctx is
+// done first.
+func acquire(ctx context.Context, sem chan struct{}) error {
+	select {
+	case sem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// pause sleeps for up to d, at random, or until ctx is done.
+func pause(ctx context.Context, d time.Duration) {
+	sleep(ctx, time.Duration(rand.Int64N(int64(d)+1)))
+}
+
+// sleep sleeps for d, or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+	case <-ctx.Done():
+	}
+}
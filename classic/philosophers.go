@@ -0,0 +1,115 @@
+package classic
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/aawadall/go-concurrency-patterns/shared"
+)
+
+const (
+	philosophers = 5
+	thinkTime    = 5 * time.Millisecond // at most
+	eatTime      = time.Millisecond
+	// reachTime is how long a philosopher takes to reach for the second
+	// fork, which gives the naive table its chance to deadlock
+	reachTime = time.Millisecond
+)
+
+// Philosophers is the dining philosophers: five philosophers around a
+// table with a fork between each two, each needing both forks next to
+// them to eat. As they are, each picks up the fork on their left, then
+// the one on their right, and once all of them hold a left fork they
+// wait for each other forever, until the run is canceled: with
+// ErrDeadlock as the cause, Run records the hungry philosophers as caught
+// in it and fails with it.
+// With Ordered set, each picks up the lower-numbered fork first, so the
+// last philosopher reaches across and there is no cycle to wait in; with
+// Waiter set, a waiter seats at most four at a time, so one of them can
+// always eat. A meal's latency is how long the philosopher was hungry.
+type Philosophers struct {
+	Ordered bool
+	Waiter  bool
+}
+
+func (p Philosophers) Name() string {
+	switch {
+	case p.Ordered:
+		return "philosophers-ordered"
+	case p.Waiter:
+		return "philosophers-waiter"
+	}
+	return "philosophers"
+}
+
+func (p Philosophers) Run(ctx context.Context, collector *shared.Collector) error {
+	forks := make([]chan struct{}, philosophers)
+	for i := range forks {
+		forks[i] = make(chan struct{}, 1)
+	}
+	var seats chan struct{}
+	if p.Waiter {
+		seats = make(chan struct{}, philosophers-1)
+	}
+
+	var wg sync.WaitGroup
+	for i := range philosophers {
+		first, second := forks[i], forks[(i+1)%philosophers]
+		if p.Ordered && i == philosophers-1 {
+			first, second = second, first
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				pause(ctx, thinkTime)
+				if ctx.Err() != nil {
+					return
+				}
+				hungry := time.Now()
+				if err := p.dine(ctx, seats, first, second, func() {
+					record(collector, i, "meal", hungry, nil)
+				}); err != nil {
+					// a philosopher still hungry when the run ends
+					// normally isn't news
+					if errors.Is(context.Cause(ctx), ErrDeadlock) {
+						record(collector, i, "meal", hungry, ErrDeadlock)
+					}
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if errors.Is(context.Cause(ctx), ErrDeadlock) {
+		return ErrDeadlock
+	}
+	return nil
+}
+
+// dine takes a seat if there is a waiter and the two forks, calls eaten
+// once the philosopher has them, eats, and puts everything back. It fails
+// if ctx is done before the philosopher could eat.
+func (p Philosophers) dine(ctx context.Context, seats, first, second chan struct{}, eaten func()) error {
+	if seats != nil {
+		if err := acquire(ctx, seats); err != nil {
+			return err
+		}
+		defer func() { <-seats }()
+	}
+	if err := acquire(ctx, first); err != nil {
+		return err
+	}
+	defer func() { <-first }()
+	sleep(ctx, reachTime)
+	if err := acquire(ctx, second); err != nil {
+		return err
+	}
+	defer func() { <-second }()
+	eaten()
+	sleep(ctx, eatTime)
+	return nil
+}
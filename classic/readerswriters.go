@@ -0,0 +1,129 @@
+package classic
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/aawadall/go-concurrency-patterns/shared"
+)
+
+const (
+	readers     = 8
+	writers     = 2
+	readTime    = 2 * time.Millisecond
+	writeTime   = time.Millisecond
+	readerPause = time.Millisecond // at most
+	writerPause = 5 * time.Millisecond
+)
+
+// ReadersWriters is readers and writers sharing a resource: any number
+// of readers may use it at once, a writer only alone. As it is, the lock
+// prefers readers, the first readers-writers problem: while any reader
+// is in, more may join, and with readers coming often enough the writers
+// never get a turn. Writers still waiting when the run ends fail with
+// ErrStarved. With RWMutex set, the lock is a sync.RWMutex, which stops
+// letting readers in once a writer is waiting. An operation's latency is
+// how long it waited for the lock.
+type ReadersWriters struct {
+	RWMutex bool
+}
+
+func (rw ReadersWriters) Name() string {
+	if rw.RWMutex {
+		return "readers-writers-rwmutex"
+	}
+	return "readers-writers"
+}
+
+// rwLock is the lock readers and writers share.
+type rwLock interface {
+	rlock(ctx context.Context) error
+	runlock()
+	lock(ctx context.Context) error
+	unlock()
+}
+
+func (rw ReadersWriters) Run(ctx context.Context, collector *shared.Collector) error {
+	var l rwLock = &readersFirst{room: make(chan struct{}, 1)}
+	if rw.RWMutex {
+		l = &rwMutex{}
+	}
+
+	var wg sync.WaitGroup
+	worker := func(id int, role string, lock func(context.Context) error, unlock func(), hold, rest time.Duration) {
+		defer wg.Done()
+		for {
+			pause(ctx, rest)
+			if ctx.Err() != nil {
+				return
+			}
+			start := time.Now()
+			if err := lock(ctx); err != nil {
+				record(collector, id, role, start, stuck(ctx))
+				return
+			}
+			record(collector, id, role, start, nil)
+			time.Sleep(hold)
+			unlock()
+		}
+	}
+	for i := range readers {
+		wg.Add(1)
+		go worker(i, "read", l.rlock, l.runlock, readTime, readerPause)
+	}
+	for i := range writers {
+		wg.Add(1)
+		go worker(readers+i, "write", l.lock, l.unlock, writeTime, writerPause)
+	}
+	wg.Wait()
+	return nil
+}
+
+// readersFirst is the textbook readers-preferring lock: the first reader
+// in takes the room for all of them, and the last one out gives it back.
+type readersFirst struct {
+	mu      sync.Mutex // guards readers, and queues readers behind the first
+	readers int
+	room    chan struct{} // held by the writer, or by the readers together
+}
+
+func (l *readersFirst) rlock(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.readers == 0 {
+		if err := acquire(ctx, l.room); err != nil {
+			return err
+		}
+	}
+	l.readers++
+	return nil
+}
+
+func (l *readersFirst) runlock() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.readers--
+	if l.readers == 0 {
+		<-l.room
+	}
+}
+
+func (l *readersFirst) lock(ctx context.Context) error {
+	return acquire(ctx, l.room)
+}
+
+func (l *readersFirst) unlock() {
+	<-l.room
+}
+
+// rwMutex is a sync.RWMutex as an rwLock. Its waits can't be given up,
+// but under it nobody waits for long.
+type rwMutex struct {
+	mu sync.RWMutex
+}
+
+func (l *rwMutex) rlock(context.Context) error { l.mu.RLock(); return nil }
+func (l *rwMutex) runlock()                    { l.mu.RUnlock() }
+func (l *rwMutex) lock(context.Context) error  { l.mu.Lock(); return nil }
+func (l *rwMutex) unlock()                     { l.mu.Unlock() }
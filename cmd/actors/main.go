@@ -0,0 +1,13 @@
+package main
+
+import (
+	"os"
+
+	"github.com/aawadall/go-concurrency-patterns/patterns"
+	"github.com/aawadall/go-concurrency-patterns/runner"
+)
+
+// Same as gcp run actors.
+func main() {
+	runner.Main(patterns.Actors{}, os.Args[1:])
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/aawadall/go-concurrency-patterns/breaker"
+	"github.com/aawadall/go-concurrency-patterns/config"
+	"github.com/aawadall/go-concurrency-patterns/shared"
+)
+
+// send requests through a circuit breaker while the target has an outage
+func main() {
+	mode := flag.String("mode", "sim", "target: sim, or http for the test server")
+	workers := flag.Int("workers", 4, "workers sending requests")
+	duration := flag.Duration("duration", 6*time.Second, "how long to run")
+	outageStart := flag.Duration("outage-start", time.Second, "when the injected outage starts")
+	outage := flag.Duration("outage", 2*time.Second, "how long every request fails for")
+	openFor := flag.Duration("open-for", 500*time.Millisecond, "how long the breaker stays open before probing")
+	flag.Parse()
+
+	cfg := config.GetDefaultConfig()
+	cfg.Mode = *mode
+	cfg.Quiet = true
+	target, err := shared.NewTarget(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	b := breaker.New(breaker.Settings{
+		Window:   50,
+		MinCalls: 10,
+		OpenFor:  *openFor,
+		OnStateChange: func(from, to breaker.State) {
+			log.Printf("breaker %s -> %s", from, to)
+		},
+	})
+	start := time.Now()
+	target = breaker.Target(b, withOutage(target, start.Add(*outageStart), *outage))
+
+	ctx, cancel := context.WithTimeout(context.Background(), *duration)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for i := 0; i < *workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx := shared.WithWorkerID(ctx, i)
+			for ctx.Err() == nil {
+				if _, err := target.Do(ctx); errors.Is(err, breaker.ErrOpen) {
+					// don't spin while the breaker is open
+					time.Sleep(10 * time.Millisecond)
+				}
+			}
+		}()
+	}
+
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			report(b, time.Since(start))
+		case <-ctx.Done():
+			wg.Wait()
+			report(b, time.Since(start))
+			return
+		}
+	}
+}
+
+// withOutage wraps t so every request fails with a 503 for d from start.
+func withOutage(t shared.Target, start time.Time, d time.Duration) shared.Target {
+	return shared.TargetFunc(func(ctx context.Context) (shared.Result, error) {
+		now := time.Now()
+		if now.Before(start) || now.After(start.Add(d)) {
+			return t.Do(ctx)
+		}
+		// fail fast, like a server shedding load
+		time.Sleep(time.Millisecond)
+		end := time.Now()
+		return shared.Result{
+			Status: 503, ErrorClass: shared.ClassNon2xx,
+			Start: now, End: end, Latency: end.Sub(now), WorkerID: shared.WorkerID(ctx),
+		}, nil
+	})
+}
+
+func report(b *breaker.Breaker, elapsed time.Duration) {
+	m := b.Metrics()
+	log.Printf("%5.1fs %-9s successes=%d failures=%d rejected=%d opened=%d",
+		elapsed.Seconds(), m.State, m.Successes, m.Failures, m.Rejected, m.Opened)
+}
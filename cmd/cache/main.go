@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aawadall/go-concurrency-patterns/cache"
+	"github.com/aawadall/go-concurrency-patterns/config"
+	"github.com/aawadall/go-concurrency-patterns/shared"
+)
+
+// look keys up from many workers through an LRU cache with a short TTL,
+// fetching misses from the test server, first filling the cache with a
+// plain get-then-set and then with Load; when a popular key expires the
+// plain way has every worker that misses it fetch it at once, a cache
+// stampede, where Load fetches it once
+func main() {
+	mode := flag.String("mode", "http", "target to fetch keys from: http or sim")
+	workers := flag.Int("workers", 32, "workers looking keys up at once")
+	duration := flag.Duration("duration", 2*time.Second, "how long to run each way")
+	keys := flag.Int("keys", 500, "distinct keys, the low ones far more popular")
+	capacity := flag.Int("capacity", 100, "entries the cache holds")
+	ttl := flag.Duration("ttl", 100*time.Millisecond, "how long an entry lasts")
+	flag.Parse()
+
+	cfg := config.GetDefaultConfig()
+	cfg.Mode = *mode
+	cfg.Quiet = true
+	cfg.Concurrency = *workers
+	cfg.Duration = *duration
+	target, err := shared.NewTarget(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var labels []string
+	var runs []shared.Manifest
+	for _, load := range []bool{false, true} {
+		name := "get-set"
+		if load {
+			name = "load"
+		}
+		f := &fetcher{target: target, inFlight: make(map[uint64]int)}
+		var reasons [3]atomic.Int64
+		c := cache.New(cache.Options[uint64, shared.Result]{
+			Capacity: *capacity,
+			TTL:      *ttl,
+			OnEvict: func(_ uint64, _ shared.Result, why cache.Reason) {
+				reasons[why].Add(1)
+			},
+		})
+		lookup := func(key uint64) (shared.Result, error) {
+			if load {
+				return c.Load(key, func() (shared.Result, error) { return f.fetch(key) })
+			}
+			if r, ok := c.Get(key); ok {
+				return r, nil
+			}
+			r, err := f.fetch(key)
+			if err == nil {
+				c.Set(key, r)
+			}
+			return r, err
+		}
+
+		m := run(name, cfg, uint64(*keys), lookup)
+		st := c.Stats()
+		log.Printf("%-8s %d lookups, %.1f%% hits, %d fetches, %d of them while the key was already being fetched",
+			name, st.Hits+st.Misses, 100*float64(st.Hits)/float64(max(st.Hits+st.Misses, 1)),
+			f.fetches.Load(), f.duplicates.Load())
+		log.Printf("%-8s %d entries evicted, %d expired", "", reasons[cache.Evicted].Load(), reasons[cache.Expired].Load())
+		labels = append(labels, name)
+		runs = append(runs, m)
+	}
+	shared.Compare(os.Stdout, labels, runs, 0.05)
+}
+
+// fetcher fetches keys from the target, counting fetches of a key that
+// is already being fetched.
+type fetcher struct {
+	target              shared.Target
+	fetches, duplicates atomic.Int64
+
+	mu       sync.Mutex
+	inFlight map[uint64]int
+}
+
+// fetch requests a key; the server answers every key the same.
+func (f *fetcher) fetch(key uint64) (shared.Result, error) {
+	f.fetches.Add(1)
+	f.mu.Lock()
+	if f.inFlight[key] > 0 {
+		f.duplicates.Add(1)
+	}
+	f.inFlight[key]++
+	f.mu.Unlock()
+	defer func() {
+		f.mu.Lock()
+		f.inFlight[key]--
+		f.mu.Unlock()
+	}()
+
+	r, err := f.target.Do(context.Background())
+	if err == nil && r.ErrorClass != "" {
+		err = fmt.Errorf("key %d: %s", key, r.ErrorClass)
+	}
+	return r, err
+}
+
+// run has cfg.Concurrency workers look up keys, most often the low ones,
+// for cfg.Duration, recording each lookup as a request.
+func run(name string, cfg *config.Config, keys uint64, lookup func(uint64) (shared.Result, error)) shared.Manifest {
+	collector := shared.NewCollectorFor(cfg)
+	start := time.Now()
+	deadline := start.Add(cfg.Duration)
+	var wg sync.WaitGroup
+	for i := range cfg.Concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			zipf := rand.NewZipf(rand.New(rand.NewPCG(uint64(i), 0)), 1.1, 1, keys-1)
+			for time.Now().Before(deadline) {
+				begin := time.Now()
+				r, _ := lookup(zipf.Uint64())
+				end := time.Now()
+				r.Start, r.End, r.Latency, r.WorkerID = begin, end, end.Sub(begin), i
+				collector.Record(r)
+			}
+		}()
+	}
+	wg.Wait()
+	return shared.NewManifest(name, cfg, start, time.Now(), collector.Summary(), nil)
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/aawadall/go-concurrency-patterns/config"
+	"github.com/aawadall/go-concurrency-patterns/shared"
+)
+
+// cancel requests in flight three ways, checking each time that every
+// goroutine started has exited afterwards
+const (
+	workers  = 8
+	deadline = 200 * time.Millisecond
+	// the tree has fanout children per node, depth levels deep
+	fanout = 3
+	depth  = 4
+)
+
+func main() {
+	cfg := config.GetDefaultConfig()
+	cfg.Mode = "sim"
+	cfg.Quiet = true
+	cfg.SimErrorRate = 0.02
+	target, err := shared.NewSimTarget(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	leaked := false
+	for _, s := range []struct {
+		name string
+		run  func(shared.Target)
+	}{
+		{"deadline", deadlineCancel},
+		{"first error", firstErrorCancel},
+		{"goroutine tree", treeCancel},
+	} {
+		log.Printf("== %s", s.name)
+		before := runtime.NumGoroutine()
+		s.run(target)
+		if n := leakedGoroutines(before); n > 0 {
+			log.Printf("LEAK: %d goroutines still running", n)
+			leaked = true
+		} else {
+			log.Printf("no goroutines leaked")
+		}
+	}
+	if leaked {
+		os.Exit(1)
+	}
+}
+
+// deadlineCancel runs workers until a deadline, which cancels the
+// requests in flight when it passes.
+func deadlineCancel(target shared.Target) {
+	ctx, cancel := context.WithTimeout(context.Background(), deadline)
+	defer cancel()
+
+	var completed, canceled atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx := shared.WithWorkerID(ctx, i)
+			for ctx.Err() == nil {
+				if r, _ := target.Do(ctx); r.ErrorClass == shared.ClassTimeout {
+					canceled.Add(1)
+				} else {
+					completed.Add(1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	log.Printf("%d requests completed, %d cut off by the %v deadline", completed.Load(), canceled.Load(), deadline)
+}
+
+// firstErrorCancel runs workers in an errgroup, whose context is canceled
+// by the first failed request, stopping the others.
+func firstErrorCancel(target shared.Target) {
+	g, ctx := errgroup.WithContext(context.Background())
+	var completed, canceled atomic.Int64
+	for i := 0; i < workers; i++ {
+		g.Go(func() error {
+			ctx := shared.WithWorkerID(ctx, i)
+			for {
+				r, _ := target.Do(ctx)
+				switch r.ErrorClass {
+				case shared.ClassNone:
+					completed.Add(1)
+				case shared.ClassCanceled:
+					canceled.Add(1)
+					return nil
+				default:
+					return fmt.Errorf("worker %d: request failed (%s)", i, r.ErrorClass)
+				}
+			}
+		})
+	}
+	err := g.Wait()
+	log.Printf("%d requests completed, then %v; %d requests canceled", completed.Load(), err, canceled.Load())
+}
+
+// treeCancel starts a tree of goroutines, each deriving its context from
+// its parent's, cancels the root and measures how long the cancellation
+// takes to reach every leaf.
+func treeCancel(target shared.Target) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		canceled time.Time
+		slowest  time.Duration
+		leaves   int
+	)
+	var grow func(ctx context.Context, level int)
+	grow = func(ctx context.Context, level int) {
+		defer wg.Done()
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		if level == depth {
+			// leaves make requests until canceled
+			for ctx.Err() == nil {
+				target.Do(ctx)
+			}
+			mu.Lock()
+			slowest = max(slowest, time.Since(canceled))
+			leaves++
+			mu.Unlock()
+			return
+		}
+		for range fanout {
+			wg.Add(1)
+			go grow(ctx, level+1)
+		}
+		<-ctx.Done()
+	}
+	wg.Add(1)
+	go grow(ctx, 0)
+
+	time.Sleep(deadline)
+	mu.Lock()
+	canceled = time.Now()
+	mu.Unlock()
+	cancel()
+	wg.Wait()
+	log.Printf("canceling the root stopped all %d leaves within %v", leaves, slowest)
+}
+
+// leakedGoroutines waits briefly for the goroutine count to fall back to
+// before and returns how many are left over.
+func leakedGoroutines(before int) int {
+	var n int
+	for range 100 {
+		if n = runtime.NumGoroutine() - before; n <= 0 {
+			return 0
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return n
+}
@@ -1,85 +1,263 @@
 package main
 
 import (
+	"flag"
+	"fmt"
+	"io"
 	"log"
 	"math/rand"
+	"os"
+	"strings"
 	"sync"
 	"time"
 )
 
-// play with channels
+// play with channels: producers send jobs into a buffered channel,
+// consumers take them out as they come, do them and send the results on,
+// and main prints the results as they arrive. When the consumers are
+// slower than the producers the buffer fills up and the producers block
+// on their sends, which is the backpressure channels give for free;
+// -timeline draws who was working, blocked or waiting when.
+//
+//	channels [-producers 3] [-consumers 2] [-jobs 12] [-buffer 2] [-results 0] [-timeline]
+
+// rng picks the sleeps; -seed makes them repeat from run to run.
+var (
+	rngMu sync.Mutex
+	rng   *rand.Rand
+)
+
+// what a goroutine is doing, as drawn on the timeline
 const (
-	bufferSize  = 5
-	workerCount = 2
-	jobCount    = 3
+	working = '='
+	sending = '>' // blocked on a send, the buffer full
+	waiting = '.' // blocked on a receive, nothing to do
 )
 
 func main() {
-	ch := make(chan int, bufferSize)
-	resultChan := make(chan int, bufferSize)
+	seed := flag.Int64("seed", time.Now().UnixNano(), "random seed for the sleeps")
+	producers := flag.Int("producers", 3, "goroutines producing jobs")
+	consumers := flag.Int("consumers", 2, "goroutines consuming them")
+	jobs := flag.Int("jobs", 12, "jobs to produce in all")
+	buffer := flag.Int("buffer", 2, "buffer of the jobs channel")
+	resultBuffer := flag.Int("results", 0, "buffer of the results channel")
+	produce := flag.Duration("produce", 100*time.Millisecond, "longest a job takes to produce")
+	consume := flag.Duration("consume", 300*time.Millisecond, "longest a job takes to consume")
+	timeline := flag.Bool("timeline", false, "draw who was working, blocked or waiting when")
+	flag.Parse()
+	if *producers < 1 || *consumers < 1 || *buffer < 0 || *resultBuffer < 0 {
+		log.Fatal("need at least a producer and a consumer, and buffers of 0 or more")
+	}
+	rng = rand.New(rand.NewSource(*seed))
+	log.Println("Seed:", *seed)
+
+	start := time.Now()
+	ch := make(chan int, *buffer)
+	resultChan := make(chan int, *resultBuffer)
+	fill := &levels{}
+	var tracks []*track
+
+	// the producers share out the jobs, and the last one done closes
+	// ch, which ends the consumers' loops
+	var producing sync.WaitGroup
+	for p := range *producers {
+		t := &track{name: fmt.Sprintf("producer %d", p)}
+		tracks = append(tracks, t)
+		producing.Add(1)
+		go func() {
+			defer producing.Done()
+			for job := p; job < *jobs; job += *producers {
+				began := time.Now()
+				time.Sleep(randomSleep(*produce))
+				t.add(began, working)
+
+				began = time.Now()
+				ch <- job + 1
+				t.add(began, sending)
+				fill.note(len(ch))
+				log.Printf("%s sent %d after %v blocked", t.name, job+1, time.Since(began).Round(time.Millisecond))
+			}
+		}()
+	}
+	go func() {
+		producing.Wait()
+		close(ch)
+	}()
+
+	// the consumers stream from ch to resultChan, and the last one done
+	// closes resultChan, which ends main's loop
+	var consuming sync.WaitGroup
+	for c := range *consumers {
+		t := &track{name: fmt.Sprintf("consumer %d", c)}
+		tracks = append(tracks, t)
+		consuming.Add(1)
+		go func() {
+			defer consuming.Done()
+			for {
+				began := time.Now()
+				val, ok := <-ch
+				t.add(began, waiting)
+				if !ok {
+					return
+				}
+				fill.note(len(ch))
+
+				began = time.Now()
+				time.Sleep(randomSleep(*consume))
+				t.add(began, working)
+
+				began = time.Now()
+				resultChan <- val * 2
+				t.add(began, sending)
+			}
+		}()
+	}
+	go func() {
+		consuming.Wait()
+		close(resultChan)
+	}()
+
+	results := 0
+	for result := range resultChan {
+		results++
+		log.Println("Result:", result)
+	}
+	end := time.Now()
+	log.Printf("Program completed: %d results in %v", results, end.Sub(start).Round(time.Millisecond))
+
+	summarize(os.Stdout, tracks)
+	if *timeline {
+		draw(os.Stdout, start, end, tracks, fill, 60)
+	}
+}
+
+// span is a stretch of time a goroutine spent in one state.
+type span struct {
+	start, end time.Time
+	state      byte
+}
 
-	defer close(ch)
-	defer close(resultChan)
+// track is what one goroutine did, written only by that goroutine and
+// read once it's done.
+type track struct {
+	name  string
+	spans []span
+}
 
-	var wg sync.WaitGroup
+// add records that the goroutine was in state from start until now.
+func (t *track) add(start time.Time, state byte) {
+	t.spans = append(t.spans, span{start, time.Now(), state})
+}
 
-	for i := range jobCount {
-		wg.Add(1)
-		log.Println("Main loop iteration:", i)
-		go func(param int) {
-			defer wg.Done()
-			channelWriter(ch, param)
-		}(i)
+// in returns how long the goroutine spent in state between from and to.
+func (t *track) in(state byte, from, to time.Time) time.Duration {
+	var d time.Duration
+	for _, s := range t.spans {
+		if s.state != state {
+			continue
+		}
+		lo, hi := s.start, s.end
+		if lo.Before(from) {
+			lo = from
+		}
+		if hi.After(to) {
+			hi = to
+		}
+		if hi.After(lo) {
+			d += hi.Sub(lo)
+		}
 	}
+	return d
+}
+
+// levels records how full the jobs channel was after each send and
+// receive.
+type levels struct {
+	mu     sync.Mutex
+	at     []time.Time
+	filled []int
+}
+
+func (l *levels) note(n int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.at = append(l.at, time.Now())
+	l.filled = append(l.filled, n)
+}
 
-	// wait for all writers to complete
-	wg.Wait()
-
-	// drain the channel ch with reader goroutines
-	readerWg := sync.WaitGroup{}
-drainLoop:
-	for {
-		select {
-		case val := <-ch:
-			readerWg.Add(1)
-			go func(v int) {
-				defer readerWg.Done()
-				channelReader(v, resultChan)
-			}(val)
-			log.Println("Drained value from channel:", val)
-		default:
-			log.Println("No more values to drain from channel")
-			break drainLoop
+// max returns the fullest the channel was between from and to.
+func (l *levels) max(from, to time.Time) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	n := 0
+	for i, at := range l.at {
+		switch {
+		case at.Before(from):
+			// the level carried into the window
+			n = l.filled[i]
+		case at.Before(to):
+			n = max(n, l.filled[i])
 		}
 	}
+	return n
+}
 
-	readerWg.Wait()
-	log.Println("All workers completed")
-
-	// dump results from resultChan until empty
-resultLoop:
-	for {
-		select {
-		case result := <-resultChan:
-			log.Println("Result from resultChan:", result)
-		default:
-			log.Println("No more results in resultChan")
-			break resultLoop
+// summarize writes how long the producers spent blocked on full buffers
+// and the consumers waiting for work.
+func summarize(w io.Writer, tracks []*track) {
+	fmt.Fprintln(w, "\nGoroutine     Working    Blocked sending  Waiting to receive")
+	for _, t := range tracks {
+		var total [256]time.Duration
+		for _, s := range t.spans {
+			total[s.state] += s.end.Sub(s.start)
 		}
+		fmt.Fprintf(w, "%-12s  %-9v  %-15v  %v\n", t.name,
+			total[working].Round(time.Millisecond),
+			total[sending].Round(time.Millisecond),
+			total[waiting].Round(time.Millisecond))
 	}
-	log.Println("Program completed")
 }
 
-func channelWriter(ch chan<- int, param int) {
-	log.Println("Writing to channel:", param+1)
-	// sleep for random time
-	time.Sleep(time.Duration(rand.Intn(1000)) * time.Millisecond)
-	ch <- param + 1
+// draw writes a timeline of width columns from start to end: a row per
+// goroutine showing what it did most in each column, and a row showing
+// how full the jobs buffer got.
+func draw(w io.Writer, start, end time.Time, tracks []*track, fill *levels, width int) {
+	step := end.Sub(start) / time.Duration(width)
+	if step <= 0 {
+		return
+	}
+	fmt.Fprintf(w, "\nTimeline, %v a column (%c working, %c blocked sending, %c waiting to receive):\n",
+		step.Round(time.Millisecond), working, sending, waiting)
+	for _, t := range tracks {
+		var row strings.Builder
+		for c := range width {
+			from := start.Add(time.Duration(c) * step)
+			to := from.Add(step)
+			best, most := byte(' '), time.Duration(0)
+			for _, state := range []byte{working, sending, waiting} {
+				if d := t.in(state, from, to); d > most {
+					best, most = state, d
+				}
+			}
+			row.WriteByte(best)
+		}
+		fmt.Fprintf(w, "  %-12s |%s|\n", t.name, row.String())
+	}
+	var row strings.Builder
+	for c := range width {
+		from := start.Add(time.Duration(c) * step)
+		n := fill.max(from, from.Add(step))
+		if n > 9 {
+			row.WriteByte('+')
+		} else {
+			row.WriteByte(byte('0' + n))
+		}
+	}
+	fmt.Fprintf(w, "  %-12s |%s|\n", "buffer", row.String())
 }
 
-func channelReader(val int, resultChan chan<- int) {
-	log.Println("Reading value:", val)
-	// sleep for random time
-	time.Sleep(time.Duration(rand.Intn(1000)) * time.Millisecond)
-	resultChan <- val * 2
+func randomSleep(longest time.Duration) time.Duration {
+	rngMu.Lock()
+	defer rngMu.Unlock()
+	return time.Duration(rng.Int63n(int64(longest) + 1))
 }
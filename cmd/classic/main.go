@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/aawadall/go-concurrency-patterns/classic"
+	"github.com/aawadall/go-concurrency-patterns/config"
+	"github.com/aawadall/go-concurrency-patterns/shared"
+	"github.com/aawadall/go-concurrency-patterns/shared/watchdog"
+)
+
+// run classic synchronization problems for a while each and report on
+// them: one in full, several side by side; a watchdog calls a deadlock
+// when a problem makes no progress for the stall interval, which prints
+// the goroutines caught in it, ends its run early and makes the exit
+// status 1
+//
+//	classic list
+//	classic [-duration 2s] [-stall 250ms] <problem>...
+func main() {
+	duration := flag.Duration("duration", 2*time.Second, "how long to run each problem")
+	stall := flag.Duration("stall", 250*time.Millisecond, "how long without progress is a deadlock")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: classic [-duration 2s] [-stall 250ms] <problem>...\n       classic list\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if flag.Arg(0) == "list" {
+		for _, p := range classic.All {
+			fmt.Println(p.Name())
+		}
+		return
+	}
+
+	var problems []classic.Problem
+	for _, name := range flag.Args() {
+		p, ok := classic.Lookup(name)
+		if !ok {
+			log.Fatalf("unknown problem %q; try classic list", name)
+		}
+		problems = append(problems, p)
+	}
+
+	cfg := config.GetDefaultConfig()
+	deadlocked := false
+	var labels []string
+	var runs []shared.Manifest
+	for _, p := range problems {
+		collector := shared.NewCollectorFor(cfg)
+		ctx, cancel := context.WithTimeout(context.Background(), *duration)
+		ctx, deadlock := context.WithCancelCause(ctx)
+		stop := watchdog.Start(watchdog.Options{
+			Interval: *stall,
+			Progress: collector.Count,
+			OnStall: func(r watchdog.Report) {
+				r.Write(os.Stderr)
+				deadlock(classic.ErrDeadlock)
+			},
+		})
+		start := time.Now()
+		err := p.Run(ctx, collector)
+		end := time.Now()
+		stop()
+		deadlock(nil)
+		cancel()
+
+		summary := collector.Summary()
+		switch {
+		case errors.Is(err, classic.ErrDeadlock):
+			deadlocked = true
+			log.Printf("%s deadlocked after %v, %d operations", p.Name(), end.Sub(start).Round(time.Millisecond), summary.Count)
+		case err != nil:
+			log.Fatal(err)
+		default:
+			log.Printf("%s ran %d operations, %d failed", p.Name(), summary.Count, summary.Errors)
+		}
+		if len(problems) == 1 {
+			if err := shared.Report(os.Stdout, shared.FormatText, summary, end.Sub(start), nil); err != nil {
+				log.Fatal(err)
+			}
+		}
+		labels = append(labels, p.Name())
+		runs = append(runs, shared.NewManifest(p.Name(), cfg, start, end, summary, nil))
+	}
+	if len(problems) > 1 {
+		shared.Compare(os.Stdout, labels, runs, 0.05)
+	}
+	if deadlocked {
+		os.Exit(1)
+	}
+}
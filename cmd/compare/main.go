@@ -0,0 +1,42 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/aawadall/go-concurrency-patterns/shared"
+)
+
+// compare prints saved results side by side, relative to the first file,
+// and exits with status 1 when any metric regressed beyond the threshold.
+func main() {
+	threshold := flag.Float64("threshold", 0.05, "relative change counted as a regression")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: compare [-threshold 0.05] baseline.json other.json...\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() < 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var labels []string
+	var runs []shared.Manifest
+	for _, path := range flag.Args() {
+		m, err := shared.LoadManifest(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		labels = append(labels, filepath.Base(path))
+		runs = append(runs, m)
+	}
+
+	if n := shared.Compare(os.Stdout, labels, runs, *threshold); n > 0 {
+		fmt.Fprintf(os.Stderr, "%d regression(s) beyond %.1f%%\n", n, *threshold*100)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import "sync"
+
+// barrier makes parties wait until all of them have arrived, then lets
+// them all go. It can be reused for phase after phase.
+type barrier interface {
+	Await()
+}
+
+// condBarrier counts arrivals under a mutex; the last to arrive starts a
+// new generation and wakes everyone with Broadcast. Waiters check the
+// generation, not the count, so a fast party arriving for the next phase
+// cannot be mistaken for a wake-up.
+type condBarrier struct {
+	mu         sync.Mutex
+	allArrived *sync.Cond
+	parties    int
+	arrived    int
+	generation int
+}
+
+func newCondBarrier(parties int) *condBarrier {
+	b := &condBarrier{parties: parties}
+	b.allArrived = sync.NewCond(&b.mu)
+	return b
+}
+
+func (b *condBarrier) Await() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	gen := b.generation
+	b.arrived++
+	if b.arrived == b.parties {
+		b.arrived = 0
+		b.generation++
+		b.allArrived.Broadcast()
+		return
+	}
+	for gen == b.generation {
+		b.allArrived.Wait()
+	}
+}
+
+// chanBarrier is the channel equivalent: each generation has a channel
+// that the last to arrive closes, which wakes every receiver at once.
+type chanBarrier struct {
+	mu      sync.Mutex
+	parties int
+	arrived int
+	release chan struct{}
+}
+
+func newChanBarrier(parties int) *chanBarrier {
+	return &chanBarrier{parties: parties, release: make(chan struct{})}
+}
+
+func (b *chanBarrier) Await() {
+	b.mu.Lock()
+	release := b.release
+	b.arrived++
+	if b.arrived == b.parties {
+		b.arrived = 0
+		b.release = make(chan struct{})
+		b.mu.Unlock()
+		close(release)
+		return
+	}
+	b.mu.Unlock()
+	<-release
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"testing"
+	"time"
+)
+
+// wake queue getters with Signal and Broadcast, run workers in phases
+// with a barrier, then benchmark the sync.Cond implementations against
+// their channel equivalents
+const (
+	getters = 5
+	items   = 1000
+	parties = 4
+	phases  = 3
+	// the wake-up demo puts fewer items, slowly enough for the getters
+	// to be waiting for each
+	slowItems = 20
+	slowPace  = time.Millisecond
+)
+
+func main() {
+	log.Println("Waking getters with Signal, then with Broadcast:")
+	for _, broadcast := range []bool{false, true} {
+		q := newCondQueue(broadcast)
+		drainSlowly(q, getters, slowItems, slowPace)
+		log.Printf("  broadcast=%-5v %d wake-ups, %d found the queue empty again",
+			broadcast, q.wakeups.Load(), q.wasted.Load())
+	}
+
+	log.Printf("Running %d workers through %d phases with a barrier:", parties, phases)
+	b := newCondBarrier(parties)
+	var wg sync.WaitGroup
+	for i := range parties {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for phase := range phases {
+				time.Sleep(time.Duration(i+1) * time.Millisecond)
+				log.Printf("  worker %d finished phase %d", i, phase)
+				b.Await()
+			}
+		}()
+	}
+	wg.Wait()
+
+	log.Println("Benchmarks:")
+	bench("queue, cond + Signal", func(b *testing.B) { drain(newCondQueue(false), getters, b.N) })
+	bench("queue, cond + Broadcast", func(b *testing.B) { drain(newCondQueue(true), getters, b.N) })
+	bench("queue, channel", func(b *testing.B) { drain(make(chanQueue, items), getters, b.N) })
+	bench("barrier, cond", func(b *testing.B) { await(newCondBarrier(parties), parties, b.N) })
+	bench("barrier, channel", func(b *testing.B) { await(newChanBarrier(parties), parties, b.N) })
+}
+
+// drain puts n items in q and takes them out with the given number of
+// getters.
+func drain(q queue, getters, n int) {
+	var wg sync.WaitGroup
+	for i := range getters {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// share the items out, the first getters taking the remainder
+			for range n/getters + min(max(n%getters-i, 0), 1) {
+				q.Get()
+			}
+		}()
+	}
+	for i := range n {
+		q.Put(i)
+	}
+	wg.Wait()
+}
+
+// drainSlowly is drain putting an item every pace, so that every getter
+// is already waiting when it arrives.
+func drainSlowly(q queue, getters, n int, pace time.Duration) {
+	var wg sync.WaitGroup
+	for range getters {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range n / getters {
+				q.Get()
+			}
+		}()
+	}
+	for i := range n / getters * getters {
+		time.Sleep(pace)
+		q.Put(i)
+	}
+	wg.Wait()
+}
+
+// await runs the parties through n phases of b.
+func await(b barrier, parties, n int) {
+	var wg sync.WaitGroup
+	for range parties {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range n {
+				b.Await()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func bench(name string, fn func(b *testing.B)) {
+	r := testing.Benchmark(fn)
+	log.Printf("  %-24s %s", name, fmt.Sprintf("%10d ns/op", r.NsPerOp()))
+}
@@ -0,0 +1,25 @@
+package main
+
+import "testing"
+
+// the benchmarks main runs, for go test -bench
+
+func BenchmarkQueueCondSignal(b *testing.B) {
+	drain(newCondQueue(false), getters, b.N)
+}
+
+func BenchmarkQueueCondBroadcast(b *testing.B) {
+	drain(newCondQueue(true), getters, b.N)
+}
+
+func BenchmarkQueueChan(b *testing.B) {
+	drain(make(chanQueue, items), getters, b.N)
+}
+
+func BenchmarkBarrierCond(b *testing.B) {
+	await(newCondBarrier(parties), parties, b.N)
+}
+
+func BenchmarkBarrierChan(b *testing.B) {
+	await(newChanBarrier(parties), parties, b.N)
+}
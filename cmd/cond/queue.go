@@ -0,0 +1,69 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// queue is a FIFO queue whose Get waits for an item.
+type queue interface {
+	Put(v int)
+	Get() int
+}
+
+// condQueue is a queue guarded by a mutex, with getters waiting on a
+// condition variable. Put wakes one getter with Signal, or every getter
+// with Broadcast if broadcast is set, in which case all but one of them
+// find the queue empty again and go back to waiting.
+type condQueue struct {
+	mu        sync.Mutex
+	nonEmpty  *sync.Cond
+	items     []int
+	broadcast bool
+
+	// wakeups counts returns from Wait, and wasted those that found
+	// nothing to take
+	wakeups atomic.Int64
+	wasted  atomic.Int64
+}
+
+func newCondQueue(broadcast bool) *condQueue {
+	q := &condQueue{broadcast: broadcast}
+	q.nonEmpty = sync.NewCond(&q.mu)
+	return q
+}
+
+func (q *condQueue) Put(v int) {
+	q.mu.Lock()
+	q.items = append(q.items, v)
+	q.mu.Unlock()
+	if q.broadcast {
+		q.nonEmpty.Broadcast()
+	} else {
+		q.nonEmpty.Signal()
+	}
+}
+
+func (q *condQueue) Get() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	// Wait must be called in a loop: by the time a woken getter holds the
+	// lock again another may have taken the item
+	for len(q.items) == 0 {
+		q.nonEmpty.Wait()
+		q.wakeups.Add(1)
+		if len(q.items) == 0 {
+			q.wasted.Add(1)
+		}
+	}
+	v := q.items[0]
+	q.items = q.items[1:]
+	return v
+}
+
+// chanQueue is the channel equivalent: the runtime wakes exactly one
+// receiver per value. Unlike condQueue it is bounded.
+type chanQueue chan int
+
+func (q chanQueue) Put(v int) { q <- v }
+func (q chanQueue) Get() int  { return <-q }
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// build a tree of contexts with values, deadlines and cancellations at
+// different levels, run a worker on every node until its context is
+// done, and draw the tree with when each worker stopped and why. Every
+// timeout and cancel has a cause naming the node it came from, so it
+// shows where a cancellation started and how far down it went; -scale
+// stretches or shrinks every duration
+//
+//	contexts [-scale 1]
+func main() {
+	scale := flag.Float64("scale", 1, "factor for every duration")
+	flag.Parse()
+	if *scale <= 0 {
+		fmt.Fprintln(os.Stderr, "contexts: -scale must be positive")
+		os.Exit(2)
+	}
+	at := func(d time.Duration) time.Duration { return time.Duration(float64(d) * *scale) }
+
+	root := tree()
+	start := time.Now()
+	var wg sync.WaitGroup
+	root.start(context.Background(), start, at, &wg)
+	wg.Wait()
+	took := time.Since(start)
+
+	fmt.Printf("Context tree, %v (%c working, %c where it stopped):\n", took.Round(time.Millisecond), working, stopped)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "context\tmade with\tdeadline\tvalues\ttimeline\tstopped\twhy\n")
+	root.draw(w, "", "", took, at)
+	w.Flush()
+}
+
+const (
+	working = '='
+	stopped = '|'
+	width   = 40
+	// how often a worker does a round of work
+	round = 10 * time.Millisecond
+)
+
+// key is the type of the context value keys, so they can't collide with
+// another package's.
+type key string
+
+// node is a context in the tree, made from its parent's, and the worker
+// running on it.
+type node struct {
+	name string
+	// made with, in this order: WithoutCancel if detach, WithValue if
+	// key is set, WithTimeoutCause if timeout is set, and
+	// WithCancelCause, canceled cancelAt into the run, if that is set
+	detach   bool
+	key      key
+	value    string
+	timeout  time.Duration
+	cancelAt time.Duration
+	children []*node
+
+	// what the worker saw
+	deadline   time.Duration // from the start; 0 for none
+	values     []string
+	ended      time.Duration
+	err, cause error
+}
+
+// tree is the demo's tree: a request with a deadline, a database call
+// with a longer one that the request's cuts short, a cache lookup with a
+// shorter one, a fan-out to replicas called off once the first answers,
+// an audit log detached from the request so it outlives it, and
+// background work that lasts until the whole run is called off.
+func tree() *node {
+	return &node{name: "run", key: "trace", value: "t-1", cancelAt: time.Second, children: []*node{
+		{name: "request", key: "user", value: "ada", timeout: 600 * time.Millisecond, children: []*node{
+			{name: "db", timeout: time.Second, children: []*node{
+				{name: "query", key: "table", value: "orders"},
+			}},
+			{name: "cache", timeout: 100 * time.Millisecond},
+			{name: "fan-out", cancelAt: 300 * time.Millisecond, children: []*node{
+				{name: "replica-1", key: "replica", value: "1"},
+				{name: "replica-2", key: "replica", value: "2", timeout: 200 * time.Millisecond},
+			}},
+			{name: "audit", detach: true, timeout: 1200 * time.Millisecond},
+		}},
+		{name: "background", children: []*node{
+			{name: "metrics"},
+		}},
+	}}
+}
+
+// start makes n's context from parent, starts its worker and its
+// children's, and cancels it when it is due to be.
+func (n *node) start(parent context.Context, start time.Time, at func(time.Duration) time.Duration, wg *sync.WaitGroup) {
+	ctx, release := parent, context.CancelFunc(func() {})
+	if n.detach {
+		ctx = context.WithoutCancel(ctx)
+	}
+	if n.key != "" {
+		ctx = context.WithValue(ctx, n.key, n.value)
+	}
+	if n.timeout > 0 {
+		ctx, release = context.WithTimeoutCause(ctx, at(n.timeout),
+			fmt.Errorf("%s's timeout of %v", n.name, at(n.timeout)))
+	}
+	if n.cancelAt > 0 {
+		var cancel context.CancelCauseFunc
+		ctx, cancel = context.WithCancelCause(ctx)
+		time.AfterFunc(at(n.cancelAt), func() {
+			cancel(fmt.Errorf("%s canceled at %v", n.name, at(n.cancelAt)))
+		})
+	}
+
+	if d, ok := ctx.Deadline(); ok {
+		n.deadline = d.Sub(start)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		// the timeout's timer is released once the worker has stopped
+		defer release()
+		n.work(ctx, start)
+	}()
+	for _, c := range n.children {
+		c.start(ctx, start, at, wg)
+	}
+}
+
+// work does rounds of work until ctx is done, and records what it saw.
+func (n *node) work(ctx context.Context, start time.Time) {
+	for _, k := range []key{"trace", "user", "table", "replica"} {
+		if v, ok := ctx.Value(k).(string); ok {
+			n.values = append(n.values, fmt.Sprintf("%s=%s", k, v))
+		}
+	}
+	t := time.NewTicker(round)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			n.ended = time.Since(start)
+			n.err, n.cause = ctx.Err(), context.Cause(ctx)
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// draw writes n's row and its children's, with the tree's branches in
+// front of the names.
+func (n *node) draw(w *tabwriter.Writer, prefix, branch string, took time.Duration, at func(time.Duration) time.Duration) {
+	var made []string
+	if n.detach {
+		made = append(made, "WithoutCancel")
+	}
+	if n.key != "" {
+		made = append(made, "WithValue")
+	}
+	if n.timeout > 0 {
+		made = append(made, fmt.Sprintf("WithTimeout %v", at(n.timeout)))
+	}
+	if n.cancelAt > 0 {
+		made = append(made, fmt.Sprintf("WithCancelCause @%v", at(n.cancelAt)))
+	}
+	if len(made) == 0 {
+		made = append(made, "-")
+	}
+
+	deadline := "none"
+	if n.deadline > 0 {
+		deadline = n.deadline.Round(time.Millisecond).String()
+		if n.timeout > 0 && n.deadline < at(n.timeout)-time.Millisecond {
+			// WithTimeout can't extend the parent's deadline
+			deadline += " (parent's)"
+		} else if n.timeout == 0 {
+			deadline += " (inherited)"
+		}
+	}
+
+	col := min(int(int64(n.ended)*width/int64(took)), width-1)
+	bar := strings.Repeat(string(working), col) + string(stopped) + strings.Repeat(" ", width-col-1)
+
+	why := "canceled: " + n.cause.Error()
+	if errors.Is(n.err, context.DeadlineExceeded) {
+		why = "deadline: " + n.cause.Error()
+	}
+
+	fmt.Fprintf(w, "%s%s\t%s\t%s\t%s\t%s\t%v\t%s\n", prefix+branch, n.name, strings.Join(made, ", "), deadline,
+		strings.Join(n.values, " "), bar, n.ended.Round(time.Millisecond), why)
+
+	// the children line up under the name, with a line down on the
+	// left while there are siblings still to come
+	switch branch {
+	case "├─ ":
+		prefix += "│  "
+	case "└─ ":
+		prefix += "   "
+	}
+	for i, c := range n.children {
+		b := "├─ "
+		if i == len(n.children)-1 {
+			b = "└─ "
+		}
+		c.draw(w, prefix, b, took, at)
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/aawadall/go-concurrency-patterns/cmd/pipelines/pipeline"
+)
+
+// crawl a site with a pipeline whose output feeds back into its input:
+// pages are fetched, their links extracted, and links not seen before and
+// within the depth limit go back in to be fetched, each host no faster
+// than the politeness rate; with no -url a small site spread over three
+// local hosts is served to crawl
+func main() {
+	root := flag.String("url", "", "page to start from; empty to crawl a built-in local site")
+	depth := flag.Int("depth", 3, "how many links deep to follow")
+	fetchers := flag.Int("fetchers", 8, "pages fetched at once")
+	perHost := flag.Float64("per-host", 50, "requests per second allowed to each host")
+	flag.Parse()
+
+	if *root == "" {
+		*root = serveSite(3, 200)
+	}
+	start, err := url.Parse(*root)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c := &crawler{client: &http.Client{Timeout: 10 * time.Second}, perHost: rate.Limit(*perHost), limiters: make(map[string]*rate.Limiter)}
+	fetch := pipeline.Stage[link, page]{
+		Name:    "fetch",
+		Workers: *fetchers,
+		Buffer:  *fetchers,
+		Function: func(m pipeline.Message[link]) (pipeline.Message[page], error) {
+			return pipeline.Message[page]{ID: m.ID, Payload: c.fetch(ctx, m.Payload)}, nil
+		},
+	}
+	extract := pipeline.Stage[page, page]{
+		Name:    "extract",
+		Workers: 2,
+		Buffer:  *fetchers,
+		Function: func(m pipeline.Message[page]) (pipeline.Message[page], error) {
+			m.Payload.links = extractLinks(m.Payload)
+			m.Payload.body = nil
+			return m, nil
+		},
+	}
+
+	began := time.Now()
+	source := make(chan pipeline.Message[link])
+	fetched, fetchGroup := fetch.Run(ctx, source)
+	pages, extractGroup := extract.Run(ctx, fetched)
+	st := feed(source, pages, link{url: start, depth: 0}, *depth)
+	for _, g := range []interface{ Wait() error }{fetchGroup, extractGroup} {
+		if err := g.Wait(); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	elapsed := time.Since(began)
+	for _, host := range slices.Sorted(maps.Keys(st.perHost)) {
+		n := st.perHost[host]
+		log.Printf("%-22s %4d pages, %.1f/s", host, n, float64(n)/elapsed.Seconds())
+	}
+	log.Printf("%d pages in %v, %d failed; %d links seen again, %d beyond depth %d, %d pages at the deepest level",
+		st.fetched, elapsed.Round(time.Millisecond), st.failed, st.duplicates, st.tooDeep, *depth, st.deepest)
+}
+
+// link is a page to fetch, found depth links from the start.
+type link struct {
+	url   *url.URL
+	depth int
+}
+
+// page is a fetched page.
+type page struct {
+	link
+	body  []byte
+	err   error
+	links []*url.URL
+}
+
+// crawler fetches pages, keeping to a rate per host.
+type crawler struct {
+	client  *http.Client
+	perHost rate.Limit
+
+	mu       sync.Mutex
+	limiters map[string]*rate.Limiter
+}
+
+func (c *crawler) limiter(host string) *rate.Limiter {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	l, ok := c.limiters[host]
+	if !ok {
+		l = rate.NewLimiter(c.perHost, 1)
+		c.limiters[host] = l
+	}
+	return l
+}
+
+// fetch gets a page, waiting its turn for the host first. Failures are
+// kept in the page rather than stopping the crawl.
+func (c *crawler) fetch(ctx context.Context, l link) page {
+	p := page{link: l}
+	if p.err = c.limiter(l.url.Host).Wait(ctx); p.err != nil {
+		return p
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, l.url.String(), nil)
+	if err != nil {
+		p.err = err
+		return p
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		p.err = err
+		return p
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		p.err = fmt.Errorf("%s: %s", l.url, resp.Status)
+		return p
+	}
+	p.body, p.err = io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	return p
+}
+
+var hrefs = regexp.MustCompile(`href="([^"#]+)"`)
+
+// extractLinks returns the http links on a page, resolved against its
+// URL.
+func extractLinks(p page) []*url.URL {
+	var links []*url.URL
+	for _, m := range hrefs.FindAllSubmatch(p.body, -1) {
+		u, err := p.url.Parse(string(m[1]))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			continue
+		}
+		links = append(links, u)
+	}
+	return links
+}
+
+// stats is what a crawl did.
+type stats struct {
+	fetched, failed, duplicates, tooDeep, deepest int
+	perHost                                       map[string]int
+}
+
+// feed closes the loop of the pipeline: it sends start into source, and
+// the links found on every page coming out of pages that haven't been
+// seen and are within maxDepth back into it. Links waiting to go in are
+// queued here rather than blocking, so the loop can't deadlock with the
+// stages all waiting to send, and source is closed once every link sent
+// has come back out as a page.
+func feed(source chan<- pipeline.Message[link], pages <-chan pipeline.Message[page], start link, maxDepth int) stats {
+	st := stats{perHost: make(map[string]int)}
+	seen := map[string]bool{start.url.String(): true}
+	queue := []link{start}
+	pending := 1 // links queued or in the pipeline
+	var id int64
+	for pending > 0 {
+		var send chan<- pipeline.Message[link]
+		var next pipeline.Message[link]
+		if len(queue) > 0 {
+			send = source
+			next = pipeline.Message[link]{ID: id, Payload: queue[0]}
+		}
+		select {
+		case send <- next:
+			queue = queue[1:]
+			id++
+		case m := <-pages:
+			pending--
+			p := m.Payload
+			st.fetched++
+			if p.err != nil {
+				st.failed++
+				continue
+			}
+			st.perHost[p.url.Host]++
+			if p.depth == maxDepth {
+				st.deepest++
+			}
+			for _, u := range p.links {
+				switch {
+				case seen[u.String()]:
+					st.duplicates++
+				case p.depth+1 > maxDepth:
+					st.tooDeep++
+				default:
+					seen[u.String()] = true
+					queue = append(queue, link{url: u, depth: p.depth + 1})
+					pending++
+				}
+			}
+		}
+	}
+	close(source)
+	return st
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// serveSite serves a site of the given number of pages spread over hosts
+// local servers, every page linking to a few others on its own host and
+// the next, and returns the URL of the first page.
+func serveSite(hosts, pages int) string {
+	addrs := make([]string, hosts)
+	listeners := make([]net.Listener, hosts)
+	for i := range hosts {
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			log.Fatal(err)
+		}
+		listeners[i] = lis
+		addrs[i] = lis.Addr().String()
+	}
+	// page n is served by host n % hosts
+	pageURL := func(n int) string {
+		return fmt.Sprintf("http://%s/page/%d", addrs[n%hosts], n)
+	}
+	for _, lis := range listeners {
+		mux := http.NewServeMux()
+		mux.HandleFunc("GET /page/{n}", func(w http.ResponseWriter, r *http.Request) {
+			n, err := strconv.Atoi(r.PathValue("n"))
+			if err != nil || n < 0 || n >= pages {
+				http.NotFound(w, r)
+				return
+			}
+			var b strings.Builder
+			fmt.Fprintf(&b, "<html><body><h1>Page %d</h1>\n", n)
+			for _, next := range []int{2*n + 1, 2*n + 2, n + hosts, (n * 7) % pages} {
+				if next < pages {
+					fmt.Fprintf(&b, "<a href=\"%s\">page %d</a>\n", pageURL(next), next)
+				}
+			}
+			// a relative link back to the start, and one to a page that
+			// isn't there
+			b.WriteString("<a href=\"/page/0\">home</a>\n")
+			fmt.Fprintf(&b, "<a href=\"/page/%d\">missing</a>\n", pages+n)
+			b.WriteString("</body></html>\n")
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(b.String()))
+		})
+		go http.Serve(lis, mux)
+	}
+	return pageURL(0)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/aawadall/go-concurrency-patterns/chanx"
+)
+
+// feed bursts of events to a debouncer and a throttler side by side
+func main() {
+	bursts := flag.Int("bursts", 4, "bursts of events")
+	burstSize := flag.Int("burst-size", 10, "events per burst")
+	gap := flag.Duration("gap", 5*time.Millisecond, "time between events in a burst")
+	pause := flag.Duration("pause", 200*time.Millisecond, "quiet time between bursts")
+	quiet := flag.Duration("quiet", 50*time.Millisecond, "quiet period the debouncer waits for")
+	interval := flag.Duration("interval", 20*time.Millisecond, "interval the throttler allows one event per")
+	flag.Parse()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	start := time.Now()
+	events := source(*bursts, *burstSize, *gap, *pause)
+	split := chanx.Tee(ctx, events, 2)
+
+	var wg sync.WaitGroup
+	for _, s := range []struct {
+		name string
+		out  <-chan int
+	}{
+		{"debounce", chanx.Debounce(ctx, split[0], *quiet)},
+		{"throttle", chanx.Throttle(ctx, split[1], *interval)},
+	} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			n := 0
+			for v := range s.out {
+				n++
+				log.Printf("%-8s event %3d at %v", s.name, v, time.Since(start).Round(time.Millisecond))
+			}
+			log.Printf("%-8s passed %d of %d events", s.name, n, *bursts**burstSize)
+		}()
+	}
+	wg.Wait()
+}
+
+// source sends numbered events in bursts, then closes.
+func source(bursts, size int, gap, pause time.Duration) <-chan int {
+	out := make(chan int)
+	go func() {
+		defer close(out)
+		n := 0
+		for b := range bursts {
+			if b > 0 {
+				time.Sleep(pause)
+			}
+			for range size {
+				n++
+				out <- n
+				time.Sleep(gap)
+			}
+		}
+	}()
+	return out
+}
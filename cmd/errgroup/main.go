@@ -0,0 +1,20 @@
+package main
+
+import (
+	"os"
+
+	"github.com/aawadall/go-concurrency-patterns/patterns"
+	"github.com/aawadall/go-concurrency-patterns/runner"
+)
+
+// Same as gcp run errgroup, or gcp run errgroup-tolerant when the first
+// argument is "tolerant".
+func main() {
+	args := os.Args[1:]
+	p := patterns.ErrGroup{}
+	if len(args) > 0 && args[0] == "tolerant" {
+		p.Tolerant = true
+		args = args[1:]
+	}
+	runner.Main(p, args)
+}
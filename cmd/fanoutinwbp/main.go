@@ -1,114 +1,13 @@
 package main
 
 import (
-	"runtime"
-	"time"
+	"os"
 
-	"github.com/aawadall/go-concurrency-patterns/config"
-	"github.com/aawadall/go-concurrency-patterns/shared"
+	"github.com/aawadall/go-concurrency-patterns/patterns"
+	"github.com/aawadall/go-concurrency-patterns/runner"
 )
 
+// Same as gcp run fanoutinwbp.
 func main() {
-	// this is a fan out fan in based client with backpressure signaling
-	cfg := config.GetDefaultConfig()
-
-	// Initial memory stats
-	var m1 runtime.MemStats
-	runtime.GC()
-	runtime.ReadMemStats(&m1)
-
-	var latencies []time.Duration
-	var statuses []int
-	startTime := time.Now()
-
-	// define request channel
-	requests := make(chan struct{}, cfg.Requests)
-
-	// define response channel
-	type response struct {
-		latency time.Duration
-		status  int
-	}
-	responses := make(chan response, cfg.Requests)
-	backpressure := make(chan struct{}, cfg.Concurrency)
-
-	// fan out
-	for i := 0; i < cfg.Concurrency; i++ {
-		go func() {
-			for range requests {
-				backpressure <- struct{}{}
-				latency, status := shared.ConsumeServer(cfg)
-				responses <- response{latency: latency, status: status}
-			}
-		}()
-
-		// if bacpressure signal sent, cool down
-		go func() {
-			timeout := time.Duration(0)
-			signalCount := 0
-			lastCheck := time.Now()
-			
-			for range backpressure {
-				signalCount++
-				
-				// Check every 100ms to adjust timeout
-				if time.Since(lastCheck) >= 100*time.Millisecond {
-					if signalCount > 10 { // High pressure
-						timeout = min(timeout+time.Millisecond, 50*time.Millisecond)
-					} else if signalCount < 3 { // Low pressure
-						timeout = max(timeout-time.Millisecond, 0)
-					}
-					signalCount = 0
-					lastCheck = time.Now()
-				}
-				
-				if timeout > 0 {
-					time.Sleep(timeout)
-				}
-				<-backpressure
-			}
-		}()
-	}
-
-	// send requests
-	go func() {
-		for i := 0; i < cfg.Requests; i++ {
-			requests <- struct{}{}
-		}
-		close(requests)
-	}()
-
-	// collect responses
-	for i := 0; i < cfg.Requests; i++ {
-		resp := <-responses
-		latencies = append(latencies, resp.latency)
-		statuses = append(statuses, resp.status)
-	}
-	close(responses)
-
-	// fan in complete
-
-	totalTime := time.Since(startTime)
-
-	// Final memory stats
-	var m2 runtime.MemStats
-	runtime.GC()
-	runtime.ReadMemStats(&m2)
-
-	// Calculate memory usage
-	memUsed := m2.Alloc - m1.Alloc
-	peakMem := m2.Sys
-
-	memProfile := map[string]uint64{
-		"InitialAlloc": m1.Alloc,
-		"FinalAlloc":   m2.Alloc,
-		"MemUsed":      memUsed,
-		"TotalAlloc":   m2.TotalAlloc,
-		"Sys":          m2.Sys,
-		"NumGC":        uint64(m2.NumGC),
-		"PeakMem":      peakMem,
-	}
-
-	shared.Report(latencies, statuses, totalTime, memProfile)
-
+	runner.Main(patterns.FanOutInBackPressure{}, os.Args[1:])
 }
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/aawadall/go-concurrency-patterns/cmd/pipelines/pipeline"
+)
+
+// walk a directory tree concurrently and run every file through a
+// pipeline that reads it, hashes it and greps it, each stage with its own
+// bounded number of workers, then report the throughput
+func main() {
+	dir := flag.String("dir", ".", "directory to walk")
+	pattern := flag.String("grep", "", "regular expression to count matching lines of, if any")
+	walkers := flag.Int("walkers", 4, "directories read at once")
+	readers := flag.Int("readers", 8, "files read at once")
+	hashers := flag.Int("hashers", runtime.GOMAXPROCS(0), "files hashed at once")
+	greppers := flag.Int("greppers", runtime.GOMAXPROCS(0), "files grepped at once")
+	maxSize := flag.Int64("max-size", 16<<20, "skip files larger than this many bytes")
+	verbose := flag.Bool("v", false, "print every file's hash")
+	flag.Parse()
+
+	var re *regexp.Regexp
+	if *pattern != "" {
+		var err error
+		if re, err = regexp.Compile(*pattern); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	start := time.Now()
+
+	paths := make(chan pipeline.Message[string], *readers)
+	var skipped atomic.Int64
+	var walkErr error
+	go func() {
+		defer close(paths)
+		walkErr = walk(ctx, *dir, *walkers, *maxSize, &skipped, paths)
+	}()
+
+	read := pipeline.Stage[string, file]{
+		Name:    "read",
+		Workers: *readers,
+		Buffer:  *readers,
+		Function: func(m pipeline.Message[string]) (pipeline.Message[file], error) {
+			data, err := os.ReadFile(m.Payload)
+			return pipeline.Message[file]{ID: m.ID, Payload: file{path: m.Payload, data: data}}, err
+		},
+	}
+	hash := pipeline.Stage[file, file]{
+		Name:    "hash",
+		Workers: *hashers,
+		Buffer:  *hashers,
+		Function: func(m pipeline.Message[file]) (pipeline.Message[file], error) {
+			sum := sha256.Sum256(m.Payload.data)
+			m.Payload.sum = hex.EncodeToString(sum[:])
+			return m, nil
+		},
+	}
+	grep := pipeline.Stage[file, file]{
+		Name:    "grep",
+		Workers: *greppers,
+		Buffer:  *greppers,
+		Function: func(m pipeline.Message[file]) (pipeline.Message[file], error) {
+			if re != nil {
+				for line := range bytes.Lines(m.Payload.data) {
+					if re.Match(line) {
+						m.Payload.matches++
+					}
+				}
+			}
+			return m, nil
+		},
+	}
+
+	read1, readGroup := read.Run(ctx, paths)
+	hashed, hashGroup := hash.Run(ctx, read1)
+	grepped, grepGroup := grep.Run(ctx, hashed)
+
+	var files, size, matches, matchingFiles int64
+	for m := range grepped {
+		f := m.Payload
+		files++
+		size += int64(len(f.data))
+		if f.matches > 0 {
+			matches += int64(f.matches)
+			matchingFiles++
+			fmt.Printf("%s: %d matching lines\n", f.path, f.matches)
+		}
+		if *verbose {
+			fmt.Printf("%s  %s\n", f.sum, f.path)
+		}
+	}
+	for _, g := range []*errgroup.Group{readGroup, hashGroup, grepGroup} {
+		if err := g.Wait(); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if walkErr != nil {
+		log.Fatal(walkErr)
+	}
+
+	elapsed := time.Since(start)
+	log.Printf("%d files, %.1f MB in %v: %.0f files/s, %.1f MB/s; %d skipped as too large",
+		files, float64(size)/1e6, elapsed.Round(time.Millisecond),
+		float64(files)/elapsed.Seconds(), float64(size)/1e6/elapsed.Seconds(), skipped.Load())
+	if re != nil {
+		log.Printf("%d lines in %d files match %s", matches, matchingFiles, re)
+	}
+}
+
+// file is a file on its way through the pipeline.
+type file struct {
+	path    string
+	data    []byte
+	sum     string
+	matches int
+}
+
+// walk sends the path of every regular file under root to out, reading
+// up to walkers directories at once. A directory found while all the
+// walkers are busy is read by the walker that found it, so they never
+// wait on each other.
+func walk(ctx context.Context, root string, walkers int, maxSize int64, skipped *atomic.Int64, out chan<- pipeline.Message[string]) error {
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(walkers)
+	var id atomic.Int64
+
+	var readDir func(dir string) error
+	readDir = func(dir string) error {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			path := filepath.Join(dir, e.Name())
+			switch {
+			case e.IsDir():
+				if !g.TryGo(func() error { return readDir(path) }) {
+					if err := readDir(path); err != nil {
+						return err
+					}
+				}
+			case e.Type().IsRegular():
+				if info, err := e.Info(); err == nil && info.Size() > maxSize {
+					skipped.Add(1)
+					continue
+				}
+				select {
+				case out <- pipeline.Message[string]{ID: id.Add(1), Payload: path}:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+		}
+		return nil
+	}
+
+	g.Go(func() error { return readDir(root) })
+	return g.Wait()
+}
@@ -0,0 +1,13 @@
+package main
+
+import (
+	"os"
+
+	"github.com/aawadall/go-concurrency-patterns/patterns"
+	"github.com/aawadall/go-concurrency-patterns/runner"
+)
+
+// Same as gcp run futures.
+func main() {
+	runner.Main(patterns.Futures{}, os.Args[1:])
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/aawadall/go-concurrency-patterns/config"
+	"github.com/aawadall/go-concurrency-patterns/patterns"
+	"github.com/aawadall/go-concurrency-patterns/runner"
+)
+
+// gcp runs any of the pattern clients:
+//
+//	gcp run <pattern> [flags]
+//	gcp run -config experiments.yaml -scenario soak [flags]
+//	gcp run <pattern> -agents host1:7070,host2:7070 [flags]
+//	gcp agent [-addr :7070]
+//	gcp list
+//	gcp scenarios <file>
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	switch os.Args[1] {
+	case "list":
+		for _, name := range patterns.Names() {
+			fmt.Println(name)
+		}
+	case "scenarios":
+		if len(os.Args) < 3 {
+			usage()
+		}
+		f, err := config.Load(os.Args[2])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		for _, name := range f.ScenarioNames() {
+			fmt.Println(name)
+		}
+	case "agent":
+		fs := flag.NewFlagSet("agent", flag.ExitOnError)
+		addr := fs.String("addr", ":7070", "address to take runs from a controller on")
+		fs.Parse(os.Args[2:])
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		if err := runner.ServeAgent(ctx, *addr, patterns.Lookup); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	case "run":
+		args := os.Args[2:]
+		var name string
+		if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+			name, args = args[0], args[1:]
+		}
+		cfg := runner.Flags(args)
+		if name == "" {
+			name = cfg.Pattern
+		}
+		p, ok := patterns.Lookup(name)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "unknown pattern %q\n", name)
+			usage()
+		}
+		runner.MainWithConfig(p, cfg)
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: gcp run <pattern> [flags]\n       gcp agent [-addr :7070]\n       gcp list\n       gcp scenarios <file>\npatterns: %v\n", patterns.Names())
+	os.Exit(2)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/aawadall/go-concurrency-patterns/config"
+	"github.com/aawadall/go-concurrency-patterns/generator"
+	"github.com/aawadall/go-concurrency-patterns/shared"
+)
+
+// compose generators with a pipeline stage: number simulated requests
+// until the first failure, or until enough have been read
+const maxRequests = 50
+
+func main() {
+	cfg := config.GetDefaultConfig()
+	cfg.Mode = "sim"
+	cfg.Quiet = true
+	cfg.SimErrorRate = 0.05
+	target, err := shared.NewSimTarget(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// canceling ctx stops every generator, however far ahead they are
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	requests := generator.FromFunc(ctx, func() (shared.Result, bool) {
+		r, _ := target.Do(ctx)
+		return r, ctx.Err() == nil
+	})
+	succeeded := generator.TakeWhile(ctx, requests, func(r shared.Result) bool {
+		return r.ErrorClass == shared.ClassNone
+	})
+	numbered := generator.Zip(ctx, generator.Ints(ctx, 1, 1), latencies(ctx, succeeded))
+
+	var total time.Duration
+	n := 0
+	for p := range numbered {
+		log.Printf("request %2d took %v", p.First, p.Second)
+		total += p.Second
+		n = p.First
+		if n == maxRequests {
+			log.Printf("read %d requests, canceling the rest", maxRequests)
+			break
+		}
+	}
+	if n < maxRequests {
+		log.Printf("request %d failed, ending the requests", n+1)
+	}
+	log.Printf("total latency %v", total)
+}
+
+// latencies is a pipeline stage turning results into their latencies.
+func latencies(ctx context.Context, results <-chan shared.Result) <-chan time.Duration {
+	out := make(chan time.Duration)
+	go func() {
+		defer close(out)
+		for r := range results {
+			select {
+			case out <- r.Latency:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
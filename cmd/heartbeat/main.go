@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aawadall/go-concurrency-patterns/config"
+	"github.com/aawadall/go-concurrency-patterns/heartbeat"
+	"github.com/aawadall/go-concurrency-patterns/shared"
+)
+
+// run supervised workers making simulated requests, a few of which hang;
+// a worker stuck on one stops beating and is restarted
+func main() {
+	workers := flag.Int("workers", 4, "supervised workers")
+	duration := flag.Duration("duration", 3*time.Second, "how long to run")
+	timeout := flag.Duration("timeout", 100*time.Millisecond, "how long a worker may go without a heartbeat")
+	hangs := flag.Float64("hangs", 0.01, "fraction of requests that hang")
+	flag.Parse()
+
+	cfg := config.GetDefaultConfig()
+	cfg.Mode = "sim"
+	cfg.Quiet = true
+	cfg.SimDistribution = "bimodal"
+	cfg.SimSlowLatency = time.Hour
+	cfg.SimSlowFraction = *hangs
+	target, err := shared.NewSimTarget(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *duration)
+	defer cancel()
+
+	var completed, restarts atomic.Int64
+	var wg sync.WaitGroup
+	for i := range *workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s := &heartbeat.Supervisor{
+				Timeout:     *timeout,
+				MaxRestarts: -1,
+				OnRestart: func(n int) {
+					restarts.Add(1)
+					log.Printf("worker %d stalled, restart %d", i, n)
+				},
+			}
+			s.Run(ctx, func(ctx context.Context, beat func()) error {
+				ctx = shared.WithWorkerID(ctx, i)
+				for ctx.Err() == nil {
+					if r, _ := target.Do(ctx); r.ErrorClass != shared.ClassCanceled {
+						completed.Add(1)
+					}
+					beat()
+				}
+				return ctx.Err()
+			})
+		}()
+	}
+	wg.Wait()
+	log.Printf("%d requests completed, %d stalled workers restarted", completed.Load(), restarts.Load())
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aawadall/go-concurrency-patterns/config"
+	"github.com/aawadall/go-concurrency-patterns/queue"
+	"github.com/aawadall/go-concurrency-patterns/shared"
+)
+
+// payment is a job: charge amount once, whatever the queue does.
+type payment struct {
+	amount int64
+}
+
+// ledger applies payments. A naive ledger applies every one it is given;
+// an idempotent one remembers which it has applied, and skips those.
+type ledger struct {
+	idempotent bool
+
+	mu         sync.Mutex
+	total      int64
+	applied    map[uint64]bool
+	duplicates int
+}
+
+func (l *ledger) apply(id uint64, p payment) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	// checking and applying under one lock stands for doing both in one
+	// transaction; if they could be torn apart, so could the guarantee
+	if l.idempotent {
+		if l.applied[id] {
+			l.duplicates++
+			return
+		}
+		l.applied[id] = true
+	}
+	l.total += p.amount
+}
+
+// run workers taking payments from a queue.Leased, making a simulated
+// request for each and applying it to two ledgers; some workers crash
+// before acking and some take longer than the visibility timeout, so
+// payments are redelivered, and only the idempotent ledger ends up right
+func main() {
+	payments := flag.Int("payments", 1000, "payments to process")
+	workers := flag.Int("workers", 8, "workers processing payments")
+	visibility := flag.Duration("visibility", 50*time.Millisecond, "how long a worker's lease on a payment lasts")
+	crash := flag.Float64("crash", 0.02, "share of payments whose worker crashes after applying them but before acking")
+	slow := flag.Float64("slow", 0.02, "share of payments whose worker overruns the visibility timeout")
+	flag.Parse()
+
+	cfg := config.GetDefaultConfig()
+	cfg.Mode = "sim"
+	cfg.Quiet = true
+	target, err := shared.NewSimTarget(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	q := queue.NewLeased[payment](*visibility)
+	var expected int64
+	for range *payments {
+		p := payment{amount: rand.Int64N(100) + 1}
+		expected += p.amount
+		if err := q.Put(p); err != nil {
+			log.Fatal(err)
+		}
+	}
+	q.Close()
+
+	naive := &ledger{}
+	idempotent := &ledger{idempotent: true, applied: make(map[uint64]bool)}
+	var crashed, overran, redelivered atomic.Int64
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := range *workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx := shared.WithWorkerID(context.Background(), i)
+			for {
+				l, err := q.Get(ctx)
+				if errors.Is(err, queue.ErrClosed) {
+					return
+				}
+				if l.Receives > 1 {
+					redelivered.Add(1)
+				}
+				// the request is the payment's side effect elsewhere, and
+				// is repeated along with it
+				target.Do(ctx)
+				if rand.Float64() < *slow {
+					overran.Add(1)
+					time.Sleep(*visibility * 2)
+				}
+				naive.apply(l.ID, l.Value)
+				idempotent.apply(l.ID, l.Value)
+				if rand.Float64() < *crash {
+					// a new worker takes over; the lease runs out
+					crashed.Add(1)
+					continue
+				}
+				if err := q.Ack(l); err != nil && !errors.Is(err, queue.ErrLeaseExpired) {
+					log.Fatal(err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	st := q.Stats()
+	log.Printf("%d payments by %d workers in %v", *payments, *workers, time.Since(start).Round(time.Millisecond))
+	log.Printf("  leases %d, acked %d, expired %d, late acks %d", st.Gets, st.Acks, st.Expired, st.LateAcks)
+	log.Printf("  crashed %d, overran %d, redelivered %d", crashed.Load(), overran.Load(), redelivered.Load())
+	log.Printf("  expected total   %d", expected)
+	log.Printf("  naive ledger     %d (%+d)", naive.total, naive.total-expected)
+	log.Printf("  idempotent       %d (%+d), %d duplicates skipped", idempotent.total, idempotent.total-expected, idempotent.duplicates)
+}
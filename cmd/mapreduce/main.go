@@ -0,0 +1,205 @@
+package main
+
+import (
+	"cmp"
+	"flag"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"math/rand"
+	"runtime"
+	"slices"
+	"sync"
+	"time"
+)
+
+// summarize a large in-memory log of requests by endpoint and status with
+// map-reduce: mappers each take a shard of the records, the pairs they
+// emit are shuffled to reducers by key over channels, and the reducers
+// summarize their keys in parallel
+var (
+	endpoints = []string{"/users", "/orders", "/search", "/login", "/health"}
+	statuses  = []int{200, 200, 200, 200, 201, 404, 500}
+)
+
+// record is one request in the log.
+type record struct {
+	endpoint string
+	status   int
+	latency  time.Duration
+}
+
+// pair is what mappers emit.
+type pair struct {
+	key     string
+	latency time.Duration
+}
+
+// summary is what reducers produce for a key.
+type summary struct {
+	key      string
+	requests int
+	mean     time.Duration
+	max      time.Duration
+}
+
+func main() {
+	records := flag.Int("records", 2_000_000, "records in the dataset")
+	mappers := flag.Int("mappers", runtime.NumCPU(), "mapper goroutines")
+	reducers := flag.Int("reducers", 4, "reducer goroutines")
+	seed := flag.Int64("seed", 1, "random seed for the dataset")
+	flag.Parse()
+
+	start := time.Now()
+	data := generate(*records, *seed)
+	log.Printf("generated %d records in %v", len(data), time.Since(start))
+
+	start = time.Now()
+	want := sequential(data)
+	log.Printf("sequential: %v", time.Since(start))
+
+	start = time.Now()
+	got := mapReduce(data, *mappers, *reducers)
+	log.Printf("map-reduce: %v", time.Since(start))
+	if !slices.Equal(got, want) {
+		log.Fatal("map-reduce and sequential results differ")
+	}
+	for _, s := range got {
+		fmt.Printf("%-14s %9d requests  mean %-10v max %v\n", s.key, s.requests, s.mean, s.max)
+	}
+}
+
+func generate(n int, seed int64) []record {
+	rng := rand.New(rand.NewSource(seed))
+	data := make([]record, n)
+	for i := range data {
+		data[i] = record{
+			endpoint: endpoints[rng.Intn(len(endpoints))],
+			status:   statuses[rng.Intn(len(statuses))],
+			latency:  time.Duration(rng.ExpFloat64() * float64(20*time.Millisecond)),
+		}
+	}
+	return data
+}
+
+// mapRecord is the map function.
+func mapRecord(r record) pair {
+	return pair{key: fmt.Sprintf("%s %d", r.endpoint, r.status), latency: r.latency}
+}
+
+// reduceKey is the reduce function.
+func reduceKey(key string, latencies []time.Duration) summary {
+	s := summary{key: key, requests: len(latencies)}
+	var total time.Duration
+	for _, l := range latencies {
+		total += l
+		s.max = max(s.max, l)
+	}
+	s.mean = total / time.Duration(len(latencies))
+	return s
+}
+
+// sequential is the same computation in one goroutine, to check against.
+func sequential(data []record) []summary {
+	groups := make(map[string][]time.Duration)
+	for _, r := range data {
+		p := mapRecord(r)
+		groups[p.key] = append(groups[p.key], p.latency)
+	}
+	var out []summary
+	for key, latencies := range groups {
+		out = append(out, reduceKey(key, latencies))
+	}
+	slices.SortFunc(out, func(a, b summary) int { return cmp.Compare(a.key, b.key) })
+	return out
+}
+
+func mapReduce(data []record, mappers, reducers int) []summary {
+	// map: each mapper emits the pairs of its shard, already split into
+	// one partition per reducer
+	start := time.Now()
+	partitions := make([][][]pair, mappers)
+	var wg sync.WaitGroup
+	shard := (len(data) + mappers - 1) / mappers
+	for m := range mappers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			parts := make([][]pair, reducers)
+			for _, r := range data[min(m*shard, len(data)):min((m+1)*shard, len(data))] {
+				p := mapRecord(r)
+				i := partition(p.key, reducers)
+				parts[i] = append(parts[i], p)
+			}
+			partitions[m] = parts
+		}()
+	}
+	wg.Wait()
+	log.Printf("map:     %v (%d mappers)", time.Since(start), mappers)
+
+	// shuffle: every mapper sends each partition to its reducer, which
+	// groups the values by key
+	start = time.Now()
+	inboxes := make([]chan []pair, reducers)
+	groups := make([]map[string][]time.Duration, reducers)
+	var grouped sync.WaitGroup
+	for i := range reducers {
+		inboxes[i] = make(chan []pair, mappers)
+		groups[i] = make(map[string][]time.Duration)
+		grouped.Add(1)
+		go func() {
+			defer grouped.Done()
+			for pairs := range inboxes[i] {
+				for _, p := range pairs {
+					groups[i][p.key] = append(groups[i][p.key], p.latency)
+				}
+			}
+		}()
+	}
+	for m := range mappers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i, pairs := range partitions[m] {
+				inboxes[i] <- pairs
+			}
+		}()
+	}
+	wg.Wait()
+	for _, inbox := range inboxes {
+		close(inbox)
+	}
+	grouped.Wait()
+	log.Printf("shuffle: %v (%d reducers)", time.Since(start), reducers)
+
+	// reduce: each reducer summarizes its keys
+	start = time.Now()
+	results := make(chan summary)
+	for i := range reducers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for key, latencies := range groups[i] {
+				results <- reduceKey(key, latencies)
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+	var out []summary
+	for s := range results {
+		out = append(out, s)
+	}
+	slices.SortFunc(out, func(a, b summary) int { return cmp.Compare(a.key, b.key) })
+	log.Printf("reduce:  %v", time.Since(start))
+	return out
+}
+
+// partition picks the reducer for a key.
+func partition(key string, reducers int) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(reducers))
+}
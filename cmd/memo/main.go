@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aawadall/go-concurrency-patterns/config"
+	"github.com/aawadall/go-concurrency-patterns/lazy"
+	"github.com/aawadall/go-concurrency-patterns/memo"
+	"github.com/aawadall/go-concurrency-patterns/shared"
+)
+
+// look up a few keys many times from many workers, each lookup a request
+// to the test server, first directly and then through a memoized
+// function, which only makes a request for a key nobody has fetched yet;
+// the target itself is created lazily, once, by whichever worker needs it
+// first
+func main() {
+	mode := flag.String("mode", "http", "target to look keys up from: http or sim")
+	workers := flag.Int("workers", 16, "workers looking keys up at once")
+	lookups := flag.Int("lookups", 2000, "lookups in all")
+	keys := flag.Int("keys", 20, "distinct keys")
+	flag.Parse()
+
+	cfg := config.GetDefaultConfig()
+	cfg.Mode = *mode
+	cfg.Quiet = true
+
+	var inits atomic.Int64
+	target := lazy.New(func() (shared.Target, error) {
+		inits.Add(1)
+		return shared.NewTarget(cfg)
+	})
+
+	// fetch looks a key up from the target; the keys stand for the
+	// records a real client would ask for, the server answers them all
+	// the same
+	var requests atomic.Int64
+	fetch := func(key int) (shared.Result, error) {
+		t, err := target.Get()
+		if err != nil {
+			return shared.Result{}, err
+		}
+		requests.Add(1)
+		r, err := t.Do(context.Background())
+		if err == nil && r.ErrorClass != "" {
+			err = fmt.Errorf("key %d: %s", key, r.ErrorClass)
+		}
+		return r, err
+	}
+
+	elapsed, failed := run(*workers, *lookups, *keys, fetch)
+	log.Printf("direct:   %d lookups, %d requests, %d failed, in %v", *lookups, requests.Load(), failed, elapsed)
+
+	requests.Store(0)
+	m := memo.New(fetch)
+	elapsed, failed = run(*workers, *lookups, *keys, m.Get)
+	st := m.Stats()
+	log.Printf("memoized: %d lookups, %d requests, %d failed, in %v", *lookups, requests.Load(), failed, elapsed)
+	log.Printf("          %d from the cache, %d waited for a request in flight", st.Hits, st.Shared)
+
+	if n := inits.Load(); n != 1 {
+		log.Fatalf("the target was created %d times", n)
+	}
+	log.Printf("the target was created once, by the first lookup")
+}
+
+// run has the workers make the lookups of random keys through get and
+// returns how long they took and how many failed.
+func run(workers, lookups, keys int, get func(int) (shared.Result, error)) (time.Duration, int64) {
+	start := time.Now()
+	var failed atomic.Int64
+	var wg sync.WaitGroup
+	for i := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range lookups/workers + min(max(lookups%workers-i, 0), 1) {
+				if _, err := get(rand.IntN(keys)); err != nil {
+					failed.Add(1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return time.Since(start), failed.Load()
+}
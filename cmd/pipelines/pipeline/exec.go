@@ -0,0 +1,180 @@
+package pipeline
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Command is an external command a stage runs for its messages.
+type Command[I any, O any] struct {
+	Path string
+	Args []string
+	// MessageArgs, if set, gives arguments for a message, added after
+	// Args. Streaming stages don't use it.
+	MessageArgs func(I) []string
+	// Input encodes a message for the command's stdin. For a streaming
+	// stage it must be one line; the newline is added if missing.
+	Input func(I) []byte
+	// Output parses what the command wrote to stdout for a message: all
+	// of it, or for a streaming stage one line without the newline.
+	Output func([]byte) (O, error)
+	// Timeout is how long a message may take, or no limit if zero.
+	Timeout time.Duration
+}
+
+// ExecStage returns a stage that runs c once per message, at most workers
+// at a time, failing if the command does. Commands still running when ctx
+// is done are killed.
+func ExecStage[I any, O any](ctx context.Context, name string, workers int, c Command[I, O]) Stage[I, O] {
+	return Stage[I, O]{
+		Name:    name,
+		Workers: workers,
+		Buffer:  workers,
+		Function: func(m Message[I]) (Message[O], error) {
+			ctx := ctx
+			if c.Timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+				defer cancel()
+			}
+			args := c.Args
+			if c.MessageArgs != nil {
+				args = append(args[:len(args):len(args)], c.MessageArgs(m.Payload)...)
+			}
+			cmd := exec.CommandContext(ctx, c.Path, args...)
+			if c.Input != nil {
+				cmd.Stdin = bytes.NewReader(c.Input(m.Payload))
+			}
+			out, err := cmd.Output()
+			if err != nil {
+				return Message[O]{}, commandError(c.Path, err)
+			}
+			o, err := c.Output(out)
+			return Message[O]{ID: m.ID, Payload: o}, err
+		},
+	}
+}
+
+// StreamStage returns a stage that keeps workers copies of c running and
+// streams messages through them, writing each as a line to a copy's stdin
+// and reading its result as a line from its stdout, which saves starting
+// a process per message. The command must answer every line with exactly
+// one, without buffering its output. A copy that fails or times out is
+// killed and started again for the next message. stop stops the copies.
+func StreamStage[I any, O any](ctx context.Context, name string, workers int, c Command[I, O]) (s Stage[I, O], stop func()) {
+	idle := make(chan *process, workers)
+	for range workers {
+		idle <- nil // started when first needed
+	}
+	var mu sync.Mutex
+	var all []*process
+
+	s = Stage[I, O]{
+		Name:    name,
+		Workers: workers,
+		Buffer:  workers,
+		Function: func(m Message[I]) (Message[O], error) {
+			p := <-idle
+			defer func() { idle <- p }()
+			if p == nil || p.failed {
+				var err error
+				if p, err = startProcess(ctx, c.Path, c.Args); err != nil {
+					return Message[O]{}, err
+				}
+				mu.Lock()
+				all = append(all, p)
+				mu.Unlock()
+			}
+			line, err := p.roundTrip(c.Input(m.Payload), c.Timeout)
+			if err != nil {
+				return Message[O]{}, fmt.Errorf("%s: %w", c.Path, err)
+			}
+			o, err := c.Output(line)
+			return Message[O]{ID: m.ID, Payload: o}, err
+		},
+	}
+	stop = func() {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, p := range all {
+			p.stop()
+		}
+	}
+	return s, stop
+}
+
+// process is a running copy of a streaming stage's command.
+type process struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *bufio.Reader
+	failed bool
+}
+
+func startProcess(ctx context.Context, path string, args []string) (*process, error) {
+	cmd := exec.CommandContext(ctx, path, args...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return &process{cmd: cmd, stdin: stdin, stdout: bufio.NewReader(stdout)}, nil
+}
+
+// roundTrip writes a line and reads the answer, killing the process if
+// it takes longer than timeout.
+func (p *process) roundTrip(line []byte, timeout time.Duration) ([]byte, error) {
+	if timeout > 0 {
+		timer := time.AfterFunc(timeout, func() { p.cmd.Process.Kill() })
+		defer timer.Stop()
+	}
+	if !bytes.HasSuffix(line, []byte("\n")) {
+		line = append(line, '\n')
+	}
+	answer, err := p.write(line)
+	if err != nil {
+		p.failed = true
+		p.stop()
+	}
+	return answer, err
+}
+
+func (p *process) write(line []byte) ([]byte, error) {
+	if _, err := p.stdin.Write(line); err != nil {
+		return nil, err
+	}
+	answer, err := p.stdout.ReadBytes('\n')
+	if errors.Is(err, io.EOF) {
+		return nil, errors.New("exited or timed out before answering")
+	}
+	return bytes.TrimSuffix(answer, []byte("\n")), err
+}
+
+// stop closes the process's stdin and waits for it to exit.
+func (p *process) stop() {
+	p.stdin.Close()
+	p.cmd.Wait()
+}
+
+// commandError adds what a failed command wrote to stderr to its error.
+func commandError(path string, err error) error {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+		return fmt.Errorf("%s: %w: %s", path, err, strings.TrimSpace(string(exitErr.Stderr)))
+	}
+	return fmt.Errorf("%s: %w", path, err)
+}
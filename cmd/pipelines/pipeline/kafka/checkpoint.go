@@ -0,0 +1,101 @@
+// Package kafka connects pipelines to Kafka: Source feeds a pipeline from
+// a topic and Sink writes what comes out of one to a topic. Offsets are
+// committed through a Checkpoint only once the sink has written every
+// message before them, so a pipeline that stops part way, however its
+// stages reordered the messages, resumes without losing any; some may be
+// processed twice.
+//
+// It is a module of its own, so the rest of the repository doesn't
+// depend on a Kafka client.
+package kafka
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// partition identifies a topic partition.
+type partition struct {
+	topic string
+	id    int
+}
+
+// position is where a message was read from.
+type position struct {
+	partition partition
+	offset    int64
+}
+
+// Checkpoint keeps track of which messages read by a Source have been
+// written by a Sink, and commits the offsets of each partition up to the
+// first message still in the pipeline. Messages are known by the ID the
+// source gave them, which stages must pass on.
+type Checkpoint struct {
+	reader *kafka.Reader
+
+	mu      sync.Mutex
+	read    map[int64]position
+	pending map[partition][]int64 // offsets read and not yet committed, in order
+	done    map[position]bool
+}
+
+// NewCheckpoint returns a checkpoint committing to r, which must read as
+// part of a consumer group.
+func NewCheckpoint(r *kafka.Reader) *Checkpoint {
+	return &Checkpoint{
+		reader:  r,
+		read:    make(map[int64]position),
+		pending: make(map[partition][]int64),
+		done:    make(map[position]bool),
+	}
+}
+
+// fetched records that the message with the given ID was read from m's
+// position.
+func (c *Checkpoint) fetched(id int64, m kafka.Message) {
+	p := partition{topic: m.Topic, id: m.Partition}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.read[id] = position{partition: p, offset: m.Offset}
+	c.pending[p] = append(c.pending[p], m.Offset)
+}
+
+// Done records that the messages with the given IDs have been handled,
+// written by a sink or dropped on purpose.
+func (c *Checkpoint) Done(ids ...int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, id := range ids {
+		if pos, ok := c.read[id]; ok {
+			delete(c.read, id)
+			c.done[pos] = true
+		}
+	}
+}
+
+// Commit commits, for each partition, the offset of the last message
+// handled with every message before it.
+func (c *Checkpoint) Commit(ctx context.Context) error {
+	var commits []kafka.Message
+	c.mu.Lock()
+	for p, offsets := range c.pending {
+		n := 0
+		for n < len(offsets) && c.done[position{partition: p, offset: offsets[n]}] {
+			delete(c.done, position{partition: p, offset: offsets[n]})
+			n++
+		}
+		if n == 0 {
+			continue
+		}
+		commits = append(commits, kafka.Message{Topic: p.topic, Partition: p.id, Offset: offsets[n-1]})
+		c.pending[p] = slices.Delete(offsets, 0, n)
+	}
+	c.mu.Unlock()
+	if len(commits) == 0 {
+		return nil
+	}
+	return c.reader.CommitMessages(ctx, commits...)
+}
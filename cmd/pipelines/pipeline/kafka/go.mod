@@ -0,0 +1,16 @@
+module github.com/aawadall/go-concurrency-patterns/cmd/pipelines/pipeline/kafka
+
+go 1.24.0
+
+require (
+	github.com/aawadall/go-concurrency-patterns v0.0.0
+	github.com/segmentio/kafka-go v0.4.50
+)
+
+require (
+	github.com/klauspost/compress v1.15.9 // indirect
+	github.com/pierrec/lz4/v4 v4.1.15 // indirect
+	golang.org/x/sync v0.17.0 // indirect
+)
+
+replace github.com/aawadall/go-concurrency-patterns => ../../../..
@@ -0,0 +1,42 @@
+package kafka
+
+import (
+	"context"
+
+	"github.com/segmentio/kafka-go"
+
+	"github.com/aawadall/go-concurrency-patterns/cmd/pipelines/pipeline"
+)
+
+// Sink writes the messages from in to w, encoding each with encode, until
+// in is closed, in batches of up to batch messages taken from those ready
+// at once. Once a batch is written its messages are marked done in cp, if
+// cp isn't nil, so their offsets can be committed.
+func Sink[T any](ctx context.Context, w *kafka.Writer, in <-chan pipeline.Message[T], cp *Checkpoint, batch int, encode func(T) kafka.Message) error {
+	msgs := make([]kafka.Message, 0, batch)
+	ids := make([]int64, 0, batch)
+	for m := range in {
+		msgs = append(msgs[:0], encode(m.Payload))
+		ids = append(ids[:0], m.ID)
+	fill:
+		for len(msgs) < batch {
+			select {
+			case m, ok := <-in:
+				if !ok {
+					break fill
+				}
+				msgs = append(msgs, encode(m.Payload))
+				ids = append(ids, m.ID)
+			default:
+				break fill
+			}
+		}
+		if err := w.WriteMessages(ctx, msgs...); err != nil {
+			return err
+		}
+		if cp != nil {
+			cp.Done(ids...)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+
+	"github.com/aawadall/go-concurrency-patterns/cmd/pipelines/pipeline"
+)
+
+// Source reads messages from r into the returned channel, numbered from
+// zero, until ctx is done or reading fails, then closes it. Every
+// commitEvery, and once more at the end, it commits the offsets cp says
+// have been handled; cp may be nil to leave committing to the caller.
+// wait returns the error that stopped it, nil if ctx did.
+func Source(ctx context.Context, r *kafka.Reader, cp *Checkpoint, buffer int, commitEvery time.Duration) (out <-chan pipeline.Message[kafka.Message], wait func() error) {
+	ch := make(chan pipeline.Message[kafka.Message], buffer)
+	errc := make(chan error, 1)
+
+	stopCommits := func() error { return nil }
+	if cp != nil && commitEvery > 0 {
+		stopCommits = commitPeriodically(cp, commitEvery)
+	}
+
+	go func() {
+		defer close(ch)
+		err := read(ctx, r, cp, ch)
+		if cerr := stopCommits(); err == nil {
+			err = cerr
+		}
+		errc <- err
+	}()
+
+	var err error
+	waited := false
+	return ch, func() error {
+		if !waited {
+			err, waited = <-errc, true
+		}
+		return err
+	}
+}
+
+func read(ctx context.Context, r *kafka.Reader, cp *Checkpoint, out chan<- pipeline.Message[kafka.Message]) error {
+	for id := int64(0); ; id++ {
+		m, err := r.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil && errors.Is(err, ctx.Err()) {
+				return nil
+			}
+			return err
+		}
+		if cp != nil {
+			cp.fetched(id, m)
+		}
+		select {
+		case out <- pipeline.Message[kafka.Message]{ID: id, Payload: m}:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// commitPeriodically commits cp every interval until stop is called,
+// which commits once more and returns the last commit's error.
+func commitPeriodically(cp *Checkpoint, every time.Duration) (stop func() error) {
+	done := make(chan struct{})
+	result := make(chan error, 1)
+	go func() {
+		ticker := time.NewTicker(every)
+		defer ticker.Stop()
+		var err error
+		for {
+			select {
+			case <-ticker.C:
+				err = cp.Commit(context.Background())
+			case <-done:
+				result <- errors.Join(err, cp.Commit(context.Background()))
+				return
+			}
+		}
+	}()
+	return func() error {
+		close(done)
+		return <-result
+	}
+}
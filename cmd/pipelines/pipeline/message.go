@@ -3,4 +3,7 @@ package pipeline
 type Message[T any] struct {
 	ID      int64
 	Payload T
+	// Worker is the index, within its stage, of the worker handling the
+	// message, set by the stage before calling its Function.
+	Worker int
 }
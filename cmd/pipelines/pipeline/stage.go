@@ -2,9 +2,12 @@ package pipeline
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"golang.org/x/sync/errgroup"
+
+	"github.com/aawadall/go-concurrency-patterns/ring"
 )
 
 // Stage represents a processing stage in a pipeline.
@@ -22,6 +25,7 @@ func (s *Stage[I, O]) Run(ctx context.Context, input <-chan Message[I]) (<-chan
 	for i := 0; i < s.Workers; i++ {
 		eg.Go(func() error {
 			for msg := range input {
+				msg.Worker = i
 				o, err := s.Function(msg)
 				if err != nil {
 					return fmt.Errorf("[%s]: %w", s.Name, err)
@@ -43,3 +47,40 @@ func (s *Stage[I, O]) Run(ctx context.Context, input <-chan Message[I]) (<-chan
 
 	return output, eg
 }
+
+// RunRing is Run with the stage connected by ring buffers rather than
+// channels: its workers all pop from input and push to the returned
+// buffer, which is closed once they are done.
+func (s *Stage[I, O]) RunRing(ctx context.Context, input *ring.MPMC[Message[I]]) (*ring.MPMC[Message[O]], *errgroup.Group) {
+	output := ring.NewMPMC[Message[O]](s.Buffer)
+	eg, ctx := errgroup.WithContext(ctx)
+
+	for i := 0; i < s.Workers; i++ {
+		eg.Go(func() error {
+			for {
+				msg, err := input.Pop(ctx)
+				if errors.Is(err, ring.ErrClosed) {
+					return nil
+				}
+				if err != nil {
+					return err
+				}
+				msg.Worker = i
+				o, err := s.Function(msg)
+				if err != nil {
+					return fmt.Errorf("[%s]: %w", s.Name, err)
+				}
+				if err := output.Push(ctx, o); err != nil {
+					return err
+				}
+			}
+		})
+	}
+
+	go func() {
+		_ = eg.Wait()
+		output.Close()
+	}()
+
+	return output, eg
+}
@@ -0,0 +1,186 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aawadall/go-concurrency-patterns/ring"
+	"github.com/aawadall/go-concurrency-patterns/shared/leakcheck"
+)
+
+// checkLeaks fails t if a goroutine started since before is still
+// running once t is done.
+func checkLeaks(t *testing.T) {
+	before := leakcheck.Take()
+	t.Cleanup(func() {
+		for _, l := range leakcheck.Check(before, time.Second) {
+			t.Errorf("%d goroutines leaked [%s]:\n%s", l.Count, l.State, l.Stack)
+		}
+	})
+}
+
+// source sends messages 1 to n, until ctx is done.
+func source(ctx context.Context, n int) <-chan Message[int] {
+	out := make(chan Message[int])
+	go func() {
+		defer close(out)
+		for i := 1; i <= n; i++ {
+			select {
+			case out <- Message[int]{ID: int64(i), Payload: i}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+var errBad = errors.New("bad message")
+
+func square(m Message[int]) (Message[int], error) {
+	if m.Payload < 0 {
+		return m, errBad
+	}
+	return Message[int]{ID: m.ID, Payload: m.Payload * m.Payload}, nil
+}
+
+func format(m Message[int]) (Message[string], error) {
+	return Message[string]{ID: m.ID, Payload: fmt.Sprint(m.Payload)}, nil
+}
+
+func TestStages(t *testing.T) {
+	tests := []struct {
+		name string
+		n    int
+		// cancelAfter cancels the run after reading this many results
+		cancelAfter int
+		// bad is a message made to fail the first stage
+		bad     int
+		want    int // results read
+		wantErr error
+	}{
+		{name: "to the end", n: 100, want: 100},
+		{name: "canceled", n: 100, cancelAfter: 10, want: 10},
+		{name: "stage error", n: 100, bad: 20, wantErr: errBad},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkLeaks(t)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			input := source(ctx, tt.n)
+			if tt.bad > 0 {
+				input = poison(ctx, input, tt.bad)
+			}
+			first := &Stage[int, int]{Name: "square", Workers: 4, Buffer: 2, Function: square}
+			second := &Stage[int, string]{Name: "format", Workers: 2, Function: format}
+			squares, eg1 := first.Run(ctx, input)
+			out, eg2 := second.Run(ctx, squares)
+
+			got := 0
+			for range out {
+				got++
+				if got == tt.cancelAfter {
+					cancel()
+					break
+				}
+			}
+			err := errors.Join(eg1.Wait(), eg2.Wait())
+			if tt.cancelAfter > 0 && errors.Is(err, context.Canceled) {
+				// workers caught sending when it was canceled say so,
+				// the ones waiting for input just stop
+				err = nil
+			}
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("got error %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if tt.bad == 0 && got != tt.want {
+				t.Errorf("got %d results, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// poison negates the payload of message bad on its way through.
+func poison(ctx context.Context, in <-chan Message[int], bad int) <-chan Message[int] {
+	out := make(chan Message[int])
+	go func() {
+		defer close(out)
+		for m := range in {
+			if m.ID == int64(bad) {
+				m.Payload = -m.Payload
+			}
+			select {
+			case out <- m:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+func TestRunRing(t *testing.T) {
+	checkLeaks(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	input := ring.NewMPMC[Message[int]](8)
+	go func() {
+		defer input.Close()
+		for i := 1; i <= 100; i++ {
+			if input.Push(ctx, Message[int]{ID: int64(i), Payload: i}) != nil {
+				return
+			}
+		}
+	}()
+	s := &Stage[int, int]{Name: "square", Workers: 4, Buffer: 8, Function: square}
+	out, eg := s.RunRing(ctx, input)
+
+	sum := 0
+	for {
+		m, err := out.Pop(ctx)
+		if errors.Is(err, ring.ErrClosed) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		sum += m.Payload
+	}
+	if err := eg.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	// 1² + 2² + ... + 100²
+	if want := 100 * 101 * 201 / 6; sum != want {
+		t.Errorf("got a sum of %d, want %d", sum, want)
+	}
+}
+
+// each worker tags the messages it handles with its index
+func TestStageSetsWorker(t *testing.T) {
+	checkLeaks(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	const workers = 4
+	s := &Stage[int, int]{Name: "worker", Workers: workers, Function: func(m Message[int]) (Message[int], error) {
+		return Message[int]{ID: m.ID, Payload: m.Worker}, nil
+	}}
+	out, eg := s.Run(ctx, source(ctx, 100))
+	for m := range out {
+		if m.Payload < 0 || m.Payload >= workers {
+			t.Errorf("message %d handled by worker %d of %d", m.ID, m.Payload, workers)
+		}
+	}
+	if err := eg.Wait(); err != nil {
+		t.Fatal(err)
+	}
+}
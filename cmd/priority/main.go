@@ -0,0 +1,13 @@
+package main
+
+import (
+	"os"
+
+	"github.com/aawadall/go-concurrency-patterns/patterns"
+	"github.com/aawadall/go-concurrency-patterns/runner"
+)
+
+// Same as gcp run priority.
+func main() {
+	runner.Main(patterns.Priority{}, os.Args[1:])
+}
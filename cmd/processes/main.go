@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+
+	"github.com/aawadall/go-concurrency-patterns/patterns"
+	"github.com/aawadall/go-concurrency-patterns/runner"
+	"github.com/aawadall/go-concurrency-patterns/shared"
+)
+
+// run the same requests through the worker pool twice, once with
+// goroutines for workers and once with child processes, and compare the
+// two; flags are those of gcp run, and the default is a sim run
+func main() {
+	args := append([]string{"-mode", "sim", "-quiet"}, os.Args[1:]...)
+	cfg := runner.Flags(args)
+	target, err := shared.NewTarget(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// quiet only for the progress dots of the target, so that the
+	// startup and round trip costs the processes pattern logs show
+	cfg.Quiet = false
+	if err := shared.SetupLogging(cfg); err != nil {
+		log.Fatal(err)
+	}
+
+	var labels []string
+	var runs []shared.Manifest
+	for _, p := range []runner.Pattern{patterns.WorkerPool{}, patterns.Processes{}} {
+		collector := shared.NewCollectorFor(cfg)
+		start := time.Now()
+		if err := p.Run(context.Background(), cfg, target, collector); err != nil {
+			log.Fatal(err)
+		}
+		end := time.Now()
+		m := shared.NewManifest(p.Name(), cfg, start, end, collector.Summary(), nil)
+		log.Printf("%-10s %.0f requests/s over %d workers", p.Name(), m.RequestsPerSecond, cfg.Concurrency)
+		labels = append(labels, p.Name())
+		runs = append(runs, m)
+	}
+	shared.Compare(os.Stdout, labels, runs, 0.05)
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"hash/fnv"
+	"log"
+	"os"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aawadall/go-concurrency-patterns/cmd/pipelines/pipeline"
+	"github.com/aawadall/go-concurrency-patterns/config"
+	"github.com/aawadall/go-concurrency-patterns/future"
+	"github.com/aawadall/go-concurrency-patterns/shared"
+)
+
+// run the same two-stage computation, a simulated fetch and then a
+// checksum of what it returned, once as a channel pipeline and once as
+// chains of futures, recording each item as a request so the usual
+// comparison report applies, along with what each costs in allocations
+// and goroutines
+func main() {
+	items := flag.Int("items", 5000, "items to process")
+	concurrency := flag.Int("concurrency", 16, "items being fetched at once")
+	work := flag.Int("work", 2000, "checksum rounds per item")
+	flag.Parse()
+
+	cfg := config.GetDefaultConfig()
+	cfg.Mode = "sim"
+	cfg.Quiet = true
+	cfg.Concurrency = *concurrency
+	cfg.Requests = *items
+	target, err := shared.NewSimTarget(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var labels []string
+	var runs []shared.Manifest
+	var sums []uint64
+	for _, v := range []struct {
+		name string
+		run  func(context.Context, *config.Config, shared.Target, int, func(item)) uint64
+	}{
+		{"pipeline", runPipeline},
+		{"futures", runFutures},
+	} {
+		m, sum, cost := measure(v.name, cfg, func(record func(item)) uint64 {
+			return v.run(context.Background(), cfg, target, *work, record)
+		})
+		log.Printf("%-8s %.0f items/s, %.1f allocs/item, peak %d goroutines",
+			v.name, m.RequestsPerSecond, float64(cost.mallocs)/float64(*items), cost.goroutines)
+		labels = append(labels, v.name)
+		runs = append(runs, m)
+		sums = append(sums, sum)
+	}
+	if sums[0] != sums[1] {
+		log.Fatalf("pipeline and futures disagree: checksums %d and %d", sums[0], sums[1])
+	}
+	shared.Compare(os.Stdout, labels, runs, 0.05)
+}
+
+// item is one piece of work on its way through the stages.
+type item struct {
+	id     int
+	start  time.Time
+	result shared.Result
+	sum    uint64
+}
+
+// fetch is the first stage: a request to the target.
+func fetch(ctx context.Context, target shared.Target, id int) item {
+	it := item{id: id, start: time.Now()}
+	it.result, _ = target.Do(ctx)
+	return it
+}
+
+// checksum is the second stage: CPU work on what was fetched.
+func checksum(it item, work int) item {
+	h := fnv.New64a()
+	buf := strconv.AppendInt(nil, int64(it.id), 10)
+	for range work {
+		h.Write(buf)
+	}
+	it.sum = h.Sum64()
+	return it
+}
+
+// runPipeline runs the stages as a pipeline, with cfg.Concurrency
+// fetchers and a checksummer per CPU, records each item coming out of it
+// and returns the sum of the checksums.
+func runPipeline(ctx context.Context, cfg *config.Config, target shared.Target, work int, record func(item)) uint64 {
+	source := make(chan pipeline.Message[int], cfg.Concurrency)
+	go func() {
+		defer close(source)
+		for i := range cfg.Requests {
+			source <- pipeline.Message[int]{ID: int64(i), Payload: i}
+		}
+	}()
+
+	fetchStage := pipeline.Stage[int, item]{
+		Name:    "fetch",
+		Workers: cfg.Concurrency,
+		Buffer:  cfg.Concurrency,
+		Function: func(m pipeline.Message[int]) (pipeline.Message[item], error) {
+			return pipeline.Message[item]{ID: m.ID, Payload: fetch(ctx, target, m.Payload)}, nil
+		},
+	}
+	checksumStage := pipeline.Stage[item, item]{
+		Name:    "checksum",
+		Workers: runtime.GOMAXPROCS(0),
+		Buffer:  cfg.Concurrency,
+		Function: func(m pipeline.Message[item]) (pipeline.Message[item], error) {
+			return pipeline.Message[item]{ID: m.ID, Payload: checksum(m.Payload, work)}, nil
+		},
+	}
+	fetched, _ := fetchStage.Run(ctx, source)
+	summed, _ := checksumStage.Run(ctx, fetched)
+
+	var sum uint64
+	for m := range summed {
+		record(m.Payload)
+		sum += m.Payload.sum
+	}
+	return sum
+}
+
+// runFutures runs the stages as a chain of futures per item, at most
+// cfg.Concurrency items at a time, records each item as its chain settles
+// and returns the sum of the checksums.
+func runFutures(ctx context.Context, cfg *config.Config, target shared.Target, work int, record func(item)) uint64 {
+	slots := make(chan struct{}, cfg.Concurrency)
+	var sum atomic.Uint64
+	var wg sync.WaitGroup
+	for i := range cfg.Requests {
+		slots <- struct{}{}
+		wg.Add(1)
+		fetched := future.Go(func() (item, error) {
+			return fetch(ctx, target, i), nil
+		})
+		summed := future.Then(fetched, func(it item) (item, error) {
+			<-slots
+			return checksum(it, work), nil
+		})
+		future.Then(summed, func(it item) (struct{}, error) {
+			defer wg.Done()
+			record(it)
+			sum.Add(it.sum)
+			return struct{}{}, nil
+		})
+	}
+	wg.Wait()
+	return sum.Load()
+}
+
+// cost is what running a variant took besides time.
+type cost struct {
+	mallocs    uint64
+	goroutines int
+}
+
+// measure runs a variant, recording its items, and returns its manifest,
+// its checksum and its cost.
+func measure(name string, cfg *config.Config, run func(record func(item)) uint64) (shared.Manifest, uint64, cost) {
+	collector := shared.NewCollectorFor(cfg)
+	record := func(it item) {
+		r := it.result
+		r.Start, r.End = it.start, time.Now()
+		r.Latency = r.End.Sub(r.Start)
+		collector.Record(r)
+	}
+
+	// sample the goroutine count while the variant runs
+	stop := make(chan struct{})
+	peak := make(chan int)
+	go func() {
+		n := 0
+		ticker := time.NewTicker(time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				n = max(n, runtime.NumGoroutine())
+			case <-stop:
+				peak <- n
+				return
+			}
+		}
+	}()
+
+	runtime.GC()
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+	sum := run(record)
+	end := time.Now()
+	runtime.ReadMemStats(&after)
+	close(stop)
+
+	c := cost{mallocs: after.Mallocs - before.Mallocs, goroutines: <-peak}
+	mem := map[string]uint64{
+		"TotalAlloc": after.TotalAlloc - before.TotalAlloc,
+		"Sys":        after.Sys - before.Sys,
+		"Mallocs":    c.mallocs,
+	}
+	return shared.NewManifest(name, cfg, start, end, collector.Summary(), mem), sum, c
+}
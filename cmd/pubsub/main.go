@@ -0,0 +1,14 @@
+package main
+
+import (
+	"github.com/aawadall/go-concurrency-patterns/pubsub"
+	"github.com/aawadall/go-concurrency-patterns/pubsub/demo"
+)
+
+// publish to a fast subscriber and slow ones under each policy, through
+// the in-memory broker
+func main() {
+	demo.Run(func() (pubsub.Client[int], error) {
+		return pubsub.NewBroker[int]().Client(), nil
+	})
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"text/tabwriter"
+
+	"github.com/aawadall/go-concurrency-patterns/races"
+)
+
+// run race conditions and their fixes under stress, every one of them
+// or those named, and report what each broke; the exit status is 1 if a
+// fix broke anything, so that this doubles as a regression test for the
+// fixes. Build with -race to have the race detector's word too:
+//
+//	races list
+//	races [-goroutines 8] [-ops 10000] [-rounds 20] [-yield] [race...]
+//	go run -race ./cmd/races
+func main() {
+	var s races.Stress
+	flag.IntVar(&s.Goroutines, "goroutines", 8, "goroutines racing")
+	flag.IntVar(&s.Ops, "ops", 10000, "operations per goroutine and round")
+	flag.IntVar(&s.Rounds, "rounds", 20, "rounds per race")
+	flag.BoolVar(&s.Yield, "yield", false, "yield in the middle of each operation, to make the races show on one CPU")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: races [-goroutines 8] [-ops 10000] [-rounds 20] [-yield] [race...]\n       races list\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.Arg(0) == "list" {
+		for _, r := range races.All {
+			fmt.Println(r.Name())
+		}
+		return
+	}
+
+	list := races.All
+	if flag.NArg() > 0 {
+		list = nil
+		for _, name := range flag.Args() {
+			r, ok := races.Lookup(name)
+			if !ok {
+				log.Fatalf("unknown race %q; try races list", name)
+			}
+			list = append(list, r)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "race\trounds\tcorrupted\tlost\tdetector\tcrash")
+	broken := false
+	for _, r := range list {
+		o, err := races.Check(ctx, r, s)
+		if err != nil {
+			log.Fatal(err)
+		}
+		detector := "-"
+		if races.Detector {
+			detector = fmt.Sprintf("%d races", o.Reports)
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d/%d\t%s\t%s\n", o.Race, o.Rounds, o.Corrupted, o.Lost, o.Ops, detector, o.Crash)
+		if o.Fixed && !o.OK() {
+			broken = true
+		}
+	}
+	w.Flush()
+	if !races.Detector {
+		fmt.Println("\nbuilt without -race: rebuild with it to see what the race detector reports")
+	}
+	if broken {
+		os.Exit(1)
+	}
+}
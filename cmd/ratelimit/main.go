@@ -0,0 +1,20 @@
+package main
+
+import (
+	"os"
+
+	"github.com/aawadall/go-concurrency-patterns/patterns"
+	"github.com/aawadall/go-concurrency-patterns/runner"
+)
+
+// Same as gcp run ratelimit, or gcp run ratelimit-leaky when the first
+// argument is "leaky".
+func main() {
+	args := os.Args[1:]
+	p := patterns.RateLimit{}
+	if len(args) > 0 && args[0] == "leaky" {
+		p.Leaky = true
+		args = args[1:]
+	}
+	runner.Main(p, args)
+}
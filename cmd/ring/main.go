@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"testing"
+
+	"github.com/aawadall/go-concurrency-patterns/cmd/pipelines/pipeline"
+	"github.com/aawadall/go-concurrency-patterns/ring"
+)
+
+// benchmark the ring buffers against buffered channels: one producer and
+// one consumer, several producers and one consumer, and a two-stage
+// pipeline connected by each; ring/ring_test.go has the same benchmarks
+// for go test -bench
+const (
+	capacity  = 1024
+	producers = 4
+	workers   = 2
+)
+
+func main() {
+	log.Println("One producer, one consumer:")
+	bench("channel", func(b *testing.B) { spsc(chanBuffer[int](make(chan int, capacity)), b.N) })
+	bench("ring.SPSC", func(b *testing.B) { spsc(ring.NewSPSC[int](capacity), b.N) })
+	bench("ring.MPMC", func(b *testing.B) { spsc(ring.NewMPMC[int](capacity), b.N) })
+
+	log.Printf("%d producers, one consumer:", producers)
+	bench("channel", func(b *testing.B) { mpsc(chanBuffer[int](make(chan int, capacity)), producers, b.N) })
+	bench("ring.MPMC", func(b *testing.B) { mpsc(ring.NewMPMC[int](capacity), producers, b.N) })
+
+	log.Printf("Two-stage pipeline, %d workers a stage:", workers)
+	bench("channels", func(b *testing.B) { pipelineChannels(b.N) })
+	bench("rings", func(b *testing.B) { pipelineRings(b.N) })
+}
+
+// buffer is what the benchmarks need of a ring buffer, or a channel.
+type buffer[T any] interface {
+	Push(ctx context.Context, v T) error
+	Pop(ctx context.Context) (T, error)
+	Close()
+}
+
+// chanBuffer is a buffered channel as a buffer.
+type chanBuffer[T any] chan T
+
+func (c chanBuffer[T]) Push(ctx context.Context, v T) error {
+	c <- v
+	return nil
+}
+
+func (c chanBuffer[T]) Pop(ctx context.Context) (T, error) {
+	v, ok := <-c
+	if !ok {
+		return v, ring.ErrClosed
+	}
+	return v, nil
+}
+
+func (c chanBuffer[T]) Close() {
+	close(c)
+}
+
+// spsc passes n values through b from one goroutine to another.
+func spsc(b buffer[int], n int) {
+	mpsc(b, 1, n)
+}
+
+// mpsc passes n values through b from the producers to one consumer.
+func mpsc(b buffer[int], producers, n int) {
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for i := range producers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// share the values out, the first producers taking the
+			// remainder
+			for v := range n/producers + min(max(n%producers-i, 0), 1) {
+				_ = b.Push(ctx, v)
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		b.Close()
+	}()
+	for {
+		if _, err := b.Pop(ctx); errors.Is(err, ring.ErrClosed) {
+			return
+		}
+	}
+}
+
+// stages returns the two stages of the benchmark pipeline.
+func stages() (pipeline.Stage[int, int], pipeline.Stage[int, int]) {
+	square := pipeline.Stage[int, int]{
+		Name:    "square",
+		Workers: workers,
+		Buffer:  capacity,
+		Function: func(m pipeline.Message[int]) (pipeline.Message[int], error) {
+			return pipeline.Message[int]{ID: m.ID, Payload: m.Payload * m.Payload}, nil
+		},
+	}
+	double := pipeline.Stage[int, int]{
+		Name:    "double",
+		Workers: workers,
+		Buffer:  capacity,
+		Function: func(m pipeline.Message[int]) (pipeline.Message[int], error) {
+			return pipeline.Message[int]{ID: m.ID, Payload: m.Payload * 2}, nil
+		},
+	}
+	return square, double
+}
+
+// pipelineChannels runs n messages through the stages over channels.
+func pipelineChannels(n int) {
+	ctx := context.Background()
+	source := make(chan pipeline.Message[int], capacity)
+	go func() {
+		defer close(source)
+		for i := range n {
+			source <- pipeline.Message[int]{ID: int64(i), Payload: i}
+		}
+	}()
+	square, double := stages()
+	squared, _ := square.Run(ctx, source)
+	doubled, _ := double.Run(ctx, squared)
+	for range doubled {
+	}
+}
+
+// pipelineRings runs n messages through the stages over ring buffers.
+func pipelineRings(n int) {
+	ctx := context.Background()
+	source := ring.NewMPMC[pipeline.Message[int]](capacity)
+	go func() {
+		defer source.Close()
+		for i := range n {
+			_ = source.Push(ctx, pipeline.Message[int]{ID: int64(i), Payload: i})
+		}
+	}()
+	square, double := stages()
+	squared, _ := square.RunRing(ctx, source)
+	doubled, _ := double.RunRing(ctx, squared)
+	for {
+		if _, err := doubled.Pop(ctx); err != nil {
+			return
+		}
+	}
+}
+
+func bench(name string, fn func(b *testing.B)) {
+	r := testing.Benchmark(fn)
+	log.Printf("  %-12s %s", name, fmt.Sprintf("%10d ns/op", r.NsPerOp()))
+}
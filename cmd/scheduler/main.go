@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aawadall/go-concurrency-patterns/config"
+	"github.com/aawadall/go-concurrency-patterns/scheduler"
+	"github.com/aawadall/go-concurrency-patterns/shared"
+)
+
+// send bursts of simulated traffic on a schedule: a burst every interval,
+// a little jittered, and one bigger spike part way through, with the
+// scheduler's workers bounding how many bursts run at once
+func main() {
+	duration := flag.Duration("duration", 3*time.Second, "how long to run")
+	interval := flag.Duration("interval", 250*time.Millisecond, "time between bursts")
+	jitter := flag.Duration("jitter", 50*time.Millisecond, "how late a burst may start")
+	size := flag.Int("burst", 50, "requests in a burst")
+	spikeAt := flag.Duration("spike-at", 1500*time.Millisecond, "when the spike comes")
+	workers := flag.Int("workers", 2, "bursts sent at once")
+	flag.Parse()
+
+	cfg := config.GetDefaultConfig()
+	cfg.Mode = "sim"
+	cfg.Quiet = true
+	target, err := shared.NewSimTarget(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	collector := shared.NewCollectorFor(cfg)
+	start := time.Now()
+
+	var bursts atomic.Int64
+	burst := func(name string, n int) scheduler.Job {
+		return func(ctx context.Context) {
+			i := bursts.Add(1)
+			begin := time.Now()
+			var failed atomic.Int64
+			var wg sync.WaitGroup
+			for range n {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					r, _ := target.Do(ctx)
+					if r.ErrorClass != "" {
+						failed.Add(1)
+					}
+					collector.Record(r)
+				}()
+			}
+			wg.Wait()
+			log.Printf("%-5s %2d at +%-6v %3d requests, %d failed, took %v",
+				name, i, begin.Sub(start).Round(time.Millisecond), n, failed.Load(), time.Since(begin).Round(time.Millisecond))
+		}
+	}
+
+	s := scheduler.New(scheduler.Options{Workers: *workers, Queue: *workers})
+	s.Every(*interval, *jitter, burst("burst", *size))
+	s.After(*spikeAt, burst("spike", 10**size))
+
+	ctx, cancel := context.WithTimeout(context.Background(), *duration)
+	defer cancel()
+	s.Run(ctx)
+
+	st := s.Stats()
+	log.Printf("%d bursts sent, %d skipped while the workers were busy", st.Runs, st.Missed)
+	if err := shared.Report(os.Stdout, shared.FormatText, collector.Summary(), time.Since(start), nil); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"text/tabwriter"
+	"time"
+)
+
+// measure the canonical select idioms, each on its own and again while
+// -load goroutines keep the scheduler busy: a timeout with time.After,
+// which makes a timer for every select, against one timer reset for
+// each; a try-send and try-receive with default against blocking ones;
+// waiting on a done channel or a context as well as the work; and a
+// priority select against a plain one, which picks at random
+//
+//	select [-load 8]
+func main() {
+	load := flag.Int("load", 8, "busy goroutines during the loaded runs")
+	flag.Parse()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "idiom\tns/op\tallocs/op\tloaded ns/op\thigh %%\n")
+	for _, i := range idioms {
+		idle := testing.Benchmark(i.bench)
+		stop := busy(*load)
+		loaded := testing.Benchmark(i.bench)
+		stop()
+
+		high := ""
+		if share, ok := idle.Extra["%high"]; ok {
+			high = fmt.Sprintf("%.0f", share)
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%s\n", i.name, idle.NsPerOp(), idle.AllocsPerOp(), loaded.NsPerOp(), high)
+	}
+	w.Flush()
+}
+
+var idioms = []struct {
+	name  string
+	bench func(b *testing.B)
+}{
+	{"timeout: time.After", timeAfter},
+	{"timeout: reused timer", timerReset},
+	{"send, receive", blocking},
+	{"try-send on a full channel", trySend},
+	{"try-receive on an empty channel", tryReceive},
+	{"receive, or done channel", orDone},
+	{"receive, or ctx.Done()", orContext},
+	{"cancel a waiting goroutine", cancelWaiter},
+	{"select, both ready", plainSelect},
+	{"priority select, both ready", prioritySelect},
+}
+
+// timeAfter waits for a value or a timeout with time.After, which makes
+// a new timer every time round, even though the value is always there
+// first.
+func timeAfter(b *testing.B) {
+	b.ReportAllocs()
+	ch := make(chan int, 1)
+	for range b.N {
+		ch <- 1
+		select {
+		case <-ch:
+		case <-time.After(time.Second):
+			b.Fatal("timed out")
+		}
+	}
+}
+
+// timerReset does the same with one timer, reset every time round;
+// since Go 1.23 a reset timer never delivers a stale tick.
+func timerReset(b *testing.B) {
+	b.ReportAllocs()
+	ch := make(chan int, 1)
+	t := time.NewTimer(time.Second)
+	defer t.Stop()
+	for range b.N {
+		ch <- 1
+		t.Reset(time.Second)
+		select {
+		case <-ch:
+		case <-t.C:
+			b.Fatal("timed out")
+		}
+	}
+}
+
+// blocking sends and receives on a buffered channel, with no select,
+// for the others to be measured against.
+func blocking(b *testing.B) {
+	b.ReportAllocs()
+	ch := make(chan int, 1)
+	for range b.N {
+		ch <- 1
+		<-ch
+	}
+}
+
+// trySend tries to send on a full channel and gives up at once, which is
+// how to drop a value rather than block.
+func trySend(b *testing.B) {
+	b.ReportAllocs()
+	ch := make(chan int, 1)
+	ch <- 1
+	for range b.N {
+		select {
+		case ch <- 1:
+			b.Fatal("sent on a full channel")
+		default:
+		}
+	}
+}
+
+// tryReceive tries to receive from an empty channel and gives up at
+// once, which is how to poll.
+func tryReceive(b *testing.B) {
+	b.ReportAllocs()
+	ch := make(chan int, 1)
+	for range b.N {
+		select {
+		case <-ch:
+			b.Fatal("received from an empty channel")
+		default:
+		}
+	}
+}
+
+// orDone receives, unless the done channel is closed first, which is
+// what every goroutine that can be canceled does while it waits.
+func orDone(b *testing.B) {
+	b.ReportAllocs()
+	ch := make(chan int, 1)
+	done := make(chan struct{})
+	for range b.N {
+		ch <- 1
+		select {
+		case <-ch:
+		case <-done:
+			b.Fatal("done")
+		}
+	}
+}
+
+// orContext is orDone with a context, the same select on the channel
+// ctx.Done() returns.
+func orContext(b *testing.B) {
+	b.ReportAllocs()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := make(chan int, 1)
+	for range b.N {
+		ch <- 1
+		select {
+		case <-ch:
+		case <-ctx.Done():
+			b.Fatal("done")
+		}
+	}
+}
+
+// cancelWaiter starts a goroutine waiting on work that never comes, or
+// done, closes done and waits for it to return: what cancellation costs,
+// from the close to the goroutine gone.
+func cancelWaiter(b *testing.B) {
+	b.ReportAllocs()
+	never := make(chan int)
+	for range b.N {
+		done := make(chan struct{})
+		exited := make(chan struct{})
+		go func() {
+			defer close(exited)
+			select {
+			case <-never:
+			case <-done:
+			}
+		}()
+		close(done)
+		<-exited
+	}
+}
+
+// plainSelect receives from whichever of two ready channels select
+// picks, which is either at random: the high priority one wins half the
+// time.
+func plainSelect(b *testing.B) {
+	b.ReportAllocs()
+	high, low := make(chan int, 1), make(chan int, 1)
+	wins := 0
+	for range b.N {
+		high <- 1
+		low <- 1
+		select {
+		case <-high:
+			wins++
+		case <-low:
+		}
+		drain(high, low)
+	}
+	b.ReportMetric(100*float64(wins)/float64(b.N), "%high")
+}
+
+// prioritySelect tries the high priority channel first, and only if it
+// has nothing waits on both: whenever both are ready, high wins.
+func prioritySelect(b *testing.B) {
+	b.ReportAllocs()
+	high, low := make(chan int, 1), make(chan int, 1)
+	wins := 0
+	for range b.N {
+		high <- 1
+		low <- 1
+		select {
+		case <-high:
+			wins++
+		default:
+			select {
+			case <-high:
+				wins++
+			case <-low:
+			}
+		}
+		drain(high, low)
+	}
+	b.ReportMetric(100*float64(wins)/float64(b.N), "%high")
+}
+
+// drain empties channels of the value a select left in them.
+func drain(chs ...chan int) {
+	for _, ch := range chs {
+		select {
+		case <-ch:
+		default:
+		}
+	}
+}
+
+// busy starts n goroutines passing a value back and forth in pairs,
+// which keeps the scheduler busy, until stop.
+func busy(n int) (stop func()) {
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for range (n + 1) / 2 {
+		ping, pong := make(chan int), make(chan int)
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for v := 0; ; v++ {
+				select {
+				case ping <- v:
+				case <-done:
+					return
+				}
+				select {
+				case <-pong:
+				case <-done:
+					return
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case v := <-ping:
+					select {
+					case pong <- v:
+					case <-done:
+						return
+					}
+				case <-done:
+					return
+				}
+			}
+		}()
+	}
+	return func() {
+		close(done)
+		wg.Wait()
+	}
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"os"
+
+	"github.com/aawadall/go-concurrency-patterns/patterns"
+	"github.com/aawadall/go-concurrency-patterns/runner"
+)
+
+// Same as gcp run semaphore, or gcp run semaphore-chan when the first
+// argument is "chan".
+func main() {
+	args := os.Args[1:]
+	p := patterns.Semaphore{}
+	if len(args) > 0 && args[0] == "chan" {
+		p.Channel = true
+		args = args[1:]
+	}
+	runner.Main(p, args)
+}
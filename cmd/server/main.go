@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"log/slog"
+	"math/rand"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"google.golang.org/grpc"
+
+	"github.com/aawadall/go-concurrency-patterns/shared/grpcbench"
+)
+
+// test server for the benchmark clients
+func main() {
+	host := flag.String("host", "localhost", "address to listen on")
+	port := flag.Int("port", 5000, "HTTP port")
+	grpcPort := flag.Int("grpc-port", 0, "gRPC port, 0 disables the gRPC service")
+	latency := flag.Duration("latency", 0, "artificial latency per request")
+	jitter := flag.Duration("jitter", 0, "random extra latency, uniform in [0, jitter)")
+	errorRate := flag.Float64("error-rate", 0.01, "fraction of requests answered with 500")
+	maxConcurrency := flag.Int("max-concurrency", 0, "requests served at once, excess get 503; 0 is unlimited")
+	payloadSize := flag.Int("payload-size", 0, "size of the padding field in each response, in bytes")
+	flag.Parse()
+
+	s := &server{
+		latency:     *latency,
+		jitter:      *jitter,
+		errorRate:   *errorRate,
+		payloadSize: *payloadSize,
+	}
+	if *maxConcurrency > 0 {
+		s.slots = make(chan struct{}, *maxConcurrency)
+	}
+
+	if *grpcPort > 0 {
+		lis, err := net.Listen("tcp", fmt.Sprintf("%s:%d", *host, *grpcPort))
+		if err != nil {
+			log.Fatal(err)
+		}
+		gs := grpc.NewServer()
+		grpcbench.Register(gs, &grpcbench.Server{Latency: *latency, PayloadSize: *payloadSize})
+		go func() {
+			slog.Info("gRPC listening", "addr", lis.Addr())
+			log.Fatal(gs.Serve(lis))
+		}()
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/data", s.handleData)
+	mux.HandleFunc("POST /batch", s.handleBatch)
+	mux.HandleFunc("/ws", s.handleWebSocket)
+
+	addr := fmt.Sprintf("%s:%d", *host, *port)
+	slog.Info("HTTP listening", "addr", addr)
+	log.Fatal(http.ListenAndServe(addr, mux))
+}
+
+type server struct {
+	latency     time.Duration
+	jitter      time.Duration
+	errorRate   float64
+	payloadSize int
+
+	// slots limits concurrent requests when non-nil
+	slots chan struct{}
+}
+
+func (s *server) handleData(w http.ResponseWriter, r *http.Request) {
+	done, ok := s.hold(w, r)
+	if !ok {
+		return
+	}
+	defer done()
+
+	if rand.Float64() < s.errorRate {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Simulated server error"})
+		return
+	}
+
+	data := map[string]string{"message": "Hello, World!"}
+	if s.payloadSize > 0 {
+		data["padding"] = strings.Repeat("x", s.payloadSize)
+	}
+	writeJSON(w, http.StatusOK, data)
+}
+
+// hold holds a request for the latency and jitter, in one of the slots,
+// which it keeps until done is called. It answers 503 itself when they are
+// all taken, and reports false if the request is not to be answered
+// further.
+func (s *server) hold(w http.ResponseWriter, r *http.Request) (done func(), ok bool) {
+	done = func() {}
+	if s.slots != nil {
+		select {
+		case s.slots <- struct{}{}:
+			done = func() { <-s.slots }
+		default:
+			writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "Too many concurrent requests"})
+			return done, false
+		}
+	}
+
+	delay := s.latency
+	if s.jitter > 0 {
+		delay += time.Duration(rand.Int63n(int64(s.jitter)))
+	}
+	select {
+	case <-r.Context().Done():
+		done()
+		return done, false
+	case <-time.After(delay):
+		return done, true
+	}
+}
+
+// handleBatch answers a bulk request for the IDs in the body, as
+// {"ids": [1, 2, 3]}, after the latency of one request, with a status for
+// each ID: every one of them fails at the error rate on its own.
+func (s *server) handleBatch(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		IDs []int64 `json:"ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	done, ok := s.hold(w, r)
+	if !ok {
+		return
+	}
+	defer done()
+
+	type result struct {
+		ID      int64  `json:"id"`
+		Status  int    `json:"status"`
+		Message string `json:"message"`
+		Padding string `json:"padding,omitempty"`
+	}
+	results := make([]result, len(req.IDs))
+	for i, id := range req.IDs {
+		results[i] = result{ID: id, Status: http.StatusOK, Message: "Hello, World!"}
+		if rand.Float64() < s.errorRate {
+			results[i].Status, results[i].Message = http.StatusInternalServerError, "Simulated server error"
+		} else if s.payloadSize > 0 {
+			results[i].Padding = strings.Repeat("x", s.payloadSize)
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"results": results})
+}
+
+var upgrader = websocket.Upgrader{}
+
+// handleWebSocket echoes every message back after the configured latency.
+func (s *server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	for {
+		kind, msg, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		time.Sleep(s.latency)
+		if err := conn.WriteMessage(kind, msg); err != nil {
+			return
+		}
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// counter is incremented by many workers at once.
+type counter interface {
+	Add(worker int, n int64)
+	Value() int64
+}
+
+// mutexCounter is one count behind one mutex, so every worker contends
+// for the same lock.
+type mutexCounter struct {
+	mu sync.Mutex
+	n  int64
+}
+
+func (c *mutexCounter) Add(_ int, n int64) {
+	c.mu.Lock()
+	c.n += n
+	c.mu.Unlock()
+}
+
+func (c *mutexCounter) Value() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.n
+}
+
+// shard is one stripe of a shardedCounter, padded to its own cache line
+// so neighbouring shards don't share one.
+type shard struct {
+	mu sync.Mutex
+	n  int64
+	_  [48]byte
+}
+
+// shardedCounter stripes the count over shards, each with its own lock;
+// a worker only locks its own shard, and reading sums them all.
+type shardedCounter struct {
+	shards []shard
+}
+
+func newShardedCounter(shards int) *shardedCounter {
+	return &shardedCounter{shards: make([]shard, shards)}
+}
+
+func (c *shardedCounter) Add(worker int, n int64) {
+	s := &c.shards[worker%len(c.shards)]
+	s.mu.Lock()
+	s.n += n
+	s.mu.Unlock()
+}
+
+func (c *shardedCounter) Value() int64 {
+	var total int64
+	for i := range c.shards {
+		s := &c.shards[i]
+		s.mu.Lock()
+		total += s.n
+		s.mu.Unlock()
+	}
+	return total
+}
+
+// atomicCounter needs no lock, but every worker still writes the same
+// cache line.
+type atomicCounter struct {
+	n atomic.Int64
+}
+
+func (c *atomicCounter) Add(_ int, n int64) { c.n.Add(n) }
+func (c *atomicCounter) Value() int64       { return c.n.Load() }
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/aawadall/go-concurrency-patterns/config"
+	"github.com/aawadall/go-concurrency-patterns/shared"
+)
+
+// increment counters from many workers at once and compare a single
+// mutex, sharded mutexes and an atomic, each batch of increments recorded
+// as a request so the usual comparison report applies
+func main() {
+	workers := flag.Int("workers", 4*runtime.GOMAXPROCS(0), "workers incrementing at once")
+	duration := flag.Duration("duration", 2*time.Second, "how long to run each counter")
+	batch := flag.Int("batch", 1000, "increments per recorded request")
+	shards := flag.Int("shards", runtime.GOMAXPROCS(0), "shards of the sharded counter")
+	flag.Parse()
+
+	cfg := config.GetDefaultConfig()
+	cfg.Mode = "sim"
+	cfg.Concurrency = *workers
+	cfg.Duration = *duration
+
+	var labels []string
+	var runs []shared.Manifest
+	for _, c := range []struct {
+		name    string
+		counter counter
+	}{
+		{"mutex", &mutexCounter{}},
+		{"sharded", newShardedCounter(*shards)},
+		{"atomic", &atomicCounter{}},
+	} {
+		m := run(c.name, c.counter, cfg, *batch)
+		if want := int64(m.Summary.Count) * int64(*batch); c.counter.Value() != want {
+			log.Fatalf("%s counted %d, want %d", c.name, c.counter.Value(), want)
+		}
+		log.Printf("%-8s %.0f increments/s", c.name, m.RequestsPerSecond*float64(*batch))
+		labels = append(labels, c.name)
+		runs = append(runs, m)
+	}
+	shared.Compare(os.Stdout, labels, runs, 0.05)
+}
+
+// run has cfg.Concurrency workers increment c in batches for cfg.Duration.
+func run(name string, c counter, cfg *config.Config, batch int) shared.Manifest {
+	collector := shared.NewCollectorFor(cfg)
+	start := time.Now()
+	deadline := start.Add(cfg.Duration)
+	var wg sync.WaitGroup
+	for i := 0; i < cfg.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				begin := time.Now()
+				if begin.After(deadline) {
+					return
+				}
+				for range batch {
+					c.Add(i, 1)
+				}
+				end := time.Now()
+				collector.Record(shared.Result{
+					Status: 200, Latency: end.Sub(begin), Start: begin, End: end, WorkerID: i, Attempts: 1,
+				})
+			}
+		}()
+	}
+	wg.Wait()
+	return shared.NewManifest(name, cfg, start, time.Now(), collector.Summary(), nil)
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"os"
+
+	"github.com/aawadall/go-concurrency-patterns/patterns"
+	"github.com/aawadall/go-concurrency-patterns/runner"
+)
+
+// Same as gcp run singleflight, or gcp run singleflight-generic when the
+// first argument is "generic".
+func main() {
+	args := os.Args[1:]
+	p := patterns.SingleFlight{}
+	if len(args) > 0 && args[0] == "generic" {
+		p.Generic = true
+		args = args[1:]
+	}
+	runner.Main(p, args)
+}
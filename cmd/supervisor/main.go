@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aawadall/go-concurrency-patterns/cmd/pipelines/pipeline"
+	"github.com/aawadall/go-concurrency-patterns/config"
+	"github.com/aawadall/go-concurrency-patterns/shared"
+	"github.com/aawadall/go-concurrency-patterns/supervisor"
+)
+
+// run a two-stage pipeline whose workers are supervised children: fetch
+// workers make simulated requests and sometimes panic, and a record
+// worker counts the results
+func main() {
+	jobs := flag.Int("jobs", 500, "messages fed through the pipeline")
+	workers := flag.Int("workers", 3, "fetch workers")
+	panics := flag.Float64("panics", 0.01, "fraction of messages a fetch worker panics on")
+	strategy := flag.String("strategy", "one-for-one", "restart strategy: one-for-one or one-for-all")
+	maxRestarts := flag.Int("max-restarts", 20, "most restarts within -within before giving up")
+	within := flag.Duration("within", time.Second, "window restarts are counted over")
+	seed := flag.Int64("seed", time.Now().UnixNano(), "random seed for the panics")
+	flag.Parse()
+
+	cfg := config.GetDefaultConfig()
+	cfg.Mode = "sim"
+	cfg.Quiet = true
+	target, err := shared.NewSimTarget(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	opts := supervisor.Options{MaxRestarts: *maxRestarts, Within: *within}
+	switch *strategy {
+	case "one-for-one":
+	case "one-for-all":
+		opts.Strategy = supervisor.OneForAll
+	default:
+		log.Fatalf("unknown strategy %q", *strategy)
+	}
+
+	source := make(chan pipeline.Message[int])
+	go func() {
+		defer close(source)
+		for i := range *jobs {
+			source <- pipeline.Message[int]{ID: int64(i), Payload: i}
+		}
+	}()
+
+	// fetched is closed once every fetch worker has finished, not just
+	// been restarted
+	fetched := make(chan pipeline.Message[shared.Result])
+	var finished sync.WaitGroup
+	finished.Add(*workers)
+	go func() {
+		finished.Wait()
+		close(fetched)
+	}()
+
+	var rngMu sync.Mutex
+	rng := rand.New(rand.NewSource(*seed))
+	var children []supervisor.Child
+	for i := range *workers {
+		// a worker stopped by a one-for-all restart just as it finished
+		// runs again and finds the source closed a second time
+		var done sync.Once
+		children = append(children, supervisor.Child{
+			Name: fmt.Sprintf("fetch-%d", i),
+			Run: func(ctx context.Context) error {
+				ctx = shared.WithWorkerID(ctx, i)
+				for {
+					select {
+					case m, ok := <-source:
+						if !ok {
+							done.Do(finished.Done)
+							return nil
+						}
+						rngMu.Lock()
+						fail := rng.Float64() < *panics
+						rngMu.Unlock()
+						if fail {
+							panic(fmt.Sprintf("message %d", m.ID))
+						}
+						r, _ := target.Do(ctx)
+						select {
+						case fetched <- pipeline.Message[shared.Result]{ID: m.ID, Payload: r}:
+						case <-ctx.Done():
+							return ctx.Err()
+						}
+					case <-ctx.Done():
+						return ctx.Err()
+					}
+				}
+			},
+		})
+	}
+	var recorded atomic.Int64
+	children = append(children, supervisor.Child{
+		Name: "record",
+		Run: func(ctx context.Context) error {
+			for {
+				select {
+				case _, ok := <-fetched:
+					if !ok {
+						return nil
+					}
+					recorded.Add(1)
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+		},
+	})
+
+	s := supervisor.New(opts, children...)
+	if err := s.Run(context.Background()); err != nil {
+		log.Printf("supervisor gave up: %v", err)
+	}
+	log.Printf("%d of %d messages made it through", recorded.Load(), *jobs)
+	for _, st := range s.Status() {
+		log.Printf("  %-8s %-8s restarts=%d last error: %v", st.Name, st.State, st.Restarts, st.LastErr)
+	}
+}
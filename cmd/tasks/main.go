@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/aawadall/go-concurrency-patterns/cmd/pipelines/pipeline"
+)
+
+// use the pipeline as a parallel task runner over external commands:
+// square numbers by streaming them through long-lived shells, then hash
+// each square with a sha256sum process of its own
+func main() {
+	count := flag.Int("count", 50, "numbers to run through the pipeline")
+	shells := flag.Int("shells", 2, "shells squaring numbers")
+	hashers := flag.Int("hashers", 4, "sha256sum processes running at once")
+	timeout := flag.Duration("timeout", 5*time.Second, "how long a command may take per message")
+	flag.Parse()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	source := make(chan pipeline.Message[int])
+	go func() {
+		defer close(source)
+		for i := 1; i <= *count; i++ {
+			source <- pipeline.Message[int]{ID: int64(i), Payload: i}
+		}
+	}()
+
+	square, stop := pipeline.StreamStage(ctx, "square", *shells, pipeline.Command[int, int]{
+		Path:    "sh",
+		Args:    []string{"-c", `while read -r n; do echo $((n * n)); done`},
+		Input:   func(n int) []byte { return strconv.AppendInt(nil, int64(n), 10) },
+		Output:  func(line []byte) (int, error) { return strconv.Atoi(string(line)) },
+		Timeout: *timeout,
+	})
+	defer stop()
+
+	type hashed struct {
+		square int
+		sum    string
+	}
+	hash := pipeline.ExecStage(ctx, "hash", *hashers, pipeline.Command[int, hashed]{
+		Path:  "sha256sum",
+		Input: func(n int) []byte { return strconv.AppendInt(nil, int64(n), 10) },
+		Output: func(out []byte) (hashed, error) {
+			sum, _, _ := bytes.Cut(out, []byte(" "))
+			return hashed{sum: string(sum)}, nil
+		},
+		Timeout: *timeout,
+	})
+	// keep the square alongside its hash
+	hashSquare := hash.Function
+	hash.Function = func(m pipeline.Message[int]) (pipeline.Message[hashed], error) {
+		h, err := hashSquare(m)
+		h.Payload.square = m.Payload
+		return h, err
+	}
+
+	start := time.Now()
+	squared, squareGroup := square.Run(ctx, source)
+	sums, hashGroup := hash.Run(ctx, squared)
+	n := 0
+	for m := range sums {
+		log.Printf("[%d] %d %s", m.ID, m.Payload.square, m.Payload.sum[:16])
+		n++
+	}
+	for _, g := range []interface{ Wait() error }{squareGroup, hashGroup} {
+		if err := g.Wait(); err != nil {
+			log.Fatal(err)
+		}
+	}
+	log.Printf("%d numbers squared and hashed in %v", n, time.Since(start))
+}
@@ -0,0 +1,319 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"text/tabwriter"
+	"time"
+)
+
+// timers and tickers done right and wrong: the Stop and Reset pitfalls,
+// work loops on a schedule with and without drift correction, a debounce
+// on one reused timer, and what time.After in a loop costs against a
+// reused timer; with no section named, all of them
+//
+//	timers [-interval 10ms] [-work 15ms] [-ticks 50] [pitfalls|ticker|debounce|bench]...
+func main() {
+	seed := flag.Int64("seed", time.Now().UnixNano(), "random seed for the work times")
+	interval := flag.Duration("interval", 10*time.Millisecond, "schedule of the work loops")
+	work := flag.Duration("work", 15*time.Millisecond, "longest a round of work takes")
+	ticks := flag.Int("ticks", 50, "rounds of each work loop")
+	flag.Parse()
+	rng := rand.New(rand.NewSource(*seed))
+
+	sections := map[string]func(){
+		"pitfalls": pitfalls,
+		"ticker":   func() { loops(*interval, *ticks, *work, rng) },
+		"debounce": debounce,
+		"bench":    bench,
+	}
+	names := flag.Args()
+	if len(names) == 0 {
+		names = []string{"pitfalls", "ticker", "debounce", "bench"}
+	}
+	for _, name := range names {
+		if sections[name] == nil {
+			log.Fatalf("unknown section %q; try pitfalls, ticker, debounce or bench", name)
+		}
+	}
+	for _, name := range names {
+		fmt.Printf("\n%s:\n", name)
+		sections[name]()
+	}
+}
+
+// blocks tells whether ch has nothing to receive for a while, which is
+// as close to "blocks forever" as a demo can wait.
+func blocks(ch <-chan time.Time) bool {
+	select {
+	case <-ch:
+		return false
+	case <-time.After(100 * time.Millisecond):
+		return true
+	}
+}
+
+// pitfalls tries the mistakes people make with Stop and Reset, and what
+// Go does about them now that timer channels are unbuffered (Go 1.23 on).
+func pitfalls() {
+	// before Go 1.23, a timer that had fired kept its tick in the
+	// channel, and a Reset without draining it first woke the next
+	// receive at once; now the stale tick is gone
+	t := time.NewTimer(time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	t.Reset(50 * time.Millisecond)
+	start := time.Now()
+	<-t.C
+	fmt.Printf("  Reset after an unreceived tick: next tick after %v, no stale one\n",
+		time.Since(start).Round(time.Millisecond))
+
+	// the drain idiom that went with it, if !t.Stop() { <-t.C }, hangs
+	// when the tick has already been received: Stop reports the timer
+	// expired, and there is nothing left to drain
+	t = time.NewTimer(time.Millisecond)
+	<-t.C
+	if !t.Stop() {
+		fmt.Printf("  Stop then drain, tick already received: drain blocks forever: %v\n", blocks(t.C))
+	}
+
+	// Stop doesn't close the channel, so a goroutine ranging over a
+	// ticker's C never ends: stop it with a done channel as well
+	tk := time.NewTicker(time.Millisecond)
+	ended := make(chan struct{})
+	go func() {
+		defer close(ended)
+		for range tk.C {
+		}
+	}()
+	time.Sleep(5 * time.Millisecond)
+	tk.Stop()
+	select {
+	case <-ended:
+		fmt.Println("  range over a stopped ticker: ended")
+	case <-time.After(100 * time.Millisecond):
+		fmt.Println("  range over a stopped ticker: still running, leaked")
+	}
+
+	// an AfterFunc timer that has fired has started its function in a
+	// goroutine of its own; Reset doesn't wait for it, so the function
+	// runs again alongside itself unless it guards against that
+	var running, most atomic.Int32
+	f := func() {
+		n := running.Add(1)
+		for {
+			m := most.Load()
+			if n <= m || most.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		running.Add(-1)
+	}
+	af := time.AfterFunc(time.Millisecond, f)
+	time.Sleep(5 * time.Millisecond)
+	fmt.Printf("  Reset of an AfterFunc that fired: Reset reported %v", af.Reset(time.Millisecond))
+	time.Sleep(50 * time.Millisecond)
+	fmt.Printf(", the function ran %d at once\n", most.Load())
+}
+
+// loops runs the same work on a schedule three ways, and shows how far
+// each drifts from it: sleeping for the interval after the work, which
+// adds the work to every round; a ticker, which keeps to the schedule
+// but drops the ticks that come while the work overruns; and a loop
+// that works out when each round is due and sleeps until then on one
+// reused timer, which catches up after an overrun.
+func loops(interval time.Duration, ticks int, work time.Duration, rng *rand.Rand) {
+	// the same work times for every loop
+	works := make([]time.Duration, ticks)
+	for i := range works {
+		works[i] = time.Duration(rng.Int63n(int64(work) + 1))
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "  loop\ttook\tdrift\tmean late\tmax late\n")
+	for _, l := range []struct {
+		name string
+		run  func(due func(int))
+	}{
+		{"sleep", func(due func(int)) {
+			for i := range ticks {
+				time.Sleep(interval)
+				due(i)
+			}
+		}},
+		{"ticker", func(due func(int)) {
+			tk := time.NewTicker(interval)
+			defer tk.Stop()
+			for i := range ticks {
+				<-tk.C
+				due(i)
+			}
+		}},
+		{"corrected", func(due func(int)) {
+			t := time.NewTimer(interval)
+			defer t.Stop()
+			start := time.Now()
+			for i := range ticks {
+				next := start.Add(time.Duration(i+1) * interval)
+				if wait := time.Until(next); wait > 0 {
+					t.Reset(wait)
+					<-t.C
+				}
+				due(i)
+			}
+		}},
+	} {
+		var late, most time.Duration
+		start := time.Now()
+		l.run(func(i int) {
+			// round i is due at its slot on the schedule
+			d := time.Since(start.Add(time.Duration(i+1) * interval))
+			late += d
+			most = max(most, d)
+			time.Sleep(works[i])
+		})
+		took := time.Since(start)
+		ideal := time.Duration(ticks) * interval
+		fmt.Fprintf(w, "  %s\t%v\t%v\t%v\t%v\n", l.name, took.Round(time.Millisecond),
+			(took - ideal).Round(time.Millisecond),
+			(late / time.Duration(ticks)).Round(100*time.Microsecond), most.Round(100*time.Microsecond))
+	}
+	w.Flush()
+}
+
+// debouncer calls f once triggers have stopped for quiet, on one timer
+// that every trigger resets, rather than a new one each time.
+type debouncer struct {
+	mu    sync.Mutex
+	t     *time.Timer
+	quiet time.Duration
+	f     func()
+}
+
+func (d *debouncer) trigger() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.t == nil {
+		d.t = time.AfterFunc(d.quiet, d.f)
+		return
+	}
+	d.t.Reset(d.quiet)
+}
+
+func (d *debouncer) stop() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.t != nil {
+		d.t.Stop()
+	}
+}
+
+// debounce triggers a debouncer in bursts, which fires once after each.
+func debounce() {
+	const (
+		bursts = 4
+		size   = 10
+		gap    = 5 * time.Millisecond
+		pause  = 100 * time.Millisecond
+		quiet  = 30 * time.Millisecond
+	)
+	start := time.Now()
+	var triggers, fired atomic.Int32
+	d := &debouncer{quiet: quiet, f: func() {
+		fired.Add(1)
+		fmt.Printf("  fired at %v, after %d triggers\n", time.Since(start).Round(time.Millisecond), triggers.Swap(0))
+	}}
+	defer d.stop()
+	for b := range bursts {
+		if b > 0 {
+			time.Sleep(pause)
+		}
+		for range size {
+			triggers.Add(1)
+			d.trigger()
+			time.Sleep(gap)
+		}
+	}
+	time.Sleep(2 * quiet)
+	fmt.Printf("  %d triggers in %d bursts, fired %d times\n", bursts*size, bursts, fired.Load())
+}
+
+// benchmarks are the ways round a loop a timer can go; main runs them
+// with bench, and go test -bench with BenchmarkTimers.
+var benchmarks = []struct {
+	name string
+	fn   func(b *testing.B)
+}{
+	{"time.After in a loop", func(b *testing.B) {
+		ch := make(chan int, 1)
+		for range b.N {
+			ch <- 1
+			select {
+			case <-ch:
+			case <-time.After(time.Second):
+			}
+		}
+	}},
+	{"NewTimer and Stop in a loop", func(b *testing.B) {
+		ch := make(chan int, 1)
+		for range b.N {
+			ch <- 1
+			t := time.NewTimer(time.Second)
+			select {
+			case <-ch:
+			case <-t.C:
+			}
+			t.Stop()
+		}
+	}},
+	{"one timer, Reset", func(b *testing.B) {
+		ch := make(chan int, 1)
+		t := time.NewTimer(time.Second)
+		defer t.Stop()
+		for range b.N {
+			ch <- 1
+			t.Reset(time.Second)
+			select {
+			case <-ch:
+			case <-t.C:
+			}
+		}
+	}},
+	{"debounce, AfterFunc per trigger", func(b *testing.B) {
+		var t *time.Timer
+		for range b.N {
+			if t != nil {
+				t.Stop()
+			}
+			t = time.AfterFunc(time.Second, func() {})
+		}
+		t.Stop()
+	}},
+	{"debounce, one AfterFunc, Reset", func(b *testing.B) {
+		d := &debouncer{quiet: time.Second, f: func() {}}
+		for range b.N {
+			d.trigger()
+		}
+		d.stop()
+	}},
+}
+
+// bench measures what a timer costs each way round a loop.
+func bench() {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "  timer\tns/op\tallocs/op\tB/op\n")
+	for _, b := range benchmarks {
+		r := testing.Benchmark(func(tb *testing.B) {
+			tb.ReportAllocs()
+			b.fn(tb)
+		})
+		fmt.Fprintf(w, "  %s\t%d\t%d\t%d\n", b.name, r.NsPerOp(), r.AllocsPerOp(), r.AllocedBytesPerOp())
+	}
+	w.Flush()
+}
@@ -0,0 +1,12 @@
+package main
+
+import "testing"
+
+func BenchmarkTimers(b *testing.B) {
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			b.ReportAllocs()
+			bm.fn(b)
+		})
+	}
+}
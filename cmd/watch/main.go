@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aawadall/go-concurrency-patterns/config"
+	"github.com/aawadall/go-concurrency-patterns/shared"
+	"github.com/aawadall/go-concurrency-patterns/watch"
+)
+
+// run workers making simulated requests while a controller changes, every
+// -step, how long they pause between requests; the settings are a
+// watch.Value, so each change reaches every running worker without
+// restarting it, and how fast it did and what the workers achieved under
+// it are reported per step
+func main() {
+	workers := flag.Int("workers", 8, "workers making requests")
+	list := flag.String("pauses", "0s,5ms,1ms,0s", "comma-separated pauses between requests, one a step")
+	step := flag.Duration("step", time.Second, "how long each step lasts")
+	flag.Parse()
+
+	var pauses []time.Duration
+	for _, s := range strings.Split(*list, ",") {
+		d, err := time.ParseDuration(strings.TrimSpace(s))
+		if err != nil {
+			log.Fatalf("bad pause %q: %v", s, err)
+		}
+		pauses = append(pauses, d)
+	}
+
+	cfg := config.GetDefaultConfig()
+	cfg.Mode = "sim"
+	cfg.Quiet = true
+	target, err := shared.NewSimTarget(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	st := &stats{requests: make([]atomic.Int64, len(pauses)), lag: make([]time.Duration, len(pauses))}
+	current := watch.New(settings{pause: pauses[0], published: time.Now()})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	for i := range *workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx := shared.WithWorkerID(ctx, i)
+			updates := current.Watch(ctx)
+			s := <-updates
+			for {
+				select {
+				case next, ok := <-updates:
+					if !ok {
+						return
+					}
+					s = next
+					st.applied(s)
+				default:
+				}
+				if s.pause > 0 {
+					time.Sleep(s.pause)
+				}
+				if _, err := target.Do(ctx); ctx.Err() != nil {
+					return
+				} else if err == nil {
+					st.requests[s.step].Add(1)
+				}
+			}
+		}()
+	}
+
+	for i := range pauses {
+		if i > 0 {
+			current.Set(settings{step: i, pause: pauses[i], published: time.Now()})
+		}
+		time.Sleep(*step)
+	}
+	cancel()
+	wg.Wait()
+
+	for i, pause := range pauses {
+		log.Printf("step %d: pause %-8v %8.0f requests/s, every worker had it %v after it was set",
+			i, pause, float64(st.requests[i].Load())/step.Seconds(), st.lag[i])
+	}
+}
+
+// settings is what the workers are told to do.
+type settings struct {
+	step      int
+	pause     time.Duration
+	published time.Time
+}
+
+// stats is what the workers did in each step.
+type stats struct {
+	requests []atomic.Int64
+
+	mu  sync.Mutex
+	lag []time.Duration // how long the slowest worker took to apply the step's settings
+}
+
+func (st *stats) applied(s settings) {
+	st.mu.Lock()
+	st.lag[s.step] = max(st.lag[s.step], time.Since(s.published))
+	st.mu.Unlock()
+}
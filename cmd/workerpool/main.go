@@ -0,0 +1,20 @@
+package main
+
+import (
+	"os"
+
+	"github.com/aawadall/go-concurrency-patterns/patterns"
+	"github.com/aawadall/go-concurrency-patterns/runner"
+)
+
+// Same as gcp run workerpool, or gcp run workerpool-queue when the first
+// argument is "queue".
+func main() {
+	args := os.Args[1:]
+	p := patterns.WorkerPool{}
+	if len(args) > 0 && args[0] == "queue" {
+		p.Queue = true
+		args = args[1:]
+	}
+	runner.Main(p, args)
+}
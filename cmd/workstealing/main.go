@@ -0,0 +1,20 @@
+package main
+
+import (
+	"os"
+
+	"github.com/aawadall/go-concurrency-patterns/patterns"
+	"github.com/aawadall/go-concurrency-patterns/runner"
+)
+
+// Same as gcp run workstealing, or gcp run workstealing-shared when the first
+// argument is "shared".
+func main() {
+	args := os.Args[1:]
+	p := patterns.WorkStealing{}
+	if len(args) > 0 && args[0] == "shared" {
+		p.Shared = true
+		args = args[1:]
+	}
+	runner.Main(p, args)
+}
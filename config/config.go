@@ -1,26 +1,298 @@
 package config
 
+import (
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"time"
+)
+
 type Config struct {
+	// Pattern names the pattern to run, for front ends that run several.
+	Pattern string
+	// ConfigFile and Scenario record where the settings were loaded from.
+	ConfigFile string
+	Scenario   string
+
+	// Mode selects the client: "http" (default), "grpc", "ws" for a
+	// WebSocket echo client holding one persistent connection per worker,
+	// or "sim" for an in-process simulated target.
+	Mode string
+
+	Scheme      string
 	Host        string
 	Port        int
 	Requests    int
 	Concurrency int
+	// Duration runs for this long instead of a fixed number of requests;
+	// 0 makes exactly Requests requests.
+	Duration time.Duration
+	// Sweep runs the pattern once at each of these concurrency levels
+	// in place of Concurrency, and reports them side by side.
+	Sweep []int
+	// A warm-up of WarmupDuration or WarmupRequests runs before the
+	// measured requests; its results are reported separately.
+	WarmupDuration time.Duration
+	WarmupRequests int
+	// ArrivalRate switches to an open-loop run issuing this many requests
+	// per second on a fixed schedule; latency is then also reported from
+	// each request's intended start. 0 keeps the closed loop.
+	ArrivalRate float64
+	// RatePerSecond caps the request rate across all workers with a token
+	// bucket holding up to Burst requests; 0 means no limit.
+	RatePerSecond float64
+	Burst         int
+	// Profile varies the load over the run, which then lasts as long as
+	// the profile. ProfileOf says what it varies: "rps", the request rate,
+	// or "concurrency", the requests in flight, up to Concurrency.
+	Profile   []Phase
+	ProfileOf string
+	// ThinkTime pauses each worker after every request, for this long on
+	// average, with ThinkDistribution "constant", "uniform" or
+	// "exponential". Pauses don't count towards latency.
+	ThinkTime         time.Duration
+	ThinkDistribution string
+	// Timeout bounds each request, retries included; 0 means no limit.
+	Timeout time.Duration
+	// QueueDepth is how many requests the worker pool pattern queues for
+	// its workers, 0 for as many as there are workers. QueueFull says
+	// what happens to requests that don't fit: "block" until there is
+	// room, or "reject" them.
+	QueueDepth int
+	QueueFull  string
+	// BatchSize is the most requests the batch pattern coalesces into one
+	// call, and BatchWait the longest it holds a request back waiting
+	// for a batch to fill.
+	BatchSize int
+	BatchWait time.Duration
+	// MaxInFlight caps the requests in flight at once, whatever the
+	// pattern's concurrency; 0 means no cap.
+	MaxInFlight int
+	// DrainTimeout is how long requests in flight get to finish when a
+	// run is interrupted; 0 cancels them at once.
+	DrainTimeout time.Duration
+
+	// Endpoints spreads requests over several targets in proportion to
+	// their weights. Each replaces the scheme, host, port, path, method
+	// and body above; when empty every request goes to the one target.
+	Endpoints []Endpoint
+
+	// Seed seeds every random choice made for the run: template values,
+	// think times, endpoint picks and sim latencies. 0 picks a seed from
+	// the clock; the one used is saved with the results.
+	Seed int64
+
+	// Request templates, evaluated per request with text/template.
+	// Available fields: {{.RequestID}}, {{.WorkerID}}; functions:
+	// randInt, randString, uuid.
+	Method string
+	Path   string
+	Body   string
+
+	// Percentiles are the latency percentiles included in the report.
+	Percentiles []float64
+	// Output is the report format: text, json or csv.
+	Output string
+	// ResultsDir is where run manifests are written; empty disables them.
+	ResultsDir string
+	// Database is an SQLite file each run's summary and samples are
+	// appended to; empty disables it. Needs a build with -tags sqlite.
+	Database string
+	// Interval is the bucket width of the throughput time series.
+	Interval time.Duration
+	// Logging. LogLevel is debug, info, warn or error; LogFormat is text
+	// or json. Quiet only logs errors and hides progress output.
+	LogLevel  string
+	LogFormat string
+	Quiet     bool
+	// Live replaces the per-request progress dots with a status line
+	// redrawn in place during the run.
+	Live bool
+	// ExportURL streams per-request samples during the run to statsd
+	// (statsd://host:port), InfluxDB over UDP (influx://host:port) or the
+	// InfluxDB HTTP write API (http://...); empty disables it.
+	ExportURL string
+	// MetricsAddr is where Prometheus metrics and net/http/pprof are
+	// served during the run, e.g. ":9100"; empty disables them.
+	MetricsAddr string
+	// AdminAddr is where an endpoint for changing the concurrency and rps
+	// of a run while it goes is served, e.g. ":9101"; empty disables it.
+	// Reload rereads them from ConfigFile on SIGHUP instead, or as well.
+	AdminAddr string
+	Reload    bool
+	// Agents are the addresses of gcp agents to split the run across,
+	// e.g. "10.0.0.2:7070"; their results are reported as one run. Empty
+	// runs it here.
+	Agents []string
+	// CPUProfile and HeapProfile save pprof profiles of the run into
+	// ResultsDir, or the working directory when that is empty.
+	CPUProfile  bool
+	HeapProfile bool
+
+	// Service level objectives, checked after the run; a failed check makes
+	// the process exit with status 1. LatencySLO caps the latency at given
+	// percentiles, e.g. {99, 200ms}. MaxErrorRate is a
+	// fraction and ApdexT the Apdex threshold; zero disables either.
+	// MinApdex fails the run when the Apdex score falls below it.
+	LatencySLO   []LatencyObjective
+	MaxErrorRate float64
+	ApdexT       time.Duration
+	MinApdex     float64
+
+	// Retry policy. MaxAttempts of 1 disables retries; the backoff doubles
+	// after each attempt up to MaxBackoff.
+	MaxAttempts         int
+	Backoff             time.Duration
+	MaxBackoff          time.Duration
+	RetryOnStatus       []int
+	RetryOnNetworkError bool
+
+	// Protocol selects the HTTP version: "http1", "h2", "h2c" (HTTP/2
+	// without TLS) or "h3". Empty leaves the Go default negotiation.
+	Protocol string
+
+	// Response validation. Responses failing any of these are counted as
+	// validation failures; an empty value disables the check.
+	ExpectStatus []int
+	BodyContains string
+	BodyPattern  string
+	MaxBodySize  int64
+
+	// GRPCMethod is the full method name called in grpc mode.
+	GRPCMethod string
+
+	// Simulated target settings, used in sim mode. SimDistribution is one
+	// of constant, uniform, normal, lognormal or bimodal. SimLatency is the
+	// mean (median for lognormal) and SimJitter the spread; bimodal draws
+	// SimSlowLatency with probability SimSlowFraction.
+	SimDistribution string
+	SimLatency      time.Duration
+	SimJitter       time.Duration
+	SimSlowLatency  time.Duration
+	SimSlowFraction float64
+	SimErrorRate    float64
+
+	// HTTP connection pool limits; 0 keeps Go's defaults. MaxIdleConns
+	// applies per host as well, as each transport talks to one host.
+	MaxIdleConns    int
+	MaxConnsPerHost int
+
+	// Pool reuses the buffers each HTTP request reads its response with
+	// from a sync.Pool instead of allocating them per request.
+	Pool bool
+
+	// Dialing. UnixSocket sends every request over the given socket;
+	// LocalPortMin/Max pin the source port range; Resolver is the address
+	// of a DNS server used instead of the system resolver.
+	UnixSocket   string
+	LocalPortMin int
+	LocalPortMax int
+	Resolver     string
+
+	// TLS settings, used when Scheme is "https". CAFile adds a custom root
+	// CA; CertFile and KeyFile configure a client certificate.
+	CAFile             string
+	CertFile           string
+	KeyFile            string
+	InsecureSkipVerify bool
 }
 
-func NewConfig(host string, port int) *Config {
-	return &Config{
-		Host:        host,
-		Port:        port,
-		Requests:    10,
-		Concurrency: 4,
+// LatencyObjective caps the latency at a percentile.
+type LatencyObjective struct {
+	Percentile float64
+	Max        time.Duration
+}
+
+// Endpoint is one of several targets sharing a run, getting Weight
+// requests for every unit of weight in the run.
+type Endpoint struct {
+	Weight float64
+	Method string
+	Scheme string
+	Host   string
+	Port   int
+	// Path and Body are request templates, as for Config.
+	Path string
+	Body string
+}
+
+// Name identifies the endpoint in reports, e.g. "GET localhost:5000/data".
+func (e Endpoint) Name() string {
+	return fmt.Sprintf("%s %s%s", e.Method, net.JoinHostPort(e.Host, strconv.Itoa(e.Port)), e.Path)
+}
+
+// Phase is one part of a load profile, over which the load moves
+// linearly from From to To; it holds steady when they are equal.
+type Phase struct {
+	Duration time.Duration
+	From, To float64
+}
+
+// RunDuration is how long a run lasts when it isn't limited to a number
+// of requests: Duration, or the length of Profile, or 0.
+func (c *Config) RunDuration() time.Duration {
+	if c.Duration > 0 {
+		return c.Duration
 	}
+	var d time.Duration
+	for _, p := range c.Profile {
+		d += p.Duration
+	}
+	return d
+}
+
+// DefaultPercentiles are the latency percentiles reported unless
+// Percentiles says otherwise.
+var DefaultPercentiles = []float64{50, 90, 99, 99.9}
+
+// NewConfig returns the defaults for a short run against host:port.
+func NewConfig(host string, port int) *Config {
+	cfg := GetDefaultConfig()
+	cfg.Host = host
+	cfg.Port = port
+	cfg.Requests = 10
+	cfg.Concurrency = 4
+	return cfg
 }
 
 func GetDefaultConfig() *Config {
 	return &Config{
+		Mode:        "http",
+		Scheme:      "http",
 		Host:        "localhost",
 		Port:        5000,
 		Requests:    7500,
 		Concurrency: 15,
+		Burst:       1,
+		ProfileOf:   "rps",
+		Method:      "GET",
+		Path:        "/data",
+		GRPCMethod:  "/gcp.bench.Bench/Call",
+
+		ThinkDistribution: "constant",
+		DrainTimeout:      5 * time.Second,
+		QueueFull:         "block",
+		BatchSize:         10,
+		BatchWait:         10 * time.Millisecond,
+
+		MaxAttempts:         1,
+		Backoff:             50 * time.Millisecond,
+		MaxBackoff:          2 * time.Second,
+		RetryOnStatus:       []int{502, 503, 504},
+		RetryOnNetworkError: true,
+
+		Percentiles: slices.Clone(DefaultPercentiles),
+		Output:      "text",
+		Interval:    time.Second,
+		LogLevel:    "info",
+		LogFormat:   "text",
+
+		SimDistribution: "normal",
+		SimLatency:      5 * time.Millisecond,
+		SimJitter:       time.Millisecond,
+		SimSlowLatency:  50 * time.Millisecond,
+		SimSlowFraction: 0.05,
+		SimErrorRate:    0.01,
 	}
 }
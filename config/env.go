@@ -0,0 +1,84 @@
+package config
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// EnvPrefix starts the name of every environment variable read by FromEnv.
+const EnvPrefix = "GCP_"
+
+// FromEnv returns the default config overridden by environment variables.
+// Each flag has one, named after it: -max-attempts is GCP_MAX_ATTEMPTS.
+// Variables in a .env file in the working directory are used when not set
+// in the environment.
+func FromEnv() (*Config, error) {
+	cfg := GetDefaultConfig()
+	flags := flag.NewFlagSet("gcp", flag.ContinueOnError)
+	cfg.AddFlags(flags)
+	if err := applyEnv(flags); err != nil {
+		return nil, err
+	}
+	return cfg, cfg.Validate()
+}
+
+// EnvName returns the environment variable for a flag.
+func EnvName(flagName string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// applyEnv sets each flag in flags from its environment variable.
+func applyEnv(flags *flag.FlagSet) error {
+	dotEnv, err := readDotEnv(".env")
+	if err != nil {
+		return err
+	}
+	var errs []error
+	flags.VisitAll(func(f *flag.Flag) {
+		name := EnvName(f.Name)
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			value, ok = dotEnv[name]
+		}
+		if !ok {
+			return
+		}
+		if err := flags.Set(f.Name, value); err != nil {
+			errs = append(errs, fmt.Errorf("%s=%q: %w", name, value, err))
+		}
+	})
+	return errors.Join(errs...)
+}
+
+// readDotEnv reads KEY=VALUE lines from path, skipping blank lines and
+// comments. A missing file is not an error.
+func readDotEnv(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	vars := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(strings.TrimPrefix(text, "export "), "=")
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: expected KEY=VALUE", path, line)
+		}
+		vars[strings.TrimSpace(key)] = strings.Trim(strings.TrimSpace(value), `"'`)
+	}
+	return vars, scanner.Err()
+}
@@ -0,0 +1,130 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// File is a config file defining named scenarios, in YAML or JSON:
+//
+//	defaults:
+//	  host: localhost
+//	  port: 5000
+//	scenarios:
+//	  smoke:
+//	    pattern: fanoutin
+//	    requests: 100
+//	  soak:
+//	    pattern: pipeline
+//	    requests: 100000
+//	    concurrency: 64
+//	    slo: {p99: 200ms}
+//
+// Keys are flag names; lists and maps are written as the flag would be,
+// so slo: {p99: 200ms} is -slo p99=200ms.
+type File struct {
+	Path      string
+	Defaults  map[string]any            `yaml:"defaults"`
+	Scenarios map[string]map[string]any `yaml:"scenarios"`
+}
+
+// Load reads a config file.
+func Load(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	f := &File{Path: path}
+	if err := yaml.Unmarshal(data, f); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return f, nil
+}
+
+// ScenarioNames returns the scenarios defined in the file, sorted.
+func (f *File) ScenarioNames() []string {
+	return slices.Sorted(maps.Keys(f.Scenarios))
+}
+
+// Config returns the default config overridden by the file's defaults and
+// then by the named scenario. The name may be empty when the file defines
+// at most one scenario.
+func (f *File) Config(scenario string) (*Config, error) {
+	cfg := GetDefaultConfig()
+	fs := flag.NewFlagSet("gcp", flag.ContinueOnError)
+	cfg.AddFlags(fs)
+	if err := f.apply(fs, scenario); err != nil {
+		return nil, err
+	}
+	return cfg, cfg.Validate()
+}
+
+// apply sets the flags in fs from the file's defaults and scenario.
+func (f *File) apply(fs *flag.FlagSet, scenario string) error {
+	if scenario == "" && len(f.Scenarios) == 1 {
+		scenario = f.ScenarioNames()[0]
+	}
+	settings, ok := f.Scenarios[scenario]
+	if scenario != "" && !ok {
+		return fmt.Errorf("%s: no scenario %q, have %v", f.Path, scenario, f.ScenarioNames())
+	}
+	if scenario == "" && len(f.Scenarios) > 1 {
+		return fmt.Errorf("%s: pick a scenario from %v", f.Path, f.ScenarioNames())
+	}
+
+	for _, values := range []map[string]any{f.Defaults, settings} {
+		for _, key := range slices.Sorted(maps.Keys(values)) {
+			fl := fs.Lookup(key)
+			if fl == nil {
+				return fmt.Errorf("%s: unknown setting %q", f.Path, key)
+			}
+			sep := ","
+			if s, ok := fl.Value.(separated); ok {
+				sep = s.Separator()
+			}
+			if err := fs.Set(key, flagValue(values[key], sep)); err != nil {
+				return fmt.Errorf("%s: %s: %w", f.Path, key, err)
+			}
+		}
+	}
+	if err := fs.Set("config", f.Path); err != nil {
+		return err
+	}
+	return fs.Set("scenario", scenario)
+}
+
+// separated is implemented by list flags whose items may contain commas.
+type separated interface {
+	// Separator returns what goes between the items of the list.
+	Separator() string
+}
+
+// flagValue formats a value decoded from a file as a flag would take it,
+// with sep between list items.
+func flagValue(v any, sep string) string {
+	switch v := v.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case []any:
+		parts := make([]string, len(v))
+		for i, item := range v {
+			parts[i] = flagValue(item, sep)
+		}
+		return strings.Join(parts, sep)
+	case map[string]any:
+		var parts []string
+		for _, key := range slices.Sorted(maps.Keys(v)) {
+			parts = append(parts, key+"="+flagValue(v[key], sep))
+		}
+		return strings.Join(parts, ",")
+	default:
+		return fmt.Sprint(v)
+	}
+}
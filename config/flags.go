@@ -0,0 +1,387 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FromFlags returns the default config overridden, in order, by a
+// scenario from the config file given with -config, by environment
+// variables (see FromEnv) and by command-line flags, e.g. os.Args[1:].
+func FromFlags(args []string) (*Config, error) {
+	cfg := GetDefaultConfig()
+	fs := flag.NewFlagSet("gcp", flag.ContinueOnError)
+	cfg.AddFlags(fs)
+
+	path, scenario, err := fileFlags(args)
+	if err != nil {
+		return nil, err
+	}
+	if path != "" {
+		f, err := Load(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := f.apply(fs, scenario); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := applyEnv(fs); err != nil {
+		return nil, err
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected arguments: %v", fs.Args())
+	}
+	return cfg, cfg.Validate()
+}
+
+// fileFlags finds the config file and scenario to start from, which
+// must be known before the other flags are applied.
+func fileFlags(args []string) (path, scenario string, err error) {
+	scratch := GetDefaultConfig()
+	fs := flag.NewFlagSet("gcp", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	scratch.AddFlags(fs)
+	if err := applyEnv(fs); err != nil {
+		return "", "", err
+	}
+	// errors are reported when the flags are parsed for real
+	fs.Parse(args)
+	return scratch.ConfigFile, scratch.Scenario, nil
+}
+
+// AddFlags defines a flag for each setting on fs, defaulting to the
+// current value in c.
+func (c *Config) AddFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.Pattern, "pattern", c.Pattern, "pattern to run, when not given as an argument")
+	fs.StringVar(&c.ConfigFile, "config", c.ConfigFile, "YAML or JSON file of scenarios to start from")
+	fs.StringVar(&c.Scenario, "scenario", c.Scenario, "scenario to use from the config file")
+	fs.StringVar(&c.Mode, "mode", c.Mode, "client: http, grpc, ws or sim")
+	fs.StringVar(&c.Scheme, "scheme", c.Scheme, "URL scheme: http or https")
+	fs.StringVar(&c.Host, "host", c.Host, "server host")
+	fs.IntVar(&c.Port, "port", c.Port, "server port")
+	fs.IntVar(&c.Requests, "requests", c.Requests, "number of requests")
+	fs.IntVar(&c.Concurrency, "concurrency", c.Concurrency, "number of workers")
+	fs.DurationVar(&c.Duration, "duration", c.Duration, "run for this long instead of a fixed number of requests, 0 to use -requests")
+	fs.Var((*intList)(&c.Sweep), "sweep", "comma-separated concurrency levels to run at in turn, e.g. 1,2,4,8")
+	fs.DurationVar(&c.WarmupDuration, "warmup", c.WarmupDuration, "warm up for this long before measuring")
+	fs.IntVar(&c.WarmupRequests, "warmup-requests", c.WarmupRequests, "warm up with this many requests before measuring")
+	fs.Float64Var(&c.ArrivalRate, "rate", c.ArrivalRate, "open-loop arrival rate in requests per second, 0 for closed loop")
+	fs.Float64Var(&c.RatePerSecond, "rps", c.RatePerSecond, "maximum requests per second across all workers, 0 for no limit")
+	fs.IntVar(&c.Burst, "burst", c.Burst, "requests allowed at once under -rps")
+	fs.Var((*phaseList)(&c.Profile), "profile", "load profile as comma-separated duration:level or duration:from-to phases, e.g. 30s:100,1m:100-400")
+	fs.StringVar(&c.ProfileOf, "profile-of", c.ProfileOf, "what the profile varies: rps or concurrency")
+	fs.DurationVar(&c.ThinkTime, "think-time", c.ThinkTime, "mean pause by each worker between requests")
+	fs.StringVar(&c.ThinkDistribution, "think-distribution", c.ThinkDistribution, "think time distribution: constant, uniform or exponential")
+	fs.DurationVar(&c.Timeout, "timeout", c.Timeout, "per-request timeout, 0 for none")
+	fs.IntVar(&c.QueueDepth, "queue-depth", c.QueueDepth, "worker pool queue size, 0 for one slot per worker")
+	fs.StringVar(&c.QueueFull, "queue-full", c.QueueFull, "worker pool policy when the queue is full: block or reject")
+	fs.IntVar(&c.BatchSize, "batch-size", c.BatchSize, "most requests coalesced into one call by the batch pattern")
+	fs.DurationVar(&c.BatchWait, "batch-wait", c.BatchWait, "longest the batch pattern waits for a batch to fill")
+	fs.IntVar(&c.MaxInFlight, "max-in-flight", c.MaxInFlight, "most requests in flight at once, 0 for no limit")
+	fs.DurationVar(&c.DrainTimeout, "drain-timeout", c.DrainTimeout, "time requests in flight get to finish after an interrupt, 0 to cancel them")
+
+	fs.Var((*endpointList)(&c.Endpoints), "endpoints", `semicolon-separated weighted targets as "weight method url [body]", e.g. "9 GET http://localhost:5000/data; 1 POST http://localhost:5000/data {}"`)
+	fs.Int64Var(&c.Seed, "seed", c.Seed, "random seed, to repeat a run exactly; 0 picks one")
+	fs.StringVar(&c.Method, "method", c.Method, "HTTP method")
+	fs.StringVar(&c.Path, "path", c.Path, "request path template")
+	fs.StringVar(&c.Body, "body", c.Body, "request body template")
+
+	fs.Var((*floatList)(&c.Percentiles), "percentiles", "comma-separated latency percentiles to report")
+	fs.StringVar(&c.Output, "output", c.Output, "report format: text, json, csv or html")
+	fs.StringVar(&c.ResultsDir, "results-dir", c.ResultsDir, "directory to save run manifests in")
+	fs.StringVar(&c.Database, "db", c.Database, "SQLite database to append runs to")
+	fs.DurationVar(&c.Interval, "interval", c.Interval, "bucket width of the throughput time series")
+	fs.StringVar(&c.LogLevel, "log-level", c.LogLevel, "log level: debug, info, warn or error")
+	fs.StringVar(&c.LogFormat, "log-format", c.LogFormat, "log format: text or json")
+	fs.BoolVar(&c.Quiet, "quiet", c.Quiet, "only log errors and hide progress")
+	fs.BoolVar(&c.Live, "live", c.Live, "show a live status line instead of progress dots")
+	fs.StringVar(&c.ExportURL, "export", c.ExportURL, "stream samples to statsd://, influx:// or an InfluxDB http:// write URL")
+	fs.StringVar(&c.MetricsAddr, "metrics-addr", c.MetricsAddr, "address to serve Prometheus metrics and pprof on")
+	fs.StringVar(&c.AdminAddr, "admin-addr", c.AdminAddr, "address to serve an endpoint for changing concurrency and rps during the run on")
+	fs.BoolVar(&c.Reload, "reload", c.Reload, "reread concurrency and rps from the config file on SIGHUP during the run")
+	fs.Var((*stringList)(&c.Agents), "agents", "comma-separated addresses of gcp agents to split the run across")
+	fs.BoolVar(&c.CPUProfile, "cpuprofile", c.CPUProfile, "save a CPU profile of the run")
+	fs.BoolVar(&c.HeapProfile, "heapprofile", c.HeapProfile, "save a heap profile after the run")
+
+	fs.Var((*objectiveList)(&c.LatencySLO), "slo", "latency objectives, e.g. p99=200ms,p50=20ms")
+	fs.Float64Var(&c.MaxErrorRate, "max-error-rate", c.MaxErrorRate, "highest fraction of failed requests allowed")
+	fs.DurationVar(&c.ApdexT, "apdex-t", c.ApdexT, "Apdex threshold T")
+	fs.Float64Var(&c.MinApdex, "min-apdex", c.MinApdex, "lowest Apdex score allowed")
+
+	fs.IntVar(&c.MaxAttempts, "max-attempts", c.MaxAttempts, "tries per request, including retries")
+	fs.DurationVar(&c.Backoff, "backoff", c.Backoff, "delay before the first retry")
+	fs.DurationVar(&c.MaxBackoff, "max-backoff", c.MaxBackoff, "longest delay between retries")
+	fs.Var((*intList)(&c.RetryOnStatus), "retry-on-status", "comma-separated status codes to retry")
+	fs.BoolVar(&c.RetryOnNetworkError, "retry-on-network-error", c.RetryOnNetworkError, "retry transient network errors")
+
+	fs.StringVar(&c.Protocol, "protocol", c.Protocol, "HTTP version: http1, h2, h2c or h3")
+	fs.Var((*intList)(&c.ExpectStatus), "expect-status", "comma-separated status codes counted as valid")
+	fs.StringVar(&c.BodyContains, "body-contains", c.BodyContains, "substring valid responses contain")
+	fs.StringVar(&c.BodyPattern, "body-pattern", c.BodyPattern, "regular expression valid responses match")
+	fs.Int64Var(&c.MaxBodySize, "max-body-size", c.MaxBodySize, "largest valid response body, in bytes")
+	fs.StringVar(&c.GRPCMethod, "grpc-method", c.GRPCMethod, "full gRPC method name")
+
+	fs.StringVar(&c.SimDistribution, "sim-distribution", c.SimDistribution, "sim latency distribution: constant, uniform, normal, lognormal or bimodal")
+	fs.DurationVar(&c.SimLatency, "sim-latency", c.SimLatency, "sim mean latency")
+	fs.DurationVar(&c.SimJitter, "sim-jitter", c.SimJitter, "sim latency spread")
+	fs.DurationVar(&c.SimSlowLatency, "sim-slow-latency", c.SimSlowLatency, "sim latency of the slow mode, for bimodal")
+	fs.Float64Var(&c.SimSlowFraction, "sim-slow-fraction", c.SimSlowFraction, "fraction of slow requests, for bimodal")
+	fs.Float64Var(&c.SimErrorRate, "sim-error-rate", c.SimErrorRate, "fraction of sim requests that fail")
+
+	fs.IntVar(&c.MaxIdleConns, "max-idle-conns", c.MaxIdleConns, "idle HTTP connections kept open, 0 for Go's default")
+	fs.IntVar(&c.MaxConnsPerHost, "max-conns-per-host", c.MaxConnsPerHost, "most HTTP connections to the host, 0 for no limit")
+	fs.BoolVar(&c.Pool, "pool", c.Pool, "reuse response buffers with sync.Pool")
+	fs.StringVar(&c.UnixSocket, "unix-socket", c.UnixSocket, "send every request over this Unix socket")
+	fs.IntVar(&c.LocalPortMin, "local-port-min", c.LocalPortMin, "lowest source port")
+	fs.IntVar(&c.LocalPortMax, "local-port-max", c.LocalPortMax, "highest source port")
+	fs.StringVar(&c.Resolver, "resolver", c.Resolver, "DNS server address to resolve with")
+
+	fs.StringVar(&c.CAFile, "ca-file", c.CAFile, "PEM file of a root CA to trust")
+	fs.StringVar(&c.CertFile, "cert-file", c.CertFile, "client certificate PEM file")
+	fs.StringVar(&c.KeyFile, "key-file", c.KeyFile, "client key PEM file")
+	fs.BoolVar(&c.InsecureSkipVerify, "insecure", c.InsecureSkipVerify, "skip TLS certificate verification")
+}
+
+// floatList is a comma-separated list flag.
+type floatList []float64
+
+func (l *floatList) String() string {
+	if l == nil {
+		return ""
+	}
+	var parts []string
+	for _, f := range *l {
+		parts = append(parts, strconv.FormatFloat(f, 'f', -1, 64))
+	}
+	return strings.Join(parts, ",")
+}
+
+func (l *floatList) Set(s string) error {
+	*l = nil
+	for _, part := range strings.Split(s, ",") {
+		f, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return err
+		}
+		*l = append(*l, f)
+	}
+	return nil
+}
+
+// intList is a comma-separated list flag.
+type intList []int
+
+func (l *intList) String() string {
+	if l == nil {
+		return ""
+	}
+	var parts []string
+	for _, n := range *l {
+		parts = append(parts, strconv.Itoa(n))
+	}
+	return strings.Join(parts, ",")
+}
+
+func (l *intList) Set(s string) error {
+	*l = nil
+	if s == "" {
+		return nil
+	}
+	for _, part := range strings.Split(s, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return err
+		}
+		*l = append(*l, n)
+	}
+	return nil
+}
+
+// stringList is a comma-separated list flag.
+type stringList []string
+
+func (l *stringList) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(s string) error {
+	*l = nil
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			*l = append(*l, part)
+		}
+	}
+	return nil
+}
+
+// objectiveList parses latency objectives written as p99=200ms,p50=20ms.
+type objectiveList []LatencyObjective
+
+func (l *objectiveList) String() string {
+	if l == nil {
+		return ""
+	}
+	var parts []string
+	for _, o := range *l {
+		parts = append(parts, fmt.Sprintf("p%s=%v", strconv.FormatFloat(o.Percentile, 'f', -1, 64), o.Max))
+	}
+	return strings.Join(parts, ",")
+}
+
+func (l *objectiveList) Set(s string) error {
+	*l = nil
+	for _, part := range strings.Split(s, ",") {
+		p, max, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return fmt.Errorf("objective %q is not of the form p99=200ms", part)
+		}
+		percentile, err := strconv.ParseFloat(strings.TrimPrefix(p, "p"), 64)
+		if err != nil {
+			return err
+		}
+		d, err := time.ParseDuration(max)
+		if err != nil {
+			return err
+		}
+		*l = append(*l, LatencyObjective{Percentile: percentile, Max: d})
+	}
+	return nil
+}
+
+// phaseList parses load profile phases written as 30s:100 or 1m:100-400.
+type phaseList []Phase
+
+func (l *phaseList) String() string {
+	if l == nil {
+		return ""
+	}
+	var parts []string
+	for _, p := range *l {
+		level := strconv.FormatFloat(p.From, 'f', -1, 64)
+		if p.To != p.From {
+			level += "-" + strconv.FormatFloat(p.To, 'f', -1, 64)
+		}
+		parts = append(parts, fmt.Sprintf("%v:%s", p.Duration, level))
+	}
+	return strings.Join(parts, ",")
+}
+
+func (l *phaseList) Set(s string) error {
+	*l = nil
+	if s == "" {
+		return nil
+	}
+	for _, part := range strings.Split(s, ",") {
+		d, level, ok := strings.Cut(strings.TrimSpace(part), ":")
+		if !ok {
+			return fmt.Errorf("phase %q is not of the form 30s:100 or 30s:100-400", part)
+		}
+		duration, err := time.ParseDuration(d)
+		if err != nil {
+			return err
+		}
+		from, to, ramp := strings.Cut(level, "-")
+		p := Phase{Duration: duration}
+		if p.From, err = strconv.ParseFloat(from, 64); err != nil {
+			return err
+		}
+		p.To = p.From
+		if ramp {
+			if p.To, err = strconv.ParseFloat(to, 64); err != nil {
+				return err
+			}
+		}
+		*l = append(*l, p)
+	}
+	return nil
+}
+
+// endpointList parses weighted endpoints written as
+// "9 GET http://host:5000/data; 1 POST http://host:5000/data {}". Bodies
+// may hold spaces and commas but not semicolons.
+type endpointList []Endpoint
+
+func (l *endpointList) String() string {
+	if l == nil {
+		return ""
+	}
+	var parts []string
+	for _, e := range *l {
+		part := fmt.Sprintf("%s %s %s://%s%s", strconv.FormatFloat(e.Weight, 'f', -1, 64),
+			e.Method, e.Scheme, net.JoinHostPort(e.Host, strconv.Itoa(e.Port)), e.Path)
+		if e.Body != "" {
+			part += " " + e.Body
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, l.Separator())
+}
+
+// Separator lets config files give the endpoints as a list.
+func (l *endpointList) Separator() string { return "; " }
+
+func (l *endpointList) Set(s string) error {
+	*l = nil
+	for _, part := range strings.Split(s, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		e, err := parseEndpoint(part)
+		if err != nil {
+			return fmt.Errorf("endpoint %q: %w", part, err)
+		}
+		*l = append(*l, e)
+	}
+	return nil
+}
+
+func parseEndpoint(s string) (Endpoint, error) {
+	var e Endpoint
+	weight, rest, _ := strings.Cut(s, " ")
+	method, rest, _ := strings.Cut(strings.TrimSpace(rest), " ")
+	target, body, _ := strings.Cut(strings.TrimSpace(rest), " ")
+	if target == "" {
+		return e, errors.New(`not of the form "weight method url [body]"`)
+	}
+	var err error
+	if e.Weight, err = strconv.ParseFloat(weight, 64); err != nil {
+		return e, err
+	}
+	e.Method = strings.ToUpper(method)
+	e.Body = strings.TrimSpace(body)
+
+	// split the URL by hand, as the path may be a template
+	scheme, rest, ok := strings.Cut(target, "://")
+	if !ok {
+		return e, fmt.Errorf("url %q has no scheme", target)
+	}
+	e.Scheme = scheme
+	hostPort, path, _ := strings.Cut(rest, "/")
+	e.Path = "/" + path
+	e.Host, e.Port = hostPort, 80
+	if scheme == "https" {
+		e.Port = 443
+	}
+	if host, port, err := net.SplitHostPort(hostPort); err == nil {
+		e.Host = host
+		if e.Port, err = strconv.Atoi(port); err != nil {
+			return e, fmt.Errorf("port %q: %w", port, err)
+		}
+	}
+	return e, nil
+}
@@ -0,0 +1,156 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+)
+
+// Validate reports every setting that can't work, so a run fails up front
+// rather than part way through.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+	oneOf := func(name, value string, allowed ...string) {
+		check(slices.Contains(allowed, value), "%s must be one of %v, got %q", name, allowed, value)
+	}
+	fraction := func(name string, f float64) {
+		check(f >= 0 && f <= 1, "%s must be between 0 and 1, got %v", name, f)
+	}
+	percentile := func(name string, p float64) {
+		check(p > 0 && p <= 100, "%s percentile must be in (0, 100], got %v", name, p)
+	}
+
+	oneOf("mode", c.Mode, "http", "grpc", "ws", "sim")
+	if c.Mode != "sim" {
+		oneOf("scheme", c.Scheme, "http", "https")
+		if c.UnixSocket == "" {
+			check(c.Host != "", "host must be set")
+			check(c.Port > 0 && c.Port < 65536, "port must be between 1 and 65535, got %d", c.Port)
+		}
+	}
+
+	for _, e := range c.Endpoints {
+		check(e.Weight > 0, "endpoint %s weight must be positive, got %v", e.Name(), e.Weight)
+		oneOf("endpoint "+e.Name()+" scheme", e.Scheme, "http", "https")
+		check(e.Host != "", "endpoint %s host must be set", e.Name())
+		check(e.Port > 0 && e.Port < 65536, "endpoint %s port must be between 1 and 65535, got %d", e.Name(), e.Port)
+	}
+
+	check(c.Duration >= 0, "duration must not be negative, got %v", c.Duration)
+	if c.RunDuration() == 0 {
+		check(c.Requests > 0, "requests must be positive, got %d", c.Requests)
+		check(c.Concurrency <= c.Requests || c.Requests <= 0,
+			"concurrency %d exceeds requests %d, so some workers would never get work", c.Concurrency, c.Requests)
+	}
+	check(c.Concurrency > 0, "concurrency must be positive, got %d", c.Concurrency)
+	for _, n := range c.Sweep {
+		check(n > 0, "sweep concurrency must be positive, got %d", n)
+		check(c.RunDuration() > 0 || n <= c.Requests,
+			"sweep concurrency %d exceeds requests %d, so some workers would never get work", n, c.Requests)
+	}
+	check(len(c.Sweep) == 0 || c.Output != "html", "html output isn't supported for sweeps")
+	check(c.WarmupDuration >= 0, "warmup must not be negative, got %v", c.WarmupDuration)
+	check(c.WarmupRequests >= 0, "warmup requests must not be negative, got %d", c.WarmupRequests)
+	check(c.WarmupDuration == 0 || c.WarmupRequests == 0, "warmup and warmup requests can't be used together")
+	check(len(c.Profile) == 0 || (c.WarmupDuration == 0 && c.WarmupRequests == 0),
+		"a profile can't be used with a warm-up; start it with a warm-up phase instead")
+	check(c.ArrivalRate >= 0, "rate must not be negative, got %v", c.ArrivalRate)
+	check(c.RatePerSecond >= 0, "rps must not be negative, got %v", c.RatePerSecond)
+	check(c.RatePerSecond == 0 || c.Burst > 0, "burst must be positive, got %d", c.Burst)
+	check(c.RatePerSecond == 0 || c.ArrivalRate == 0, "rate and rps can't be used together")
+	if len(c.Profile) > 0 {
+		oneOf("profile-of", c.ProfileOf, "rps", "concurrency")
+		check(c.Duration == 0, "duration and profile can't be used together")
+		check(c.ProfileOf != "rps" || (c.ArrivalRate == 0 && c.RatePerSecond == 0),
+			"an rps profile can't be used with rate or rps")
+		check(c.ProfileOf != "rps" || c.Burst > 0, "burst must be positive, got %d", c.Burst)
+	}
+	for i, p := range c.Profile {
+		check(p.Duration > 0, "profile phase %d must last a positive time, got %v", i+1, p.Duration)
+		if c.ProfileOf == "concurrency" {
+			for _, level := range []float64{p.From, p.To} {
+				check(level >= 1 && level <= float64(c.Concurrency),
+					"profile phase %d concurrency must be between 1 and concurrency %d, got %v", i+1, c.Concurrency, level)
+			}
+		} else {
+			check(p.From >= 0 && p.To >= 0, "profile phase %d rate must not be negative", i+1)
+		}
+	}
+	if c.AdminAddr != "" || c.Reload {
+		check(c.Duration > 0, "changing settings during a run needs a duration")
+		check(len(c.Sweep) == 0, "settings can't be changed during a sweep")
+		check(!c.Reload || c.ConfigFile != "", "reload needs a config file")
+	}
+	if len(c.Agents) > 0 {
+		check(len(c.Sweep) == 0, "a sweep can't be split across agents")
+		check(c.AdminAddr == "" && !c.Reload, "settings can't be changed during a run split across agents")
+	}
+	check(c.ThinkTime >= 0, "think time must not be negative, got %v", c.ThinkTime)
+	oneOf("think distribution", c.ThinkDistribution, "constant", "uniform", "exponential")
+	check(c.Timeout >= 0, "timeout must not be negative, got %v", c.Timeout)
+	check(c.QueueDepth >= 0, "queue depth must not be negative, got %d", c.QueueDepth)
+	oneOf("queue-full", c.QueueFull, "block", "reject")
+	check(c.BatchSize > 0, "batch size must be positive, got %d", c.BatchSize)
+	check(c.BatchWait >= 0, "batch wait must not be negative, got %v", c.BatchWait)
+	check(c.MaxInFlight >= 0, "max in-flight must not be negative, got %d", c.MaxInFlight)
+	check(c.MaxIdleConns >= 0, "max idle conns must not be negative, got %d", c.MaxIdleConns)
+	check(c.MaxConnsPerHost >= 0, "max conns per host must not be negative, got %d", c.MaxConnsPerHost)
+	check(c.DrainTimeout >= 0, "drain timeout must not be negative, got %v", c.DrainTimeout)
+	check(c.Interval > 0, "interval must be positive, got %v", c.Interval)
+
+	for _, p := range c.Percentiles {
+		percentile("reported", p)
+	}
+	oneOf("output", c.Output, "text", "json", "csv", "html")
+	oneOf("log level", c.LogLevel, "debug", "info", "warn", "error", "DEBUG", "INFO", "WARN", "ERROR")
+	oneOf("log format", c.LogFormat, "text", "json")
+	check(!c.Live || !c.Quiet, "live and quiet can't be used together")
+
+	for _, o := range c.LatencySLO {
+		percentile("SLO", o.Percentile)
+		check(o.Max > 0, "SLO latency for p%v must be positive, got %v", o.Percentile, o.Max)
+	}
+	fraction("max error rate", c.MaxErrorRate)
+	fraction("min apdex", c.MinApdex)
+	check(c.ApdexT >= 0, "apdex T must not be negative, got %v", c.ApdexT)
+	check(c.MinApdex == 0 || c.ApdexT > 0, "min apdex needs apdex T to be set")
+
+	check(c.MaxAttempts >= 1, "max attempts must be at least 1, got %d", c.MaxAttempts)
+	check(c.Backoff >= 0, "backoff must not be negative, got %v", c.Backoff)
+	check(c.MaxBackoff >= c.Backoff, "max backoff %v is less than backoff %v", c.MaxBackoff, c.Backoff)
+	check(c.Timeout == 0 || c.MaxAttempts == 1 || c.Backoff < c.Timeout,
+		"backoff %v is not shorter than timeout %v, so no retry could ever be made", c.Backoff, c.Timeout)
+
+	oneOf("protocol", c.Protocol, "", "http1", "h2", "h2c", "h3")
+	check(c.Protocol != "h2" && c.Protocol != "h3" || c.Scheme == "https",
+		"protocol %s needs the https scheme; use h2c for HTTP/2 without TLS", c.Protocol)
+	for _, status := range c.ExpectStatus {
+		check(status >= 100 && status < 600, "expected status %d is not an HTTP status", status)
+	}
+	if c.BodyPattern != "" {
+		_, err := regexp.Compile(c.BodyPattern)
+		check(err == nil, "body pattern: %v", err)
+	}
+	check(c.MaxBodySize >= 0, "max body size must not be negative, got %d", c.MaxBodySize)
+
+	if c.Mode == "sim" {
+		oneOf("sim distribution", c.SimDistribution, "constant", "uniform", "normal", "lognormal", "bimodal")
+		check(c.SimLatency >= 0 && c.SimJitter >= 0 && c.SimSlowLatency >= 0, "sim latencies must not be negative")
+		fraction("sim slow fraction", c.SimSlowFraction)
+		fraction("sim error rate", c.SimErrorRate)
+	}
+
+	if c.LocalPortMin != 0 || c.LocalPortMax != 0 {
+		check(c.LocalPortMin > 0 && c.LocalPortMax < 65536 && c.LocalPortMin <= c.LocalPortMax,
+			"local port range %d-%d is not valid", c.LocalPortMin, c.LocalPortMax)
+	}
+	check((c.CertFile == "") == (c.KeyFile == ""), "cert file and key file must be given together")
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,185 @@
+// Package future is futures and promises built on channels. A Future is
+// a value that will be ready later; whoever holds it can wait for it,
+// chain more work onto it with Then, or combine it with others. A future
+// is settled exactly once, with a value or an error.
+package future
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrTimeout is the error of a future from WithTimeout that did not
+// settle in time.
+var ErrTimeout = errors.New("future: timed out")
+
+// ErrNoFutures is the error of Any or First given no futures to wait for.
+var ErrNoFutures = errors.New("future: no futures")
+
+// Future is a value of type T, or an error, that will be ready later.
+type Future[T any] struct {
+	done chan struct{}
+	once sync.Once
+	val  T
+	err  error
+}
+
+// New returns an unsettled future and the promise that settles it. Only
+// the first call to settle counts.
+func New[T any]() (*Future[T], func(T, error)) {
+	f := &Future[T]{done: make(chan struct{})}
+	return f, f.settle
+}
+
+func (f *Future[T]) settle(val T, err error) {
+	f.once.Do(func() {
+		f.val, f.err = val, err
+		close(f.done)
+	})
+}
+
+// Go runs fn in a goroutine and returns the future of its result.
+func Go[T any](fn func() (T, error)) *Future[T] {
+	f, settle := New[T]()
+	go func() {
+		settle(fn())
+	}()
+	return f
+}
+
+// Resolved returns a future already settled with val.
+func Resolved[T any](val T) *Future[T] {
+	f, settle := New[T]()
+	settle(val, nil)
+	return f
+}
+
+// Done is closed once the future is settled.
+func (f *Future[T]) Done() <-chan struct{} {
+	return f.done
+}
+
+// Await waits for the future and returns its value, or ctx's error if
+// ctx is done first.
+func (f *Future[T]) Await(ctx context.Context) (T, error) {
+	select {
+	case <-f.done:
+		return f.val, f.err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// result returns the value of a settled future.
+func (f *Future[T]) result() (T, error) {
+	<-f.done
+	return f.val, f.err
+}
+
+// Then returns the future of fn applied to f's value. If f fails, fn is
+// not called and the new future fails with the same error.
+func Then[T, U any](f *Future[T], fn func(T) (U, error)) *Future[U] {
+	return Go(func() (U, error) {
+		val, err := f.result()
+		if err != nil {
+			var zero U
+			return zero, err
+		}
+		return fn(val)
+	})
+}
+
+// All returns the future of every value, in order. It fails as soon as
+// any of fs fails.
+func All[T any](fs ...*Future[T]) *Future[[]T] {
+	all, settle := New[[]T]()
+	vals := make([]T, len(fs))
+	var wg sync.WaitGroup
+	for i, f := range fs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			val, err := f.result()
+			if err != nil {
+				settle(nil, err)
+				return
+			}
+			vals[i] = val
+		}()
+	}
+	go func() {
+		wg.Wait()
+		settle(vals, nil)
+	}()
+	return all
+}
+
+// Any returns the future of the first of fs to succeed. If all of them
+// fail it fails with their errors joined, and with no fs it fails at once
+// with ErrNoFutures.
+func Any[T any](fs ...*Future[T]) *Future[T] {
+	first, settle := New[T]()
+	if len(fs) == 0 {
+		var zero T
+		settle(zero, ErrNoFutures)
+		return first
+	}
+	errs := make([]error, len(fs))
+	var wg sync.WaitGroup
+	for i, f := range fs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			val, err := f.result()
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			settle(val, nil)
+		}()
+	}
+	go func() {
+		wg.Wait()
+		var zero T
+		settle(zero, errors.Join(errs...))
+	}()
+	return first
+}
+
+// First returns the future of the first of fs to settle, whether it
+// succeeded or failed. With no fs it fails at once with ErrNoFutures.
+func First[T any](fs ...*Future[T]) *Future[T] {
+	first, settle := New[T]()
+	if len(fs) == 0 {
+		var zero T
+		settle(zero, ErrNoFutures)
+		return first
+	}
+	for _, f := range fs {
+		go func() {
+			settle(f.result())
+		}()
+	}
+	return first
+}
+
+// WithTimeout returns a future settled like f, or failed with ErrTimeout
+// if f is not settled within d. It does not stop the work behind f.
+func WithTimeout[T any](f *Future[T], d time.Duration) *Future[T] {
+	timed, settle := New[T]()
+	go func() {
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		select {
+		case <-f.done:
+			settle(f.val, f.err)
+		case <-timer.C:
+			var zero T
+			settle(zero, ErrTimeout)
+		}
+	}()
+	return timed
+}
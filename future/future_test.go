@@ -0,0 +1,77 @@
+package future
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+var errFailed = errors.New("failed")
+
+// after returns a future settled with val and err after d.
+func after[T any](d time.Duration, val T, err error) *Future[T] {
+	return Go(func() (T, error) {
+		time.Sleep(d)
+		return val, err
+	})
+}
+
+func TestCombinators(t *testing.T) {
+	tests := []struct {
+		name    string
+		f       func() *Future[int]
+		want    int
+		wantErr error
+	}{
+		{"Any/first success", func() *Future[int] {
+			return Any(after(0, 0, errFailed), after(10*time.Millisecond, 2, nil), after(50*time.Millisecond, 3, nil))
+		}, 2, nil},
+		{"Any/all fail", func() *Future[int] {
+			return Any(after(0, 0, errFailed), after(0, 0, ErrTimeout))
+		}, 0, errFailed},
+		{"Any/no futures", func() *Future[int] { return Any[int]() }, 0, ErrNoFutures},
+		{"First/a failure", func() *Future[int] {
+			return First(after(0, 0, errFailed), after(50*time.Millisecond, 2, nil))
+		}, 0, errFailed},
+		{"First/no futures", func() *Future[int] { return First[int]() }, 0, ErrNoFutures},
+		{"Then", func() *Future[int] {
+			return Then(Resolved(3), func(v int) (int, error) { return v * 2, nil })
+		}, 6, nil},
+		{"Then/failed", func() *Future[int] {
+			return Then(after(0, 0, errFailed), func(v int) (int, error) { return v * 2, nil })
+		}, 0, errFailed},
+		{"WithTimeout", func() *Future[int] {
+			return WithTimeout(after(time.Second, 1, nil), 10*time.Millisecond)
+		}, 0, ErrTimeout},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+			got, err := tt.f().Await(ctx)
+			if errors.Is(err, context.DeadlineExceeded) {
+				t.Fatal("the future never settled")
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("got %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAll(t *testing.T) {
+	got, err := All(after(20*time.Millisecond, 1, nil), Resolved(2), after(0, 3, nil)).Await(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || got[0] != 1 || got[1] != 2 || got[2] != 3 {
+		t.Errorf("got %v, want [1 2 3]", got)
+	}
+	if _, err := All(Resolved(1), after(0, 0, errFailed)).Await(context.Background()); !errors.Is(err, errFailed) {
+		t.Errorf("got error %v, want %v", err, errFailed)
+	}
+}
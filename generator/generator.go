@@ -0,0 +1,98 @@
+// Package generator is lazy generators built on channels. A generator
+// computes each value only when it is read, closes its channel when it
+// runs out and stops early, closing it, when its context is done, so a
+// reader can give up on an endless one without leaking its goroutine.
+package generator
+
+import "context"
+
+// send sends v on out unless ctx is done first, reporting whether it did.
+func send[T any](ctx context.Context, out chan<- T, v T) bool {
+	select {
+	case out <- v:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Ints yields start, start+step, start+2*step and so on, without end.
+func Ints(ctx context.Context, start, step int) <-chan int {
+	out := make(chan int)
+	go func() {
+		defer close(out)
+		for i := start; send(ctx, out, i); i += step {
+		}
+	}()
+	return out
+}
+
+// FromFunc yields what fn returns until it returns false.
+func FromFunc[T any](ctx context.Context, fn func() (T, bool)) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for {
+			v, ok := fn()
+			if !ok || !send(ctx, out, v) {
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// TakeWhile yields the values of in up to the first for which keep is
+// false.
+func TakeWhile[T any](ctx context.Context, in <-chan T, keep func(T) bool) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case v, ok := <-in:
+				if !ok || !keep(v) || !send(ctx, out, v) {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// Pair is a value from each of two generators.
+type Pair[A, B any] struct {
+	First  A
+	Second B
+}
+
+// Zip pairs up the values of a and b in order, ending with the shorter.
+func Zip[A, B any](ctx context.Context, a <-chan A, b <-chan B) <-chan Pair[A, B] {
+	out := make(chan Pair[A, B])
+	go func() {
+		defer close(out)
+		for {
+			var p Pair[A, B]
+			var ok bool
+			select {
+			case p.First, ok = <-a:
+			case <-ctx.Done():
+				return
+			}
+			if !ok {
+				return
+			}
+			select {
+			case p.Second, ok = <-b:
+			case <-ctx.Done():
+				return
+			}
+			if !ok || !send(ctx, out, p) {
+				return
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,89 @@
+// Package heartbeat supervises long-running workers by their heartbeats.
+// A worker calls beat whenever it makes progress; the beats go out on a
+// channel without ever blocking the worker, and a supervisor that hears
+// none for too long takes the worker to be stalled, cancels it and starts
+// a fresh one.
+package heartbeat
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrStalled is returned by Supervisor.Run when the worker has stalled
+// more times than the supervisor restarts it.
+var ErrStalled = errors.New("heartbeat: worker stalled")
+
+// Worker is a long-running function. It calls beat to show it is alive
+// and must return once ctx is done.
+type Worker func(ctx context.Context, beat func()) error
+
+// Start runs w in a goroutine. It returns the channel of w's heartbeats,
+// which drops beats nobody is waiting for, and a channel that receives
+// w's error when it returns.
+func Start(ctx context.Context, w Worker) (beats <-chan struct{}, done <-chan error) {
+	b := make(chan struct{}, 1)
+	d := make(chan error, 1)
+	go func() {
+		d <- w(ctx, func() {
+			select {
+			case b <- struct{}{}:
+			default:
+			}
+		})
+	}()
+	return b, d
+}
+
+// Supervisor restarts a worker whose heartbeats stop.
+type Supervisor struct {
+	// Timeout is how long the worker may go without a beat.
+	Timeout time.Duration
+	// MaxRestarts is how many times a stalled worker is replaced before
+	// Run gives up; negative means without limit.
+	MaxRestarts int
+	// OnRestart, if set, is called before each restart with how many
+	// there have been, this one included.
+	OnRestart func(restarts int)
+}
+
+// Run runs w until it returns or ctx is done, restarting it each time it
+// stalls. A stalled worker's context is canceled; Run doesn't wait for it
+// to return, so it is not held up by one that ignores its context.
+func (s *Supervisor) Run(ctx context.Context, w Worker) error {
+	timer := time.NewTimer(s.Timeout)
+	defer timer.Stop()
+	for restarts := 0; ; restarts++ {
+		if restarts > 0 {
+			if s.MaxRestarts >= 0 && restarts > s.MaxRestarts {
+				return ErrStalled
+			}
+			if s.OnRestart != nil {
+				s.OnRestart(restarts)
+			}
+		}
+		if stalled, err := s.watch(ctx, w, timer); !stalled {
+			return err
+		}
+	}
+}
+
+// watch runs one instance of w, reporting whether it stalled and, if it
+// didn't, what it returned.
+func (s *Supervisor) watch(ctx context.Context, w Worker, timer *time.Timer) (bool, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	beats, done := Start(ctx, w)
+	timer.Reset(s.Timeout)
+	for {
+		select {
+		case <-beats:
+			timer.Reset(s.Timeout)
+		case err := <-done:
+			return false, err
+		case <-timer.C:
+			return true, nil
+		}
+	}
+}
@@ -0,0 +1,30 @@
+// Package lazy is lazy initialization: a value that is only computed when
+// it is first asked for, once, however many goroutines ask at the same
+// time.
+package lazy
+
+import "sync"
+
+// Value is a value computed by a function on first use. Callers that ask
+// while it is being computed wait for it, and every caller gets the same
+// value, or the same error.
+type Value[T any] struct {
+	once sync.Once
+	fn   func() (T, error)
+	val  T
+	err  error
+}
+
+// New returns a Value computed by fn.
+func New[T any](fn func() (T, error)) *Value[T] {
+	return &Value[T]{fn: fn}
+}
+
+// Get returns the value, computing it if this is the first call.
+func (v *Value[T]) Get() (T, error) {
+	v.once.Do(func() {
+		v.val, v.err = v.fn()
+		v.fn = nil
+	})
+	return v.val, v.err
+}
@@ -0,0 +1,85 @@
+// Package memo is memoization safe for concurrent use: a function whose
+// results are cached by key, where callers asking for a key that is being
+// computed wait for that computation rather than starting their own.
+package memo
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/aawadall/go-concurrency-patterns/singleflight"
+)
+
+// Stats counts how the calls to a Func were answered.
+type Stats struct {
+	Calls  int64 // calls to fn
+	Hits   int64 // answered from the cache
+	Shared int64 // answered by waiting for another caller's call to fn
+}
+
+// Func is fn with its results cached. Errors are not cached, so the next
+// caller for that key calls fn again.
+type Func[K comparable, V any] struct {
+	fn     func(K) (V, error)
+	flight singleflight.Group[K, V]
+
+	mu    sync.RWMutex
+	cache map[K]V
+
+	calls, hits, shared atomic.Int64
+}
+
+// New returns fn memoized.
+func New[K comparable, V any](fn func(K) (V, error)) *Func[K, V] {
+	return &Func[K, V]{fn: fn, cache: make(map[K]V)}
+}
+
+// Get returns fn's result for key, calling fn only if it isn't cached or
+// already being called for key.
+func (f *Func[K, V]) Get(key K) (V, error) {
+	f.mu.RLock()
+	v, ok := f.cache[key]
+	f.mu.RUnlock()
+	if ok {
+		f.hits.Add(1)
+		return v, nil
+	}
+
+	leader := false
+	v, err, _ := f.flight.Do(key, func() (V, error) {
+		leader = true
+		// a call that finished since the cache was checked has cached
+		// the result
+		f.mu.RLock()
+		v, ok := f.cache[key]
+		f.mu.RUnlock()
+		if ok {
+			f.hits.Add(1)
+			return v, nil
+		}
+		f.calls.Add(1)
+		v, err := f.fn(key)
+		if err == nil {
+			f.mu.Lock()
+			f.cache[key] = v
+			f.mu.Unlock()
+		}
+		return v, err
+	})
+	if !leader {
+		f.shared.Add(1)
+	}
+	return v, err
+}
+
+// Forget drops key's cached result.
+func (f *Func[K, V]) Forget(key K) {
+	f.mu.Lock()
+	delete(f.cache, key)
+	f.mu.Unlock()
+}
+
+// Stats returns how the calls so far were answered.
+func (f *Func[K, V]) Stats() Stats {
+	return Stats{Calls: f.calls.Load(), Hits: f.hits.Load(), Shared: f.shared.Load()}
+}
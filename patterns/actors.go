@@ -0,0 +1,93 @@
+package patterns
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aawadall/go-concurrency-patterns/actor"
+	"github.com/aawadall/go-concurrency-patterns/config"
+	"github.com/aawadall/go-concurrency-patterns/runner"
+	"github.com/aawadall/go-concurrency-patterns/shared"
+)
+
+// actorRestarts is how many panics a worker actor survives.
+const actorRestarts = 3
+
+// workerMsg asks a worker actor for one request, or, with flushed set,
+// for a reply once every request told to it before has finished.
+type workerMsg struct {
+	flushed chan<- struct{}
+}
+
+// recorderMsg hands a result to the recorder actor, or asks it to reply
+// once every result told to it before has been recorded.
+type recorderMsg struct {
+	result  shared.Result
+	flushed chan<- struct{}
+}
+
+// Actors runs the benchmark client as actors: cfg.Concurrency worker
+// actors each make the requests they are told to, one at a time, and
+// tell a single recorder actor the results, which it alone records.
+// Requests go to the workers round robin, so a slow request holds up
+// the ones queued behind it. At the end each worker, then the recorder,
+// is asked to flush; mailboxes are first in, first out, so the replies
+// mean every request has been made and recorded.
+type Actors struct{}
+
+func (Actors) Name() string { return "actors" }
+
+func (Actors) Run(ctx context.Context, cfg *config.Config, target shared.Target, collector *shared.Collector) error {
+	// The actors outlive ctx so results of a canceled run are still
+	// recorded; they are stopped once flushed.
+	life := context.WithoutCancel(ctx)
+
+	recorder := actor.Spawn(life, func() actor.Handler[recorderMsg] {
+		return func(_ context.Context, msg recorderMsg) {
+			if msg.flushed != nil {
+				msg.flushed <- struct{}{}
+				return
+			}
+			collector.Record(msg.result)
+		}
+	}, actor.Options{MailboxSize: cfg.Concurrency, MaxRestarts: actorRestarts})
+	defer recorder.Stop()
+
+	workers := make([]*actor.Ref[workerMsg], cfg.Concurrency)
+	for i := range workers {
+		wctx := shared.WithWorkerID(ctx, i)
+		workers[i] = actor.Spawn(life, func() actor.Handler[workerMsg] {
+			return func(_ context.Context, msg workerMsg) {
+				if msg.flushed != nil {
+					msg.flushed <- struct{}{}
+					return
+				}
+				resp, _ := target.Do(wctx)
+				recorder.Tell(life, recorderMsg{result: resp})
+			}
+		}, actor.Options{MailboxSize: 1, MaxRestarts: actorRestarts})
+		defer workers[i].Stop()
+	}
+
+	next := 0
+	for range runner.Requests(ctx, cfg) {
+		if err := workers[next].Tell(ctx, workerMsg{}); err != nil {
+			break
+		}
+		next = (next + 1) % len(workers)
+	}
+
+	for i, w := range workers {
+		if _, err := actor.Ask(life, w, func(reply chan<- struct{}) workerMsg {
+			return workerMsg{flushed: reply}
+		}); err != nil {
+			return fmt.Errorf("worker %d: %w", i, w.Err())
+		}
+	}
+	if _, err := actor.Ask(life, recorder, func(reply chan<- struct{}) recorderMsg {
+		return recorderMsg{flushed: reply}
+	}); err != nil {
+		return fmt.Errorf("recorder: %w", recorder.Err())
+	}
+	return ctx.Err()
+}
@@ -0,0 +1,135 @@
+package patterns
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aawadall/go-concurrency-patterns/config"
+	"github.com/aawadall/go-concurrency-patterns/runner"
+	"github.com/aawadall/go-concurrency-patterns/shared"
+)
+
+// batchRequest is a request submitted to the aggregator, which answers on
+// reply.
+type batchRequest struct {
+	id    int64
+	reply chan<- shared.Result
+}
+
+// errNoBatches is the error of every request sent to a target that can't
+// make bulk calls, such as the gRPC and WebSocket clients.
+var errNoBatches = errors.New("the target can't make bulk calls")
+
+// Batch has cfg.Concurrency workers submit their requests to an
+// aggregator, which gathers up to cfg.BatchSize of them, or as many as
+// arrive within cfg.BatchWait of the first, into one bulk call to the
+// server carrying every request's ID. The server answers each request on
+// its own, and the aggregator sends each caller its own result on their
+// reply channel; only a failure of the bulk call as a whole fails them
+// all alike. Each worker's request is recorded, timed from when it was
+// submitted, and how many calls the batching saved is logged at the end.
+type Batch struct{}
+
+func (Batch) Name() string { return "batch" }
+
+func (Batch) Run(ctx context.Context, cfg *config.Config, target shared.Target, collector *shared.Collector) error {
+	submit := make(chan batchRequest)
+	var calls atomic.Int64
+
+	// aggregate
+	var flushes sync.WaitGroup
+	aggregated := make(chan struct{})
+	go func() {
+		defer close(aggregated)
+		flush := func(batch []batchRequest) {
+			calls.Add(1)
+			flushes.Add(1)
+			go func() {
+				defer flushes.Done()
+				b := &shared.Batch{IDs: make([]int64, len(batch))}
+				for i, req := range batch {
+					b.IDs[i] = req.id
+				}
+				bulk, err := target.Do(shared.WithBatch(ctx, b))
+				for i, req := range batch {
+					switch {
+					case err != nil || bulk.ErrorClass != shared.ClassNone:
+						req.reply <- bulk
+					case b.Results == nil:
+						req.reply <- shared.Result{Err: errNoBatches, ErrorClass: shared.ClassClient}
+					default:
+						r := b.Results[i]
+						r.Attempts = bulk.Attempts
+						req.reply <- r
+					}
+				}
+			}()
+		}
+		timer := time.NewTimer(cfg.BatchWait)
+		timer.Stop()
+		defer timer.Stop()
+		var batch []batchRequest
+		for {
+			select {
+			case req, ok := <-submit:
+				if !ok {
+					if len(batch) > 0 {
+						flush(batch)
+					}
+					return
+				}
+				batch = append(batch, req)
+				if len(batch) == 1 {
+					timer.Reset(cfg.BatchWait)
+				}
+				if len(batch) == cfg.BatchSize {
+					timer.Stop()
+					flush(batch)
+					batch = nil
+				}
+			case <-timer.C:
+				flush(batch)
+				batch = nil
+			}
+		}
+	}()
+
+	jobs := make(chan struct{})
+	go func() {
+		defer close(jobs)
+		for range runner.Requests(ctx, cfg) {
+			jobs <- struct{}{}
+		}
+	}()
+
+	var requests, ids atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < cfg.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			reply := make(chan shared.Result, 1)
+			for range jobs {
+				requests.Add(1)
+				start := time.Now()
+				submit <- batchRequest{id: ids.Add(1), reply: reply}
+				resp := <-reply
+				resp.Start, resp.End = start, time.Now()
+				resp.Latency = resp.End.Sub(start)
+				resp.WorkerID = i
+				collector.Record(resp)
+			}
+		}()
+	}
+	wg.Wait()
+	close(submit)
+	<-aggregated
+	flushes.Wait()
+
+	n, made := requests.Load(), calls.Load()
+	shared.Logger().Info("batched requests", "requests", n, "calls", made, "saved", n-made)
+	return ctx.Err()
+}
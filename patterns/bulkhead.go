@@ -0,0 +1,100 @@
+package patterns
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+
+	"github.com/aawadall/go-concurrency-patterns/config"
+	"github.com/aawadall/go-concurrency-patterns/runner"
+	"github.com/aawadall/go-concurrency-patterns/shared"
+)
+
+// Bulkhead gives each of cfg.Endpoints its own pool of workers, sized by
+// the endpoint's share of the weights out of cfg.Concurrency, with its
+// own queue of cfg.QueueDepth. Requests are picked an endpoint up front
+// and queued for its pool; when that queue is full the request is
+// rejected rather than waited for, so a slow endpoint that saturates its
+// pool cannot hold up requests to the others. Submitting only waits while
+// every pool is full. The per-endpoint breakdown in the report shows each
+// pool's results and rejections.
+type Bulkhead struct{}
+
+func (Bulkhead) Name() string { return "bulkhead" }
+
+func (Bulkhead) Run(ctx context.Context, cfg *config.Config, target shared.Target, collector *shared.Collector) error {
+	if len(cfg.Endpoints) == 0 {
+		return errors.New("the bulkhead pattern needs -endpoints")
+	}
+	total := 0.0
+	for _, e := range cfg.Endpoints {
+		total += e.Weight
+	}
+	sizes := make([]int, len(cfg.Endpoints))
+	pools := make([]chan struct{}, len(cfg.Endpoints))
+	capacity := 0
+	for i, e := range cfg.Endpoints {
+		sizes[i] = max(int(math.Round(float64(cfg.Concurrency)*e.Weight/total)), 1)
+		depth := cfg.QueueDepth
+		if depth == 0 {
+			depth = sizes[i]
+		}
+		pools[i] = make(chan struct{}, depth)
+		capacity += sizes[i] + depth
+	}
+	// slots holds a token for each request queued or in flight in any
+	// pool, so submitting waits while they are all full
+	slots := make(chan struct{}, capacity)
+	results := make(chan shared.Result, cfg.Concurrency)
+
+	var wg sync.WaitGroup
+	worker := 0
+	for i, pool := range pools {
+		ectx := shared.WithEndpoint(ctx, i)
+		for range sizes[i] {
+			wg.Add(1)
+			go func(id int) {
+				defer wg.Done()
+				ctx := shared.WithWorkerID(ectx, id)
+				for range pool {
+					resp, _ := target.Do(ctx)
+					results <- resp
+					<-slots
+				}
+			}(worker)
+			worker++
+		}
+	}
+
+	// submit, never waiting on one full pool
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer func() {
+			for _, pool := range pools {
+				close(pool)
+			}
+		}()
+		picker := shared.NewEndpointPicker(cfg)
+		for range runner.Requests(ctx, cfg) {
+			slots <- struct{}{}
+			i := picker.Pick()
+			select {
+			case pools[i] <- struct{}{}:
+			default:
+				<-slots
+				r := shared.Rejected(ctx)
+				r.Endpoint = cfg.Endpoints[i].Name()
+				results <- r
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+	collector.Collect(results)
+	return ctx.Err()
+}
@@ -0,0 +1,59 @@
+package patterns
+
+import (
+	"context"
+
+	"github.com/aawadall/go-concurrency-patterns/config"
+	"github.com/aawadall/go-concurrency-patterns/runner"
+	"github.com/aawadall/go-concurrency-patterns/scope"
+	"github.com/aawadall/go-concurrency-patterns/shared"
+)
+
+// Channels streams work through small buffered channels, like the
+// cmd/channels demo: a writer produces jobs while cfg.Concurrency readers
+// consume them and send results on, so no channel ever holds the whole
+// run. The goroutines run in a scope, so none of them outlives Run.
+type Channels struct{}
+
+func (Channels) Name() string { return "channels" }
+
+func (Channels) Run(ctx context.Context, cfg *config.Config, target shared.Target, collector *shared.Collector) error {
+	jobs := make(chan int, cfg.Concurrency)
+	results := make(chan shared.Result, cfg.Concurrency)
+
+	err := scope.Run(ctx, func(s *scope.Scope) error {
+		s.Go(func(ctx context.Context) error {
+			defer close(jobs)
+			for i := range runner.Requests(ctx, cfg) {
+				jobs <- i
+			}
+			return nil
+		})
+
+		// the readers get a scope of their own, which ends once they
+		// have all returned
+		s.Go(func(ctx context.Context) error {
+			defer close(results)
+			return scope.Run(ctx, func(readers *scope.Scope) error {
+				for i := 0; i < cfg.Concurrency; i++ {
+					readers.Go(func(ctx context.Context) error {
+						ctx = shared.WithWorkerID(ctx, i)
+						for range jobs {
+							resp, _ := target.Do(ctx)
+							results <- resp
+						}
+						return nil
+					})
+				}
+				return nil
+			})
+		})
+
+		collector.Collect(results)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return ctx.Err()
+}
@@ -0,0 +1,50 @@
+package patterns
+
+import (
+	"context"
+	"fmt"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/aawadall/go-concurrency-patterns/config"
+	"github.com/aawadall/go-concurrency-patterns/runner"
+	"github.com/aawadall/go-concurrency-patterns/shared"
+)
+
+// ErrGroup issues every request through an errgroup.Group limited to
+// cfg.Concurrency goroutines with SetLimit, so Go blocks until one is
+// free. The first failed request cancels the group's context, stopping
+// the run and canceling requests in flight, unless Tolerant is set, in
+// which case failures are only recorded.
+type ErrGroup struct {
+	Tolerant bool
+}
+
+func (g ErrGroup) Name() string {
+	if g.Tolerant {
+		return "errgroup-tolerant"
+	}
+	return "errgroup"
+}
+
+func (g ErrGroup) Run(ctx context.Context, cfg *config.Config, target shared.Target, collector *shared.Collector) error {
+	group, gctx := errgroup.WithContext(ctx)
+	group.SetLimit(cfg.Concurrency)
+	for range runner.Requests(gctx, cfg) {
+		group.Go(func() error {
+			resp, err := target.Do(gctx)
+			collector.Record(resp)
+			if g.Tolerant || resp.ErrorClass == shared.ClassNone {
+				return nil
+			}
+			if err == nil {
+				err = fmt.Errorf("status %d", resp.Status)
+			}
+			return fmt.Errorf("request failed (%s): %w", resp.ErrorClass, err)
+		})
+	}
+	if err := group.Wait(); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
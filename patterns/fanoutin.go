@@ -0,0 +1,48 @@
+package patterns
+
+import (
+	"context"
+
+	"github.com/aawadall/go-concurrency-patterns/chanx"
+	"github.com/aawadall/go-concurrency-patterns/config"
+	"github.com/aawadall/go-concurrency-patterns/runner"
+	"github.com/aawadall/go-concurrency-patterns/shared"
+)
+
+// FanOutIn fans requests out to cfg.Concurrency workers over a channel and
+// fans their results back in with chanx.FanIn.
+type FanOutIn struct{}
+
+func (FanOutIn) Name() string { return "fanoutin" }
+
+func (FanOutIn) Run(ctx context.Context, cfg *config.Config, target shared.Target, collector *shared.Collector) error {
+	// define request channel
+	requests := make(chan struct{}, cfg.Concurrency)
+
+	// fan out, each worker with its own response channel
+	workers := make([]<-chan shared.Result, cfg.Concurrency)
+	for i := range workers {
+		responses := make(chan shared.Result, 1)
+		workers[i] = responses
+		go func() {
+			defer close(responses)
+			ctx := shared.WithWorkerID(ctx, i)
+			for range requests {
+				resp, _ := target.Do(ctx)
+				responses <- resp
+			}
+		}()
+	}
+
+	// send requests
+	go func() {
+		for range runner.Requests(ctx, cfg) {
+			requests <- struct{}{}
+		}
+		close(requests)
+	}()
+
+	// fan in, closing once every worker is done
+	collector.Collect(chanx.FanIn(workers...))
+	return ctx.Err()
+}
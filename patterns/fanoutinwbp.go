@@ -0,0 +1,88 @@
+package patterns
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/aawadall/go-concurrency-patterns/config"
+	"github.com/aawadall/go-concurrency-patterns/runner"
+	"github.com/aawadall/go-concurrency-patterns/shared"
+)
+
+// FanOutInBackPressure is FanOutIn with a back-pressure signal: each
+// worker reports in-flight requests on a channel, and a cool-down
+// goroutine slows the workers when the signal stays high.
+type FanOutInBackPressure struct{}
+
+func (FanOutInBackPressure) Name() string { return "fanoutinwbp" }
+
+func (FanOutInBackPressure) Run(ctx context.Context, cfg *config.Config, target shared.Target, collector *shared.Collector) error {
+	// define request channel
+	requests := make(chan struct{}, cfg.Concurrency)
+
+	// define response channel
+	responses := make(chan shared.Result, cfg.Concurrency)
+	backpressure := make(chan struct{}, cfg.Concurrency)
+
+	// fan out
+	var wg sync.WaitGroup
+	for i := 0; i < cfg.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx := shared.WithWorkerID(ctx, i)
+			for range requests {
+				backpressure <- struct{}{}
+				resp, _ := target.Do(ctx)
+				responses <- resp
+			}
+		}()
+
+		// if bacpressure signal sent, cool down
+		go func() {
+			timeout := time.Duration(0)
+			signalCount := 0
+			lastCheck := time.Now()
+
+			for range backpressure {
+				signalCount++
+
+				// Check every 100ms to adjust timeout
+				if time.Since(lastCheck) >= 100*time.Millisecond {
+					if signalCount > 10 { // High pressure
+						timeout = min(timeout+time.Millisecond, 50*time.Millisecond)
+					} else if signalCount < 3 { // Low pressure
+						timeout = max(timeout-time.Millisecond, 0)
+					}
+					signalCount = 0
+					lastCheck = time.Now()
+				}
+
+				if timeout > 0 {
+					time.Sleep(timeout)
+				}
+				<-backpressure
+			}
+		}()
+	}
+
+	// send requests
+	go func() {
+		for range runner.Requests(ctx, cfg) {
+			requests <- struct{}{}
+		}
+		close(requests)
+	}()
+
+	// close responses once every worker is done
+	go func() {
+		wg.Wait()
+		close(backpressure)
+		close(responses)
+	}()
+
+	// collect responses
+	collector.Collect(responses)
+	return ctx.Err()
+}
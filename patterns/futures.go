@@ -0,0 +1,45 @@
+package patterns
+
+import (
+	"context"
+
+	"github.com/aawadall/go-concurrency-patterns/config"
+	"github.com/aawadall/go-concurrency-patterns/future"
+	"github.com/aawadall/go-concurrency-patterns/runner"
+	"github.com/aawadall/go-concurrency-patterns/shared"
+)
+
+// Futures fires requests as futures, cfg.Concurrency at a time, and
+// gathers each batch with future.All before firing the next, the same
+// batching as WaitGroups would give with a group per batch but composed
+// as values instead of counted. A request that fails still resolves its
+// future with the failed result, so one failure does not fail the batch.
+type Futures struct{}
+
+func (Futures) Name() string { return "futures" }
+
+func (Futures) Run(ctx context.Context, cfg *config.Config, target shared.Target, collector *shared.Collector) error {
+	// gather canceled requests' results as well
+	gctx := context.WithoutCancel(ctx)
+	batch := make([]*future.Future[shared.Result], 0, cfg.Concurrency)
+	gather := func() {
+		results, _ := future.All(batch...).Await(gctx)
+		for _, r := range results {
+			collector.Record(r)
+		}
+		batch = batch[:0]
+	}
+
+	for range runner.Requests(ctx, cfg) {
+		wctx := shared.WithWorkerID(ctx, len(batch))
+		batch = append(batch, future.Go(func() (shared.Result, error) {
+			resp, _ := target.Do(wctx)
+			return resp, nil
+		}))
+		if len(batch) == cfg.Concurrency {
+			gather()
+		}
+	}
+	gather()
+	return ctx.Err()
+}
@@ -0,0 +1,54 @@
+// Package patterns implements the benchmark client once per concurrency
+// pattern. Each one is a runner.Pattern.
+package patterns
+
+import (
+	"github.com/aawadall/go-concurrency-patterns/runner"
+)
+
+// All lists every pattern, as run by gcp.
+var All = []runner.Pattern{
+	Simple{},
+	WaitGroups{},
+	FanOutIn{},
+	FanOutInBackPressure{},
+	Pipeline{},
+	Channels{},
+	WorkerPool{},
+	WorkerPool{Queue: true},
+	Processes{},
+	Semaphore{},
+	Semaphore{Channel: true},
+	ErrGroup{},
+	ErrGroup{Tolerant: true},
+	RateLimit{},
+	RateLimit{Leaky: true},
+	Actors{},
+	Futures{},
+	SingleFlight{},
+	SingleFlight{Generic: true},
+	Bulkhead{},
+	Priority{},
+	Batch{},
+	WorkStealing{},
+	WorkStealing{Shared: true},
+}
+
+// Lookup returns the pattern with the given name.
+func Lookup(name string) (runner.Pattern, bool) {
+	for _, p := range All {
+		if p.Name() == name {
+			return p, true
+		}
+	}
+	return nil, false
+}
+
+// Names returns the pattern names.
+func Names() []string {
+	var names []string
+	for _, p := range All {
+		names = append(names, p.Name())
+	}
+	return names
+}
@@ -0,0 +1,234 @@
+package patterns
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aawadall/go-concurrency-patterns/config"
+	"github.com/aawadall/go-concurrency-patterns/runner"
+	"github.com/aawadall/go-concurrency-patterns/shared"
+	"github.com/aawadall/go-concurrency-patterns/shared/leakcheck"
+)
+
+// fakeTarget answers every call after a short delay, counting them. It
+// makes bulk calls too, answering each request of a batch with status,
+// if set, and 200 otherwise.
+type fakeTarget struct {
+	calls  atomic.Int64
+	status func(id int64) int
+}
+
+func (f *fakeTarget) Do(ctx context.Context) (shared.Result, error) {
+	f.calls.Add(1)
+	start := time.Now()
+	select {
+	case <-time.After(100 * time.Microsecond):
+	case <-ctx.Done():
+		return shared.Result{Err: ctx.Err(), Start: start, End: time.Now()}, ctx.Err()
+	}
+	if b := shared.BatchFrom(ctx); b != nil {
+		b.Results = make([]shared.Result, len(b.IDs))
+		for i, id := range b.IDs {
+			status := 200
+			if f.status != nil {
+				status = f.status(id)
+			}
+			b.Results[i] = shared.Result{Status: status, Bytes: id}
+			if status != 200 {
+				b.Results[i].ErrorClass = shared.ClassNon2xx
+			}
+		}
+	}
+	end := time.Now()
+	return shared.Result{Status: 200, Attempts: 1, Start: start, End: end, Latency: end.Sub(start), WorkerID: shared.WorkerID(ctx)}, nil
+}
+
+// every pattern runs against a fake target, with no server behind it
+func TestPatternsWithFakeTarget(t *testing.T) {
+	const requests = 40
+	for _, p := range All {
+		t.Run(p.Name(), func(t *testing.T) {
+			if _, ok := p.(Processes); ok {
+				t.Skip("runs its workers in child processes of the binary")
+			}
+			before := leakcheck.Take()
+			cfg := config.NewConfig("localhost", 5000)
+			cfg.Requests = requests
+			cfg.Concurrency = 4
+			cfg.Seed = 1
+			cfg.LogLevel = "warn"
+			// for the patterns that need them
+			cfg.RatePerSecond = 10000
+			cfg.Burst = 10
+			cfg.Endpoints = []config.Endpoint{
+				{Weight: 3, Method: "GET", Host: "a", Port: 1, Path: "/data"},
+				{Weight: 1, Method: "GET", Host: "b", Port: 1, Path: "/data"},
+			}
+			if err := shared.SetupLogging(cfg); err != nil {
+				t.Fatal(err)
+			}
+			target := &fakeTarget{}
+			collector := shared.NewCollectorFor(cfg)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			if err := p.Run(ctx, cfg, collector.Track(target), collector); err != nil {
+				t.Fatal(err)
+			}
+			s := collector.Summary()
+			if s.Count != requests {
+				t.Errorf("recorded %d results, want %d", s.Count, requests)
+			}
+			// the bulkhead turns some away by design
+			if failed := s.Errors - s.ErrorClasses[shared.ClassRejected]; failed != 0 {
+				t.Errorf("got %d errors", failed)
+			}
+			// batching and coalescing patterns call it fewer times
+			if n := target.calls.Load(); n == 0 {
+				t.Error("the target was never called")
+			}
+			for _, l := range leakcheck.Check(before, time.Second) {
+				t.Errorf("%d goroutines leaked [%s]:\n%s", l.Count, l.State, l.Stack)
+			}
+		})
+	}
+}
+
+// results keeps every Result recorded by a Collector.
+type results struct {
+	mu  sync.Mutex
+	all []shared.Result
+}
+
+func (r *results) Record(res shared.Result) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.all = append(r.all, res)
+}
+
+// each caller of a batch gets the result of its own request, not a copy of
+// the bulk call's
+func TestBatchAnswersEachCaller(t *testing.T) {
+	const requests = 40
+	cfg := config.NewConfig("localhost", 5000)
+	cfg.Requests = requests
+	cfg.Concurrency = 8
+	cfg.BatchSize = 4
+	cfg.BatchWait = 5 * time.Millisecond
+	cfg.LogLevel = "warn"
+	if err := shared.SetupLogging(cfg); err != nil {
+		t.Fatal(err)
+	}
+	// requests with odd IDs fail
+	target := &fakeTarget{status: func(id int64) int {
+		if id%2 == 1 {
+			return 500
+		}
+		return 200
+	}}
+	collector := shared.NewCollectorFor(cfg)
+	got := &results{}
+	collector.AddSink(got)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := (Batch{}).Run(ctx, cfg, target, collector); err != nil {
+		t.Fatal(err)
+	}
+	if n := target.calls.Load(); n >= requests {
+		t.Errorf("made %d calls for %d requests, want fewer", n, requests)
+	}
+	if len(got.all) != requests {
+		t.Fatalf("recorded %d results, want %d", len(got.all), requests)
+	}
+	// the fake sends each request's ID back as its Bytes
+	seen := make(map[int64]bool)
+	for _, r := range got.all {
+		id := r.Bytes
+		if seen[id] {
+			t.Errorf("request %d answered twice", id)
+		}
+		seen[id] = true
+		want := 200
+		if id%2 == 1 {
+			want = 500
+		}
+		if r.Status != want {
+			t.Errorf("request %d got status %d, want %d", id, r.Status, want)
+		}
+	}
+	if s := collector.Summary(); s.ErrorClasses[shared.ClassNon2xx] != requests/2 {
+		t.Errorf("got %d failed requests, want %d", s.ErrorClasses[shared.ClassNon2xx], requests/2)
+	}
+}
+
+// the patterns tag each request with the index of the worker, or slot,
+// making it
+func TestWorkerIDs(t *testing.T) {
+	for _, p := range []runner.Pattern{Pipeline{}, Semaphore{}, Semaphore{Channel: true}} {
+		t.Run(p.Name(), func(t *testing.T) {
+			cfg := config.NewConfig("localhost", 5000)
+			cfg.Requests = 40
+			cfg.Concurrency = 4
+			cfg.LogLevel = "warn"
+			if err := shared.SetupLogging(cfg); err != nil {
+				t.Fatal(err)
+			}
+			collector := shared.NewCollectorFor(cfg)
+			got := &results{}
+			collector.AddSink(got)
+			if err := p.Run(context.Background(), cfg, &fakeTarget{}, collector); err != nil {
+				t.Fatal(err)
+			}
+			for _, r := range got.all {
+				if r.WorkerID < 0 || r.WorkerID >= cfg.Concurrency {
+					t.Fatalf("got worker ID %d of %d", r.WorkerID, cfg.Concurrency)
+				}
+			}
+			// the fake takes long enough for the workers to overlap
+			if s := collector.Summary(); len(s.Workers) < 2 {
+				t.Errorf("requests made by %d workers, want several", len(s.Workers))
+			}
+		})
+	}
+}
+
+// the queueing patterns keep the Intended time an open-loop target has
+// set, only filling it in when it's missing
+func TestIntendedKept(t *testing.T) {
+	intended := time.Now().Add(-time.Hour)
+	target := shared.TargetFunc(func(ctx context.Context) (shared.Result, error) {
+		now := time.Now()
+		return shared.Result{Status: 200, Start: now, End: now, Intended: intended}, nil
+	})
+	for _, p := range []runner.Pattern{RateLimit{}, Priority{}, WorkStealing{}} {
+		t.Run(p.Name(), func(t *testing.T) {
+			cfg := config.NewConfig("localhost", 5000)
+			cfg.Requests = 20
+			cfg.Concurrency = 4
+			cfg.RatePerSecond = 10000
+			cfg.Burst = 10
+			cfg.LogLevel = "warn"
+			if err := shared.SetupLogging(cfg); err != nil {
+				t.Fatal(err)
+			}
+			collector := shared.NewCollectorFor(cfg)
+			got := &results{}
+			collector.AddSink(got)
+			if err := p.Run(context.Background(), cfg, target, collector); err != nil {
+				t.Fatal(err)
+			}
+			if len(got.all) != cfg.Requests {
+				t.Fatalf("recorded %d results, want %d", len(got.all), cfg.Requests)
+			}
+			for _, r := range got.all {
+				if !r.Intended.Equal(intended) {
+					t.Fatalf("got Intended %v, want the target's %v", r.Intended, intended)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package patterns
+
+import (
+	"context"
+
+	"github.com/aawadall/go-concurrency-patterns/cmd/pipelines/pipeline"
+	"github.com/aawadall/go-concurrency-patterns/config"
+	"github.com/aawadall/go-concurrency-patterns/runner"
+	"github.com/aawadall/go-concurrency-patterns/shared"
+)
+
+// Pipeline feeds request IDs through a pipeline stage of cfg.Concurrency
+// workers that makes the requests, each tagged with its worker's index.
+type Pipeline struct{}
+
+func (Pipeline) Name() string { return "pipeline" }
+
+func (Pipeline) Run(ctx context.Context, cfg *config.Config, target shared.Target, collector *shared.Collector) error {
+	source := make(chan pipeline.Message[struct{}])
+	go func() {
+		defer close(source)
+		for i := range runner.Requests(ctx, cfg) {
+			source <- pipeline.Message[struct{}]{ID: int64(i)}
+		}
+	}()
+
+	requestStage := pipeline.Stage[struct{}, shared.Result]{
+		Name:    "Request Stage",
+		Workers: cfg.Concurrency,
+		Buffer:  cfg.Concurrency,
+		Function: func(m pipeline.Message[struct{}]) (pipeline.Message[shared.Result], error) {
+			resp, _ := target.Do(shared.WithWorkerID(ctx, m.Worker))
+			return pipeline.Message[shared.Result]{ID: m.ID, Payload: resp}, nil
+		},
+	}
+
+	// the stage runs until source closes, so requests in flight when ctx
+	// is canceled are still collected
+	out, _ := requestStage.Run(context.WithoutCancel(ctx), source)
+	for m := range out {
+		collector.Record(m.Payload)
+	}
+	return ctx.Err()
+}
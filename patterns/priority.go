@@ -0,0 +1,198 @@
+package patterns
+
+import (
+	"container/heap"
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/aawadall/go-concurrency-patterns/config"
+	"github.com/aawadall/go-concurrency-patterns/runner"
+	"github.com/aawadall/go-concurrency-patterns/shared"
+)
+
+// priorities are the job priorities, highest first, with the share of
+// jobs given each.
+var priorities = []struct {
+	name  string
+	share float64
+}{
+	{"high", 0.2},
+	{"normal", 0.5},
+	{"low", 0.3},
+}
+
+// priorityProducers is how many producers enqueue jobs.
+const priorityProducers = 2
+
+// Priority has producers give each request a priority and enqueue it in a
+// priority queue of cfg.QueueDepth, guarded by a mutex with condition
+// variables, from which cfg.Concurrency workers always take the highest
+// priority job waiting, oldest first. Producers wait while the queue is
+// full and workers while it is empty. Under load low priority jobs starve;
+// how long each priority waited in the queue is logged at the end, and
+// each Result's Intended time is when it was enqueued, unless an
+// open-loop target has set it already, so the corrected latencies include
+// the wait.
+type Priority struct{}
+
+func (Priority) Name() string { return "priority" }
+
+func (Priority) Run(ctx context.Context, cfg *config.Config, target shared.Target, collector *shared.Collector) error {
+	depth := cfg.QueueDepth
+	if depth == 0 {
+		depth = cfg.Concurrency
+	}
+	queue := newPriorityQueue(depth)
+
+	waits := make([]*shared.Histogram, len(priorities))
+	for i := range waits {
+		waits[i] = shared.NewHistogram()
+	}
+	var waitsMu sync.Mutex
+
+	var workers sync.WaitGroup
+	for i := 0; i < cfg.Concurrency; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			ctx := shared.WithWorkerID(ctx, i)
+			for {
+				job, ok := queue.pop()
+				if !ok {
+					return
+				}
+				waitsMu.Lock()
+				waits[job.priority].RecordDuration(time.Since(job.enqueued))
+				waitsMu.Unlock()
+				resp, _ := target.Do(ctx)
+				if resp.Intended.IsZero() {
+					resp.Intended = job.enqueued
+				}
+				collector.Record(resp)
+			}
+		}()
+	}
+
+	jobs := make(chan struct{})
+	go func() {
+		defer close(jobs)
+		for range runner.Requests(ctx, cfg) {
+			jobs <- struct{}{}
+		}
+	}()
+	var producers sync.WaitGroup
+	for i := range priorityProducers {
+		producers.Add(1)
+		go func() {
+			defer producers.Done()
+			rng := shared.WorkerRand(cfg.Seed, i)
+			for range jobs {
+				queue.push(priorityJob{priority: pickPriority(rng), enqueued: time.Now()})
+			}
+		}()
+	}
+	producers.Wait()
+	queue.close()
+	workers.Wait()
+
+	for i, p := range priorities {
+		h := waits[i]
+		shared.Logger().Info("queue wait", "priority", p.name, "jobs", h.Count(),
+			"mean", time.Duration(h.Mean()), "p99", h.DurationAtPercentile(99), "max", time.Duration(h.Max()))
+	}
+	return ctx.Err()
+}
+
+func pickPriority(rng *rand.Rand) int {
+	x := rng.Float64()
+	for i, p := range priorities {
+		if x < p.share {
+			return i
+		}
+		x -= p.share
+	}
+	return len(priorities) - 1
+}
+
+type priorityJob struct {
+	priority int
+	enqueued time.Time
+	// seq keeps jobs of the same priority in order
+	seq int
+}
+
+// jobHeap orders jobs by priority, then by age.
+type jobHeap []priorityJob
+
+func (h jobHeap) Len() int { return len(h) }
+func (h jobHeap) Less(i, j int) bool {
+	if h[i].priority != h[j].priority {
+		return h[i].priority < h[j].priority
+	}
+	return h[i].seq < h[j].seq
+}
+func (h jobHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *jobHeap) Push(x any)   { *h = append(*h, x.(priorityJob)) }
+func (h *jobHeap) Pop() any {
+	old := *h
+	job := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return job
+}
+
+// priorityQueue is a bounded priority queue. push waits on notFull while
+// it is full and pop on notEmpty while it is empty.
+type priorityQueue struct {
+	mu       sync.Mutex
+	notFull  *sync.Cond
+	notEmpty *sync.Cond
+	jobs     jobHeap
+	size     int
+	seq      int
+	closed   bool
+}
+
+func newPriorityQueue(size int) *priorityQueue {
+	q := &priorityQueue{size: size}
+	q.notFull = sync.NewCond(&q.mu)
+	q.notEmpty = sync.NewCond(&q.mu)
+	return q
+}
+
+func (q *priorityQueue) push(job priorityJob) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for len(q.jobs) >= q.size {
+		q.notFull.Wait()
+	}
+	job.seq = q.seq
+	q.seq++
+	heap.Push(&q.jobs, job)
+	q.notEmpty.Signal()
+}
+
+// pop returns the highest priority job, or false once the queue is closed
+// and empty.
+func (q *priorityQueue) pop() (priorityJob, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for len(q.jobs) == 0 && !q.closed {
+		q.notEmpty.Wait()
+	}
+	if len(q.jobs) == 0 {
+		return priorityJob{}, false
+	}
+	job := heap.Pop(&q.jobs).(priorityJob)
+	q.notFull.Signal()
+	return job, true
+}
+
+// close wakes every worker waiting on an empty queue to finish.
+func (q *priorityQueue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+	q.notEmpty.Broadcast()
+}
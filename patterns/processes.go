@@ -0,0 +1,273 @@
+package patterns
+
+import (
+	"bufio"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/aawadall/go-concurrency-patterns/config"
+	"github.com/aawadall/go-concurrency-patterns/runner"
+	"github.com/aawadall/go-concurrency-patterns/scope"
+	"github.com/aawadall/go-concurrency-patterns/shared"
+)
+
+// childEnv is set in the environment of the child processes of the
+// Processes pattern, which makes them serve requests instead of running
+// their main.
+const childEnv = "GCP_PROCESS_WORKER"
+
+// Processes is the worker pool with processes for workers: it starts
+// cfg.Concurrency copies of the running binary, each making the requests
+// it is sent over its stdin, one at a time, with a target of its own, and
+// sending the results back over its stdout. The requests never share an
+// address space, a garbage collector or a scheduler, at the cost of a
+// round trip through two pipes and gob for each. How long the children
+// took to start and what the round trips added to each request are
+// logged at the end. Only the rate limit and timeout of the config are
+// applied, the first by the parent and the second by the children.
+//
+// Any binary importing this package can be a child: an init function
+// takes over when childEnv is set.
+type Processes struct{}
+
+func (Processes) Name() string { return "processes" }
+
+// LimitsRate tells the runner not to limit the rate a second time: the
+// parent limits it before handing out requests.
+func (Processes) LimitsRate() bool { return true }
+
+func init() {
+	if os.Getenv(childEnv) != "" {
+		if err := serveChild(os.Stdin, os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, "process worker:", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+}
+
+// childStart is the first thing a child is sent.
+type childStart struct {
+	Config config.Config
+	Worker int
+}
+
+// childResult is a shared.Result as a child sends it back, with its
+// error reduced to its message.
+type childResult struct {
+	Latency         time.Duration
+	Status          int
+	Err             string
+	ErrorClass      shared.ErrorClass
+	Bytes           int64
+	Start, End      time.Time
+	Endpoint        string
+	Trace           shared.Trace
+	ValidationError string
+	Attempts        int
+	FirstLatency    time.Duration
+}
+
+func (p Processes) Run(ctx context.Context, cfg *config.Config, target shared.Target, collector *shared.Collector) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	var lim *rate.Limiter
+	if cfg.RatePerSecond > 0 {
+		lim = rate.NewLimiter(rate.Limit(cfg.RatePerSecond), cfg.Burst)
+	}
+
+	var startup, ipc atomic.Int64
+	var requests atomic.Int64
+	err = scope.Run(ctx, func(s *scope.Scope) error {
+		jobs := make(chan int)
+		s.Go(func(ctx context.Context) error {
+			defer close(jobs)
+			for n := range runner.Requests(ctx, cfg) {
+				select {
+				case jobs <- n:
+				case <-ctx.Done():
+					return nil
+				}
+			}
+			return nil
+		})
+
+		for i := 0; i < cfg.Concurrency; i++ {
+			s.Go(func(ctx context.Context) error {
+				start := time.Now()
+				c, err := startChild(exe, cfg, i)
+				if err != nil {
+					return fmt.Errorf("process worker %d: %w", i, err)
+				}
+				defer c.stop()
+				startup.Add(int64(time.Since(start)))
+
+				for n := range jobs {
+					if lim != nil && lim.Wait(ctx) != nil {
+						continue
+					}
+					sent := time.Now()
+					r, err := c.do(n)
+					if err != nil {
+						return fmt.Errorf("process worker %d: %w", i, err)
+					}
+					ipc.Add(int64(time.Since(sent) - r.Latency))
+					requests.Add(1)
+					r.WorkerID = i
+					collector.Record(r)
+				}
+				return c.stop()
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	shared.Logger().Info("process workers", "children", cfg.Concurrency,
+		"startup", time.Duration(startup.Load()/int64(max(cfg.Concurrency, 1))),
+		"ipc", time.Duration(ipc.Load()/max(requests.Load(), 1)))
+	return ctx.Err()
+}
+
+// child is a running child process and the pipes to it.
+type child struct {
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+	enc   *gob.Encoder
+	dec   *gob.Decoder
+	done  bool
+}
+
+// startChild starts a child as worker i of a run of cfg, and waits for it
+// to be ready.
+func startChild(exe string, cfg *config.Config, i int) (*child, error) {
+	cmd := exec.Command(exe)
+	cmd.Env = append(os.Environ(), childEnv+"=1")
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	c := &child{cmd: cmd, stdin: stdin, enc: gob.NewEncoder(stdin), dec: gob.NewDecoder(bufio.NewReader(stdout))}
+
+	c1 := *cfg
+	c1.Seed = cfg.Seed + int64(i)
+	var ready bool
+	if err := c.enc.Encode(childStart{Config: c1, Worker: i}); err != nil {
+		c.stop()
+		return nil, err
+	}
+	if err := c.dec.Decode(&ready); err != nil {
+		c.stop()
+		return nil, fmt.Errorf("starting: %w", err)
+	}
+	return c, nil
+}
+
+// do has the child make request n and returns its result.
+func (c *child) do(n int) (shared.Result, error) {
+	if err := c.enc.Encode(n); err != nil {
+		return shared.Result{}, err
+	}
+	var cr childResult
+	if err := c.dec.Decode(&cr); err != nil {
+		return shared.Result{}, err
+	}
+	r := shared.Result{
+		Latency: cr.Latency, Status: cr.Status, ErrorClass: cr.ErrorClass, Bytes: cr.Bytes,
+		Start: cr.Start, End: cr.End, Endpoint: cr.Endpoint, Trace: cr.Trace,
+		ValidationError: cr.ValidationError, Attempts: cr.Attempts, FirstLatency: cr.FirstLatency,
+	}
+	if cr.Err != "" {
+		r.Err = errors.New(cr.Err)
+	}
+	return r, nil
+}
+
+// stop closes the child's stdin, which ends it, and waits for it to exit.
+func (c *child) stop() error {
+	if c.done {
+		return nil
+	}
+	c.done = true
+	c.stdin.Close()
+	return c.cmd.Wait()
+}
+
+// serveChild is the child's side: it reads its config, then makes a
+// request for each job it reads until its input ends.
+func serveChild(in io.Reader, out io.Writer) error {
+	dec := gob.NewDecoder(bufio.NewReader(in))
+	w := bufio.NewWriter(out)
+	enc := gob.NewEncoder(w)
+
+	var start childStart
+	if err := dec.Decode(&start); err != nil {
+		return err
+	}
+	cfg := &start.Config
+	cfg.Quiet, cfg.Live = true, false
+	if err := shared.SetupLogging(cfg); err != nil {
+		return err
+	}
+	shared.Seed(cfg.Seed)
+	target, err := shared.NewTarget(cfg)
+	if err != nil {
+		return err
+	}
+	if cfg.Timeout > 0 {
+		target = shared.WithTimeout(target, cfg.Timeout)
+	}
+	ctx := shared.WithWorkerID(context.Background(), start.Worker)
+	if err := enc.Encode(true); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	for {
+		var n int
+		if err := dec.Decode(&n); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		r, _ := target.Do(ctx)
+		cr := childResult{
+			Latency: r.Latency, Status: r.Status, ErrorClass: r.ErrorClass, Bytes: r.Bytes,
+			Start: r.Start, End: r.End, Endpoint: r.Endpoint, Trace: r.Trace,
+			ValidationError: r.ValidationError, Attempts: r.Attempts, FirstLatency: r.FirstLatency,
+		}
+		if r.Err != nil {
+			cr.Err = r.Err.Error()
+		}
+		if err := enc.Encode(cr); err != nil {
+			return err
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+}
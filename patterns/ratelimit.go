@@ -0,0 +1,106 @@
+package patterns
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/aawadall/go-concurrency-patterns/config"
+	"github.com/aawadall/go-concurrency-patterns/runner"
+	"github.com/aawadall/go-concurrency-patterns/shared"
+)
+
+// RateLimit has cfg.Concurrency workers wait on a limiter of
+// cfg.RatePerSecond before each request: a token bucket from
+// golang.org/x/time/rate allowing bursts of cfg.Burst, or, with Leaky
+// set, a hand-rolled leaky bucket letting requests out at an even pace.
+// Each Result's Intended time, unless an open-loop target has set it
+// already, is when it joined the limiter's queue, so the corrected
+// latencies include the time spent queueing.
+type RateLimit struct {
+	Leaky bool
+}
+
+func (l RateLimit) Name() string {
+	if l.Leaky {
+		return "ratelimit-leaky"
+	}
+	return "ratelimit"
+}
+
+// LimitsRate tells the runner not to limit the rate a second time.
+func (RateLimit) LimitsRate() bool { return true }
+
+// limiter is what the workers wait on before each request.
+type limiter interface {
+	Wait(ctx context.Context) error
+}
+
+func (l RateLimit) Run(ctx context.Context, cfg *config.Config, target shared.Target, collector *shared.Collector) error {
+	if cfg.RatePerSecond <= 0 {
+		return errors.New("the rate limit patterns need -rps")
+	}
+	var lim limiter = rate.NewLimiter(rate.Limit(cfg.RatePerSecond), cfg.Burst)
+	if l.Leaky {
+		bucket := newLeakyBucket(cfg.RatePerSecond)
+		defer bucket.stop()
+		lim = bucket
+	}
+
+	jobs := make(chan struct{})
+	go func() {
+		defer close(jobs)
+		for range runner.Requests(ctx, cfg) {
+			jobs <- struct{}{}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < cfg.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx := shared.WithWorkerID(ctx, i)
+			for range jobs {
+				queued := time.Now()
+				if err := lim.Wait(ctx); err != nil {
+					continue
+				}
+				resp, _ := target.Do(ctx)
+				if resp.Intended.IsZero() {
+					resp.Intended = queued
+				}
+				collector.Record(resp)
+			}
+		}()
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+// leakyBucket lets waiters out one at a time at a constant rate. A
+// ticker drips; since its channel holds a single tick, an idle bucket
+// saves up at most one request's worth, so there are no bursts.
+type leakyBucket struct {
+	ticker *time.Ticker
+}
+
+func newLeakyBucket(perSecond float64) *leakyBucket {
+	return &leakyBucket{ticker: time.NewTicker(time.Duration(float64(time.Second) / perSecond))}
+}
+
+func (b *leakyBucket) Wait(ctx context.Context) error {
+	select {
+	case <-b.ticker.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (b *leakyBucket) stop() {
+	b.ticker.Stop()
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"os"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/aawadall/go-concurrency-patterns/patterns/redisqueue"
+	"github.com/aawadall/go-concurrency-patterns/runner"
+)
+
+// Like gcp run workerpool, with the queue in the Redis server at
+// $REDIS_ADDR (localhost:6379 by default). With "producer" or "worker"
+// as the first argument it only produces jobs or only works on them, so
+// that several worker processes can share a producer's jobs. The
+// producer starts from an empty queue, so start the workers after it.
+func main() {
+	args := os.Args[1:]
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		addr = "localhost:6379"
+	}
+	p := redisqueue.Pattern{
+		Options:    redis.Options{Addr: addr},
+		Stream:     "gcp:jobs",
+		Visibility: time.Second,
+		Abandon:    0.01,
+	}
+	if len(args) > 0 {
+		switch args[0] {
+		case "producer":
+			p.Role = redisqueue.Producer
+			args = args[1:]
+		case "worker":
+			p.Role = redisqueue.Worker
+			args = args[1:]
+		}
+	}
+	runner.Main(p, args)
+}
@@ -0,0 +1,168 @@
+package redisqueue
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Queue is a work queue in a Redis stream, consumed by a consumer group
+// so that each job goes to one worker. A job stays pending until it is
+// acknowledged; one left pending for longer than the visibility timeout,
+// because its worker died or gave up, is claimed by another worker. Jobs
+// are therefore delivered at least once, and can be done twice.
+type Queue struct {
+	client     *redis.Client
+	stream     string
+	group      string
+	visibility time.Duration
+}
+
+// NewQueue returns the queue in stream, consumed by group. Jobs pending
+// for longer than visibility are handed to another worker.
+func NewQueue(client *redis.Client, stream, group string, visibility time.Duration) *Queue {
+	return &Queue{client: client, stream: stream, group: group, visibility: visibility}
+}
+
+// Job is a job taken from a Queue.
+type Job struct {
+	ID string
+	N  int
+	// Deliveries is how many times the job has been handed out,
+	// including this one.
+	Deliveries int64
+}
+
+// Create creates the queue if it doesn't exist.
+func (q *Queue) Create(ctx context.Context) error {
+	err := q.client.XGroupCreateMkStream(ctx, q.stream, q.group, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return err
+	}
+	return nil
+}
+
+// Reset deletes the queue and its counters and creates it empty.
+func (q *Queue) Reset(ctx context.Context) error {
+	if err := q.client.Del(ctx, q.stream, q.key("produced"), q.key("acked")).Err(); err != nil {
+		return err
+	}
+	return q.Create(ctx)
+}
+
+// Push adds job n to the queue.
+func (q *Queue) Push(ctx context.Context, n int) error {
+	return q.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: q.stream,
+		Values: []any{"n", n},
+	}).Err()
+}
+
+// Produced records that the producer is done after pushing n jobs, so
+// that workers can tell when they have all been acknowledged.
+func (q *Queue) Produced(ctx context.Context, n int) error {
+	return q.client.Set(ctx, q.key("produced"), n, 0).Err()
+}
+
+// Done reports whether the producer is done and every job it pushed
+// has been acknowledged.
+func (q *Queue) Done(ctx context.Context) (bool, error) {
+	vals, err := q.client.MGet(ctx, q.key("produced"), q.key("acked")).Result()
+	if err != nil || vals[0] == nil {
+		return false, err
+	}
+	produced, _ := strconv.Atoi(vals[0].(string))
+	acked := 0
+	if vals[1] != nil {
+		acked, _ = strconv.Atoi(vals[1].(string))
+	}
+	return acked >= produced, nil
+}
+
+// Next takes a job for consumer, waiting up to wait for one.
+// It returns nil if there is none.
+func (q *Queue) Next(ctx context.Context, consumer string, wait time.Duration) (*Job, error) {
+	streams, err := q.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    q.group,
+		Consumer: consumer,
+		Streams:  []string{q.stream, ">"},
+		Count:    1,
+		Block:    wait,
+	}).Result()
+	err = q.recreate(ctx, err)
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil || len(streams) == 0 || len(streams[0].Messages) == 0 {
+		return nil, err
+	}
+	return job(streams[0].Messages[0], 1), nil
+}
+
+// recreate creates the queue again if err is because it is missing,
+// as it is after a producer resets it.
+func (q *Queue) recreate(ctx context.Context, err error) error {
+	if err != nil && strings.HasPrefix(err.Error(), "NOGROUP") {
+		return q.Create(ctx)
+	}
+	return err
+}
+
+// Reclaim takes for consumer a job left pending by another worker for
+// longer than the visibility timeout. It returns nil if there is none.
+func (q *Queue) Reclaim(ctx context.Context, consumer string) (*Job, error) {
+	msgs, _, err := q.client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+		Stream:   q.stream,
+		Group:    q.group,
+		Consumer: consumer,
+		MinIdle:  q.visibility,
+		Start:    "0-0",
+		Count:    1,
+	}).Result()
+	if err = q.recreate(ctx, err); err != nil || len(msgs) == 0 {
+		return nil, err
+	}
+	pending, err := q.client.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream: q.stream,
+		Group:  q.group,
+		Start:  msgs[0].ID,
+		End:    msgs[0].ID,
+		Count:  1,
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+	deliveries := int64(2)
+	if len(pending) > 0 {
+		deliveries = pending[0].RetryCount
+	}
+	return job(msgs[0], deliveries), nil
+}
+
+// Ack acknowledges job, so that it is not handed out again. Only the
+// first acknowledgement of a job counts towards Done.
+func (q *Queue) Ack(ctx context.Context, j *Job) error {
+	return ack.Run(ctx, q.client, []string{q.stream, q.key("acked")}, q.group, j.ID).Err()
+}
+
+// ack acknowledges a job and counts it in one step, so that the count
+// can't miss an acknowledgement or count one twice.
+var ack = redis.NewScript(`
+if redis.call("XACK", KEYS[1], ARGV[1], ARGV[2]) == 1 then
+	redis.call("INCR", KEYS[2])
+end
+return 0
+`)
+
+func (q *Queue) key(name string) string {
+	return q.stream + ":" + name
+}
+
+func job(msg redis.XMessage, deliveries int64) *Job {
+	n, _ := strconv.Atoi(msg.Values["n"].(string))
+	return &Job{ID: msg.ID, N: n, Deliveries: deliveries}
+}
@@ -0,0 +1,185 @@
+// Package redisqueue is the worker pool with its queue in Redis, so that
+// producers and workers can be separate processes. Jobs are delivered at
+// least once: a worker acknowledges a job once it has made its request,
+// and a job it never acknowledges is handed to another worker after the
+// visibility timeout. It is a module of its own so that only it depends
+// on a Redis client.
+package redisqueue
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/aawadall/go-concurrency-patterns/config"
+	"github.com/aawadall/go-concurrency-patterns/runner"
+	"github.com/aawadall/go-concurrency-patterns/scope"
+	"github.com/aawadall/go-concurrency-patterns/shared"
+)
+
+// Role is what part of the pattern a process plays.
+type Role int
+
+const (
+	// Both produces jobs and runs workers for them.
+	Both Role = iota
+	// Producer only produces jobs, for workers in other processes.
+	Producer
+	// Worker only runs workers, until the producer is done and every
+	// job has been acknowledged.
+	Worker
+)
+
+// Pattern pushes a job to a Redis stream for each request, and
+// cfg.Concurrency workers, each with a connection of its own as if it
+// were a process of its own, take jobs from the stream's consumer group
+// and make the requests. Unlike the in-memory worker pool, the queue is
+// unbounded and outlives the processes.
+type Pattern struct {
+	// Options are how to connect to Redis.
+	Options redis.Options
+	// Stream is the key of the stream; its counters are kept in keys
+	// next to it.
+	Stream string
+	Role   Role
+	// Visibility is how long a job can stay unacknowledged before
+	// another worker takes it.
+	Visibility time.Duration
+	// Abandon is the share of jobs a worker drops after making the
+	// request but before acknowledging it, as a worker that crashes
+	// would; they are done again once their visibility timeout passes.
+	Abandon float64
+}
+
+func (p Pattern) Name() string {
+	return "redis-queue"
+}
+
+const group = "workers"
+
+func (p Pattern) Run(ctx context.Context, cfg *config.Config, target shared.Target, collector *shared.Collector) error {
+	client := redis.NewClient(&p.Options)
+	defer client.Close()
+	q := NewQueue(client, p.Stream, group, p.Visibility)
+	create := q.Reset
+	if p.Role == Worker {
+		// leave the jobs for other workers alone
+		create = q.Create
+	}
+	if err := create(ctx); err != nil {
+		return fmt.Errorf("redis: %w", err)
+	}
+
+	var pushed, acked, redelivered, abandoned atomic.Int64
+	results := make(chan shared.Result, cfg.Concurrency)
+	err := scope.Run(ctx, func(s *scope.Scope) error {
+		s.Go(func(ctx context.Context) error {
+			defer close(results)
+			return scope.Run(ctx, func(pool *scope.Scope) error {
+				if p.Role != Producer {
+					for i := 0; i < cfg.Concurrency; i++ {
+						pool.Go(func(ctx context.Context) error {
+							w := worker{
+								Pattern: p, id: i, target: target, results: results,
+								acked: &acked, redelivered: &redelivered, abandoned: &abandoned,
+							}
+							return w.run(shared.WithWorkerID(ctx, i))
+						})
+					}
+				}
+				if p.Role != Worker {
+					pool.Go(func(ctx context.Context) error {
+						for n := range runner.Requests(ctx, cfg) {
+							if err := q.Push(ctx, n); err != nil {
+								return fmt.Errorf("redis: %w", err)
+							}
+							pushed.Add(1)
+						}
+						if ctx.Err() != nil {
+							return nil
+						}
+						return q.Produced(ctx, int(pushed.Load()))
+					})
+				}
+				return nil
+			})
+		})
+		collector.Collect(results)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	shared.Logger().Info("redis queue", "pushed", pushed.Load(), "acked", acked.Load(),
+		"redelivered", redelivered.Load(), "abandoned", abandoned.Load())
+	return ctx.Err()
+}
+
+// worker takes jobs from the queue until it is done.
+type worker struct {
+	Pattern
+	id      int
+	target  shared.Target
+	results chan<- shared.Result
+
+	acked, redelivered, abandoned *atomic.Int64
+}
+
+func (w worker) run(ctx context.Context) error {
+	client := redis.NewClient(&w.Options)
+	defer client.Close()
+	q := NewQueue(client, w.Stream, group, w.Visibility)
+	consumer := fmt.Sprintf("worker-%d", w.id)
+
+	wait := w.Visibility / 4
+	nextCheck := time.Now()
+	for ctx.Err() == nil {
+		// look for abandoned jobs, and whether all is done, now and then
+		var j *Job
+		var err error
+		if time.Now().After(nextCheck) {
+			nextCheck = time.Now().Add(wait)
+			if j, err = q.Reclaim(ctx, consumer); err == nil && j == nil {
+				var done bool
+				if done, err = q.Done(ctx); done {
+					return nil
+				}
+			}
+		}
+		if j == nil && err == nil {
+			j, err = q.Next(ctx, consumer, wait)
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("redis: %w", err)
+		}
+		if j == nil {
+			continue
+		}
+		if j.Deliveries > 1 {
+			w.redelivered.Add(1)
+		}
+
+		resp, _ := w.target.Do(ctx)
+		if rand.Float64() < w.Abandon {
+			w.abandoned.Add(1)
+			continue
+		}
+		w.results <- resp
+		if err := q.Ack(ctx, j); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("redis: %w", err)
+		}
+		w.acked.Add(1)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package patterns
+
+import (
+	"context"
+
+	"golang.org/x/sync/semaphore"
+
+	"github.com/aawadall/go-concurrency-patterns/config"
+	"github.com/aawadall/go-concurrency-patterns/runner"
+	"github.com/aawadall/go-concurrency-patterns/shared"
+)
+
+// Semaphore starts a goroutine per request, like WaitGroups, but bounds
+// how many run at once with a semaphore of cfg.Concurrency: the loop
+// acquires before starting each goroutine, which releases when done.
+// Unlike a worker pool no goroutine outlives its request. Channel selects
+// a buffered channel of tokens instead of golang.org/x/sync/semaphore.
+// Either way each holder is tagged with the index of its slot as its
+// worker ID.
+type Semaphore struct {
+	Channel bool
+}
+
+func (s Semaphore) Name() string {
+	if s.Channel {
+		return "semaphore-chan"
+	}
+	return "semaphore"
+}
+
+func (s Semaphore) Run(ctx context.Context, cfg *config.Config, target shared.Target, collector *shared.Collector) error {
+	results := make(chan shared.Result, cfg.Concurrency)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		collector.Collect(results)
+	}()
+
+	if s.Channel {
+		runChannelSemaphore(ctx, cfg, target, results)
+	} else {
+		runWeightedSemaphore(ctx, cfg, target, results)
+	}
+	close(results)
+	<-done
+	return ctx.Err()
+}
+
+func runWeightedSemaphore(ctx context.Context, cfg *config.Config, target shared.Target, results chan<- shared.Result) {
+	n := int64(cfg.Concurrency)
+	sem := semaphore.NewWeighted(n)
+	// free holds the indexes of the slots not held, so that each holder
+	// gets one as its worker ID; there is always one once Acquire returns
+	free := make(chan int, n)
+	for i := range cfg.Concurrency {
+		free <- i
+	}
+	for range runner.Requests(ctx, cfg) {
+		if err := sem.Acquire(ctx, 1); err != nil {
+			break
+		}
+		id := <-free
+		go func() {
+			defer sem.Release(1)
+			defer func() { free <- id }()
+			resp, _ := target.Do(shared.WithWorkerID(ctx, id))
+			results <- resp
+		}()
+	}
+	// holding every slot means every request is done
+	_ = sem.Acquire(context.WithoutCancel(ctx), n)
+}
+
+func runChannelSemaphore(ctx context.Context, cfg *config.Config, target shared.Target, results chan<- shared.Result) {
+	tokens := make(chan int, cfg.Concurrency)
+	for i := 0; i < cfg.Concurrency; i++ {
+		tokens <- i
+	}
+	for range runner.Requests(ctx, cfg) {
+		var id int
+		select {
+		case id = <-tokens:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		go func() {
+			defer func() { tokens <- id }()
+			resp, _ := target.Do(shared.WithWorkerID(ctx, id))
+			results <- resp
+		}()
+	}
+	for range cfg.Concurrency {
+		<-tokens
+	}
+}
@@ -0,0 +1,23 @@
+package patterns
+
+import (
+	"context"
+
+	"github.com/aawadall/go-concurrency-patterns/config"
+	"github.com/aawadall/go-concurrency-patterns/runner"
+	"github.com/aawadall/go-concurrency-patterns/shared"
+)
+
+// Simple makes every request in turn from a single goroutine, as a
+// baseline for the concurrent patterns.
+type Simple struct{}
+
+func (Simple) Name() string { return "simple" }
+
+func (Simple) Run(ctx context.Context, cfg *config.Config, target shared.Target, collector *shared.Collector) error {
+	for range runner.Requests(ctx, cfg) {
+		resp, _ := target.Do(ctx)
+		collector.Record(resp)
+	}
+	return ctx.Err()
+}
@@ -0,0 +1,94 @@
+package patterns
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	xsingleflight "golang.org/x/sync/singleflight"
+
+	"github.com/aawadall/go-concurrency-patterns/config"
+	"github.com/aawadall/go-concurrency-patterns/runner"
+	"github.com/aawadall/go-concurrency-patterns/shared"
+	"github.com/aawadall/go-concurrency-patterns/singleflight"
+)
+
+// SingleFlight has cfg.Concurrency workers make the same request, each
+// through a single-flight group, so a worker that asks while another's
+// request is in flight waits for that one and shares its result instead
+// of calling the server. It uses golang.org/x/sync/singleflight, or with
+// Generic set the home-grown singleflight package. Every worker's request
+// is recorded, timed from when it asked, and how many calls the
+// coalescing avoided is logged at the end.
+type SingleFlight struct {
+	Generic bool
+}
+
+func (s SingleFlight) Name() string {
+	if s.Generic {
+		return "singleflight-generic"
+	}
+	return "singleflight"
+}
+
+// coalescer is what the workers call through.
+type coalescer func(key string, fn func() (shared.Result, error)) (shared.Result, bool)
+
+func (s SingleFlight) Run(ctx context.Context, cfg *config.Config, target shared.Target, collector *shared.Collector) error {
+	var do coalescer
+	if s.Generic {
+		var g singleflight.Group[string, shared.Result]
+		do = func(key string, fn func() (shared.Result, error)) (shared.Result, bool) {
+			r, _, coalesced := g.Do(key, fn)
+			return r, coalesced
+		}
+	} else {
+		var g xsingleflight.Group
+		do = func(key string, fn func() (shared.Result, error)) (shared.Result, bool) {
+			v, _, coalesced := g.Do(key, func() (any, error) { return fn() })
+			return v.(shared.Result), coalesced
+		}
+	}
+
+	// every request is identical, so they all share one key
+	var calls atomic.Int64
+	call := func() (shared.Result, error) {
+		calls.Add(1)
+		resp, _ := target.Do(ctx)
+		return resp, nil
+	}
+
+	jobs := make(chan struct{})
+	go func() {
+		defer close(jobs)
+		for range runner.Requests(ctx, cfg) {
+			jobs <- struct{}{}
+		}
+	}()
+
+	var requests atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < cfg.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range jobs {
+				requests.Add(1)
+				start := time.Now()
+				resp, coalesced := do("request", call)
+				if coalesced {
+					resp.Start, resp.End = start, time.Now()
+					resp.Latency = resp.End.Sub(start)
+				}
+				resp.WorkerID = i
+				collector.Record(resp)
+			}
+		}()
+	}
+	wg.Wait()
+
+	n, made := requests.Load(), calls.Load()
+	shared.Logger().Info("coalesced requests", "requests", n, "calls", made, "avoided", n-made)
+	return ctx.Err()
+}
@@ -0,0 +1,43 @@
+package patterns
+
+import (
+	"context"
+	"sync"
+
+	"github.com/aawadall/go-concurrency-patterns/config"
+	"github.com/aawadall/go-concurrency-patterns/runner"
+	"github.com/aawadall/go-concurrency-patterns/shared"
+)
+
+// WaitGroups starts a goroutine per request, with no limit on
+// concurrency, and waits for them all with a sync.WaitGroup. With a
+// duration set it keeps starting goroutines as fast as it can, so prefer
+// a bounded pattern for long runs.
+type WaitGroups struct{}
+
+func (WaitGroups) Name() string { return "waitgroups" }
+
+func (WaitGroups) Run(ctx context.Context, cfg *config.Config, target shared.Target, collector *shared.Collector) error {
+	results := make(chan shared.Result, cfg.Concurrency)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		collector.Collect(results)
+	}()
+
+	wg := sync.WaitGroup{}
+	for range runner.Requests(ctx, cfg) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, _ := target.Do(ctx)
+			results <- resp
+		}()
+	}
+
+	// close results once every request is done
+	wg.Wait()
+	close(results)
+	<-done
+	return ctx.Err()
+}
@@ -0,0 +1,160 @@
+package patterns
+
+import (
+	"context"
+	"time"
+
+	"github.com/aawadall/go-concurrency-patterns/config"
+	"github.com/aawadall/go-concurrency-patterns/queue"
+	"github.com/aawadall/go-concurrency-patterns/runner"
+	"github.com/aawadall/go-concurrency-patterns/scope"
+	"github.com/aawadall/go-concurrency-patterns/shared"
+)
+
+// WorkerPool is the classic bounded worker pool: cfg.Concurrency workers
+// take requests from a queue of cfg.QueueDepth. When the queue is full the
+// submitter either blocks until there is room or, with cfg.QueueFull set
+// to "reject", turns the request away and records it as rejected, the way
+// a server sheds load. With Queue set the queue is a queue.Bounded rather
+// than a channel, and how full it ran and how long the submitter and the
+// workers waited on it are logged at the end.
+type WorkerPool struct {
+	Queue bool
+}
+
+func (p WorkerPool) Name() string {
+	if p.Queue {
+		return "workerpool-queue"
+	}
+	return "workerpool"
+}
+
+func (p WorkerPool) Run(ctx context.Context, cfg *config.Config, target shared.Target, collector *shared.Collector) error {
+	depth := cfg.QueueDepth
+	if depth == 0 {
+		depth = cfg.Concurrency
+	}
+	var q jobQueue = make(chanQueue, depth)
+	var bounded *queue.Bounded[struct{}]
+	if p.Queue {
+		bounded = queue.NewBounded[struct{}](depth)
+		q = boundedQueue{bounded}
+	}
+	results := make(chan shared.Result, cfg.Concurrency)
+
+	err := scope.Run(ctx, func(s *scope.Scope) error {
+		// the workers and the submitter share a scope, and results is
+		// closed once it ends
+		s.Go(func(ctx context.Context) error {
+			defer close(results)
+			return scope.Run(ctx, func(pool *scope.Scope) error {
+				for i := 0; i < cfg.Concurrency; i++ {
+					pool.Go(func(ctx context.Context) error {
+						ctx = shared.WithWorkerID(ctx, i)
+						for q.get(ctx) {
+							resp, _ := target.Do(ctx)
+							results <- resp
+						}
+						return nil
+					})
+				}
+
+				// submit
+				pool.Go(func(ctx context.Context) error {
+					defer q.close()
+					for range runner.Requests(ctx, cfg) {
+						if cfg.QueueFull != "reject" {
+							if !q.put(ctx) {
+								return nil
+							}
+							continue
+						}
+						if !q.tryPut() {
+							results <- shared.Rejected(ctx)
+						}
+					}
+					return nil
+				})
+				return nil
+			})
+		})
+
+		collector.Collect(results)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if bounded != nil {
+		st := bounded.Stats()
+		shared.Logger().Info("queue waits", "depth", st.MaxDepth, "rejected", st.Rejected,
+			"put", meanWait(st.PutWait, st.Puts), "get", meanWait(st.GetWait, st.Gets))
+	}
+	return ctx.Err()
+}
+
+// jobQueue is the worker pool's queue of requests to make.
+type jobQueue interface {
+	// put queues a request, waiting for room, and reports false if it
+	// gave up because ctx was done.
+	put(ctx context.Context) bool
+	// tryPut queues a request if there is room.
+	tryPut() bool
+	// get takes a request, waiting for one, and reports false once the
+	// queue is closed and drained or ctx is done.
+	get(ctx context.Context) bool
+	close()
+}
+
+// chanQueue is a buffered channel as a jobQueue.
+type chanQueue chan struct{}
+
+func (q chanQueue) put(ctx context.Context) bool {
+	q <- struct{}{}
+	return true
+}
+
+func (q chanQueue) tryPut() bool {
+	select {
+	case q <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (q chanQueue) get(ctx context.Context) bool {
+	_, ok := <-q
+	return ok
+}
+
+func (q chanQueue) close() { close(q) }
+
+// boundedQueue is a queue.Bounded as a jobQueue.
+type boundedQueue struct {
+	q *queue.Bounded[struct{}]
+}
+
+func (b boundedQueue) put(ctx context.Context) bool {
+	return b.q.Put(ctx, struct{}{}) == nil
+}
+
+func (b boundedQueue) tryPut() bool {
+	return b.q.TryPut(struct{}{}) == nil
+}
+
+func (b boundedQueue) get(ctx context.Context) bool {
+	_, err := b.q.Get(ctx)
+	return err == nil
+}
+
+func (b boundedQueue) close() { b.q.Close() }
+
+// meanWait is total spread over n waits.
+func meanWait(total time.Duration, n int64) time.Duration {
+	if n == 0 {
+		return 0
+	}
+	return total / time.Duration(n)
+}
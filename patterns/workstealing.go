@@ -0,0 +1,192 @@
+package patterns
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aawadall/go-concurrency-patterns/config"
+	"github.com/aawadall/go-concurrency-patterns/runner"
+	"github.com/aawadall/go-concurrency-patterns/shared"
+)
+
+// WorkStealing gives each of cfg.Concurrency workers its own queue, which
+// requests are dealt to in turn. A worker takes the newest request from
+// its own queue and, once that is empty, steals the oldest from another
+// worker's, so workers stuck on slow requests get help and none sits idle
+// while there is work. With Shared set every worker takes requests from
+// one queue instead, for comparison. Up to cfg.QueueDepth requests are
+// queued in all. How often the queue locks were contended, how long was
+// spent waiting for them and how many requests were stolen are logged at
+// the end; the per-worker idle time is in the report.
+type WorkStealing struct {
+	Shared bool
+}
+
+func (s WorkStealing) Name() string {
+	if s.Shared {
+		return "workstealing-shared"
+	}
+	return "workstealing"
+}
+
+// lockStats counts contention on the queue locks.
+type lockStats struct {
+	contended atomic.Int64
+	waited    atomic.Int64
+}
+
+// deque is a double-ended queue of requests, each its enqueue time.
+type deque struct {
+	mu    sync.Mutex
+	jobs  []time.Time
+	stats *lockStats
+}
+
+// lock locks the deque, counting the time spent waiting if it was held.
+func (d *deque) lock() {
+	if d.mu.TryLock() {
+		return
+	}
+	start := time.Now()
+	d.mu.Lock()
+	d.stats.contended.Add(1)
+	d.stats.waited.Add(int64(time.Since(start)))
+}
+
+func (d *deque) push(job time.Time) {
+	d.lock()
+	defer d.mu.Unlock()
+	d.jobs = append(d.jobs, job)
+}
+
+// pop takes the newest job, as the owner does.
+func (d *deque) pop() (time.Time, bool) {
+	d.lock()
+	defer d.mu.Unlock()
+	if len(d.jobs) == 0 {
+		return time.Time{}, false
+	}
+	job := d.jobs[len(d.jobs)-1]
+	d.jobs = d.jobs[:len(d.jobs)-1]
+	return job, true
+}
+
+// steal takes the oldest job, as other workers do.
+func (d *deque) steal() (time.Time, bool) {
+	d.lock()
+	defer d.mu.Unlock()
+	if len(d.jobs) == 0 {
+		return time.Time{}, false
+	}
+	job := d.jobs[0]
+	d.jobs = d.jobs[1:]
+	return job, true
+}
+
+func (s WorkStealing) Run(ctx context.Context, cfg *config.Config, target shared.Target, collector *shared.Collector) error {
+	var stats lockStats
+	var steals atomic.Int64
+	queues := make([]*deque, cfg.Concurrency)
+	if s.Shared {
+		q := &deque{stats: &stats}
+		for i := range queues {
+			queues[i] = q
+		}
+	} else {
+		for i := range queues {
+			queues[i] = &deque{stats: &stats}
+		}
+	}
+
+	depth := cfg.QueueDepth
+	if depth == 0 {
+		depth = 4 * cfg.Concurrency
+	}
+	// slots bounds the queued requests; wake tells idle workers there is
+	// work; done is closed once every request is queued
+	slots := make(chan struct{}, depth)
+	wake := make(chan struct{}, cfg.Concurrency)
+	done := make(chan struct{})
+
+	// take returns the next job for worker i, stealing if it must
+	take := func(i int, rng *rand.Rand) (time.Time, bool) {
+		if s.Shared {
+			return queues[i].steal()
+		}
+		if job, ok := queues[i].pop(); ok {
+			return job, true
+		}
+		offset := rng.Intn(len(queues))
+		for k := range queues {
+			victim := (offset + k) % len(queues)
+			if victim == i {
+				continue
+			}
+			if job, ok := queues[victim].steal(); ok {
+				steals.Add(1)
+				return job, true
+			}
+		}
+		return time.Time{}, false
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < cfg.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx := shared.WithWorkerID(ctx, i)
+			rng := shared.WorkerRand(cfg.Seed, i)
+			for {
+				// check for the end before looking for work, so a job
+				// queued just before it is still found
+				var finished bool
+				select {
+				case <-done:
+					finished = true
+				default:
+				}
+				enqueued, ok := take(i, rng)
+				if !ok {
+					if finished {
+						return
+					}
+					select {
+					case <-wake:
+					case <-done:
+					}
+					continue
+				}
+				<-slots
+				resp, _ := target.Do(ctx)
+				// an open-loop target's schedule is when the request
+				// was meant to start, earlier than it was queued
+				if resp.Intended.IsZero() {
+					resp.Intended = enqueued
+				}
+				collector.Record(resp)
+			}
+		}()
+	}
+
+	// deal requests out to the queues in turn
+	next := 0
+	for range runner.Requests(ctx, cfg) {
+		slots <- struct{}{}
+		queues[next].push(time.Now())
+		next = (next + 1) % len(queues)
+		select {
+		case wake <- struct{}{}:
+		default:
+		}
+	}
+	close(done)
+	wg.Wait()
+
+	shared.Logger().Info("queue contention", "contended", stats.contended.Load(),
+		"waited", time.Duration(stats.waited.Load()), "steals", steals.Load())
+	return ctx.Err()
+}
@@ -0,0 +1,85 @@
+// Package demo is the pubsub demo, a fast subscriber and slow ones under
+// each policy, written against pubsub.Client so that it runs the same
+// against the in-memory broker and a network one.
+package demo
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/aawadall/go-concurrency-patterns/pubsub"
+)
+
+const (
+	messages    = 50
+	bufferSize  = 5
+	publishRate = time.Millisecond
+	slowPace    = 5 * time.Millisecond
+)
+
+type subscriber struct {
+	name   string
+	pace   time.Duration
+	policy pubsub.Policy
+}
+
+// Run runs the demo, with a new client from newClient for each part.
+func Run(newClient func() (pubsub.Client[int], error)) {
+	log.Println("Slow subscribers that drop or disconnect:")
+	run(newClient, []subscriber{
+		{"fast", 0, pubsub.Block},
+		{"slow-drop", slowPace, pubsub.Drop},
+		{"slow-disconnect", slowPace, pubsub.Disconnect},
+	})
+
+	// one blocking subscriber holds everyone back
+	log.Println("Adding a slow subscriber that blocks:")
+	run(newClient, []subscriber{
+		{"fast", 0, pubsub.Block},
+		{"slow-drop", slowPace, pubsub.Drop},
+		{"slow-block", slowPace, pubsub.Block},
+	})
+}
+
+func run(newClient func() (pubsub.Client[int], error), subscribers []subscriber) {
+	broker, err := newClient()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	received := make([]int, len(subscribers))
+	subs := make([]pubsub.Sub[int], len(subscribers))
+	for i, sub := range subscribers {
+		s, err := broker.Subscribe("ticks", bufferSize, sub.policy)
+		if err != nil {
+			log.Fatal(err)
+		}
+		subs[i] = s
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range s.C() {
+				received[i]++
+				time.Sleep(sub.pace)
+			}
+		}()
+	}
+
+	start := time.Now()
+	for i := range messages {
+		if _, err := broker.Publish(context.Background(), "ticks", i); err != nil {
+			log.Fatal(err)
+		}
+		time.Sleep(publishRate)
+	}
+	log.Printf("  published %d messages in %v", messages, time.Since(start).Round(time.Millisecond))
+	broker.Close()
+	wg.Wait()
+
+	for i, sub := range subscribers {
+		log.Printf("  %-16s policy %-10s received %3d, dropped %3d", sub.name, sub.policy, received[i], subs[i].Dropped())
+	}
+}
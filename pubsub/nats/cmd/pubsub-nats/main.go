@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/aawadall/go-concurrency-patterns/pubsub"
+	"github.com/aawadall/go-concurrency-patterns/pubsub/demo"
+	natspubsub "github.com/aawadall/go-concurrency-patterns/pubsub/nats"
+)
+
+// run the pubsub demo against a NATS server: slow subscribers fall behind
+// in the client rather than holding up the publisher
+func main() {
+	url := flag.String("url", nats.DefaultURL, "NATS server URL")
+	useJetStream := flag.Bool("jetstream", false, "publish through a JetStream stream, waiting for each message to be stored")
+	flag.Parse()
+
+	conn, err := nats.Connect(*url)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer conn.Close()
+
+	if *useJetStream {
+		js, err := jetstream.New(conn)
+		if err != nil {
+			log.Fatal(err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_, err = js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+			Name:     "ticks",
+			Subjects: []string{"ticks"},
+			Storage:  jetstream.MemoryStorage,
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	demo.Run(func() (pubsub.Client[int], error) {
+		return natspubsub.NewBroker[int](conn, natspubsub.Options{JetStream: *useJetStream})
+	})
+}
@@ -0,0 +1,18 @@
+module github.com/aawadall/go-concurrency-patterns/pubsub/nats
+
+go 1.24.0
+
+require (
+	github.com/aawadall/go-concurrency-patterns v0.0.0
+	github.com/nats-io/nats.go v1.48.0
+)
+
+require (
+	github.com/klauspost/compress v1.18.0 // indirect
+	github.com/nats-io/nkeys v0.4.11 // indirect
+	github.com/nats-io/nuid v1.0.1 // indirect
+	golang.org/x/crypto v0.39.0 // indirect
+	golang.org/x/sys v0.33.0 // indirect
+)
+
+replace github.com/aawadall/go-concurrency-patterns => ../..
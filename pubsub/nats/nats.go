@@ -0,0 +1,227 @@
+// Package nats is a pubsub.Client backed by a NATS server, so that what
+// the in-memory broker does with channels can be compared to a network
+// broker: topics are subjects, messages are JSON, and each subscription
+// feeds its channel from the NATS client's delivery goroutine.
+package nats
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/aawadall/go-concurrency-patterns/pubsub"
+)
+
+// Broker publishes and subscribes through a NATS connection. It is safe
+// for concurrent use.
+type Broker[T any] struct {
+	conn *nats.Conn
+	js   jetstream.JetStream
+
+	mu     sync.Mutex
+	subs   map[*Subscription[T]]struct{}
+	closed bool
+}
+
+// Options configure a Broker.
+type Options struct {
+	// JetStream publishes through JetStream, so Publish returns once a
+	// stream covering the subject has stored the message, and fails if no
+	// stream does. Subscriptions are unchanged: streams deliver to plain
+	// subscribers too.
+	JetStream bool
+}
+
+// NewBroker returns a Broker using conn, which it does not close.
+func NewBroker[T any](conn *nats.Conn, opts Options) (*Broker[T], error) {
+	b := &Broker[T]{conn: conn, subs: make(map[*Subscription[T]]struct{})}
+	if opts.JetStream {
+		js, err := jetstream.New(conn)
+		if err != nil {
+			return nil, err
+		}
+		b.js = js
+	}
+	return b, nil
+}
+
+var _ pubsub.Client[int] = (*Broker[int])(nil)
+
+// Publish sends msg to topic. The server does not say who got it, so
+// the count is always 0; with Options.JetStream, it is 1 once stored.
+func (b *Broker[T]) Publish(ctx context.Context, topic string, msg T) (int, error) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return 0, err
+	}
+	if b.js != nil {
+		if _, err := b.js.Publish(ctx, topic, data); err != nil {
+			return 0, err
+		}
+		return 1, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return 0, b.conn.Publish(topic, data)
+}
+
+// Subscribe receives the messages published to topic into a channel of
+// buffer messages. What happens when it is full depends on policy:
+//   - Drop discards the message, as the in-memory broker does.
+//   - Block holds up the delivery goroutine. Unlike the in-memory broker,
+//     the publisher never waits: messages queue up in the client, and past
+//     its pending limits NATS drops them and reports a slow consumer.
+//   - Disconnect ends the subscription.
+func (b *Broker[T]) Subscribe(topic string, buffer int, policy pubsub.Policy) (pubsub.Sub[T], error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil, pubsub.ErrClosed
+	}
+
+	s := &Subscription[T]{
+		broker: b,
+		policy: policy,
+		ch:     make(chan T, buffer),
+		done:   make(chan struct{}),
+	}
+	sub, err := b.conn.Subscribe(topic, s.deliver)
+	if err != nil {
+		return nil, err
+	}
+	s.sub = sub
+	b.subs[s] = struct{}{}
+	return s, nil
+}
+
+// Close drains every subscription, delivering what the client already
+// received, and closes their channels.
+func (b *Broker[T]) Close() {
+	b.mu.Lock()
+	b.closed = true
+	subs := b.subs
+	b.subs = nil
+	b.mu.Unlock()
+
+	// make sure the server has seen our publishes before draining
+	_ = b.conn.Flush()
+	for s := range subs {
+		s.end(true)
+	}
+}
+
+// Subscription is a subscription of a Broker.
+type Subscription[T any] struct {
+	broker *Broker[T]
+	sub    *nats.Subscription
+	policy pubsub.Policy
+
+	// mu keeps ch open while deliver sends on it; done unblocks a send
+	// held up by the Block policy so that end can take mu and close ch
+	mu    sync.RWMutex
+	ch    chan T
+	done  chan struct{}
+	ended bool
+	once  sync.Once
+
+	dropped  atomic.Int64
+	decoding atomic.Int64
+}
+
+// C returns the channel messages are delivered on. It is closed when the
+// subscription ends.
+func (s *Subscription[T]) C() <-chan T {
+	return s.ch
+}
+
+// Dropped returns how many messages were discarded, by the Drop policy
+// or by the NATS client for a slow consumer.
+func (s *Subscription[T]) Dropped() int64 {
+	// the client forgets its count once the subscription is closed, so
+	// end adds it to ours first
+	if dropped, err := s.sub.Dropped(); err == nil {
+		return s.dropped.Load() + int64(dropped)
+	}
+	return s.dropped.Load()
+}
+
+// Errors returns how many messages could not be decoded.
+func (s *Subscription[T]) Errors() int64 {
+	return s.decoding.Load()
+}
+
+// Unsubscribe ends the subscription, discarding what was not delivered.
+func (s *Subscription[T]) Unsubscribe() {
+	s.broker.mu.Lock()
+	delete(s.broker.subs, s)
+	s.broker.mu.Unlock()
+	s.end(false)
+}
+
+// deliver runs on the subscription's delivery goroutine, one message at
+// a time.
+func (s *Subscription[T]) deliver(m *nats.Msg) {
+	var v T
+	if err := json.Unmarshal(m.Data, &v); err != nil {
+		s.decoding.Add(1)
+		return
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.ended {
+		return
+	}
+	switch s.policy {
+	case pubsub.Block:
+		select {
+		case s.ch <- v:
+		case <-s.done:
+		}
+	case pubsub.Drop:
+		select {
+		case s.ch <- v:
+		default:
+			s.dropped.Add(1)
+		}
+	case pubsub.Disconnect:
+		select {
+		case s.ch <- v:
+		default:
+			// Unsubscribe waits for deliver to return, so not from here
+			go s.Unsubscribe()
+		}
+	}
+}
+
+// end stops deliveries and closes the channel, after delivering the
+// messages the client already has when drain is set.
+func (s *Subscription[T]) end(drain bool) {
+	s.once.Do(func() {
+		// a draining subscription gets no more messages, so drops no more
+		if dropped, err := s.sub.Dropped(); err == nil {
+			s.dropped.Add(int64(dropped))
+		}
+		if drain {
+			// the subscription closes once the callback has had them all
+			closed := s.sub.StatusChanged(nats.SubscriptionClosed)
+			if err := s.sub.Drain(); err == nil {
+				for s.sub.IsValid() {
+					<-closed
+				}
+			}
+		}
+		_ = s.sub.Unsubscribe()
+
+		close(s.done)
+		s.mu.Lock()
+		s.ended = true
+		close(s.ch)
+		s.mu.Unlock()
+	})
+}
@@ -0,0 +1,232 @@
+// Package pubsub is an in-memory publish-subscribe broker. Publishers
+// send messages to a topic and every subscriber to the topic gets its own
+// copy through a buffered queue; what happens when a subscriber falls
+// behind and its queue fills up is chosen per subscriber.
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrClosed is returned when using a broker after Close.
+var ErrClosed = errors.New("pubsub: broker closed")
+
+// Policy says what Publish does when a subscriber's queue is full.
+type Policy int
+
+const (
+	// Block waits for room in the queue, slowing the publisher down to
+	// the pace of the slowest subscriber.
+	Block Policy = iota
+	// Drop skips the message for that subscriber and counts it.
+	Drop
+	// Disconnect unsubscribes the subscriber, closing its channel.
+	Disconnect
+)
+
+func (p Policy) String() string {
+	switch p {
+	case Block:
+		return "block"
+	case Drop:
+		return "drop"
+	case Disconnect:
+		return "disconnect"
+	default:
+		return "unknown"
+	}
+}
+
+// Client is a publish-subscribe broker as its users see it. Broker is
+// one, through its Client method; others are backed by a network broker,
+// like the NATS one in pubsub/nats.
+type Client[T any] interface {
+	// Publish sends msg to the subscribers of topic and returns how many
+	// got it, if the broker can tell.
+	Publish(ctx context.Context, topic string, msg T) (int, error)
+	// Subscribe starts receiving the messages published to topic,
+	// through a queue of buffer messages managed according to policy.
+	Subscribe(topic string, buffer int, policy Policy) (Sub[T], error)
+	// Close ends every subscription.
+	Close()
+}
+
+// Sub is a subscription of a Client.
+type Sub[T any] interface {
+	C() <-chan T
+	Dropped() int64
+	Unsubscribe()
+}
+
+// Broker routes messages of type T from publishers to subscribers. It is
+// safe for concurrent use.
+type Broker[T any] struct {
+	mu     sync.RWMutex
+	topics map[string]map[*Subscription[T]]struct{}
+	closed bool
+}
+
+func NewBroker[T any]() *Broker[T] {
+	return &Broker[T]{topics: make(map[string]map[*Subscription[T]]struct{})}
+}
+
+// Client returns b as a Client.
+func (b *Broker[T]) Client() Client[T] {
+	return client[T]{b}
+}
+
+// client is a Broker as a Client.
+type client[T any] struct {
+	*Broker[T]
+}
+
+func (c client[T]) Subscribe(topic string, buffer int, policy Policy) (Sub[T], error) {
+	s, err := c.Broker.Subscribe(topic, buffer, policy)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Subscription receives the messages published to one topic.
+type Subscription[T any] struct {
+	Topic  string
+	Policy Policy
+
+	broker *Broker[T]
+	ch     chan T
+	// done is closed on unsubscribing, releasing blocked publishers;
+	// ch is closed once none is sending any more.
+	done    chan struct{}
+	sending sync.WaitGroup
+	once    sync.Once
+	dropped atomic.Int64
+}
+
+// Subscribe starts receiving the messages published to topic, through a
+// queue of buffer messages managed according to policy.
+func (b *Broker[T]) Subscribe(topic string, buffer int, policy Policy) (*Subscription[T], error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil, ErrClosed
+	}
+	s := &Subscription[T]{
+		Topic:  topic,
+		Policy: policy,
+		broker: b,
+		ch:     make(chan T, buffer),
+		done:   make(chan struct{}),
+	}
+	if b.topics[topic] == nil {
+		b.topics[topic] = make(map[*Subscription[T]]struct{})
+	}
+	b.topics[topic][s] = struct{}{}
+	return s, nil
+}
+
+// C returns the channel messages arrive on. It is closed when the
+// subscription ends.
+func (s *Subscription[T]) C() <-chan T {
+	return s.ch
+}
+
+// Dropped returns the number of messages skipped because the queue was
+// full, under the Drop policy.
+func (s *Subscription[T]) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+// Unsubscribe stops the subscription and closes its channel. Messages
+// already queued can still be received.
+func (s *Subscription[T]) Unsubscribe() {
+	s.once.Do(func() {
+		b := s.broker
+		b.mu.Lock()
+		delete(b.topics[s.Topic], s)
+		if len(b.topics[s.Topic]) == 0 {
+			delete(b.topics, s.Topic)
+		}
+		b.mu.Unlock()
+
+		close(s.done)
+		s.sending.Wait()
+		close(s.ch)
+	})
+}
+
+// Publish sends msg to every subscriber of topic and returns how many
+// got it. Under the Block policy it waits for room in their queues, or
+// until ctx is done.
+func (b *Broker[T]) Publish(ctx context.Context, topic string, msg T) (int, error) {
+	b.mu.RLock()
+	if b.closed {
+		b.mu.RUnlock()
+		return 0, ErrClosed
+	}
+	subs := make([]*Subscription[T], 0, len(b.topics[topic]))
+	for s := range b.topics[topic] {
+		s.sending.Add(1)
+		subs = append(subs, s)
+	}
+	b.mu.RUnlock()
+
+	delivered := 0
+	for _, s := range subs {
+		ok, full := s.deliver(ctx, msg)
+		if ok {
+			delivered++
+		}
+		if full && s.Policy == Disconnect {
+			s.Unsubscribe()
+		}
+	}
+	return delivered, ctx.Err()
+}
+
+// deliver queues msg for the subscriber, reporting whether it was queued
+// and whether the queue was full.
+func (s *Subscription[T]) deliver(ctx context.Context, msg T) (ok, full bool) {
+	defer s.sending.Done()
+	if s.Policy == Block {
+		select {
+		case s.ch <- msg:
+			return true, false
+		case <-s.done:
+		case <-ctx.Done():
+		}
+		return false, false
+	}
+	select {
+	case s.ch <- msg:
+		return true, false
+	case <-s.done:
+		return false, false
+	default:
+		if s.Policy == Drop {
+			s.dropped.Add(1)
+		}
+		return false, true
+	}
+}
+
+// Close ends every subscription; publishing or subscribing afterwards
+// fails with ErrClosed.
+func (b *Broker[T]) Close() {
+	b.mu.Lock()
+	b.closed = true
+	var subs []*Subscription[T]
+	for _, topic := range b.topics {
+		for s := range topic {
+			subs = append(subs, s)
+		}
+	}
+	b.mu.Unlock()
+
+	for _, s := range subs {
+		s.Unsubscribe()
+	}
+}
@@ -0,0 +1,208 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrLeaseExpired is the error of Ack and Extend on a lease that has run
+// out, after which its value may have gone to another caller.
+var ErrLeaseExpired = errors.New("queue: lease expired")
+
+// LeaseStats is what a Leased queue has seen so far.
+type LeaseStats struct {
+	Puts      int64
+	Gets      int64
+	Acks      int64
+	Expired   int64 // leases that ran out before their value was acked
+	LateAcks  int64 // acks that came after their lease ran out
+	MaxLeased int
+}
+
+// Lease is a value taken from a Leased queue, until it is acked or the
+// lease runs out.
+type Lease[T any] struct {
+	ID    uint64 // the same for every lease of a value
+	Value T
+	// Receives is how many times the value has been leased, this time
+	// included; more than one means it was handed out before and never
+	// acked.
+	Receives int
+	receipt  uint64
+}
+
+// Leased is an unbounded FIFO queue whose values are leased rather than
+// taken, as in SQS: a value Get returns is invisible to other callers
+// for the visibility timeout, and goes back in the queue unless it is
+// acked by then. A value is therefore handed out at least once, and
+// whoever processes it must cope with doing so twice.
+type Leased[T any] struct {
+	visibility time.Duration
+
+	mu      sync.Mutex
+	visible []*leased[T]
+	leased  map[uint64]*leased[T]
+	changed chan struct{} // closed, and replaced, when Get may succeed
+	nextID  uint64
+	receipt uint64
+	closed  bool
+	stats   LeaseStats
+}
+
+// leased is a value in a Leased queue.
+type leased[T any] struct {
+	id       uint64
+	value    T
+	receives int
+	receipt  uint64 // of its current lease
+	timer    *time.Timer
+}
+
+// NewLeased returns a queue whose leases last for visibility.
+func NewLeased[T any](visibility time.Duration) *Leased[T] {
+	return &Leased[T]{
+		visibility: visibility,
+		leased:     make(map[uint64]*leased[T]),
+		changed:    make(chan struct{}),
+	}
+}
+
+// Put adds v to the queue. It fails if the queue is closed.
+func (q *Leased[T]) Put(v T) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return ErrClosed
+	}
+	q.nextID++
+	q.visible = append(q.visible, &leased[T]{id: q.nextID, value: v})
+	q.stats.Puts++
+	q.notify()
+	return nil
+}
+
+// Get leases the oldest visible value, waiting while there is none. It
+// fails with ErrClosed once the queue is closed and every value has been
+// acked, or with ctx's error if ctx is done first.
+func (q *Leased[T]) Get(ctx context.Context) (Lease[T], error) {
+	for {
+		q.mu.Lock()
+		if len(q.visible) > 0 {
+			l := q.lease()
+			q.mu.Unlock()
+			return l, nil
+		}
+		if q.closed && len(q.leased) == 0 {
+			q.mu.Unlock()
+			return Lease[T]{}, ErrClosed
+		}
+		changed := q.changed
+		q.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return Lease[T]{}, ctx.Err()
+		}
+	}
+}
+
+// Ack removes l's value from the queue for good. It fails with
+// ErrLeaseExpired if the lease ran out first, whether or not the value
+// has been leased again since.
+func (q *Leased[T]) Ack(l Lease[T]) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	e, ok := q.leased[l.ID]
+	if !ok || e.receipt != l.receipt {
+		q.stats.LateAcks++
+		return ErrLeaseExpired
+	}
+	e.timer.Stop()
+	delete(q.leased, l.ID)
+	q.stats.Acks++
+	if q.closed && len(q.leased) == 0 {
+		// wake Gets waiting for the last values to come back
+		q.notify()
+	}
+	return nil
+}
+
+// Extend makes l last for d from now, for work that takes longer than
+// the visibility timeout. It fails with ErrLeaseExpired if the lease
+// already ran out.
+func (q *Leased[T]) Extend(l Lease[T], d time.Duration) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	e, ok := q.leased[l.ID]
+	if !ok || e.receipt != l.receipt || !e.timer.Stop() {
+		// a timer that couldn't be stopped is about to expire the lease
+		return ErrLeaseExpired
+	}
+	e.timer.Reset(d)
+	return nil
+}
+
+// Close marks the end of the values: Puts fail from then on, and Gets
+// once every value has been acked.
+func (q *Leased[T]) Close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if !q.closed {
+		q.closed = true
+		q.notify()
+	}
+}
+
+// Len returns the number of values in the queue, leased or not.
+func (q *Leased[T]) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.visible) + len(q.leased)
+}
+
+// Stats returns what the queue has seen so far.
+func (q *Leased[T]) Stats() LeaseStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.stats
+}
+
+// lease leases the oldest visible value. q.mu is held.
+func (q *Leased[T]) lease() Lease[T] {
+	e := q.visible[0]
+	q.visible[0] = nil
+	q.visible = q.visible[1:]
+
+	q.receipt++
+	e.receipt = q.receipt
+	e.receives++
+	receipt := e.receipt
+	e.timer = time.AfterFunc(q.visibility, func() { q.expire(e, receipt) })
+	q.leased[e.id] = e
+	q.stats.Gets++
+	q.stats.MaxLeased = max(q.stats.MaxLeased, len(q.leased))
+	return Lease[T]{ID: e.id, Value: e.value, Receives: e.receives, receipt: receipt}
+}
+
+// expire puts e back in the queue if its lease receipt is still current.
+func (q *Leased[T]) expire(e *leased[T], receipt uint64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if cur, ok := q.leased[e.id]; !ok || cur.receipt != receipt {
+		// acked, or leased again since
+		return
+	}
+	delete(q.leased, e.id)
+	q.visible = append(q.visible, e)
+	q.stats.Expired++
+	q.notify()
+}
+
+// notify wakes every waiting Get. q.mu is held.
+func (q *Leased[T]) notify() {
+	close(q.changed)
+	q.changed = make(chan struct{})
+}
@@ -0,0 +1,191 @@
+// Package queue is a blocking bounded queue: what a buffered channel
+// does, plus timeouts, a put that fails rather than blocks, and metrics
+// on how full the queue ran and how long callers waited on it. Leased is
+// a queue whose values are leased, and handed out again unless acked.
+package queue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrFull is the error of TryPut on a full queue.
+	ErrFull = errors.New("queue: full")
+	// ErrTimeout is the error of PutTimeout when the queue stayed full.
+	ErrTimeout = errors.New("queue: timed out")
+	// ErrClosed is the error of Put on a closed queue, and of Get on a
+	// closed, empty one.
+	ErrClosed = errors.New("queue: closed")
+)
+
+// Stats is what a queue has seen so far.
+type Stats struct {
+	Puts     int64
+	Gets     int64
+	Rejected int64 // puts that failed because the queue was full
+	MaxDepth int
+	PutWait  time.Duration // total time puts spent waiting for room
+	GetWait  time.Duration // total time gets spent waiting for a value
+}
+
+// Bounded is a FIFO queue of at most a fixed number of values. Room in
+// the queue and values in it are each counted by a semaphore channel, so
+// waiting for either can be given up on with a context.
+type Bounded[T any] struct {
+	slots  chan struct{} // a token for each free place
+	items  chan struct{} // a token for each value
+	closed chan struct{}
+	once   sync.Once
+
+	mu    sync.Mutex
+	buf   []T // a ring of len(buf) places
+	head  int // where the oldest value is
+	n     int // how many values there are
+	stats Stats
+}
+
+// NewBounded returns a queue of at most n values.
+func NewBounded[T any](n int) *Bounded[T] {
+	q := &Bounded[T]{
+		slots:  make(chan struct{}, n),
+		items:  make(chan struct{}, n),
+		closed: make(chan struct{}),
+		buf:    make([]T, n),
+	}
+	for range n {
+		q.slots <- struct{}{}
+	}
+	return q
+}
+
+// Put adds v to the queue, waiting while it is full. It fails if ctx is
+// done first or the queue is closed.
+func (q *Bounded[T]) Put(ctx context.Context, v T) error {
+	start := time.Now()
+	select {
+	case <-q.closed:
+		return ErrClosed
+	case <-q.slots:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	q.push(v, time.Since(start))
+	return nil
+}
+
+// PutTimeout is Put giving up with ErrTimeout after d.
+func (q *Bounded[T]) PutTimeout(v T, d time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+	err := q.Put(ctx, v)
+	if errors.Is(err, context.DeadlineExceeded) {
+		q.reject()
+		return ErrTimeout
+	}
+	return err
+}
+
+// TryPut adds v to the queue if there is room, failing with ErrFull if
+// not.
+func (q *Bounded[T]) TryPut(v T) error {
+	select {
+	case <-q.closed:
+		return ErrClosed
+	default:
+	}
+	select {
+	case <-q.slots:
+		q.push(v, 0)
+		return nil
+	default:
+		q.reject()
+		return ErrFull
+	}
+}
+
+// Get takes the oldest value from the queue, waiting while it is empty.
+// It fails with ErrClosed once the queue is closed and drained, or with
+// ctx's error if ctx is done first.
+func (q *Bounded[T]) Get(ctx context.Context) (T, error) {
+	start := time.Now()
+	select {
+	case <-q.items:
+	default:
+		select {
+		case <-q.items:
+		case <-q.closed:
+			// values put before Close are still taken
+			select {
+			case <-q.items:
+			default:
+				var zero T
+				return zero, ErrClosed
+			}
+		case <-ctx.Done():
+			var zero T
+			return zero, ctx.Err()
+		}
+	}
+	return q.pop(time.Since(start)), nil
+}
+
+// Close marks the end of the values, like closing a channel: Puts fail
+// from then on, and Gets once the queue is drained.
+func (q *Bounded[T]) Close() {
+	q.once.Do(func() { close(q.closed) })
+}
+
+// Len returns the number of values in the queue.
+func (q *Bounded[T]) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.n
+}
+
+// Cap returns the number of values the queue holds.
+func (q *Bounded[T]) Cap() int {
+	return cap(q.slots)
+}
+
+// Stats returns what the queue has seen so far.
+func (q *Bounded[T]) Stats() Stats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.stats
+}
+
+// push adds v to a place already reserved for it.
+func (q *Bounded[T]) push(v T, waited time.Duration) {
+	q.mu.Lock()
+	q.buf[(q.head+q.n)%len(q.buf)] = v
+	q.n++
+	q.stats.Puts++
+	q.stats.PutWait += waited
+	q.stats.MaxDepth = max(q.stats.MaxDepth, q.n)
+	q.mu.Unlock()
+	q.items <- struct{}{}
+}
+
+// pop takes the oldest value, one already reserved, and frees its place.
+func (q *Bounded[T]) pop(waited time.Duration) T {
+	var zero T
+	q.mu.Lock()
+	v := q.buf[q.head]
+	q.buf[q.head] = zero
+	q.head = (q.head + 1) % len(q.buf)
+	q.n--
+	q.stats.Gets++
+	q.stats.GetWait += waited
+	q.mu.Unlock()
+	q.slots <- struct{}{}
+	return v
+}
+
+func (q *Bounded[T]) reject() {
+	q.mu.Lock()
+	q.stats.Rejected++
+	q.mu.Unlock()
+}
@@ -0,0 +1,7 @@
+//go:build !race
+
+package races
+
+// Detector tells whether this binary was built with -race, so that Check
+// gets the race detector's reports. It wasn't.
+const Detector = false
@@ -0,0 +1,153 @@
+package races
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// childEnv is set, to the name of a race, in the environment of the
+// child processes Check starts, which makes them run the race instead of
+// their main.
+const childEnv = "GCP_RACE"
+
+// raceExit is the status the race detector exits with when it has
+// reported a race.
+const raceExit = 66
+
+// Stress is how hard Check pushes a race.
+type Stress struct {
+	Goroutines int
+	Ops        int // per goroutine and round
+	Rounds     int
+	// Yield has the operations yield in the middle of the race.
+	Yield bool
+}
+
+// Outcome is what a race did under stress.
+type Outcome struct {
+	Race   string
+	Fixed  bool
+	Rounds int // run, including one that crashed
+	// Corrupted is how many rounds lost or got an operation wrong, and
+	// Lost how many operations they did so with, out of Ops.
+	Corrupted int
+	Lost      int
+	Ops       int
+	// Crash is the fatal error that ended the child early, if any.
+	Crash string
+	// Reports is how many data races the race detector reported, in a
+	// binary built with -race.
+	Reports int
+}
+
+// OK tells whether nothing went wrong.
+func (o Outcome) OK() bool {
+	return o.Corrupted == 0 && o.Crash == "" && o.Reports == 0
+}
+
+// Any binary importing this package can be a child: an init function
+// takes over when childEnv is set.
+func init() {
+	name := os.Getenv(childEnv)
+	if name == "" {
+		return
+	}
+	if err := serveChild(name); err != nil {
+		fmt.Fprintln(os.Stderr, "races:", err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+// serveChild runs the rounds of the race it's named, with the stress it
+// is sent on stdin, and writes what each lost on a line of stdout, so
+// that the parent keeps the rounds before a crash.
+func serveChild(name string) error {
+	r, ok := Lookup(name)
+	if !ok {
+		return fmt.Errorf("unknown race %q", name)
+	}
+	var s Stress
+	if err := json.NewDecoder(os.Stdin).Decode(&s); err != nil {
+		return err
+	}
+	for range s.Rounds {
+		if _, err := fmt.Println(r.Run(s.Goroutines, s.Ops, s.Yield)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Check runs race for s.Rounds rounds in a child process, a copy of the
+// running binary, and reports what went wrong.
+func Check(ctx context.Context, race Race, s Stress) (Outcome, error) {
+	o := Outcome{Race: race.Name(), Fixed: race.Fixed()}
+	exe, err := os.Executable()
+	if err != nil {
+		return o, err
+	}
+	stdin, err := json.Marshal(s)
+	if err != nil {
+		return o, err
+	}
+	cmd := exec.CommandContext(ctx, exe)
+	cmd.Env = append(os.Environ(), childEnv+"="+race.Name())
+	cmd.Stdin = bytes.NewReader(stdin)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return o, err
+	}
+	if err := cmd.Start(); err != nil {
+		return o, err
+	}
+	lines := bufio.NewScanner(stdout)
+	for lines.Scan() {
+		lost, err := strconv.Atoi(lines.Text())
+		if err != nil {
+			cmd.Process.Kill()
+			cmd.Wait()
+			return o, fmt.Errorf("race %s: %q from the child", race.Name(), lines.Text())
+		}
+		o.Rounds++
+		o.Ops += s.Goroutines * s.Ops
+		o.Lost += lost
+		if lost > 0 {
+			o.Corrupted++
+		}
+	}
+	err = cmd.Wait()
+
+	o.Reports = strings.Count(stderr.String(), "WARNING: DATA RACE")
+	for line := range strings.Lines(stderr.String()) {
+		if strings.HasPrefix(line, "fatal error: ") || strings.HasPrefix(line, "panic: ") {
+			o.Crash = strings.TrimSpace(line)
+			break
+		}
+	}
+	var exit *exec.ExitError
+	switch {
+	case err == nil:
+	case o.Crash != "":
+		// the round that crashed
+		o.Rounds++
+		o.Ops += s.Goroutines * s.Ops
+		o.Corrupted++
+	case errors.As(err, &exit) && exit.ExitCode() == raceExit && o.Reports > 0:
+	case ctx.Err() != nil:
+		return o, ctx.Err()
+	default:
+		return o, fmt.Errorf("race %s: %w: %s", race.Name(), err, strings.TrimSpace(stderr.String()))
+	}
+	return o, nil
+}
@@ -0,0 +1,233 @@
+// Package races is common race conditions, each next to its fix: a
+// counter incremented from several goroutines, a map written from them,
+// a slice appended to from them as the waitgroups pattern once did, and
+// a check-then-act on a balance. A race's Run counts the operations it
+// lost or got wrong, and Check runs it for rounds under stress in a
+// child process, since a racy map can take the whole process down, and
+// reports what it broke and what the race detector made of it.
+package races
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// Race is a race condition, or its fix.
+type Race interface {
+	Name() string
+	// Fixed tells whether this is the fix, which should never lose an
+	// operation.
+	Fixed() bool
+	// Run has goroutines goroutines do ops operations each, all at
+	// once, and returns how many operations were lost or went wrong.
+	// With yield set, each operation yields the processor where the race
+	// is, between reading shared state and writing it back, which makes
+	// the race show even on a single CPU.
+	Run(goroutines, ops int, yield bool) int
+}
+
+// All lists every race and its fix.
+var All = []Race{
+	Counter{},
+	Counter{Atomic: true},
+	Map{},
+	Map{Mutex: true},
+	Append{},
+	Append{Indexed: true},
+	CheckThenAct{},
+	CheckThenAct{CAS: true},
+}
+
+// Lookup returns the race with the given name.
+func Lookup(name string) (Race, bool) {
+	for _, r := range All {
+		if r.Name() == name {
+			return r, true
+		}
+	}
+	return nil, false
+}
+
+// together runs f on goroutines goroutines, started together so that
+// they overlap as much as they can, and waits for them.
+func together(goroutines int, f func(g int)) {
+	gate := make(chan struct{})
+	var wg sync.WaitGroup
+	for g := range goroutines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-gate
+			f(g)
+		}()
+	}
+	close(gate)
+	wg.Wait()
+}
+
+// pause yields the processor if yield is set.
+func pause(yield bool) {
+	if yield {
+		runtime.Gosched()
+	}
+}
+
+// Counter increments a shared int, which loses increments when two
+// goroutines read the same value before either writes it back; with
+// Atomic set, it's an atomic.Int64.
+type Counter struct {
+	Atomic bool
+}
+
+func (c Counter) Name() string {
+	if c.Atomic {
+		return "counter-atomic"
+	}
+	return "counter"
+}
+
+func (c Counter) Fixed() bool { return c.Atomic }
+
+func (c Counter) Run(goroutines, ops int, yield bool) int {
+	var n int
+	var a atomic.Int64
+	together(goroutines, func(int) {
+		for range ops {
+			if c.Atomic {
+				pause(yield)
+				a.Add(1)
+			} else {
+				// n++, spelled out
+				v := n
+				pause(yield)
+				n = v + 1
+			}
+		}
+	})
+	if c.Atomic {
+		n = int(a.Load())
+	}
+	return goroutines*ops - n
+}
+
+// Map writes a key per operation to a shared map, which the runtime
+// catches more often than not and answers with a fatal error, and
+// otherwise loses keys; with Mutex set, the writes hold a sync.Mutex. A
+// map write can't be split to yield in the middle of, so this one needs
+// more than one CPU, or luck.
+type Map struct {
+	Mutex bool
+}
+
+func (m Map) Name() string {
+	if m.Mutex {
+		return "map-writes-mutex"
+	}
+	return "map-writes"
+}
+
+func (m Map) Fixed() bool { return m.Mutex }
+
+func (m Map) Run(goroutines, ops int, _ bool) int {
+	keys := make(map[int]bool)
+	var mu sync.Mutex
+	together(goroutines, func(g int) {
+		for i := range ops {
+			if m.Mutex {
+				mu.Lock()
+			}
+			keys[g*ops+i] = true
+			if m.Mutex {
+				mu.Unlock()
+			}
+		}
+	})
+	return goroutines*ops - len(keys)
+}
+
+// Append appends a value per operation to a shared slice, the way the
+// waitgroups pattern once collected its results, which loses values when
+// two goroutines append to the same length, or to a backing array one of
+// them is about to replace; with Indexed set, each operation writes its
+// own element of a slice made long enough up front.
+type Append struct {
+	Indexed bool
+}
+
+func (a Append) Name() string {
+	if a.Indexed {
+		return "append-indexed"
+	}
+	return "append"
+}
+
+func (a Append) Fixed() bool { return a.Indexed }
+
+func (a Append) Run(goroutines, ops int, yield bool) int {
+	var values []int
+	if a.Indexed {
+		values = make([]int, goroutines*ops)
+	}
+	together(goroutines, func(g int) {
+		for i := range ops {
+			if a.Indexed {
+				pause(yield)
+				values[g*ops+i] = 1
+			} else {
+				v := values
+				pause(yield)
+				values = append(v, 1)
+			}
+		}
+	})
+	n := 0
+	for _, v := range values {
+		n += v
+	}
+	return goroutines*ops - n
+}
+
+// CheckThenAct withdraws from a balance worth half the operations,
+// checking that there is enough before taking it, so that two goroutines
+// can both see the last of it and both take it: what it gets wrong is
+// the withdrawals past zero. Every access is atomic, so the race
+// detector has nothing to say; the race is in the logic. With CAS set,
+// the withdrawal is a compare-and-swap of the balance it checked, which
+// fails and checks again if another got there first.
+type CheckThenAct struct {
+	CAS bool
+}
+
+func (c CheckThenAct) Name() string {
+	if c.CAS {
+		return "check-then-act-cas"
+	}
+	return "check-then-act"
+}
+
+func (c CheckThenAct) Fixed() bool { return c.CAS }
+
+func (c CheckThenAct) Run(goroutines, ops int, yield bool) int {
+	var balance atomic.Int64
+	balance.Store(int64(goroutines * ops / 2))
+	together(goroutines, func(int) {
+		for range ops {
+			for {
+				b := balance.Load()
+				if b < 1 {
+					break
+				}
+				pause(yield)
+				if !c.CAS {
+					balance.Add(-1)
+					break
+				}
+				if balance.CompareAndSwap(b, b-1) {
+					break
+				}
+			}
+		}
+	})
+	return int(max(-balance.Load(), 0))
+}
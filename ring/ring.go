@@ -0,0 +1,268 @@
+// Package ring is bounded ring buffers that pass values between
+// goroutines without locks or channels: SPSC for one producer and one
+// consumer, and MPMC for any number of producers and consumers. Both can
+// be used like a buffered channel through Push, Pop and Close, or without
+// blocking through TryPush and TryPop.
+package ring
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+// ErrClosed is the error of Pop on a closed, empty buffer and of Push on
+// a closed one.
+var ErrClosed = errors.New("ring: closed")
+
+// pad keeps the producer's and the consumer's indexes on separate cache
+// lines, so they don't slow each other down.
+type pad [64]byte
+
+// size is n rounded up to a power of two, so an index is masked rather
+// than divided into a slot.
+func size(n int) uint64 {
+	if n < 1 {
+		n = 1
+	}
+	s := uint64(1)
+	for s < uint64(n) {
+		s <<= 1
+	}
+	return s
+}
+
+// wait backs off a caller that found the buffer full or empty for the
+// n-th time in a row: it yields at first, then sleeps.
+func wait(ctx context.Context, n int) error {
+	if n < 64 {
+		runtime.Gosched()
+	} else {
+		time.Sleep(50 * time.Microsecond)
+	}
+	return ctx.Err()
+}
+
+// push and pop are the blocking Push and Pop of either buffer.
+func push[T any](ctx context.Context, closed *atomic.Bool, try func(T) bool, v T) error {
+	for n := 0; ; n++ {
+		if closed.Load() {
+			return ErrClosed
+		}
+		if try(v) {
+			return nil
+		}
+		if err := wait(ctx, n); err != nil {
+			return err
+		}
+	}
+}
+
+func pop[T any](ctx context.Context, closed *atomic.Bool, try func() (T, bool)) (T, error) {
+	for n := 0; ; n++ {
+		// check closed before trying, so a value pushed before Close is
+		// never missed
+		done := closed.Load()
+		if v, ok := try(); ok {
+			return v, nil
+		}
+		var zero T
+		if done {
+			return zero, ErrClosed
+		}
+		if err := wait(ctx, n); err != nil {
+			return zero, err
+		}
+	}
+}
+
+// SPSC is a ring buffer for a single producer and a single consumer.
+// Each side only writes its own index, so neither needs more than an
+// atomic load and store.
+type SPSC[T any] struct {
+	buf    []T
+	mask   uint64
+	_      pad
+	head   atomic.Uint64 // next slot to pop, written by the consumer
+	_      pad
+	tail   atomic.Uint64 // next slot to push, written by the producer
+	_      pad
+	closed atomic.Bool
+}
+
+// NewSPSC returns an SPSC buffer holding at least n values.
+func NewSPSC[T any](n int) *SPSC[T] {
+	s := size(n)
+	return &SPSC[T]{buf: make([]T, s), mask: s - 1}
+}
+
+// TryPush adds v to the buffer, reporting false if it is full.
+func (r *SPSC[T]) TryPush(v T) bool {
+	tail := r.tail.Load()
+	if tail-r.head.Load() == uint64(len(r.buf)) {
+		return false
+	}
+	r.buf[tail&r.mask] = v
+	r.tail.Store(tail + 1)
+	return true
+}
+
+// TryPop takes the oldest value from the buffer, reporting false if it
+// is empty.
+func (r *SPSC[T]) TryPop() (T, bool) {
+	var zero T
+	head := r.head.Load()
+	if head == r.tail.Load() {
+		return zero, false
+	}
+	v := r.buf[head&r.mask]
+	r.buf[head&r.mask] = zero
+	r.head.Store(head + 1)
+	return v, true
+}
+
+// Push adds v to the buffer, waiting while it is full. It fails if ctx is
+// done first or the buffer is closed.
+func (r *SPSC[T]) Push(ctx context.Context, v T) error {
+	return push(ctx, &r.closed, r.TryPush, v)
+}
+
+// Pop takes the oldest value from the buffer, waiting while it is empty.
+// It fails with ErrClosed once the buffer is closed and drained, or with
+// ctx's error if ctx is done first.
+func (r *SPSC[T]) Pop(ctx context.Context) (T, error) {
+	return pop(ctx, &r.closed, r.TryPop)
+}
+
+// Close marks the end of the values, like closing a channel; the
+// producer must not push after it.
+func (r *SPSC[T]) Close() {
+	r.closed.Store(true)
+}
+
+// Len returns the number of values in the buffer.
+func (r *SPSC[T]) Len() int {
+	head := r.head.Load()
+	return int(r.tail.Load() - head)
+}
+
+// Cap returns the number of values the buffer holds.
+func (r *SPSC[T]) Cap() int {
+	return len(r.buf)
+}
+
+// slot is a value of an MPMC buffer and its sequence number, which says
+// whose turn it is: the producer of position seq, or the consumer of
+// position seq-1.
+type slot[T any] struct {
+	seq atomic.Uint64
+	val T
+}
+
+// MPMC is a ring buffer for any number of producers and consumers, which
+// may push and pop at once. Each side claims a slot with a
+// compare-and-swap on its index and hands it to the other through the
+// slot's sequence number.
+type MPMC[T any] struct {
+	slots  []slot[T]
+	mask   uint64
+	_      pad
+	head   atomic.Uint64 // next position to pop
+	_      pad
+	tail   atomic.Uint64 // next position to push
+	_      pad
+	closed atomic.Bool
+}
+
+// NewMPMC returns an MPMC buffer holding at least n values.
+func NewMPMC[T any](n int) *MPMC[T] {
+	s := size(n)
+	r := &MPMC[T]{slots: make([]slot[T], s), mask: s - 1}
+	for i := range r.slots {
+		r.slots[i].seq.Store(uint64(i))
+	}
+	return r
+}
+
+// TryPush adds v to the buffer, reporting false if it is full.
+func (r *MPMC[T]) TryPush(v T) bool {
+	pos := r.tail.Load()
+	for {
+		s := &r.slots[pos&r.mask]
+		switch seq := s.seq.Load(); {
+		case seq == pos:
+			if r.tail.CompareAndSwap(pos, pos+1) {
+				s.val = v
+				s.seq.Store(pos + 1)
+				return true
+			}
+			pos = r.tail.Load()
+		case seq < pos:
+			// the slot still holds the value pushed a lap ago
+			return false
+		default:
+			// another producer took the slot
+			pos = r.tail.Load()
+		}
+	}
+}
+
+// TryPop takes the oldest value from the buffer, reporting false if it
+// is empty.
+func (r *MPMC[T]) TryPop() (T, bool) {
+	var zero T
+	pos := r.head.Load()
+	for {
+		s := &r.slots[pos&r.mask]
+		switch seq := s.seq.Load(); {
+		case seq == pos+1:
+			if r.head.CompareAndSwap(pos, pos+1) {
+				v := s.val
+				s.val = zero
+				s.seq.Store(pos + r.mask + 1)
+				return v, true
+			}
+			pos = r.head.Load()
+		case seq < pos+1:
+			// nothing has been pushed to the slot yet
+			return zero, false
+		default:
+			// another consumer took the slot
+			pos = r.head.Load()
+		}
+	}
+}
+
+// Push adds v to the buffer, waiting while it is full. It fails if ctx is
+// done first or the buffer is closed.
+func (r *MPMC[T]) Push(ctx context.Context, v T) error {
+	return push(ctx, &r.closed, r.TryPush, v)
+}
+
+// Pop takes the oldest value from the buffer, waiting while it is empty.
+// It fails with ErrClosed once the buffer is closed and drained, or with
+// ctx's error if ctx is done first.
+func (r *MPMC[T]) Pop(ctx context.Context) (T, error) {
+	return pop(ctx, &r.closed, r.TryPop)
+}
+
+// Close marks the end of the values, like closing a channel; it must be
+// called once every producer is done pushing.
+func (r *MPMC[T]) Close() {
+	r.closed.Store(true)
+}
+
+// Len returns the number of values in the buffer, which may be out of
+// date by the time it returns.
+func (r *MPMC[T]) Len() int {
+	// load head first: tail only grows, so it can't be behind it
+	head := r.head.Load()
+	return int(r.tail.Load() - head)
+}
+
+// Cap returns the number of values the buffer holds.
+func (r *MPMC[T]) Cap() int {
+	return len(r.slots)
+}
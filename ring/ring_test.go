@@ -0,0 +1,364 @@
+package ring_test
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aawadall/go-concurrency-patterns/cmd/pipelines/pipeline"
+	"github.com/aawadall/go-concurrency-patterns/ring"
+)
+
+// the ring buffers against buffered channels of the same capacity: one
+// producer and one consumer, several producers and one consumer, and a
+// two-stage pipeline connected by each
+const (
+	capacity  = 1024
+	producers = 4
+	workers   = 2
+)
+
+// buffer is what the benchmarks need of a ring buffer, or a channel.
+type buffer[T any] interface {
+	Push(ctx context.Context, v T) error
+	Pop(ctx context.Context) (T, error)
+	Close()
+}
+
+// ringBuffer is what both ring buffers offer.
+type ringBuffer[T any] interface {
+	buffer[T]
+	TryPush(v T) bool
+	TryPop() (T, bool)
+	Len() int
+	Cap() int
+}
+
+// rings makes each kind of ring buffer holding at least n ints.
+var rings = []struct {
+	name string
+	make func(n int) ringBuffer[int]
+}{
+	{"SPSC", func(n int) ringBuffer[int] { return ring.NewSPSC[int](n) }},
+	{"MPMC", func(n int) ringBuffer[int] { return ring.NewMPMC[int](n) }},
+}
+
+func TestFIFO(t *testing.T) {
+	const n = 10000
+	for _, rb := range rings {
+		t.Run(rb.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			r := rb.make(16)
+			go func() {
+				defer r.Close()
+				for i := range n {
+					if err := r.Push(ctx, i); err != nil {
+						t.Error(err)
+						return
+					}
+				}
+			}()
+			for want := 0; ; want++ {
+				v, err := r.Pop(ctx)
+				if errors.Is(err, ring.ErrClosed) {
+					if want != n {
+						t.Errorf("closed after %d values, want %d", want, n)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				if v != want {
+					t.Fatalf("popped %d, want %d", v, want)
+				}
+			}
+		})
+	}
+}
+
+// every value pushed by any producer is popped by exactly one consumer
+func TestMPMCExactlyOnce(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+	tests := []struct {
+		name                 string
+		producers, consumers int
+	}{
+		{"many producers", 4, 1},
+		{"many consumers", 1, 4},
+		{"many of both", 4, 4},
+	}
+	const perProducer = 5000
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			r := ring.NewMPMC[int](8)
+
+			var producers sync.WaitGroup
+			for p := range tt.producers {
+				producers.Add(1)
+				go func() {
+					defer producers.Done()
+					for i := range perProducer {
+						if err := r.Push(ctx, p*perProducer+i); err != nil {
+							t.Error(err)
+							return
+						}
+					}
+				}()
+			}
+			go func() {
+				producers.Wait()
+				r.Close()
+			}()
+
+			popped := make([][]int, tt.consumers)
+			var consumers sync.WaitGroup
+			for c := range tt.consumers {
+				consumers.Add(1)
+				go func() {
+					defer consumers.Done()
+					for {
+						v, err := r.Pop(ctx)
+						if err != nil {
+							if !errors.Is(err, ring.ErrClosed) {
+								t.Error(err)
+							}
+							return
+						}
+						popped[c] = append(popped[c], v)
+					}
+				}()
+			}
+			consumers.Wait()
+
+			seen := make([]int, tt.producers*perProducer)
+			for _, vals := range popped {
+				for _, v := range vals {
+					seen[v]++
+				}
+			}
+			for v, n := range seen {
+				if n != 1 {
+					t.Fatalf("value %d popped %d times", v, n)
+				}
+			}
+		})
+	}
+}
+
+func TestTryOnFullAndEmpty(t *testing.T) {
+	for _, rb := range rings {
+		t.Run(rb.name, func(t *testing.T) {
+			r := rb.make(3)
+			if r.Cap() != 4 {
+				t.Fatalf("got a capacity of %d, want 3 rounded up to 4", r.Cap())
+			}
+			if _, ok := r.TryPop(); ok {
+				t.Fatal("TryPop on an empty buffer succeeded")
+			}
+			for i := range r.Cap() {
+				if !r.TryPush(i) {
+					t.Fatalf("TryPush %d failed with room left", i)
+				}
+			}
+			if r.TryPush(99) {
+				t.Fatal("TryPush on a full buffer succeeded")
+			}
+			if r.Len() != r.Cap() {
+				t.Errorf("got a length of %d when full, want %d", r.Len(), r.Cap())
+			}
+			// a pop makes room for exactly one more, through the wrap
+			if v, ok := r.TryPop(); !ok || v != 0 {
+				t.Fatalf("TryPop got %d, %v, want 0", v, ok)
+			}
+			if !r.TryPush(4) || r.TryPush(5) {
+				t.Fatal("one pop didn't make room for exactly one push")
+			}
+			for want := 1; want <= 4; want++ {
+				if v, ok := r.TryPop(); !ok || v != want {
+					t.Fatalf("TryPop got %d, %v, want %d", v, ok, want)
+				}
+			}
+			if _, ok := r.TryPop(); ok || r.Len() != 0 {
+				t.Fatal("buffer not empty after popping everything")
+			}
+		})
+	}
+}
+
+func TestClose(t *testing.T) {
+	for _, rb := range rings {
+		t.Run(rb.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			// values pushed before Close are still popped, then Pop fails
+			r := rb.make(4)
+			for i := range 3 {
+				_ = r.Push(ctx, i)
+			}
+			r.Close()
+			if err := r.Push(ctx, 3); !errors.Is(err, ring.ErrClosed) {
+				t.Errorf("Push after Close got %v, want %v", err, ring.ErrClosed)
+			}
+			for want := range 3 {
+				if v, err := r.Pop(ctx); err != nil || v != want {
+					t.Fatalf("Pop got %d, %v, want %d", v, err, want)
+				}
+			}
+			if _, err := r.Pop(ctx); !errors.Is(err, ring.ErrClosed) {
+				t.Errorf("Pop of a drained buffer got %v, want %v", err, ring.ErrClosed)
+			}
+
+			// Close wakes a Pop waiting on an empty buffer
+			r = rb.make(4)
+			done := make(chan error, 1)
+			go func() {
+				_, err := r.Pop(ctx)
+				done <- err
+			}()
+			time.Sleep(10 * time.Millisecond)
+			r.Close()
+			if err := <-done; !errors.Is(err, ring.ErrClosed) {
+				t.Errorf("waiting Pop got %v, want %v", err, ring.ErrClosed)
+			}
+
+			// and a done context stops one
+			r = rb.make(4)
+			canceled, cancelPop := context.WithCancel(ctx)
+			cancelPop()
+			if _, err := r.Pop(canceled); !errors.Is(err, context.Canceled) {
+				t.Errorf("Pop with a canceled context got %v", err)
+			}
+		})
+	}
+}
+
+// chanBuffer is a buffered channel as a buffer.
+type chanBuffer[T any] chan T
+
+func (c chanBuffer[T]) Push(ctx context.Context, v T) error {
+	c <- v
+	return nil
+}
+
+func (c chanBuffer[T]) Pop(ctx context.Context) (T, error) {
+	v, ok := <-c
+	if !ok {
+		return v, ring.ErrClosed
+	}
+	return v, nil
+}
+
+func (c chanBuffer[T]) Close() {
+	close(c)
+}
+
+// mpsc passes n values through buf from the producers to one consumer.
+func mpsc(b *testing.B, buf buffer[int], producers int) {
+	ctx := context.Background()
+	n := b.N
+	var wg sync.WaitGroup
+	for i := range producers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// share the values out, the first producers taking the
+			// remainder
+			for v := range n/producers + min(max(n%producers-i, 0), 1) {
+				_ = buf.Push(ctx, v)
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		buf.Close()
+	}()
+	for {
+		if _, err := buf.Pop(ctx); errors.Is(err, ring.ErrClosed) {
+			return
+		}
+	}
+}
+
+func BenchmarkChanSPSC(b *testing.B) {
+	mpsc(b, chanBuffer[int](make(chan int, capacity)), 1)
+}
+
+func BenchmarkRingSPSC(b *testing.B) {
+	mpsc(b, ring.NewSPSC[int](capacity), 1)
+}
+
+func BenchmarkRingMPMCOneProducer(b *testing.B) {
+	mpsc(b, ring.NewMPMC[int](capacity), 1)
+}
+
+func BenchmarkChanManyProducers(b *testing.B) {
+	mpsc(b, chanBuffer[int](make(chan int, capacity)), producers)
+}
+
+func BenchmarkRingManyProducers(b *testing.B) {
+	mpsc(b, ring.NewMPMC[int](capacity), producers)
+}
+
+// stages returns the two stages of the benchmark pipeline.
+func stages() (pipeline.Stage[int, int], pipeline.Stage[int, int]) {
+	square := pipeline.Stage[int, int]{
+		Name:    "square",
+		Workers: workers,
+		Buffer:  capacity,
+		Function: func(m pipeline.Message[int]) (pipeline.Message[int], error) {
+			return pipeline.Message[int]{ID: m.ID, Payload: m.Payload * m.Payload}, nil
+		},
+	}
+	double := pipeline.Stage[int, int]{
+		Name:    "double",
+		Workers: workers,
+		Buffer:  capacity,
+		Function: func(m pipeline.Message[int]) (pipeline.Message[int], error) {
+			return pipeline.Message[int]{ID: m.ID, Payload: m.Payload * 2}, nil
+		},
+	}
+	return square, double
+}
+
+func BenchmarkChanPipeline(b *testing.B) {
+	ctx := context.Background()
+	source := make(chan pipeline.Message[int], capacity)
+	go func() {
+		defer close(source)
+		for i := range b.N {
+			source <- pipeline.Message[int]{ID: int64(i), Payload: i}
+		}
+	}()
+	square, double := stages()
+	squared, _ := square.Run(ctx, source)
+	doubled, _ := double.Run(ctx, squared)
+	for range doubled {
+	}
+}
+
+func BenchmarkRingPipeline(b *testing.B) {
+	ctx := context.Background()
+	source := ring.NewMPMC[pipeline.Message[int]](capacity)
+	go func() {
+		defer source.Close()
+		for i := range b.N {
+			_ = source.Push(ctx, pipeline.Message[int]{ID: int64(i), Payload: i})
+		}
+	}()
+	square, double := stages()
+	squared, _ := square.RunRing(ctx, source)
+	doubled, _ := double.RunRing(ctx, squared)
+	for {
+		if _, err := doubled.Pop(ctx); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aawadall/go-concurrency-patterns/config"
+	"github.com/aawadall/go-concurrency-patterns/shared"
+)
+
+const (
+	// agentFlush is how often an agent sends the results it has buffered.
+	agentFlush = 100 * time.Millisecond
+	// agentBuffer is how many results an agent holds before the run waits
+	// for the controller to take them.
+	agentBuffer = 10000
+)
+
+// plan is the share of a run a controller gives an agent.
+type plan struct {
+	Pattern string
+	Config  config.Config
+	// WorkerOffset is added to the agent's worker IDs, so that workers
+	// of different agents stay apart in the report.
+	WorkerOffset int
+}
+
+// agentMessage is a line of an agent's response: a result, or, last of
+// all, the end of the run.
+type agentMessage struct {
+	Result *wireResult `json:",omitempty"`
+	Done   *agentDone  `json:",omitempty"`
+}
+
+type agentDone struct {
+	Err string `json:",omitempty"`
+	// Canceled is set when the run was interrupted rather than failed.
+	Canceled  bool `json:",omitempty"`
+	TotalTime time.Duration
+}
+
+// wireResult is a shared.Result as it travels from agent to controller,
+// with its error reduced to its message.
+type wireResult struct {
+	Latency         time.Duration
+	Status          int
+	Err             string `json:",omitempty"`
+	ErrorClass      shared.ErrorClass
+	Bytes           int64
+	Start           time.Time
+	End             time.Time
+	WorkerID        int
+	Endpoint        string    `json:",omitempty"`
+	Intended        time.Time `json:",omitzero"`
+	Trace           shared.Trace
+	ValidationError string `json:",omitempty"`
+	Attempts        int
+	FirstLatency    time.Duration
+}
+
+func toWire(r shared.Result) *wireResult {
+	w := &wireResult{
+		Latency: r.Latency, Status: r.Status, ErrorClass: r.ErrorClass, Bytes: r.Bytes,
+		Start: r.Start, End: r.End, WorkerID: r.WorkerID, Endpoint: r.Endpoint,
+		Intended: r.Intended, Trace: r.Trace, ValidationError: r.ValidationError,
+		Attempts: r.Attempts, FirstLatency: r.FirstLatency,
+	}
+	if r.Err != nil {
+		w.Err = r.Err.Error()
+	}
+	return w
+}
+
+func (w *wireResult) result() shared.Result {
+	r := shared.Result{
+		Latency: w.Latency, Status: w.Status, ErrorClass: w.ErrorClass, Bytes: w.Bytes,
+		Start: w.Start, End: w.End, WorkerID: w.WorkerID, Endpoint: w.Endpoint,
+		Intended: w.Intended, Trace: w.Trace, ValidationError: w.ValidationError,
+		Attempts: w.Attempts, FirstLatency: w.FirstLatency,
+	}
+	if w.Err != "" {
+		r.Err = errors.New(w.Err)
+	}
+	return r
+}
+
+// ServeAgent serves runs for a controller on addr until ctx is done.
+// POST /run runs the plan in its body with the pattern lookup finds for
+// it, streaming each result back as a line of JSON, then a last line for
+// the end of the run; DELETE /run interrupts it, as an interrupt would a
+// local run. An agent runs one plan at a time.
+func ServeAgent(ctx context.Context, addr string, lookup func(name string) (Pattern, bool)) error {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	// runs set up logging as their plans say, quietly; the agent's own
+	// messages keep to the logger it started with
+	a := &agent{lookup: lookup, log: shared.Logger()}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /run", a.run)
+	mux.HandleFunc("DELETE /run", a.stop)
+	srv := &http.Server{Handler: mux}
+	stop := context.AfterFunc(ctx, func() { srv.Close() })
+	defer stop()
+	a.log.Info("agent listening", "addr", lis.Addr().String())
+	if err := srv.Serve(lis); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+type agent struct {
+	lookup func(string) (Pattern, bool)
+	log    *slog.Logger
+
+	mu     sync.Mutex
+	cancel context.CancelFunc // of the run going, if any
+}
+
+func (a *agent) run(w http.ResponseWriter, r *http.Request) {
+	var pl plan
+	if err := json.NewDecoder(r.Body).Decode(&pl); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	p, ok := a.lookup(pl.Pattern)
+	if !ok {
+		http.Error(w, "unknown pattern "+pl.Pattern, http.StatusNotFound)
+		return
+	}
+
+	// the run outlives the request only as long as the controller is
+	// there to read it; DELETE stops it while it still is
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stop := context.AfterFunc(r.Context(), cancel)
+	defer stop()
+	a.mu.Lock()
+	if a.cancel != nil {
+		a.mu.Unlock()
+		http.Error(w, "agent busy", http.StatusConflict)
+		return
+	}
+	a.cancel = cancel
+	a.mu.Unlock()
+	defer func() {
+		a.mu.Lock()
+		a.cancel = nil
+		a.mu.Unlock()
+	}()
+
+	a.log.Info("agent run", "pattern", pl.Pattern, "requests", pl.Config.Requests,
+		"duration", pl.Config.Duration, "concurrency", pl.Config.Concurrency)
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	s := newStreamSink(w)
+	_, totalTime, err := run(ctx, p, &pl.Config, io.Discard, offsetSink{s, pl.WorkerOffset})
+	s.close()
+
+	done := &agentDone{TotalTime: totalTime, Canceled: errors.Is(err, context.Canceled)}
+	if err != nil && !done.Canceled {
+		done.Err = err.Error()
+	}
+	json.NewEncoder(w).Encode(agentMessage{Done: done})
+	a.log.Info("agent run finished", "pattern", pl.Pattern, "time", totalTime, "err", err)
+}
+
+func (a *agent) stop(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.cancel == nil {
+		http.Error(w, "no run going", http.StatusNotFound)
+		return
+	}
+	a.cancel()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// offsetSink moves the results it passes on to worker IDs from offset.
+type offsetSink struct {
+	shared.Sink
+	offset int
+}
+
+func (s offsetSink) Record(r shared.Result) {
+	r.WorkerID += s.offset
+	s.Sink.Record(r)
+}
+
+// streamSink writes the results it is given to an HTTP response as lines
+// of JSON, flushing them every agentFlush. Unlike an Exporter, it never
+// drops a result: the controller's report needs every one, so a slow
+// controller slows the run down.
+type streamSink struct {
+	results chan shared.Result
+	done    chan struct{}
+}
+
+func newStreamSink(w http.ResponseWriter) *streamSink {
+	s := &streamSink{results: make(chan shared.Result, agentBuffer), done: make(chan struct{})}
+	go s.write(w)
+	return s
+}
+
+func (s *streamSink) Record(r shared.Result) {
+	s.results <- r
+}
+
+// close sends the results still buffered. Nothing may be recorded after.
+func (s *streamSink) close() {
+	close(s.results)
+	<-s.done
+}
+
+func (s *streamSink) write(w http.ResponseWriter) {
+	defer close(s.done)
+	rc := http.NewResponseController(w)
+	enc := json.NewEncoder(w)
+	ticker := time.NewTicker(agentFlush)
+	defer ticker.Stop()
+	failed := false
+	for {
+		select {
+		case r, ok := <-s.results:
+			if !ok {
+				rc.Flush()
+				return
+			}
+			// after a failed write, keep draining so the run isn't stuck
+			if !failed && enc.Encode(agentMessage{Result: toWire(r)}) != nil {
+				failed = true
+			}
+		case <-ticker.C:
+			rc.Flush()
+		}
+	}
+}
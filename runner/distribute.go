@@ -0,0 +1,234 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/aawadall/go-concurrency-patterns/config"
+	"github.com/aawadall/go-concurrency-patterns/shared"
+)
+
+// Distribute runs p across the agents in cfg.Agents, each making its
+// share of the requests at its share of the concurrency and rate, and
+// reports their results as one run, as Run does. Start and end times come
+// from each agent's clock, so the agents' clocks should be in sync. The
+// memory profile is the controller's own.
+func Distribute(ctx context.Context, p Pattern, cfg *config.Config) (shared.Summary, error) {
+	name := p.Name()
+	var summary shared.Summary
+	if err := cfg.Validate(); err != nil {
+		return summary, err
+	}
+	if err := shared.SetupLogging(cfg); err != nil {
+		return summary, err
+	}
+	if cfg.Seed == 0 {
+		c := *cfg
+		c.Seed = time.Now().UnixNano()
+		cfg = &c
+	}
+	format, err := shared.ParseFormat(cfg.Output)
+	if err != nil {
+		return summary, err
+	}
+	plans := split(name, cfg)
+	shared.Logger().Info("starting distributed run", "pattern", name, "agents", len(plans), "seed", cfg.Seed)
+
+	var m1 runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&m1)
+
+	collector := shared.NewCollectorFor(cfg)
+	if cfg.MetricsAddr != "" {
+		stopMetrics, err := shared.ServeMetrics(cfg.MetricsAddr, collector)
+		if err != nil {
+			return summary, err
+		}
+		defer stopMetrics()
+	}
+	stopLive := func() {}
+	if cfg.Live {
+		stopLive = collector.StartLive(os.Stderr, 250*time.Millisecond)
+	}
+
+	// an interrupt stops the agents, which then send what they have
+	client := &http.Client{}
+	stopAgents := context.AfterFunc(ctx, func() {
+		for _, addr := range cfg.Agents {
+			if err := stopAgent(client, addr); err != nil {
+				shared.Logger().Warn("stopping agent", "agent", addr, "err", err)
+			}
+		}
+	})
+	defer stopAgents()
+
+	// the run lasts as long as the slowest agent's, which like a local
+	// one leaves out the warm-up
+	startTime := time.Now()
+	times := make([]time.Duration, len(plans))
+	g, gctx := errgroup.WithContext(context.WithoutCancel(ctx))
+	for i, addr := range cfg.Agents {
+		g.Go(func() error {
+			done, n, err := runAgent(gctx, client, addr, plans[i], collector)
+			if err != nil {
+				return fmt.Errorf("agent %s: %w", addr, err)
+			}
+			times[i] = done.TotalTime
+			shared.Logger().Info("agent finished", "agent", addr, "results", n, "time", done.TotalTime)
+			if done.Err != "" {
+				return fmt.Errorf("agent %s: %s", addr, done.Err)
+			}
+			return nil
+		})
+	}
+	runErr := g.Wait()
+	if runErr == nil {
+		// interrupted agents send what they have and end without error
+		runErr = ctx.Err()
+	}
+	totalTime := slices.Max(times)
+	stopLive()
+
+	var m2 runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&m2)
+	memProfile := memoryProfile(&m1, &m2)
+
+	summary = collector.Summary()
+	summary.Partial = ctx.Err() != nil
+	summary.TargetRate = max(cfg.ArrivalRate, cfg.RatePerSecond)
+	if err := shared.Report(os.Stdout, format, summary, totalTime, memProfile); err != nil {
+		return summary, err
+	}
+	if cfg.ResultsDir != "" {
+		manifest := shared.NewManifest(name, cfg, startTime, startTime.Add(totalTime), summary, memProfile)
+		if _, err := shared.WriteManifest(cfg.ResultsDir, manifest); err != nil {
+			return summary, err
+		}
+	}
+	return summary, runErr
+}
+
+// split divides the run cfg describes into a plan for each agent. Counts
+// are shared out as evenly as they go, and rates and profile levels
+// divided evenly; what each agent saves or serves locally is left to the
+// controller.
+func split(pattern string, cfg *config.Config) []plan {
+	n := len(cfg.Agents)
+	plans := make([]plan, n)
+	offset := 0
+	for i := range plans {
+		c := *cfg
+		c.Agents = nil
+		c.Requests = share(cfg.Requests, i, n)
+		c.WarmupRequests = share(cfg.WarmupRequests, i, n)
+		c.Concurrency = max(share(cfg.Concurrency, i, n), 1)
+		c.MaxInFlight = share(cfg.MaxInFlight, i, n)
+		c.RatePerSecond = cfg.RatePerSecond / float64(n)
+		c.ArrivalRate = cfg.ArrivalRate / float64(n)
+		if cfg.Burst > 0 {
+			c.Burst = max(share(cfg.Burst, i, n), 1)
+		}
+		c.Profile = nil
+		for _, ph := range cfg.Profile {
+			c.Profile = append(c.Profile, config.Phase{Duration: ph.Duration, From: ph.From / float64(n), To: ph.To / float64(n)})
+		}
+		c.Seed = cfg.Seed + int64(i)
+
+		c.Quiet, c.Live = true, false
+		c.ResultsDir, c.Database, c.ExportURL, c.MetricsAddr = "", "", "", ""
+		c.CPUProfile, c.HeapProfile = false, false
+		plans[i] = plan{Pattern: pattern, Config: c, WorkerOffset: offset}
+		offset += c.Concurrency
+	}
+	return plans
+}
+
+// share is agent i's part of total spread over n agents.
+func share(total, i, n int) int {
+	s := total / n
+	if i < total%n {
+		s++
+	}
+	return s
+}
+
+// runAgent has the agent at addr run pl, recording the results it sends
+// in collector, and returns how the run ended and how many results came.
+func runAgent(ctx context.Context, client *http.Client, addr string, pl plan, collector *shared.Collector) (*agentDone, int, error) {
+	body, err := json.Marshal(pl)
+	if err != nil {
+		return nil, 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, agentURL(addr), bytes.NewReader(body))
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<10))
+		return nil, 0, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	dec := json.NewDecoder(resp.Body)
+	n := 0
+	for {
+		var m agentMessage
+		if err := dec.Decode(&m); err != nil {
+			if errors.Is(err, io.EOF) {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, n, err
+		}
+		if m.Done != nil {
+			return m.Done, n, nil
+		}
+		if m.Result != nil {
+			collector.Record(m.Result.result())
+			n++
+		}
+	}
+}
+
+// stopAgent interrupts the run going on the agent at addr.
+func stopAgent(client *http.Client, addr string) error {
+	req, err := http.NewRequest(http.MethodDelete, agentURL(addr), nil)
+	if err != nil {
+		return err
+	}
+	// don't leave a connection behind on the agent, mid run
+	req.Close = true
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusNotFound {
+		return errors.New(resp.Status)
+	}
+	return nil
+}
+
+func agentURL(addr string) string {
+	if !strings.Contains(addr, "://") {
+		addr = "http://" + addr
+	}
+	return strings.TrimSuffix(addr, "/") + "/run"
+}
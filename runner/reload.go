@@ -0,0 +1,54 @@
+package runner
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/aawadall/go-concurrency-patterns/config"
+	"github.com/aawadall/go-concurrency-patterns/shared"
+	"github.com/aawadall/go-concurrency-patterns/watch"
+)
+
+// reloadOnHangup rereads the concurrency and rps of cfg's scenario from
+// its config file into settings on every SIGHUP, until stop is called. A
+// file that fails to load or gives unusable settings is logged and
+// ignored.
+func reloadOnHangup(cfg *config.Config, settings *watch.Value[shared.Settings]) (stop func()) {
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-hangups:
+			}
+			s, err := reload(cfg)
+			if err != nil {
+				shared.Logger().Error("reloading settings", "config", cfg.ConfigFile, "err", err)
+				continue
+			}
+			settings.Set(s)
+		}
+	}()
+	return func() {
+		signal.Stop(hangups)
+		close(done)
+	}
+}
+
+// reload reads the settings of cfg's scenario from its config file.
+func reload(cfg *config.Config) (shared.Settings, error) {
+	f, err := config.Load(cfg.ConfigFile)
+	if err != nil {
+		return shared.Settings{}, err
+	}
+	c, err := f.Config(cfg.Scenario)
+	if err != nil {
+		return shared.Settings{}, err
+	}
+	s := shared.Settings{Concurrency: c.Concurrency, RatePerSecond: c.RatePerSecond}
+	return s, s.Check(cfg.Concurrency)
+}
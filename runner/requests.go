@@ -0,0 +1,43 @@
+package runner
+
+import (
+	"context"
+	"iter"
+	"time"
+
+	"github.com/aawadall/go-concurrency-patterns/config"
+)
+
+// Requests yields the number of each request a pattern should make: up
+// to cfg.Requests, or, for runs of a set duration or load profile, as
+// many as it can until that much time has passed since iteration began.
+// Warm-up requests come first, on top of those. It stops early once ctx
+// is done.
+func Requests(ctx context.Context, cfg *config.Config) iter.Seq[int] {
+	return func(yield func(int) bool) {
+		duration := cfg.RunDuration()
+		var deadline <-chan time.Time
+		if duration > 0 {
+			timer := time.NewTimer(cfg.WarmupDuration + duration)
+			defer timer.Stop()
+			deadline = timer.C
+		}
+		warmupEnd := time.Now().Add(cfg.WarmupDuration)
+		warmup := cfg.WarmupRequests
+		for i := 0; duration > 0 || i < cfg.Requests+warmup; i++ {
+			select {
+			case <-ctx.Done():
+				return
+			case <-deadline:
+				return
+			default:
+			}
+			if time.Now().Before(warmupEnd) {
+				warmup++
+			}
+			if !yield(i) {
+				return
+			}
+		}
+	}
+}
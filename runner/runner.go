@@ -0,0 +1,343 @@
+// Package runner holds the measurement harness shared by the pattern
+// clients: it builds the target and collector from the config, times the
+// run, records memory use, and reports and saves the results.
+package runner
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"runtime"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/aawadall/go-concurrency-patterns/config"
+	"github.com/aawadall/go-concurrency-patterns/shared"
+	"github.com/aawadall/go-concurrency-patterns/shared/leakcheck"
+	"github.com/aawadall/go-concurrency-patterns/watch"
+)
+
+// leakWait is how long goroutines started by a pattern get to finish
+// after it returns before they are reported as leaked.
+const leakWait = time.Second
+
+// Pattern is a benchmark client built around one concurrency pattern.
+type Pattern interface {
+	// Name identifies the pattern on the command line and in results.
+	Name() string
+	// Run calls target once for each request yielded by Requests,
+	// recording every result in collector. It should stop early when ctx
+	// is canceled.
+	Run(ctx context.Context, cfg *config.Config, target shared.Target, collector *shared.Collector) error
+}
+
+// RateLimiting is implemented by patterns that enforce
+// cfg.RatePerSecond themselves, so Run doesn't limit the rate again.
+type RateLimiting interface {
+	LimitsRate() bool
+}
+
+// Main runs p configured from the command-line args and exits, with
+// status 1 if the run failed or missed its SLO. An interrupt cancels the
+// run's context: no more requests are sent, those in flight get
+// cfg.DrainTimeout to finish, and the results so far are reported as a
+// partial run, with status 130. A second interrupt quits at once.
+func Main(p Pattern, args []string) {
+	MainWithConfig(p, Flags(args))
+}
+
+// Flags returns the config given by args, as for config.FromFlags, and
+// exits if they are invalid or ask for help.
+func Flags(args []string) *config.Config {
+	cfg, err := config.FromFlags(args)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	return cfg
+}
+
+// MainWithConfig is Main with the config already parsed.
+func MainWithConfig(p Pattern, cfg *config.Config) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	done := make(chan struct{})
+	go func() {
+		<-ctx.Done()
+		select {
+		case <-done:
+			return
+		default:
+		}
+		// let a second interrupt kill the process
+		stop()
+		shared.Logger().Warn("interrupted, finishing requests in flight; interrupt again to quit", "drain_timeout", cfg.DrainTimeout)
+	}()
+	var summaries []shared.Summary
+	var err error
+	if len(cfg.Sweep) > 0 {
+		var points []shared.SweepPoint
+		points, err = Sweep(ctx, p, cfg)
+		for _, point := range points {
+			summaries = append(summaries, point.Summary)
+		}
+	} else {
+		var summary shared.Summary
+		if len(cfg.Agents) > 0 {
+			summary, err = Distribute(ctx, p, cfg)
+		} else {
+			summary, err = Run(ctx, p, cfg)
+		}
+		summaries = append(summaries, summary)
+	}
+	interrupted := ctx.Err() != nil
+	close(done)
+	stop()
+	if interrupted && errors.Is(err, context.Canceled) {
+		os.Exit(130)
+	}
+	if err != nil {
+		shared.Logger().Error("run failed", "pattern", p.Name(), "err", err)
+		os.Exit(1)
+	}
+	for _, summary := range summaries {
+		if summary.SLO != nil && !summary.SLO.Pass {
+			os.Exit(1)
+		}
+	}
+}
+
+// Sweep runs p once at each concurrency level in cfg.Sweep, saving each
+// run as configured, then writes a table of the runs to stdout. It stops
+// at the first run that fails.
+func Sweep(ctx context.Context, p Pattern, cfg *config.Config) ([]shared.SweepPoint, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if err := shared.SetupLogging(cfg); err != nil {
+		return nil, err
+	}
+	format, err := shared.ParseFormat(cfg.Output)
+	if err != nil {
+		return nil, err
+	}
+	var points []shared.SweepPoint
+	var runErr error
+	for _, n := range cfg.Sweep {
+		if ctx.Err() != nil {
+			break
+		}
+		c := *cfg
+		c.Concurrency = n
+		c.Sweep = nil
+		shared.Logger().Info("sweep", "pattern", p.Name(), "concurrency", n)
+		var point shared.SweepPoint
+		point.Summary, point.TotalTime, runErr = run(ctx, p, &c, io.Discard)
+		point.Concurrency = n
+		points = append(points, point)
+		if runErr != nil {
+			break
+		}
+	}
+	if err := shared.ReportSweep(os.Stdout, format, points); err != nil {
+		return points, err
+	}
+	return points, runErr
+}
+
+// Run runs p, writes the report to stdout and saves the results as
+// configured. Results collected before a pattern fails are still reported.
+func Run(ctx context.Context, p Pattern, cfg *config.Config) (shared.Summary, error) {
+	summary, _, err := run(ctx, p, cfg, os.Stdout)
+	return summary, err
+}
+
+// run is Run writing the report to out, and also returning how long the
+// run took. Every result is passed to sinks as well.
+func run(ctx context.Context, p Pattern, cfg *config.Config, out io.Writer, sinks ...shared.Sink) (shared.Summary, time.Duration, error) {
+	name := p.Name()
+	var summary shared.Summary
+	var totalTime time.Duration
+	if err := cfg.Validate(); err != nil {
+		return summary, totalTime, err
+	}
+	if err := shared.SetupLogging(cfg); err != nil {
+		return summary, totalTime, err
+	}
+	if cfg.Seed == 0 {
+		c := *cfg
+		c.Seed = time.Now().UnixNano()
+		cfg = &c
+	}
+	shared.Seed(cfg.Seed)
+	shared.Logger().Info("starting run", "pattern", name, "seed", cfg.Seed)
+	target, err := shared.NewTarget(cfg)
+	if err != nil {
+		return summary, totalTime, err
+	}
+	format, err := shared.ParseFormat(cfg.Output)
+	if err != nil {
+		return summary, totalTime, err
+	}
+
+	// Initial memory stats
+	var m1 runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&m1)
+
+	collector := shared.NewCollectorFor(cfg)
+	for _, s := range sinks {
+		collector.AddSink(s)
+	}
+	if cfg.Timeout > 0 {
+		target = shared.WithTimeout(target, cfg.Timeout)
+	}
+	target = shared.Drain(target, ctx, cfg.DrainTimeout)
+	if cfg.MaxInFlight > 0 {
+		target = shared.LimitInFlight(target, cfg.MaxInFlight)
+	}
+	if cfg.ArrivalRate > 0 {
+		target = shared.OpenLoop(target, cfg.ArrivalRate)
+	}
+	stopSettings := func() {}
+	if cfg.AdminAddr != "" || cfg.Reload {
+		// the rps is limited with the concurrency, so it can change too
+		settings := watch.New(shared.Settings{Concurrency: cfg.Concurrency, RatePerSecond: cfg.RatePerSecond})
+		var stopReconfig func()
+		target, stopReconfig = shared.Reconfigurable(target, collector, settings, cfg.Burst)
+		stopSettings = stopReconfig
+		if cfg.AdminAddr != "" {
+			stopAdmin, err := shared.ServeAdmin(cfg.AdminAddr, settings, cfg.Concurrency)
+			if err != nil {
+				stopReconfig()
+				return summary, totalTime, err
+			}
+			stopSettings = func() { stopAdmin(); stopReconfig() }
+		}
+		if cfg.Reload {
+			stopReload := reloadOnHangup(cfg, settings)
+			stopOthers := stopSettings
+			stopSettings = func() { stopReload(); stopOthers() }
+		}
+		// stopped once the pattern returns, or on the way out if it never
+		// runs
+		stopSettings = sync.OnceFunc(stopSettings)
+		defer stopSettings()
+	} else if rl, ok := p.(RateLimiting); cfg.RatePerSecond > 0 && !(ok && rl.LimitsRate()) {
+		target = shared.RateLimit(target, cfg.RatePerSecond, cfg.Burst)
+	}
+	if len(cfg.Profile) > 0 {
+		var stopProfile func()
+		target, stopProfile = shared.LoadProfile(target, collector, cfg)
+		defer stopProfile()
+	}
+	target = collector.Track(target)
+	if cfg.ThinkTime > 0 {
+		if target, err = shared.ThinkTime(target, cfg.ThinkTime, cfg.ThinkDistribution, cfg.Seed); err != nil {
+			return summary, totalTime, err
+		}
+	}
+	if cfg.MetricsAddr != "" {
+		stopMetrics, err := shared.ServeMetrics(cfg.MetricsAddr, collector)
+		if err != nil {
+			return summary, totalTime, err
+		}
+		defer stopMetrics()
+	}
+	var store *shared.RunStore
+	if cfg.Database != "" {
+		if store, err = shared.OpenRunStore(cfg.Database, name); err != nil {
+			return summary, totalTime, err
+		}
+		collector.AddSink(store)
+	}
+	var exporter *shared.Exporter
+	if cfg.ExportURL != "" {
+		if exporter, err = shared.NewExporter(cfg.ExportURL, name); err != nil {
+			return summary, totalTime, err
+		}
+		collector.AddSink(exporter)
+	}
+
+	if cfg.WarmupDuration > 0 || cfg.WarmupRequests > 0 {
+		collector.StartWarmup(cfg.WarmupRequests, cfg.WarmupDuration)
+	}
+	stopSampler := collector.SampleRuntime(cfg.Interval)
+	stopLive := func() {}
+	if cfg.Live {
+		stopLive = collector.StartLive(os.Stderr, 250*time.Millisecond)
+	}
+	startTime := time.Now()
+	stopProfiles, err := shared.StartProfiles(cfg, name, startTime)
+	if err != nil {
+		return summary, totalTime, err
+	}
+
+	goroutines := leakcheck.Take()
+	runErr := p.Run(ctx, cfg, target, collector)
+
+	totalTime = time.Since(startTime)
+	stopSettings()
+	stopLive()
+	stopSampler()
+	if err := stopProfiles(); err != nil {
+		return summary, totalTime, err
+	}
+	if exporter != nil {
+		exporter.Close()
+	}
+
+	// Final memory stats
+	var m2 runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&m2)
+	memProfile := memoryProfile(&m1, &m2)
+
+	summary = collector.Summary()
+	summary.Leaks = leakcheck.Check(goroutines, leakWait)
+	summary.Partial = ctx.Err() != nil
+	summary.TargetRate = max(cfg.ArrivalRate, cfg.RatePerSecond)
+	if summary.Warmup != nil {
+		totalTime = max(totalTime-summary.Warmup.Duration, 0)
+	}
+	if err := shared.Report(out, format, summary, totalTime, memProfile); err != nil {
+		return summary, totalTime, err
+	}
+
+	manifest := shared.NewManifest(name, cfg, startTime, startTime.Add(totalTime), summary, memProfile)
+	if cfg.ResultsDir != "" {
+		if _, err := shared.WriteManifest(cfg.ResultsDir, manifest); err != nil {
+			return summary, totalTime, err
+		}
+	}
+	if store != nil {
+		if err := store.Close(manifest); err != nil {
+			return summary, totalTime, fmt.Errorf("saving run: %w", err)
+		}
+	}
+	return summary, totalTime, runErr
+}
+
+// memoryProfile reports the memory used by the run, from stats taken
+// before and after it.
+func memoryProfile(before, after *runtime.MemStats) map[string]uint64 {
+	var alloc uint64
+	if after.Alloc > before.Alloc {
+		alloc = after.Alloc - before.Alloc
+	}
+	return map[string]uint64{
+		"Alloc":      alloc,
+		"TotalAlloc": after.TotalAlloc - before.TotalAlloc,
+		"Sys":        after.Sys - before.Sys,
+		"PeakMem":    after.Sys,
+		"Mallocs":    after.Mallocs - before.Mallocs,
+		"NumGC":      uint64(after.NumGC - before.NumGC),
+	}
+}
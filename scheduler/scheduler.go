@@ -0,0 +1,209 @@
+// Package scheduler runs jobs later: once after a delay, or periodically,
+// with optional jitter so that jobs on the same period don't all fire at
+// once. Due jobs are kept in a heap ordered by when they fire, with one
+// timer set for the earliest, and are handed to a fixed number of
+// workers, so however many jobs come due together only so many run at a
+// time.
+package scheduler
+
+import (
+	"container/heap"
+	"context"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Job is a scheduled piece of work. Its context is done when the
+// scheduler stops.
+type Job func(ctx context.Context)
+
+// Options configures a Scheduler.
+type Options struct {
+	// Workers is how many jobs run at once, one if zero.
+	Workers int
+	// Queue is how many due jobs may wait for a worker. A job that comes
+	// due when the queue is full is skipped and counted as missed.
+	Queue int
+}
+
+// Stats counts what a scheduler has done so far.
+type Stats struct {
+	Runs   int64 // jobs run
+	Missed int64 // runs skipped because the workers were busy or behind
+}
+
+// entry is a scheduled job.
+type entry struct {
+	at       time.Time // when it fires next
+	next     time.Time // for a periodic job, the unjittered time it fires
+	job      Job
+	every    time.Duration
+	jitter   time.Duration
+	canceled atomic.Bool
+	index    int
+}
+
+// entries is a heap of entries, the one firing first on top.
+type entries []*entry
+
+func (h entries) Len() int           { return len(h) }
+func (h entries) Less(i, j int) bool { return h[i].at.Before(h[j].at) }
+func (h entries) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index, h[j].index = i, j
+}
+func (h *entries) Push(x any) {
+	e := x.(*entry)
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+func (h *entries) Pop() any {
+	old := *h
+	e := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return e
+}
+
+// Scheduler runs jobs at the times they were scheduled for while Run is
+// running. Jobs can be scheduled before or during Run.
+type Scheduler struct {
+	opts Options
+	wake chan struct{}
+
+	mu    sync.Mutex
+	queue entries
+
+	runs, missed atomic.Int64
+}
+
+// New returns a scheduler with nothing scheduled.
+func New(opts Options) *Scheduler {
+	opts.Workers = max(opts.Workers, 1)
+	return &Scheduler{opts: opts, wake: make(chan struct{}, 1)}
+}
+
+// After schedules job to run once, d from now. cancel unschedules it if
+// it hasn't run yet.
+func (s *Scheduler) After(d time.Duration, job Job) (cancel func()) {
+	return s.schedule(&entry{at: time.Now().Add(d), job: job})
+}
+
+// Every schedules job to run every interval from now, each time up to
+// jitter late, until cancel is called. The jitter doesn't add up: each
+// run is late from its place on the interval, not from the last run.
+func (s *Scheduler) Every(interval, jitter time.Duration, job Job) (cancel func()) {
+	e := &entry{next: time.Now().Add(interval), job: job, every: interval, jitter: jitter}
+	e.at = e.next.Add(e.delay())
+	return s.schedule(e)
+}
+
+func (s *Scheduler) schedule(e *entry) func() {
+	s.mu.Lock()
+	heap.Push(&s.queue, e)
+	s.mu.Unlock()
+	s.notify()
+	return func() {
+		e.canceled.Store(true)
+		s.notify()
+	}
+}
+
+// notify wakes Run to look at the queue again.
+func (s *Scheduler) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// delay returns a random delay up to the entry's jitter.
+func (e *entry) delay() time.Duration {
+	if e.jitter <= 0 {
+		return 0
+	}
+	return rand.N(e.jitter)
+}
+
+// Run runs the jobs as they come due until ctx is done, then waits for
+// those running to return.
+func (s *Scheduler) Run(ctx context.Context) error {
+	work := make(chan Job, s.opts.Queue)
+	var wg sync.WaitGroup
+	for range s.opts.Workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range work {
+				job(ctx)
+				s.runs.Add(1)
+			}
+		}()
+	}
+	defer func() {
+		close(work)
+		wg.Wait()
+	}()
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		due, wait := s.due(time.Now())
+		for _, job := range due {
+			select {
+			case work <- job:
+			default:
+				s.missed.Add(1)
+			}
+		}
+		if wait >= 0 {
+			timer.Reset(wait)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		case <-s.wake:
+		}
+	}
+}
+
+// due takes the jobs due by now off the queue, putting periodic ones back
+// for their next run, and returns them with how long until the next job
+// is due, or -1 if none is scheduled.
+func (s *Scheduler) due(now time.Time) ([]Job, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var due []Job
+	for len(s.queue) > 0 {
+		e := s.queue[0]
+		if e.canceled.Load() {
+			heap.Pop(&s.queue)
+			continue
+		}
+		if e.at.After(now) {
+			return due, e.at.Sub(now)
+		}
+		due = append(due, e.job)
+		if e.every <= 0 {
+			heap.Pop(&s.queue)
+			continue
+		}
+		// runs that were due while the scheduler was behind are skipped
+		e.next = e.next.Add(e.every)
+		for !e.next.After(now) {
+			e.next = e.next.Add(e.every)
+			s.missed.Add(1)
+		}
+		e.at = e.next.Add(e.delay())
+		heap.Fix(&s.queue, e.index)
+	}
+	return due, -1
+}
+
+// Stats returns what the scheduler has done so far.
+func (s *Scheduler) Stats() Stats {
+	return Stats{Runs: s.runs.Load(), Missed: s.missed.Load()}
+}
@@ -0,0 +1,81 @@
+// Package scope is structured concurrency: goroutines are started within
+// a scope, and the scope doesn't end until every one of them has
+// returned, so none outlives the function that started it. The first
+// error or panic in a scope cancels its context, asking the rest to stop,
+// and is what the scope returns. Scopes nest: a goroutine can open a
+// scope of its own.
+package scope
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+)
+
+// PanicError is the error of a scope in which a goroutine panicked.
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("scope: panic: %v\n\n%s", e.Value, e.Stack)
+}
+
+// Scope is an open scope, which goroutines can be started in.
+type Scope struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	once sync.Once
+	err  error
+}
+
+// Run opens a scope, calls body in it and, once body and every goroutine
+// it started have returned, closes it, returning the first error or
+// panic from any of them.
+func Run(ctx context.Context, body func(s *Scope) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	s := &Scope{ctx: ctx, cancel: cancel}
+	s.call(func(context.Context) error { return body(s) })
+	s.wg.Wait()
+	cancel()
+	return s.err
+}
+
+// Go starts fn in a goroutine within the scope, passing it the scope's
+// context, which is canceled once any goroutine in the scope fails.
+func (s *Scope) Go(fn func(ctx context.Context) error) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.call(fn)
+	}()
+}
+
+// Context returns the scope's context.
+func (s *Scope) Context() context.Context {
+	return s.ctx
+}
+
+// call runs fn, failing the scope if it returns an error or panics.
+func (s *Scope) call(fn func(ctx context.Context) error) {
+	defer func() {
+		if p := recover(); p != nil {
+			s.fail(&PanicError{Value: p, Stack: debug.Stack()})
+		}
+	}()
+	if err := fn(s.ctx); err != nil {
+		s.fail(err)
+	}
+}
+
+// fail records the scope's first error and cancels it.
+func (s *Scope) fail(err error) {
+	s.once.Do(func() {
+		s.err = err
+		s.cancel()
+	})
+}
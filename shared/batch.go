@@ -0,0 +1,139 @@
+package shared
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aawadall/go-concurrency-patterns/config"
+)
+
+// Batch is one bulk call carrying the requests of several callers. A
+// target that understands batches, called with a context from WithBatch,
+// sends all of IDs at once and fills Results with the answer to each, in
+// the order of IDs; the Result it returns is the bulk call's own. Results
+// is left nil by targets that can't batch.
+type Batch struct {
+	IDs     []int64
+	Results []Result
+}
+
+type batchKey struct{}
+
+// WithBatch asks the target called with the returned context to make b's
+// bulk call instead of a single request.
+func WithBatch(ctx context.Context, b *Batch) context.Context {
+	return context.WithValue(ctx, batchKey{}, b)
+}
+
+// BatchFrom returns the Batch a target called with ctx is asked to make,
+// or nil for a single request.
+func BatchFrom(ctx context.Context) *Batch {
+	b, _ := ctx.Value(batchKey{}).(*Batch)
+	return b
+}
+
+// batchPath is where cmd/server takes bulk requests.
+const batchPath = "/batch"
+
+// batchRequest and batchResponse are the bodies of a bulk call to
+// batchPath: the request IDs, and a status for each.
+type batchRequest struct {
+	IDs []int64 `json:"ids"`
+}
+
+type batchResponse struct {
+	Results []struct {
+		ID     int64 `json:"id"`
+		Status int   `json:"status"`
+	} `json:"results"`
+}
+
+// doBatch makes b's bulk call over HTTP, posting the IDs to batchPath.
+func doBatch(ctx context.Context, cfg *config.Config, b *Batch) (result Result, err error) {
+	start := time.Now()
+	defer func() {
+		result.Start = start
+		result.End = time.Now()
+		result.Latency = result.End.Sub(start)
+		result.FirstLatency = result.Latency
+		result.WorkerID = WorkerID(ctx)
+		result.Attempts = 1
+		result.Err = err
+	}()
+
+	client, err := clientFor(cfg)
+	if err != nil {
+		return Result{ErrorClass: ClassClient}, err
+	}
+	scheme := cfg.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+	url := fmt.Sprintf("%s://%s%s", scheme, net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)), batchPath)
+	body, err := json.Marshal(batchRequest{IDs: b.IDs})
+	if err != nil {
+		return Result{ErrorClass: ClassClient}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return Result{ErrorClass: ClassClient}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return Result{ErrorClass: classifyError(err)}, err
+	}
+	defer resp.Body.Close()
+	r := Result{Status: resp.StatusCode}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		r.ErrorClass = ClassNon2xx
+		r.Bytes, _ = io.Copy(io.Discard, resp.Body)
+		return r, nil
+	}
+	counted := &countingReader{r: resp.Body}
+	var reply batchResponse
+	err = json.NewDecoder(counted).Decode(&reply)
+	r.Bytes = counted.n
+	if err != nil {
+		r.ErrorClass = ClassRead
+		return r, fmt.Errorf("reading bulk response: %w", err)
+	}
+
+	statuses := make(map[int64]int, len(reply.Results))
+	for _, res := range reply.Results {
+		statuses[res.ID] = res.Status
+	}
+	b.Results = make([]Result, len(b.IDs))
+	for i, id := range b.IDs {
+		status, ok := statuses[id]
+		switch {
+		case !ok:
+			b.Results[i] = Result{ErrorClass: ClassRead, Err: fmt.Errorf("no result for request %d in the bulk response", id)}
+		case status < 200 || status > 299:
+			b.Results[i] = Result{Status: status, ErrorClass: ClassNon2xx}
+		default:
+			b.Results[i] = Result{Status: status}
+		}
+	}
+	return r, nil
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,72 @@
+package shared
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/aawadall/go-concurrency-patterns/config"
+)
+
+// a bulk call posts every ID, and each gets the status the server gave it
+func TestHTTPTargetBatch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != batchPath {
+			http.NotFound(w, r)
+			return
+		}
+		var req batchRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		// answer every ID but 3, failing the even ones
+		var resp batchResponse
+		for _, id := range req.IDs {
+			if id == 3 {
+				continue
+			}
+			status := 200
+			if id%2 == 0 {
+				status = 500
+			}
+			resp.Results = append(resp.Results, struct {
+				ID     int64 `json:"id"`
+				Status int   `json:"status"`
+			}{id, status})
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer srv.Close()
+	host, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+	cfg := &config.Config{Host: host}
+	cfg.Port, _ = strconv.Atoi(port)
+	target := &HTTPTarget{Config: cfg}
+
+	b := &Batch{IDs: []int64{1, 2, 3, 4}}
+	r, err := target.Do(WithBatch(context.Background(), b))
+	if err != nil || r.Status != 200 || r.Bytes == 0 {
+		t.Fatalf("bulk call got %+v, %v", r, err)
+	}
+	want := []struct {
+		status int
+		class  ErrorClass
+	}{
+		{200, ClassNone},
+		{500, ClassNon2xx},
+		{0, ClassRead},
+		{500, ClassNon2xx},
+	}
+	if len(b.Results) != len(want) {
+		t.Fatalf("got %d results, want %d", len(b.Results), len(want))
+	}
+	for i, w := range want {
+		if got := b.Results[i]; got.Status != w.status || got.ErrorClass != w.class {
+			t.Errorf("request %d got status %d, class %v, want %d, %v", b.IDs[i], got.Status, got.ErrorClass, w.status, w.class)
+		}
+	}
+}
@@ -1,59 +1,223 @@
 package shared
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"slices"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/aawadall/go-concurrency-patterns/config"
 )
 
-const RED = "\033[0;31m"
-const RESET = "\033[0m"
+// requestSeq hands out request IDs for templates.
+var requestSeq atomic.Int64
 
 func ConsumeServer(cfg *config.Config) (latency time.Duration, status int) {
+	r := Fetch(cfg, 0)
+	return r.Latency, r.Status
+}
+
+// Fetch performs a single request on behalf of the given worker, rendering
+// the configured path and body templates first and retrying according to
+// the config's retry policy. cfg.Mode selects HTTP, gRPC or WebSocket.
+func Fetch(cfg *config.Config, workerID int) Result {
+	r, _ := fetch(context.Background(), cfg, workerID, attemptFor(cfg))
+	return r
+}
+
+// attemptFunc performs a single try with the rendered path and body.
+type attemptFunc func(ctx context.Context, cfg *config.Config, workerID int, path, body string) (Result, error)
+
+func attemptFor(cfg *config.Config) attemptFunc {
+	switch cfg.Mode {
+	case "grpc":
+		return doGRPC
+	case "ws":
+		return doWebSocket
+	default:
+		return doRequest
+	}
+}
+
+// fetch renders the request and runs attempts until one succeeds or the
+// retry policy gives up. The returned error is the last network error.
+func fetch(ctx context.Context, cfg *config.Config, workerID int, do attemptFunc) (result Result, netErr error) {
 	startTime := time.Now()
 	defer func() {
-		latency = time.Since(startTime)
+		result.Start = startTime
+		result.End = time.Now()
+		result.Latency = result.End.Sub(startTime)
+		result.WorkerID = workerID
+		result.Err = netErr
+		if result.ErrorClass == ClassNone && (result.Status < 200 || result.Status > 299) {
+			result.ErrorClass = ClassNon2xx
+		}
 	}()
+
+	pathTemplate := cfg.Path
+	if pathTemplate == "" {
+		pathTemplate = "/data"
+	}
+	tmpl, err := templateFor(pathTemplate, cfg.Body)
+	if err != nil {
+		logger.Warn("parsing template", "err", err)
+		return Result{ErrorClass: ClassClient}, err
+	}
+	path, body, err := tmpl.Render(RequestData{
+		RequestID: requestSeq.Add(1),
+		WorkerID:  workerID,
+	})
+	if err != nil {
+		logger.Warn("rendering template", "err", err)
+		return Result{ErrorClass: ClassClient}, err
+	}
+
+	maxAttempts := max(cfg.MaxAttempts, 1)
+	backoff := cfg.Backoff
+	var firstLatency time.Duration
+	for attempt := 1; ; attempt++ {
+		attemptStart := time.Now()
+		result, netErr = do(ctx, cfg, workerID, path, body)
+		result.Attempts = attempt
+		if attempt == 1 {
+			firstLatency = time.Since(attemptStart)
+		}
+		if attempt >= maxAttempts || !shouldRetry(cfg, result, netErr) {
+			break
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(backoff):
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		backoff = min(backoff*2, max(cfg.MaxBackoff, cfg.Backoff))
+	}
+	result.FirstLatency = firstLatency
+
+	if netErr == nil {
+		progress(cfg)
+	}
+	return result, netErr
+}
+
+func shouldRetry(cfg *config.Config, result Result, netErr error) bool {
+	if netErr != nil {
+		return cfg.RetryOnNetworkError && result.ErrorClass.retryable()
+	}
+	return slices.Contains(cfg.RetryOnStatus, result.Status)
+}
+
+// doRequest performs one HTTP attempt. The returned error is non-nil when
+// no complete response was received.
+func doRequest(ctx context.Context, cfg *config.Config, _ int, path, body string) (Result, error) {
 	// URL + port
-	path := "/data"
-	serverURL := fmt.Sprintf("http://%s:%d%s", cfg.Host, cfg.Port, path)
+	scheme := cfg.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+	serverURL := fmt.Sprintf("%s://%s:%d%s", scheme, cfg.Host, cfg.Port, path)
 
 	parsedURL, err := url.Parse(serverURL)
 	if err != nil {
-		fmt.Printf("%s Error parsing URL: %v %s\n", RED, err, RESET)
-		return -1, 500
+		logger.Warn("parsing URL", "err", err)
+		return Result{ErrorClass: ClassClient}, err
 	}
 
-	// Create HTTP client
-	client := &http.Client{}
+	// Get the HTTP client for this config
+	client, err := clientFor(cfg)
+	if err != nil {
+		logger.Warn("creating client", "err", err)
+		return Result{ErrorClass: ClassClient}, err
+	}
 
 	// Create HTTP request
-	req, err := http.NewRequest("GET", parsedURL.String(), nil)
+	method := cfg.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	var reqBody io.Reader
+	if body != "" {
+		reqBody = strings.NewReader(body)
+	}
+	t, ctx := newTracer(ctx)
+	req, err := http.NewRequestWithContext(ctx, method, parsedURL.String(), reqBody)
 	if err != nil {
-		fmt.Printf("%s Error creating request: %v %s\n", RED, err, RESET)
-		return -1, 500
+		logger.Warn("creating request", "err", err)
+		return Result{ErrorClass: ClassClient}, err
 	}
 
 	// Perform the request
 	resp, err := client.Do(req)
+	t.stop()
 	if err != nil {
-		fmt.Printf("%s Error performing request: %v %s\n", RED, err, RESET)
-		return -1, 500
+		logger.Warn("performing request", "err", err)
+		return Result{ErrorClass: classifyError(err), Trace: t.result()}, err
 	}
 	defer resp.Body.Close()
 
-	// Read the response body
-	_, err = io.Copy(io.Discard, resp.Body)
+	// Read the response body, keeping it only if the validator looks at
+	// it, and no further than one byte past a size limit it checks
+	var kept *bytes.Buffer
+	sink := io.Discard
+	var src io.Reader = resp.Body
+	if v, _ := validatorFor(cfg); v != nil {
+		if v.needsBody() {
+			var release func()
+			kept, release = bodyBuffer(cfg)
+			defer release()
+			sink = &limitedWriter{w: kept, n: maxValidatedBody}
+		}
+		if v.maxSize > 0 {
+			src = io.LimitReader(resp.Body, v.maxSize+1)
+		}
+	}
+	size, err := copyBody(cfg, sink, src)
+	t.bodyDone()
 	if err != nil {
-		fmt.Printf("%s Error reading response body: %v %s\n", RED, err, RESET)
-		return -1, resp.StatusCode
+		logger.Warn("reading response body", "err", err)
+		class := classifyError(err)
+		if class == ClassOther {
+			class = ClassRead
+		}
+		return Result{Status: resp.StatusCode, ErrorClass: class, Bytes: size, Trace: t.result()}, err
+	}
+
+	var keptBody []byte
+	if kept != nil {
+		keptBody = kept.Bytes()
 	}
+	return Result{
+		Status:          resp.StatusCode,
+		Bytes:           size,
+		Trace:           t.result(),
+		ValidationError: validate(cfg, resp.StatusCode, keptBody, size),
+	}, nil
+}
+
+// maxValidatedBody caps how much of a body is kept in memory for checks.
+const maxValidatedBody = 1 << 20
 
-	fmt.Printf(".")
+// limitedWriter keeps the first n bytes written and drops the rest.
+type limitedWriter struct {
+	w io.Writer
+	n int64
+}
 
-	return latency, resp.StatusCode
+func (l *limitedWriter) Write(p []byte) (int, error) {
+	if l.n > 0 {
+		keep := p[:min(int64(len(p)), l.n)]
+		l.n -= int64(len(keep))
+		if _, err := l.w.Write(keep); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
 }
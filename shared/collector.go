@@ -0,0 +1,712 @@
+package shared
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aawadall/go-concurrency-patterns/config"
+	"github.com/aawadall/go-concurrency-patterns/shared/leakcheck"
+)
+
+// Collector aggregates Results while a run is in progress. It keeps running
+// statistics and fixed-size histograms rather than the Results themselves,
+// so memory stays constant however many requests are made.
+// A Collector is safe for concurrent use.
+type Collector struct {
+	mu sync.Mutex
+
+	start       time.Time
+	percentiles []float64
+	count       int
+	// Welford's online mean and variance, in nanoseconds
+	mean, m2 float64
+	min, max time.Duration
+
+	errors     int
+	classes    map[ErrorClass]int
+	statuses   map[int]int
+	validation map[string]int
+
+	retried, attempts int
+	latencies         *Histogram
+	firstLatencies    *Histogram
+	// corrected measures from the intended start, in open-loop runs
+	corrected *Histogram
+
+	bytes    int64
+	minBytes int64
+	maxBytes int64
+	sizes    *Histogram
+
+	traced bool
+	phases [len(tracePhases)]*Histogram
+
+	workers map[int]*workerStats
+
+	interval time.Duration
+	timeline Timeline
+
+	inFlight atomic.Int64
+	// recent holds latencies since the live view last redrew
+	recent *Histogram
+
+	slo   SLO
+	sinks []Sink
+
+	// runtime is set while SampleRuntime is running
+	runtime *RuntimeSummary
+
+	stages    []*stageStats
+	endpoints map[string]*stageStats
+
+	// warmup holds the results left out of the statistics while the run
+	// warms up, until warmupRequests results or warmupUntil.
+	warmup         *stageStats
+	warmupRequests int
+	warmupUntil    time.Time
+	warm           bool
+}
+
+// Sink receives every Result recorded by a Collector, e.g. to store or
+// stream raw samples.
+type Sink interface {
+	Record(r Result)
+}
+
+// AddSink passes every Result recorded from now on to s as well. Sinks are
+// called outside the Collector's lock, possibly concurrently.
+func (c *Collector) AddSink(s Sink) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sinks = append(c.sinks, s)
+}
+
+// workerStats tracks the results produced by a single worker.
+type workerStats struct {
+	requests int
+	latency  time.Duration
+	idle     time.Duration
+	lastEnd  time.Time
+}
+
+// stageStats tracks the results of requests started during one stage of
+// a run.
+type stageStats struct {
+	name      string
+	start     time.Time
+	lastEnd   time.Time
+	requests  int
+	failures  int
+	rejected  int
+	latency   time.Duration
+	latencies *Histogram
+}
+
+func (st *stageStats) add(r Result) {
+	st.requests++
+	st.latency += r.Latency
+	st.latencies.RecordDuration(r.Latency)
+	if r.ErrorClass != ClassNone {
+		st.failures++
+	}
+	if r.ErrorClass == ClassRejected {
+		st.rejected++
+	}
+	if r.End.After(st.lastEnd) {
+		st.lastEnd = r.End
+	}
+}
+
+// summary describes the stage, which ran until end, with its start given
+// relative to origin.
+func (st *stageStats) summary(origin, end time.Time) StageSummary {
+	s := StageSummary{
+		Name:     st.name,
+		Start:    st.start.Sub(origin),
+		Duration: max(end.Sub(st.start), 0),
+		Requests: st.requests,
+		Failures: st.failures,
+		P50:      st.latencies.DurationAtPercentile(50),
+		P99:      st.latencies.DurationAtPercentile(99),
+	}
+	if st.requests > 0 {
+		s.Mean = st.latency / time.Duration(st.requests)
+	}
+	if s.Duration > 0 {
+		s.Throughput = float64(st.requests) / s.Duration.Seconds()
+	}
+	return s
+}
+
+// histogramRows is the number of buckets in Summary.Histogram.
+const histogramRows = 10
+
+// NewCollector returns a Collector reporting the given latency
+// percentiles, or config.DefaultPercentiles.
+func NewCollector(percentiles ...float64) *Collector {
+	if len(percentiles) == 0 {
+		percentiles = config.DefaultPercentiles
+	}
+	c := &Collector{
+		start:          time.Now(),
+		percentiles:    slices.Clone(percentiles),
+		classes:        make(map[ErrorClass]int),
+		statuses:       make(map[int]int),
+		validation:     make(map[string]int),
+		latencies:      NewHistogram(),
+		firstLatencies: NewHistogram(),
+		corrected:      NewHistogram(),
+		sizes:          NewHistogram(),
+		workers:        make(map[int]*workerStats),
+		endpoints:      make(map[string]*stageStats),
+		interval:       time.Second,
+		recent:         NewHistogram(),
+	}
+	for i := range c.phases {
+		c.phases[i] = NewHistogram()
+	}
+	return c
+}
+
+// NewCollectorFor returns a Collector set up with the percentiles,
+// timeline interval and SLO in cfg.
+func NewCollectorFor(cfg *config.Config) *Collector {
+	c := NewCollector(cfg.Percentiles...)
+	c.SetInterval(cfg.Interval)
+	c.SetSLO(NewSLO(cfg))
+	return c
+}
+
+// SetSLO sets the objectives checked in Summary.
+func (c *Collector) SetSLO(slo SLO) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.slo = slo
+}
+
+// SetInterval sets the bucket width of the throughput time series. It must
+// be called before any results are recorded.
+func (c *Collector) SetInterval(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if d > 0 {
+		c.interval = d
+	}
+}
+
+// StartWarmup starts a warm-up of the given number of results or
+// duration, whichever is set. Warm-up results are left out of the
+// statistics and passed to no sinks; Summary reports them separately, and
+// measures time from the end of the warm-up.
+func (c *Collector) StartWarmup(requests int, d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	c.warmup = &stageStats{name: "warm-up", start: now, latencies: NewHistogram()}
+	c.warmupRequests = requests
+	if d > 0 {
+		c.warmupUntil = now.Add(d)
+		c.start = c.warmupUntil
+	}
+}
+
+// warmingUp reports whether r belongs to the warm-up. The caller holds
+// c.mu.
+func (c *Collector) warmingUp(r Result) bool {
+	if c.warmup == nil || c.warm {
+		return false
+	}
+	if !c.warmupUntil.IsZero() {
+		return r.Start.Before(c.warmupUntil)
+	}
+	if c.warmup.requests < c.warmupRequests {
+		return true
+	}
+	c.warm = true
+	c.start = time.Now()
+	return false
+}
+
+// StartStage begins a new stage of the run, such as a load profile phase:
+// requests started from now on are also reported under name.
+func (c *Collector) StartStage(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stages = append(c.stages, &stageStats{name: name, start: time.Now(), latencies: NewHistogram()})
+}
+
+// stage returns the stage r started in, or nil. The caller holds c.mu.
+func (c *Collector) stage(r Result) *stageStats {
+	start := r.Start
+	if start.IsZero() {
+		start = time.Now()
+	}
+	for i := len(c.stages) - 1; i >= 0; i-- {
+		if !start.Before(c.stages[i].start) {
+			return c.stages[i]
+		}
+	}
+	return nil
+}
+
+// failures counts results with an error class. The caller holds c.mu.
+func (c *Collector) failures() int {
+	n := 0
+	for _, count := range c.classes {
+		n += count
+	}
+	return n
+}
+
+// Track wraps t so the Collector knows how many requests are in flight.
+func (c *Collector) Track(t Target) Target {
+	return TargetFunc(func(ctx context.Context) (Result, error) {
+		c.inFlight.Add(1)
+		defer c.inFlight.Add(-1)
+		return t.Do(ctx)
+	})
+}
+
+// InFlight returns the number of requests started through a tracked
+// Target that have not finished yet.
+func (c *Collector) InFlight() int64 {
+	return c.inFlight.Load()
+}
+
+// Record adds one result to the statistics.
+func (c *Collector) Record(r Result) {
+	for _, s := range c.record(r) {
+		s.Record(r)
+	}
+}
+
+// record updates the statistics and returns the sinks to pass r on to.
+func (c *Collector) record(r Result) []Sink {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.warmingUp(r) {
+		c.warmup.add(r)
+		return nil
+	}
+
+	c.count++
+	delta := float64(r.Latency) - c.mean
+	c.mean += delta / float64(c.count)
+	c.m2 += delta * (float64(r.Latency) - c.mean)
+	if c.count == 1 || r.Latency < c.min {
+		c.min = r.Latency
+	}
+	if r.Latency > c.max {
+		c.max = r.Latency
+	}
+
+	if r.Err != nil {
+		c.errors++
+	}
+	if r.ErrorClass != ClassNone {
+		c.classes[r.ErrorClass]++
+	}
+	c.statuses[r.Status]++
+	if r.ValidationError != "" {
+		c.validation[r.ValidationError]++
+	}
+
+	c.attempts += r.Attempts
+	if r.Attempts > 1 {
+		c.retried++
+	}
+	c.bytes += r.Bytes
+	if c.count == 1 || r.Bytes < c.minBytes {
+		c.minBytes = r.Bytes
+	}
+	c.maxBytes = max(c.maxBytes, r.Bytes)
+	c.sizes.Record(r.Bytes)
+
+	c.latencies.RecordDuration(r.Latency)
+	c.recent.RecordDuration(r.Latency)
+	if !r.Intended.IsZero() && !r.End.IsZero() {
+		c.corrected.RecordDuration(r.End.Sub(r.Intended))
+	}
+	c.firstLatencies.RecordDuration(r.FirstLatency)
+
+	if r.Trace != (Trace{}) {
+		c.traced = true
+	}
+	for i, phase := range tracePhases {
+		c.phases[i].RecordDuration(phase.get(r.Trace))
+	}
+
+	if !r.End.IsZero() {
+		c.timeline.add(max(int(r.End.Sub(c.start)/c.interval), 0), r.ErrorClass != ClassNone)
+	}
+
+	if st := c.stage(r); st != nil {
+		st.add(r)
+	}
+	if r.Endpoint != "" {
+		e, ok := c.endpoints[r.Endpoint]
+		if !ok {
+			e = &stageStats{name: r.Endpoint, latencies: NewHistogram()}
+			c.endpoints[r.Endpoint] = e
+		}
+		e.add(r)
+	}
+
+	if r.ErrorClass == ClassRejected {
+		// never reached a worker
+		return c.sinks
+	}
+	w, ok := c.workers[r.WorkerID]
+	if !ok {
+		w = &workerStats{}
+		c.workers[r.WorkerID] = w
+	}
+	w.requests++
+	w.latency += r.Latency
+	// time between finishing the previous request and starting this one
+	if !w.lastEnd.IsZero() && r.Start.After(w.lastEnd) {
+		w.idle += r.Start.Sub(w.lastEnd)
+	}
+	if r.End.After(w.lastEnd) {
+		w.lastEnd = r.End
+	}
+	return c.sinks
+}
+
+// Collect records every result received until the channel is closed.
+func (c *Collector) Collect(results <-chan Result) {
+	for r := range results {
+		c.Record(r)
+	}
+}
+
+// Count returns the number of results recorded so far.
+func (c *Collector) Count() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.count
+}
+
+// StartProgress writes a progress line to w every interval until the
+// returned stop function is called.
+func (c *Collector) StartProgress(w io.Writer, interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				c.mu.Lock()
+				count, errors := c.count, c.errors
+				c.mu.Unlock()
+				elapsed := time.Since(c.start)
+				fmt.Fprintf(w, "\n[%v] %d done, %.1f req/s, %d errors\n",
+					elapsed.Round(time.Second), count, float64(count)/elapsed.Seconds(), errors)
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		wg.Wait()
+	}
+}
+
+// Summary is a snapshot of the statistics gathered by a Collector.
+// Durations are encoded in JSON as nanoseconds.
+type Summary struct {
+	Count  int           `json:"count"`
+	Errors int           `json:"errors"`
+	Mean   time.Duration `json:"mean_ns"`
+	StdDev time.Duration `json:"stddev_ns"`
+	Min    time.Duration `json:"min_ns"`
+	Median time.Duration `json:"median_ns"`
+	Max    time.Duration `json:"max_ns"`
+	// Partial is set when the run was interrupted before it finished.
+	Partial bool `json:"partial,omitempty"`
+	// TargetRate is the request rate the run aimed for, per second, or 0
+	// if it wasn't rate limited.
+	TargetRate float64 `json:"target_rate,omitempty"`
+	// Percentiles holds the latency at each requested percentile.
+	Percentiles []Percentile `json:"percentiles"`
+	// Corrected holds the same percentiles measured from each request's
+	// intended start, correcting for coordinated omission. It is only set
+	// in open-loop runs.
+	Corrected []Percentile `json:"corrected,omitempty"`
+	// Histogram spreads latencies over equal-width buckets from Min to Max;
+	// bucket bounds are in nanoseconds.
+	Histogram []HistogramBucket `json:"histogram"`
+	// Timeline counts completions over the course of the run.
+	Timeline Timeline `json:"timeline"`
+
+	// StatusCounts includes status 0 for requests that got no response.
+	StatusCounts       map[int]int        `json:"status_counts"`
+	ErrorClasses       map[ErrorClass]int `json:"error_classes"`
+	ValidationFailures map[string]int     `json:"validation_failures"`
+
+	Transfer TransferSummary `json:"transfer"`
+	Retries  RetrySummary    `json:"retries"`
+	// Phases is empty when the target recorded no phase timings.
+	Phases []PhaseSummary `json:"phases,omitempty"`
+	// Workers is ordered by worker ID.
+	Workers []WorkerSummary `json:"workers"`
+	// Endpoints is empty unless requests were spread over several
+	// endpoints. It is ordered by name.
+	Endpoints []EndpointSummary `json:"endpoints,omitempty"`
+	// Warmup is nil unless the run warmed up first. Its results are
+	// counted there alone.
+	Warmup *StageSummary `json:"warmup,omitempty"`
+	// Stages is empty unless the run was split into stages, e.g. by a
+	// load profile.
+	Stages []StageSummary `json:"stages,omitempty"`
+	// Runtime is nil unless the Go runtime was sampled during the run.
+	Runtime *RuntimeSummary `json:"runtime,omitempty"`
+	// SLO is nil when no objectives were set.
+	SLO *SLOResult `json:"slo,omitempty"`
+	// Leaks lists the goroutines the pattern left running, grouped by
+	// stack.
+	Leaks []leakcheck.Leak `json:"leaks,omitempty"`
+}
+
+// StageSummary describes the requests started during one stage of a run.
+type StageSummary struct {
+	Name string `json:"name"`
+	// Start is the offset from the start of the run.
+	Start      time.Duration `json:"start_ns"`
+	Duration   time.Duration `json:"duration_ns"`
+	Requests   int           `json:"requests"`
+	Failures   int           `json:"failures"`
+	Throughput float64       `json:"throughput"`
+	Mean       time.Duration `json:"mean_ns"`
+	P50        time.Duration `json:"p50_ns"`
+	P99        time.Duration `json:"p99_ns"`
+	// Knee marks the stage with the most throughput per unit of mean
+	// latency; past it, more load mostly adds latency.
+	Knee bool `json:"knee,omitempty"`
+}
+
+// EndpointSummary describes the requests sent to one endpoint.
+type EndpointSummary struct {
+	Name     string        `json:"name"`
+	Requests int           `json:"requests"`
+	Failures int           `json:"failures"`
+	Mean     time.Duration `json:"mean_ns"`
+	P50      time.Duration `json:"p50_ns"`
+	P99      time.Duration `json:"p99_ns"`
+
+	// Rejected counts the failures turned away without being sent.
+	Rejected int `json:"rejected,omitempty"`
+}
+
+// WorkerSummary describes the share of work done by one worker.
+type WorkerSummary struct {
+	ID       int           `json:"id"`
+	Requests int           `json:"requests"`
+	Mean     time.Duration `json:"mean_ns"`
+	// Idle is the time spent between requests, e.g. waiting for work.
+	Idle time.Duration `json:"idle_ns"`
+}
+
+// Percentile is the latency at percentile P (0-100).
+type Percentile struct {
+	P       float64       `json:"p"`
+	Latency time.Duration `json:"latency_ns"`
+}
+
+// TransferSummary describes response body sizes, in bytes.
+type TransferSummary struct {
+	Total int64 `json:"total"`
+	Min   int64 `json:"min"`
+	Mean  int64 `json:"mean"`
+	P50   int64 `json:"p50"`
+	P90   int64 `json:"p90"`
+	P99   int64 `json:"p99"`
+	Max   int64 `json:"max"`
+}
+
+// RetrySummary compares first-attempt latency with total latency.
+type RetrySummary struct {
+	Retried  int           `json:"retried"`
+	Attempts int           `json:"attempts"`
+	FirstP50 time.Duration `json:"first_p50_ns"`
+	FirstP99 time.Duration `json:"first_p99_ns"`
+	TotalP50 time.Duration `json:"total_p50_ns"`
+	TotalP99 time.Duration `json:"total_p99_ns"`
+}
+
+// Timeline is a time series of completed requests, bucketed by Interval
+// from the start of the run.
+type Timeline struct {
+	Interval time.Duration `json:"interval_ns"`
+	Requests []int         `json:"requests"`
+	// Failures counts results with an error class, including non-2xx.
+	Failures []int `json:"failures"`
+}
+
+func (t *Timeline) add(i int, failed bool) {
+	for len(t.Requests) <= i {
+		t.Requests = append(t.Requests, 0)
+		t.Failures = append(t.Failures, 0)
+	}
+	t.Requests[i]++
+	if failed {
+		t.Failures[i]++
+	}
+}
+
+// Rates returns the requests per second in each interval.
+func (t Timeline) Rates() []float64 {
+	rates := make([]float64, len(t.Requests))
+	for i, n := range t.Requests {
+		rates[i] = float64(n) / t.Interval.Seconds()
+	}
+	return rates
+}
+
+// PhaseSummary holds the percentiles of one request phase.
+type PhaseSummary struct {
+	Name string        `json:"name"`
+	P50  time.Duration `json:"p50_ns"`
+	P90  time.Duration `json:"p90_ns"`
+	P99  time.Duration `json:"p99_ns"`
+}
+
+func (c *Collector) Summary() Summary {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s := Summary{
+		Count:              c.count,
+		Errors:             c.errors,
+		Mean:               time.Duration(c.mean),
+		Min:                c.min,
+		Median:             c.latencies.DurationAtPercentile(50),
+		Max:                c.max,
+		StatusCounts:       make(map[int]int, len(c.statuses)),
+		ErrorClasses:       make(map[ErrorClass]int, len(c.classes)),
+		ValidationFailures: make(map[string]int, len(c.validation)),
+		Transfer: TransferSummary{
+			Total: c.bytes,
+			Min:   c.minBytes,
+			P50:   c.sizes.ValueAtPercentile(50),
+			P90:   c.sizes.ValueAtPercentile(90),
+			P99:   c.sizes.ValueAtPercentile(99),
+			Max:   c.maxBytes,
+		},
+		Retries: RetrySummary{
+			Retried:  c.retried,
+			Attempts: c.attempts,
+			FirstP50: c.firstLatencies.DurationAtPercentile(50),
+			FirstP99: c.firstLatencies.DurationAtPercentile(99),
+			TotalP50: c.latencies.DurationAtPercentile(50),
+			TotalP99: c.latencies.DurationAtPercentile(99),
+		},
+	}
+	s.Histogram = c.latencies.Buckets(histogramRows)
+	s.SLO = c.slo.evaluate(c)
+	if c.runtime != nil {
+		rt := *c.runtime
+		rt.Samples = slices.Clone(rt.Samples)
+		s.Runtime = &rt
+	}
+	s.Timeline = Timeline{
+		Interval: c.interval,
+		Requests: slices.Clone(c.timeline.Requests),
+		Failures: slices.Clone(c.timeline.Failures),
+	}
+	for _, p := range c.percentiles {
+		s.Percentiles = append(s.Percentiles, Percentile{P: p, Latency: c.latencies.DurationAtPercentile(p)})
+		if c.corrected.Count() > 0 {
+			s.Corrected = append(s.Corrected, Percentile{P: p, Latency: c.corrected.DurationAtPercentile(p)})
+		}
+	}
+	if c.count > 0 {
+		s.Transfer.Mean = c.bytes / int64(c.count)
+	}
+	if c.count > 1 {
+		s.StdDev = time.Duration(math.Sqrt(c.m2 / float64(c.count-1)))
+	}
+	for k, v := range c.statuses {
+		s.StatusCounts[k] = v
+	}
+	for k, v := range c.classes {
+		s.ErrorClasses[k] = v
+	}
+	for k, v := range c.validation {
+		s.ValidationFailures[k] = v
+	}
+	if c.traced {
+		for i, phase := range tracePhases {
+			s.Phases = append(s.Phases, PhaseSummary{
+				Name: phase.name,
+				P50:  c.phases[i].DurationAtPercentile(50),
+				P90:  c.phases[i].DurationAtPercentile(90),
+				P99:  c.phases[i].DurationAtPercentile(99),
+			})
+		}
+	}
+	for id, w := range c.workers {
+		s.Workers = append(s.Workers, WorkerSummary{
+			ID:       id,
+			Requests: w.requests,
+			Mean:     w.latency / time.Duration(w.requests),
+			Idle:     w.idle,
+		})
+	}
+	slices.SortFunc(s.Workers, func(a, b WorkerSummary) int { return a.ID - b.ID })
+	s.Stages = c.stageSummaries()
+	for _, name := range slices.Sorted(maps.Keys(c.endpoints)) {
+		e := c.endpoints[name]
+		st := e.summary(c.start, c.start)
+		s.Endpoints = append(s.Endpoints, EndpointSummary{
+			Name: name, Requests: st.Requests, Failures: st.Failures, Rejected: e.rejected,
+			Mean: st.Mean, P50: st.P50, P99: st.P99,
+		})
+	}
+	if c.warmup != nil {
+		end := c.start
+		if !c.warm && c.warmupUntil.IsZero() {
+			end = c.warmup.lastEnd // the run ended during the warm-up
+		}
+		warmup := c.warmup.summary(c.warmup.start, end)
+		s.Warmup = &warmup
+	}
+	return s
+}
+
+// stageSummaries summarizes each stage and marks the knee. The caller
+// holds c.mu.
+func (c *Collector) stageSummaries() []StageSummary {
+	var stages []StageSummary
+	knee, best := -1, 0.0
+	for i, st := range c.stages {
+		end := st.lastEnd
+		if i+1 < len(c.stages) {
+			end = c.stages[i+1].start
+		}
+		summary := st.summary(c.start, end)
+		if summary.Mean > 0 {
+			if power := summary.Throughput / summary.Mean.Seconds(); power > best {
+				knee, best = i, power
+			}
+		}
+		stages = append(stages, summary)
+	}
+	if len(stages) > 1 && knee >= 0 {
+		stages[knee].Knee = true
+	}
+	return stages
+}
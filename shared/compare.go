@@ -0,0 +1,136 @@
+package shared
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// LoadManifest reads a result file written by WriteManifest. JSON reports
+// written to stdout with the json format can be loaded too, though they
+// carry no pattern, config or environment.
+func LoadManifest(path string) (Manifest, error) {
+	var m Manifest
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return m, err
+	}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return m, fmt.Errorf("%s: %w", path, err)
+	}
+	if m.RequestsPerSecond == 0 {
+		m.RequestsPerSecond = requestsPerSecond(m.Summary.Count, m.TotalTime)
+	}
+	return m, nil
+}
+
+// metric is one row of a comparison.
+type metric struct {
+	name string
+	// higherIsBetter is true for throughput, false for latency and cost
+	higherIsBetter bool
+	get            func(Manifest) float64
+	format         func(float64) string
+}
+
+func compareMetrics(baseline Manifest) []metric {
+	duration := func(v float64) string { return time.Duration(v).String() }
+	number := func(v float64) string { return fmt.Sprintf("%.2f", v) }
+	percent := func(v float64) string { return fmt.Sprintf("%.2f%%", v*100) }
+	latency := func(name string, get func(Summary) time.Duration) metric {
+		return metric{name, false, func(m Manifest) float64 { return float64(get(m.Summary)) }, duration}
+	}
+
+	metrics := []metric{
+		{"requests/s", true, func(m Manifest) float64 { return m.RequestsPerSecond }, number},
+		{"error rate", false, errorRate, percent},
+		latency("mean", func(s Summary) time.Duration { return s.Mean }),
+		latency("min", func(s Summary) time.Duration { return s.Min }),
+	}
+	for _, p := range baseline.Summary.Percentiles {
+		metrics = append(metrics, latency(formatPercentile(p.P), func(s Summary) time.Duration {
+			for _, q := range s.Percentiles {
+				if q.P == p.P {
+					return q.Latency
+				}
+			}
+			return 0
+		}))
+	}
+	metrics = append(metrics, latency("max", func(s Summary) time.Duration { return s.Max }))
+	for _, key := range []string{"TotalAlloc", "Sys"} {
+		metrics = append(metrics, metric{key, false,
+			func(m Manifest) float64 { return float64(m.Memory[key]) },
+			func(v float64) string { return formatBytes(int64(v)) }})
+	}
+	return metrics
+}
+
+// errorRate counts every failed request, including non-2xx responses.
+func errorRate(m Manifest) float64 {
+	if m.Summary.Count == 0 {
+		return 0
+	}
+	failed := 0
+	for _, n := range m.Summary.ErrorClasses {
+		failed += n
+	}
+	return float64(failed) / float64(m.Summary.Count)
+}
+
+// Compare prints the runs side by side, each with its change relative to
+// the first, and returns the number of metrics that got worse by more
+// than threshold (a fraction, e.g. 0.05 for 5%). Regressions are marked
+// with "!".
+func Compare(w io.Writer, labels []string, runs []Manifest, threshold float64) int {
+	if len(runs) == 0 {
+		return 0
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "metric\t%s\t\n", strings.Join(labels, "\t"))
+
+	regressions := 0
+	for _, m := range compareMetrics(runs[0]) {
+		base := m.get(runs[0])
+		cells := []string{m.format(base)}
+		for _, run := range runs[1:] {
+			v := m.get(run)
+			change := relativeChange(base, v)
+			worse := change > threshold
+			if m.higherIsBetter {
+				worse = -change > threshold
+			}
+			mark := ""
+			if worse {
+				mark = " !"
+				regressions++
+			}
+			cells = append(cells, fmt.Sprintf("%s (%s)%s", m.format(v), formatChange(change), mark))
+		}
+		fmt.Fprintf(tw, "%s\t%s\t\n", m.name, strings.Join(cells, "\t"))
+	}
+	tw.Flush()
+	return regressions
+}
+
+func relativeChange(base, v float64) float64 {
+	switch {
+	case base == v:
+		return 0
+	case base == 0:
+		return math.Copysign(math.Inf(1), v)
+	}
+	return (v - base) / base
+}
+
+func formatChange(change float64) string {
+	if math.IsInf(change, 0) {
+		return "new"
+	}
+	return fmt.Sprintf("%+.1f%%", change*100)
+}
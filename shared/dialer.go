@@ -0,0 +1,70 @@
+package shared
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/aawadall/go-concurrency-patterns/config"
+)
+
+// dialFunc matches http.Transport.DialContext.
+type dialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// newDialer builds the dial function for a config: a Unix socket, a fixed
+// source port range, and/or a custom DNS resolver. It returns nil if the
+// config needs none of these, so the default dialer is used.
+func newDialer(cfg *config.Config) (dialFunc, error) {
+	if cfg.UnixSocket == "" && cfg.LocalPortMin == 0 && cfg.Resolver == "" {
+		return nil, nil
+	}
+
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+
+	if cfg.UnixSocket != "" {
+		// every address resolves to the socket
+		return func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "unix", cfg.UnixSocket)
+		}, nil
+	}
+
+	if cfg.Resolver != "" {
+		dialer.Resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, network, cfg.Resolver)
+			},
+		}
+	}
+
+	if cfg.LocalPortMin == 0 {
+		return dialer.DialContext, nil
+	}
+	if cfg.LocalPortMax < cfg.LocalPortMin {
+		return nil, fmt.Errorf("invalid local port range %d-%d", cfg.LocalPortMin, cfg.LocalPortMax)
+	}
+
+	ports := cfg.LocalPortMax - cfg.LocalPortMin + 1
+	var next atomic.Int64
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		// walk the range from where the last dial left off, skipping
+		// ports that are still in use
+		var err error
+		for range ports {
+			port := cfg.LocalPortMin + int(next.Add(1)-1)%ports
+			d := *dialer
+			d.LocalAddr = &net.TCPAddr{Port: port}
+			var conn net.Conn
+			conn, err = d.DialContext(ctx, network, addr)
+			if err == nil || !errors.Is(err, syscall.EADDRINUSE) {
+				return conn, err
+			}
+		}
+		return nil, fmt.Errorf("no free source port in %d-%d: %w", cfg.LocalPortMin, cfg.LocalPortMax, err)
+	}, nil
+}
@@ -0,0 +1,43 @@
+package shared
+
+import (
+	"context"
+	"time"
+)
+
+// Drain wraps t so that calls in flight when stop is done get up to grace
+// to finish instead of being canceled with it, letting a run that is
+// stopped early keep the results of the requests it already sent. A grace
+// of 0 cancels them along with stop. Calls whose own context is canceled
+// for any other reason are canceled at once, and a deadline of their own,
+// earlier than stop's, still holds.
+func Drain(t Target, stop context.Context, grace time.Duration) Target {
+	if grace <= 0 {
+		return t
+	}
+	hard, cancel := context.WithCancel(context.WithoutCancel(stop))
+	context.AfterFunc(stop, func() {
+		time.AfterFunc(grace, cancel)
+	})
+	return TargetFunc(func(ctx context.Context) (Result, error) {
+		call, cancelCall := context.WithCancel(context.WithoutCancel(ctx))
+		if d, ok := ctx.Deadline(); ok && ownDeadline(d, stop) {
+			call, cancelCall = context.WithDeadline(context.WithoutCancel(ctx), d)
+		}
+		defer cancelCall()
+		defer context.AfterFunc(hard, cancelCall)()
+		defer context.AfterFunc(ctx, func() {
+			if stop.Err() == nil {
+				cancelCall()
+			}
+		})()
+		return t.Do(call)
+	})
+}
+
+// ownDeadline tells whether d is a call's own deadline rather than the one
+// it inherited from stop, which the grace is there to outlast.
+func ownDeadline(d time.Time, stop context.Context) bool {
+	sd, ok := stop.Deadline()
+	return !ok || d.Before(sd)
+}
@@ -0,0 +1,69 @@
+package shared
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// waitTarget blocks every call until its context is done.
+var waitTarget = TargetFunc(func(ctx context.Context) (Result, error) {
+	<-ctx.Done()
+	return Result{Err: ctx.Err()}, ctx.Err()
+})
+
+func TestDrain(t *testing.T) {
+	tests := []struct {
+		name string
+		// stopIn is when the run is stopped, by its deadline if
+		// stopDeadline
+		stopIn       time.Duration
+		stopDeadline bool
+		// callTimeout is the call's own deadline, if any
+		callTimeout time.Duration
+		grace       time.Duration
+		// the call ends about this long after it starts, by its own
+		// deadline if wantDeadline
+		want         time.Duration
+		wantDeadline bool
+	}{
+		{name: "grace after stop", stopIn: 20 * time.Millisecond, grace: 50 * time.Millisecond, want: 70 * time.Millisecond},
+		{name: "grace after stop's deadline", stopIn: 20 * time.Millisecond, stopDeadline: true, grace: 50 * time.Millisecond, want: 70 * time.Millisecond},
+		{name: "own deadline during grace", stopIn: 20 * time.Millisecond, callTimeout: 40 * time.Millisecond, grace: time.Second, want: 40 * time.Millisecond, wantDeadline: true},
+		{name: "own deadline before stop", stopIn: 200 * time.Millisecond, stopDeadline: true, callTimeout: 30 * time.Millisecond, grace: time.Second, want: 30 * time.Millisecond},
+		{name: "no grace", stopIn: 20 * time.Millisecond, want: 20 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stop context.Context
+			var cancel context.CancelFunc
+			if tt.stopDeadline {
+				stop, cancel = context.WithTimeout(context.Background(), tt.stopIn)
+			} else {
+				stop, cancel = context.WithCancel(context.Background())
+				time.AfterFunc(tt.stopIn, cancel)
+			}
+			defer cancel()
+			ctx := stop
+			if tt.callTimeout > 0 {
+				var cancelCall context.CancelFunc
+				ctx, cancelCall = context.WithTimeout(ctx, tt.callTimeout)
+				defer cancelCall()
+			}
+
+			start := time.Now()
+			_, err := Drain(waitTarget, stop, tt.grace).Do(ctx)
+			took := time.Since(start)
+			if err == nil {
+				t.Fatal("the call wasn't canceled")
+			}
+			if tt.wantDeadline && !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("got error %v, want the call's deadline", err)
+			}
+			if took < tt.want-5*time.Millisecond || took > tt.want+30*time.Millisecond {
+				t.Errorf("the call ended after %v, want about %v", took, tt.want)
+			}
+		})
+	}
+}
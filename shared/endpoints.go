@@ -0,0 +1,78 @@
+package shared
+
+import (
+	"context"
+	"math/rand"
+	"sort"
+
+	"github.com/aawadall/go-concurrency-patterns/config"
+)
+
+// EndpointPicker picks one of cfg.Endpoints at random in proportion to
+// their weights.
+type EndpointPicker struct {
+	// cumulative holds the running total of the weights
+	cumulative []float64
+	rng        *rand.Rand
+}
+
+func NewEndpointPicker(cfg *config.Config) *EndpointPicker {
+	p := &EndpointPicker{rng: newRand(cfg.Seed, streamEndpoints)}
+	total := 0.0
+	for _, e := range cfg.Endpoints {
+		total += e.Weight
+		p.cumulative = append(p.cumulative, total)
+	}
+	return p
+}
+
+// Pick returns the index of the endpoint picked.
+func (p *EndpointPicker) Pick() int {
+	x := p.rng.Float64() * p.cumulative[len(p.cumulative)-1]
+	return min(sort.SearchFloat64s(p.cumulative, x), len(p.cumulative)-1)
+}
+
+type endpointKey struct{}
+
+// WithEndpoint tags ctx so that a run spread over several endpoints sends
+// the request to cfg.Endpoints[i] instead of picking one.
+func WithEndpoint(ctx context.Context, i int) context.Context {
+	return context.WithValue(ctx, endpointKey{}, i)
+}
+
+// weightedTarget sends each call to one of several targets, picked at
+// random in proportion to their weights unless the context names one.
+type weightedTarget struct {
+	targets []Target
+	names   []string
+	picker  *EndpointPicker
+}
+
+// newWeightedTarget returns a Target spreading calls over cfg.Endpoints,
+// each configured as cfg with the endpoint's settings in place.
+func newWeightedTarget(cfg *config.Config) (Target, error) {
+	w := &weightedTarget{picker: NewEndpointPicker(cfg)}
+	for _, e := range cfg.Endpoints {
+		c := *cfg
+		c.Endpoints = nil
+		c.Scheme, c.Host, c.Port = e.Scheme, e.Host, e.Port
+		c.Method, c.Path, c.Body = e.Method, e.Path, e.Body
+		t, err := NewTarget(&c)
+		if err != nil {
+			return nil, err
+		}
+		w.targets = append(w.targets, t)
+		w.names = append(w.names, e.Name())
+	}
+	return w, nil
+}
+
+func (w *weightedTarget) Do(ctx context.Context) (Result, error) {
+	i, ok := ctx.Value(endpointKey{}).(int)
+	if !ok || i < 0 || i >= len(w.targets) {
+		i = w.picker.Pick()
+	}
+	r, err := w.targets[i].Do(ctx)
+	r.Endpoint = w.names[i]
+	return r, err
+}
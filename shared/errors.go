@@ -0,0 +1,85 @@
+package shared
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"syscall"
+	"time"
+)
+
+// ErrorClass says why a request failed. The zero value means it didn't.
+type ErrorClass string
+
+const (
+	ClassNone        ErrorClass = ""
+	ClassClient      ErrorClass = "client"
+	ClassDNS         ErrorClass = "dns"
+	ClassConnRefused ErrorClass = "connection refused"
+	ClassConnect     ErrorClass = "connect"
+	ClassTimeout     ErrorClass = "timeout"
+	ClassCanceled    ErrorClass = "canceled"
+	ClassTLS         ErrorClass = "tls"
+	ClassRead        ErrorClass = "read"
+	ClassNon2xx      ErrorClass = "non-2xx"
+	ClassRejected    ErrorClass = "rejected"
+	ClassOther       ErrorClass = "other"
+)
+
+// ErrRejected is the error of a request the client turned away without
+// sending, e.g. because a queue was full.
+var ErrRejected = errors.New("request rejected")
+
+// Rejected returns the Result of a request turned away at once.
+func Rejected(ctx context.Context) Result {
+	now := time.Now()
+	return Result{
+		Err: ErrRejected, ErrorClass: ClassRejected,
+		Start: now, End: now, WorkerID: WorkerID(ctx),
+	}
+}
+
+// classifyError maps a network error onto an ErrorClass.
+func classifyError(err error) ErrorClass {
+	if err == nil {
+		return ClassNone
+	}
+
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	var certErr *tls.CertificateVerificationError
+	var headerErr tls.RecordHeaderError
+	var alertErr tls.AlertError
+	var authorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var opErr *net.OpError
+
+	switch {
+	case errors.Is(err, ErrRejected):
+		return ClassRejected
+	case errors.Is(err, context.Canceled):
+		return ClassCanceled
+	case errors.Is(err, context.DeadlineExceeded),
+		errors.As(err, &netErr) && netErr.Timeout():
+		return ClassTimeout
+	case errors.As(err, &dnsErr):
+		return ClassDNS
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return ClassConnRefused
+	case errors.As(err, &certErr), errors.As(err, &headerErr), errors.As(err, &alertErr),
+		errors.As(err, &authorityErr), errors.As(err, &hostnameErr):
+		return ClassTLS
+	case errors.As(err, &opErr) && opErr.Op == "dial":
+		return ClassConnect
+	default:
+		return ClassOther
+	}
+}
+
+// retryable reports whether a failure of this class may succeed if tried
+// again; bad requests and certificate problems won't.
+func (c ErrorClass) retryable() bool {
+	return c != ClassClient && c != ClassTLS && c != ClassCanceled && c != ClassRejected
+}
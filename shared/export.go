@@ -0,0 +1,195 @@
+package shared
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	exportBuffer   = 10000
+	exportBatch    = 500
+	exportInterval = time.Second
+	// maxDatagram keeps UDP packets within a typical MTU
+	maxDatagram = 1400
+)
+
+// Exporter streams per-request samples to an observability backend while
+// the run is going. The URL scheme picks the protocol:
+//
+//	statsd://host:8125             statsd over UDP
+//	influx://host:8089             InfluxDB line protocol over UDP
+//	http://host:8086/api/v2/write?org=o&bucket=b
+//	                               InfluxDB line protocol over HTTP
+//
+// Samples are buffered and sent in batches; when the backend can't keep up
+// they are dropped rather than slowing the run down.
+type Exporter struct {
+	pattern string
+	format  func(b *bytes.Buffer, pattern string, r Result)
+	send    func(batch []byte) error
+
+	samples chan Result
+	done    chan struct{}
+	dropped atomic.Int64
+}
+
+func NewExporter(rawURL, pattern string) (*Exporter, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	e := &Exporter{
+		pattern: pattern,
+		samples: make(chan Result, exportBuffer),
+		done:    make(chan struct{}),
+	}
+
+	switch u.Scheme {
+	case "statsd", "influx":
+		conn, err := net.Dial("udp", u.Host)
+		if err != nil {
+			return nil, err
+		}
+		e.send = datagramSender(conn)
+		e.format = formatLineProtocol
+		if u.Scheme == "statsd" {
+			e.format = formatStatsd
+		}
+	case "http", "https":
+		e.send = influxHTTPSender(u.String())
+		e.format = formatLineProtocol
+	default:
+		return nil, fmt.Errorf("unknown exporter scheme %q", u.Scheme)
+	}
+
+	go e.run()
+	return e, nil
+}
+
+// Record queues one sample for export.
+func (e *Exporter) Record(r Result) {
+	select {
+	case e.samples <- r:
+	default:
+		e.dropped.Add(1)
+	}
+}
+
+// Close flushes the queued samples. Send failures are logged rather than
+// returned, as they shouldn't fail the run. No samples may be recorded
+// after Close.
+func (e *Exporter) Close() {
+	close(e.samples)
+	<-e.done
+	if n := e.dropped.Load(); n > 0 {
+		logger.Warn("exporter dropped samples", "count", n)
+	}
+}
+
+func (e *Exporter) run() {
+	defer close(e.done)
+	var buf bytes.Buffer
+	pending := 0
+	flush := func() {
+		if pending == 0 {
+			return
+		}
+		if err := e.send(buf.Bytes()); err != nil {
+			logger.Warn("exporting samples", "err", err)
+		}
+		buf.Reset()
+		pending = 0
+	}
+
+	ticker := time.NewTicker(exportInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case r, ok := <-e.samples:
+			if !ok {
+				flush()
+				return
+			}
+			e.format(&buf, e.pattern, r)
+			pending++
+			if pending >= exportBatch {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+func formatLineProtocol(b *bytes.Buffer, pattern string, r Result) {
+	class := string(r.ErrorClass)
+	if class == "" {
+		class = "none"
+	}
+	fmt.Fprintf(b, "gcp_request,pattern=%s,status=%d,class=%s latency_ns=%di,bytes=%di,worker=%di %d\n",
+		escapeTag(pattern), r.Status, escapeTag(class), int64(r.Latency), r.Bytes, r.WorkerID, r.End.UnixNano())
+}
+
+func formatStatsd(b *bytes.Buffer, pattern string, r Result) {
+	prefix := "gcp." + pattern
+	fmt.Fprintf(b, "%s.latency:%s|ms\n", prefix, strconv.FormatFloat(float64(r.Latency)/float64(time.Millisecond), 'f', 3, 64))
+	fmt.Fprintf(b, "%s.status.%d:1|c\n", prefix, r.Status)
+	if r.ErrorClass != ClassNone {
+		fmt.Fprintf(b, "%s.failures.%s:1|c\n", prefix, r.ErrorClass)
+	}
+}
+
+// escapeTag escapes the characters line protocol treats specially in tag
+// values.
+func escapeTag(s string) string {
+	var b bytes.Buffer
+	for _, c := range s {
+		if c == ',' || c == '=' || c == ' ' {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// datagramSender splits a batch into packets on line boundaries.
+func datagramSender(conn net.Conn) func([]byte) error {
+	return func(batch []byte) error {
+		for len(batch) > 0 {
+			n := len(batch)
+			if n > maxDatagram {
+				n = bytes.LastIndexByte(batch[:maxDatagram], '\n') + 1
+				if n == 0 {
+					n = bytes.IndexByte(batch, '\n') + 1
+				}
+			}
+			if _, err := conn.Write(batch[:n]); err != nil {
+				return err
+			}
+			batch = batch[n:]
+		}
+		return nil
+	}
+}
+
+func influxHTTPSender(endpoint string) func([]byte) error {
+	client := &http.Client{Timeout: 10 * time.Second}
+	return func(batch []byte) error {
+		resp, err := client.Post(endpoint, "text/plain; charset=utf-8", bytes.NewReader(batch))
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode/100 != 2 {
+			return errors.New("influx write: " + resp.Status)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,71 @@
+package shared
+
+import (
+	"context"
+	"sync"
+)
+
+// LimitInFlight wraps t so that at most n calls, shared by all callers,
+// are in flight at once; the rest wait their turn.
+func LimitInFlight(t Target, n int) Target {
+	g := newGate()
+	g.setLimit(n)
+	return TargetFunc(func(ctx context.Context) (Result, error) {
+		if err := g.enter(ctx); err != nil {
+			return Result{Err: err, ErrorClass: classifyError(err)}, err
+		}
+		defer g.leave()
+		return t.Do(ctx)
+	})
+}
+
+// gate lets a changing number of callers through at once.
+type gate struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	limit  int
+	active int
+}
+
+func newGate() *gate {
+	g := &gate{limit: 1}
+	g.cond = sync.NewCond(&g.mu)
+	return g
+}
+
+// enter waits for a free slot, or until ctx is done. If it takes a slot,
+// leave must be called to give it back.
+func (g *gate) enter(ctx context.Context) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	stop := context.AfterFunc(ctx, func() {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		g.cond.Broadcast()
+	})
+	defer stop()
+	for g.active >= g.limit {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		g.cond.Wait()
+	}
+	g.active++
+	return nil
+}
+
+func (g *gate) leave() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.active--
+	g.cond.Broadcast()
+}
+
+func (g *gate) setLimit(n int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if n > g.limit {
+		g.cond.Broadcast()
+	}
+	g.limit = n
+}
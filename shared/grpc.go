@@ -0,0 +1,140 @@
+package shared
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/aawadall/go-concurrency-patterns/config"
+	"github.com/aawadall/go-concurrency-patterns/shared/grpcbench"
+)
+
+// ConsumeGRPC performs a single unary gRPC call against the configured
+// service, regardless of cfg.Mode.
+func ConsumeGRPC(cfg *config.Config) (latency time.Duration, status int) {
+	r, _ := fetch(context.Background(), cfg, 0, doGRPC)
+	return r.Latency, r.Status
+}
+
+// grpcConns caches one client connection per connection settings.
+var grpcConns sync.Map
+
+func grpcConnFor(cfg *config.Config) (*grpc.ClientConn, error) {
+	key := connKeyOf(cfg)
+	if c, ok := grpcConns.Load(key); ok {
+		return c.(*grpc.ClientConn), nil
+	}
+
+	creds := insecure.NewCredentials()
+	if cfg.Scheme == "https" {
+		tlsConfig, err := newTLSConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
+		creds = credentials.NewTLS(tlsConfig)
+	}
+
+	opts := []grpc.DialOption{grpc.WithTransportCredentials(creds)}
+	dial, err := newDialer(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if dial != nil {
+		opts = append(opts, grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+			return dial(ctx, "tcp", addr)
+		}))
+	}
+
+	target := net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port))
+	conn, err := grpc.NewClient(target, opts...)
+	if err != nil {
+		return nil, err
+	}
+	actual, loaded := grpcConns.LoadOrStore(key, conn)
+	if loaded {
+		conn.Close()
+	}
+	return actual.(*grpc.ClientConn), nil
+}
+
+// doGRPC performs one gRPC attempt. The body template is sent as the
+// request value; the status code is mapped onto its HTTP equivalent so the
+// report reads the same for both modes.
+func doGRPC(ctx context.Context, cfg *config.Config, _ int, _, body string) (Result, error) {
+	conn, err := grpcConnFor(cfg)
+	if err != nil {
+		logger.Warn("creating gRPC client", "err", err)
+		return Result{ErrorClass: ClassClient}, err
+	}
+
+	method := cfg.GRPCMethod
+	if method == "" {
+		method = grpcbench.CallMethod
+	}
+
+	start := time.Now()
+	out := new(wrapperspb.BytesValue)
+	err = conn.Invoke(ctx, method, wrapperspb.String(body), out)
+	trace := Trace{TTFB: time.Since(start)}
+	if err != nil {
+		st := status.Convert(err)
+		switch st.Code() {
+		case codes.Unavailable:
+			// the call never reached a server
+			logger.Warn("performing gRPC call", "err", err)
+			return Result{ErrorClass: ClassConnect, Trace: trace}, err
+		case codes.DeadlineExceeded:
+			return Result{ErrorClass: ClassTimeout, Trace: trace}, err
+		case codes.Canceled:
+			return Result{ErrorClass: ClassCanceled, Trace: trace}, err
+		}
+		status := grpcHTTPStatus(st.Code())
+		return Result{Status: status, Trace: trace, ValidationError: validate(cfg, status, nil, 0)}, nil
+	}
+	return Result{
+		Status:          http.StatusOK,
+		Bytes:           int64(len(out.Value)),
+		Trace:           trace,
+		ValidationError: validate(cfg, http.StatusOK, out.Value, int64(len(out.Value))),
+	}, nil
+}
+
+// grpcHTTPStatus maps a gRPC status code to the closest HTTP status.
+func grpcHTTPStatus(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return 499
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,65 @@
+// Package grpcbench provides the gRPC benchmark service described in
+// bench.proto, along with a simple server implementation.
+package grpcbench
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// CallMethod is the full method name of Bench.Call.
+const CallMethod = "/gcp.bench.Bench/Call"
+
+// BenchServer is the server API for the Bench service.
+type BenchServer interface {
+	Call(context.Context, *wrapperspb.StringValue) (*wrapperspb.BytesValue, error)
+}
+
+// ServiceDesc describes the Bench service for grpc.Server.RegisterService.
+var ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "gcp.bench.Bench",
+	HandlerType: (*BenchServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "Call", Handler: callHandler},
+	},
+	Metadata: "bench.proto",
+}
+
+func callHandler(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+	in := new(wrapperspb.StringValue)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BenchServer).Call(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: CallMethod}
+	handler := func(ctx context.Context, req any) (any, error) {
+		return srv.(BenchServer).Call(ctx, req.(*wrapperspb.StringValue))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Register registers srv with the gRPC server s.
+func Register(s grpc.ServiceRegistrar, srv BenchServer) {
+	s.RegisterService(&ServiceDesc, srv)
+}
+
+// Server is a BenchServer that sleeps for Latency and returns PayloadSize
+// bytes.
+type Server struct {
+	Latency     time.Duration
+	PayloadSize int
+}
+
+func (s *Server) Call(ctx context.Context, _ *wrapperspb.StringValue) (*wrapperspb.BytesValue, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(s.Latency):
+	}
+	return wrapperspb.Bytes(make([]byte, s.PayloadSize)), nil
+}
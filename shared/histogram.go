@@ -0,0 +1,166 @@
+package shared
+
+import (
+	"math/bits"
+	"time"
+)
+
+// Histogram records non-negative values in log-linear buckets, in the
+// style of an HDR histogram: values below 128 are exact, larger values are
+// kept to within 1/64 (about 1.6%) of their magnitude. Memory is fixed at a
+// few thousand counters no matter how many values are recorded.
+// A Histogram is not safe for concurrent use.
+type Histogram struct {
+	counts   [histogramBuckets]uint64
+	total    uint64
+	sum      float64
+	min, max int64
+}
+
+const (
+	subBucketBits    = 7
+	subBuckets       = 1 << subBucketBits // exact values below this
+	halfSubBuckets   = subBuckets / 2
+	histogramBuckets = subBuckets + (64-subBucketBits)*halfSubBuckets
+)
+
+func NewHistogram() *Histogram {
+	return &Histogram{}
+}
+
+// bucketOf returns the index of the bucket holding v.
+func bucketOf(v int64) int {
+	if v < subBuckets {
+		return int(v)
+	}
+	shift := bits.Len64(uint64(v)) - subBucketBits
+	return subBuckets + (shift-1)*halfSubBuckets + int(v>>shift) - halfSubBuckets
+}
+
+// bucketRange returns the smallest and largest values in bucket i.
+func bucketRange(i int) (lo, hi int64) {
+	if i < subBuckets {
+		return int64(i), int64(i)
+	}
+	shift := (i-subBuckets)/halfSubBuckets + 1
+	top := int64((i-subBuckets)%halfSubBuckets + halfSubBuckets)
+	lo = top << shift
+	return lo, lo + (1 << shift) - 1
+}
+
+// Record adds a value; negative values are recorded as 0.
+func (h *Histogram) Record(v int64) {
+	v = max(v, 0)
+	h.counts[bucketOf(v)]++
+	if h.total == 0 || v < h.min {
+		h.min = v
+	}
+	h.max = max(h.max, v)
+	h.total++
+	h.sum += float64(v)
+}
+
+// RecordDuration records d in nanoseconds.
+func (h *Histogram) RecordDuration(d time.Duration) {
+	h.Record(int64(d))
+}
+
+func (h *Histogram) Count() uint64 { return h.total }
+func (h *Histogram) Min() int64    { return h.min }
+func (h *Histogram) Max() int64    { return h.max }
+func (h *Histogram) Sum() float64  { return h.sum }
+
+func (h *Histogram) Mean() float64 {
+	if h.total == 0 {
+		return 0
+	}
+	return h.sum / float64(h.total)
+}
+
+// ValueAtPercentile returns the value at percentile p (0-100), using the
+// midpoint of the bucket it falls in.
+func (h *Histogram) ValueAtPercentile(p float64) int64 {
+	if h.total == 0 {
+		return 0
+	}
+	rank := uint64(p/100*float64(h.total) + 0.5)
+	rank = min(max(rank, 1), h.total)
+
+	var seen uint64
+	for i, n := range h.counts {
+		seen += n
+		if seen >= rank {
+			lo, hi := bucketRange(i)
+			return min(max(lo+(hi-lo)/2, h.min), h.max)
+		}
+	}
+	return h.max
+}
+
+// CountAtOrBelow returns how many recorded values are at most v, to
+// within the precision of the bucket holding v.
+func (h *Histogram) CountAtOrBelow(v int64) uint64 {
+	if v < 0 {
+		return 0
+	}
+	var n uint64
+	for _, count := range h.counts[:bucketOf(v)+1] {
+		n += count
+	}
+	return n
+}
+
+// DurationAtPercentile is ValueAtPercentile for histograms of durations.
+func (h *Histogram) DurationAtPercentile(p float64) time.Duration {
+	return time.Duration(h.ValueAtPercentile(p))
+}
+
+// Merge adds all values recorded in other to h.
+func (h *Histogram) Merge(other *Histogram) {
+	if other.total == 0 {
+		return
+	}
+	for i, n := range other.counts {
+		h.counts[i] += n
+	}
+	if h.total == 0 || other.min < h.min {
+		h.min = other.min
+	}
+	h.max = max(h.max, other.max)
+	h.total += other.total
+	h.sum += other.sum
+}
+
+// HistogramBucket counts the values up to and including Upper.
+type HistogramBucket struct {
+	Upper int64  `json:"upper"`
+	Count uint64 `json:"count"`
+}
+
+// Buckets spreads the recorded values over n equal-width buckets between
+// the minimum and the maximum, for display.
+func (h *Histogram) Buckets(n int) []HistogramBucket {
+	if h.total == 0 || n <= 0 {
+		return nil
+	}
+	width := float64(h.max-h.min) / float64(n)
+	out := make([]HistogramBucket, n)
+	for i := range out {
+		out[i].Upper = h.min + int64(width*float64(i+1))
+	}
+	out[n-1].Upper = h.max
+
+	for i, count := range h.counts {
+		if count == 0 {
+			continue
+		}
+		lo, hi := bucketRange(i)
+		v := min(max(lo+(hi-lo)/2, h.min), h.max)
+		j := n - 1
+		if width > 0 {
+			j = min(int(float64(v-h.min)/width), n-1)
+		}
+		out[j].Count += count
+	}
+	return out
+}
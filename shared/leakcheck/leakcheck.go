@@ -0,0 +1,162 @@
+// Package leakcheck finds goroutines left running by a piece of code:
+// take a Snapshot before it runs and call Check after, and any goroutine
+// started in between that is still there, once given a moment to finish,
+// is reported with its stack.
+package leakcheck
+
+import (
+	"cmp"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ignored lists functions whose goroutines outlive a run on purpose, such
+// as the connections kept open by HTTP and gRPC clients.
+var ignored = []string{
+	"net/http.(*persistConn)",
+	"net/http.(*http2ClientConn)",
+	"net/http.(*Transport).dialConnFor",
+	"google.golang.org/grpc",
+	"github.com/quic-go",
+}
+
+// Leak is a group of leaked goroutines with the same stack.
+type Leak struct {
+	Count int    `json:"count"`
+	State string `json:"state"`
+	Stack string `json:"stack"`
+}
+
+// Goroutine is one goroutine of a stack dump.
+type Goroutine struct {
+	ID int
+	// State is what it is doing, e.g. "chan receive", without how long
+	// it has been at it.
+	State string
+	// Stack has its frames without argument values, so that goroutines
+	// doing the same thing have the same stack.
+	Stack string
+}
+
+// Snapshot records the goroutines running at one moment.
+type Snapshot struct {
+	ids map[int]bool
+}
+
+// Take snapshots the goroutines running now.
+func Take() Snapshot {
+	s := Snapshot{ids: make(map[int]bool)}
+	for _, g := range Goroutines() {
+		s.ids[g.ID] = true
+	}
+	return s
+}
+
+// Check reports the goroutines started since before that are still
+// running, waiting up to wait for them to finish first. Goroutines of
+// long-lived connections are not counted.
+func Check(before Snapshot, wait time.Duration) []Leak {
+	deadline := time.Now().Add(wait)
+	for {
+		leaked := before.started()
+		if len(leaked) == 0 || time.Now().After(deadline) {
+			return group(leaked)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// started returns the goroutines running now that s did not see.
+func (s Snapshot) started() []Goroutine {
+	var out []Goroutine
+	for _, g := range Goroutines() {
+		if !s.ids[g.ID] && !isIgnored(g.Stack) {
+			out = append(out, g)
+		}
+	}
+	return out
+}
+
+func isIgnored(stack string) bool {
+	for _, fn := range ignored {
+		if strings.Contains(stack, fn) {
+			return true
+		}
+	}
+	return false
+}
+
+// group merges goroutines with the same state and stack, most common
+// first.
+func group(gs []Goroutine) []Leak {
+	var leaks []Leak
+	index := make(map[[2]string]int)
+	for _, g := range gs {
+		key := [2]string{g.State, g.Stack}
+		i, ok := index[key]
+		if !ok {
+			i = len(leaks)
+			index[key] = i
+			leaks = append(leaks, Leak{State: g.State, Stack: g.Stack})
+		}
+		leaks[i].Count++
+	}
+	slices.SortStableFunc(leaks, func(a, b Leak) int { return cmp.Compare(b.Count, a.Count) })
+	return leaks
+}
+
+// Goroutines parses the stacks of every goroutine except the caller's.
+func Goroutines() []Goroutine {
+	buf := make([]byte, 1<<16)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+
+	var out []Goroutine
+	// the first block is the calling goroutine
+	for _, block := range strings.Split(string(buf), "\n\n")[1:] {
+		header, stack, _ := strings.Cut(block, "\n")
+		// goroutine 12 [chan receive, 2 minutes]:
+		fields := strings.SplitN(strings.TrimPrefix(header, "goroutine "), " ", 2)
+		if len(fields) != 2 {
+			continue
+		}
+		id, err := strconv.Atoi(fields[0])
+		if err != nil {
+			continue
+		}
+		state := strings.TrimSuffix(strings.TrimPrefix(fields[1], "["), "]:")
+		// drop how long it has waited, which differs between goroutines
+		// that are otherwise the same
+		state, _, _ = strings.Cut(state, ",")
+		out = append(out, Goroutine{ID: id, State: state, Stack: normalize(stack)})
+	}
+	return out
+}
+
+// normalize drops the argument values and parent goroutine from a stack,
+// so that goroutines doing the same thing have the same stack.
+func normalize(stack string) string {
+	lines := strings.Split(strings.TrimSpace(stack), "\n")
+	for i, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "\t"):
+			// file:line
+		case strings.HasPrefix(line, "created by "):
+			lines[i], _, _ = strings.Cut(line, " in goroutine ")
+		default:
+			if j := strings.LastIndex(line, "("); j > 0 {
+				lines[i] = line[:j] + "(...)"
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
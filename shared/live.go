@@ -0,0 +1,55 @@
+package shared
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// StartLive redraws a status line in place on w every interval until stop
+// is called: throughput and p99 over the last interval, requests in
+// flight and failures so far. w should be a terminal.
+func (c *Collector) StartLive(w io.Writer, interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		last, lastTick := 0, time.Now()
+		for {
+			select {
+			case <-done:
+				c.drawLive(w, &last, &lastTick)
+				fmt.Fprintln(w)
+				return
+			case <-ticker.C:
+				c.drawLive(w, &last, &lastTick)
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		wg.Wait()
+	}
+}
+
+func (c *Collector) drawLive(w io.Writer, last *int, lastTick *time.Time) {
+	inFlight := c.InFlight()
+	c.mu.Lock()
+	count, failures := c.count, c.failures()
+	p99 := c.recent.DurationAtPercentile(99)
+	c.recent = NewHistogram()
+	c.mu.Unlock()
+
+	now := time.Now()
+	rate := float64(count-*last) / now.Sub(*lastTick).Seconds()
+	*last, *lastTick = count, now
+
+	// \r returns to the start of the line, \033[K clears the rest of it
+	fmt.Fprintf(w, "\r\033[K[%v] %d done  %.1f req/s  %d in flight  p99 %v  %d failed",
+		time.Since(c.start).Round(100*time.Millisecond), count, rate, inFlight,
+		p99.Round(time.Microsecond), failures)
+}
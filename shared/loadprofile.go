@@ -0,0 +1,131 @@
+package shared
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/aawadall/go-concurrency-patterns/config"
+)
+
+// profileTick is how often a load profile adjusts the load.
+const profileTick = 100 * time.Millisecond
+
+// minProfileRate is the rate a profile at 0 rps runs at instead: a
+// rate.Limiter at 0 lets its burst through and then nothing, ever, even
+// once the limit is raised again.
+const minProfileRate = 0.01
+
+// profiled moves the load on a target along a load profile, either by
+// rate limiting calls or by letting only so many run at once.
+type profiled struct {
+	target    Target
+	phases    []config.Phase
+	collector *Collector
+	limiter   *adjustableLimiter
+	gate      *gate
+
+	once sync.Once
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// LoadProfile wraps t so that the request rate, or the number of calls in
+// flight, follows cfg.Profile from the first call to Do. Each phase is
+// recorded in c as a separate stage. The returned stop function ends the
+// profile.
+func LoadProfile(t Target, c *Collector, cfg *config.Config) (Target, func()) {
+	p := &profiled{target: t, phases: cfg.Profile, collector: c, done: make(chan struct{})}
+	if cfg.ProfileOf == "concurrency" {
+		p.gate = newGate()
+	} else {
+		p.limiter = newAdjustableLimiter(minProfileRate, cfg.Burst)
+	}
+	stop := func() {
+		p.once.Do(func() {}) // don't start after stopping
+		close(p.done)
+		p.wg.Wait()
+	}
+	return p, stop
+}
+
+func (p *profiled) Do(ctx context.Context) (Result, error) {
+	p.once.Do(p.start)
+	var err error
+	if p.gate != nil {
+		if err = p.gate.enter(ctx); err == nil {
+			defer p.gate.leave()
+		}
+	} else {
+		err = p.limiter.Wait(ctx)
+		if err != nil && ctx.Err() != nil {
+			err = ctx.Err()
+		}
+	}
+	if err != nil {
+		return Result{Err: err, ErrorClass: classifyError(err)}, err
+	}
+	return p.target.Do(ctx)
+}
+
+// start sets the first level and keeps adjusting it until the profile
+// ends or is stopped.
+func (p *profiled) start() {
+	begin := time.Now()
+	current := -1
+	adjust := func() bool {
+		level, phase := profileLevel(p.phases, time.Since(begin))
+		if phase < 0 {
+			return false
+		}
+		if phase != current {
+			current = phase
+			ph := p.phases[phase]
+			name := fmt.Sprintf("%d: %v at %v", phase+1, ph.Duration, ph.From)
+			if ph.To != ph.From {
+				name = fmt.Sprintf("%d: %v from %v to %v", phase+1, ph.Duration, ph.From, ph.To)
+			}
+			p.collector.StartStage(name)
+		}
+		if p.gate != nil {
+			p.gate.setLimit(max(int(math.Round(level)), 1))
+		} else {
+			p.limiter.SetLimit(rate.Limit(max(level, minProfileRate)))
+		}
+		return true
+	}
+	adjust()
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		ticker := time.NewTicker(profileTick)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-p.done:
+				return
+			case <-ticker.C:
+				if !adjust() {
+					return
+				}
+			}
+		}
+	}()
+}
+
+// profileLevel returns the load d into a profile and the index of the
+// phase it falls in, or -1 once the profile is over.
+func profileLevel(phases []config.Phase, d time.Duration) (float64, int) {
+	for i, p := range phases {
+		if d < p.Duration {
+			return p.From + (p.To-p.From)*float64(d)/float64(p.Duration), i
+		}
+		d -= p.Duration
+	}
+	return 0, -1
+}
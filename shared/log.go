@@ -0,0 +1,58 @@
+package shared
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/aawadall/go-concurrency-patterns/config"
+)
+
+// logger carries diagnostics, kept on stderr and apart from the report.
+var logger = slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+// Logger returns the logger used by this package.
+func Logger() *slog.Logger {
+	return logger
+}
+
+// SetupLogging configures the package logger from cfg. Quiet raises the
+// level to error and turns off progress output.
+func SetupLogging(cfg *config.Config) error {
+	l, err := NewLogger(os.Stderr, cfg)
+	if err != nil {
+		return err
+	}
+	logger = l
+	return nil
+}
+
+// NewLogger returns a logger writing to w at cfg.LogLevel in cfg.LogFormat.
+func NewLogger(w io.Writer, cfg *config.Config) (*slog.Logger, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(cfg.LogLevel)); err != nil {
+		return nil, fmt.Errorf("unknown log level %q", cfg.LogLevel)
+	}
+	if cfg.Quiet {
+		level = max(level, slog.LevelError)
+	}
+	opts := &slog.HandlerOptions{Level: level}
+
+	switch cfg.LogFormat {
+	case "", "text":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	default:
+		return nil, fmt.Errorf("unknown log format %q", cfg.LogFormat)
+	}
+}
+
+// progress prints a dot per completed request, unless the run is quiet or
+// shows the live status line instead.
+func progress(cfg *config.Config) {
+	if !cfg.Quiet && !cfg.Live {
+		fmt.Fprint(os.Stderr, ".")
+	}
+}
@@ -0,0 +1,119 @@
+package shared
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/aawadall/go-concurrency-patterns/config"
+)
+
+// Manifest records everything needed to compare or reproduce a run: the
+// effective config, the environment it ran in, and its results.
+type Manifest struct {
+	Pattern    string    `json:"pattern"`
+	Version    string    `json:"version"`
+	GoVersion  string    `json:"go_version"`
+	GOMAXPROCS int       `json:"gomaxprocs"`
+	NumCPU     int       `json:"num_cpu"`
+	Start      time.Time `json:"start"`
+	End        time.Time `json:"end"`
+
+	Config *config.Config `json:"config"`
+	// ConfigHash identifies runs made with the same config.
+	ConfigHash string `json:"config_hash"`
+
+	TotalTime         time.Duration     `json:"total_time_ns"`
+	RequestsPerSecond float64           `json:"requests_per_second"`
+	Summary           Summary           `json:"summary"`
+	Memory            map[string]uint64 `json:"memory,omitempty"`
+}
+
+func NewManifest(pattern string, cfg *config.Config, start, end time.Time, summary Summary, memProfile map[string]uint64) Manifest {
+	return Manifest{
+		Pattern:           pattern,
+		Version:           buildVersion(),
+		GoVersion:         runtime.Version(),
+		GOMAXPROCS:        runtime.GOMAXPROCS(0),
+		NumCPU:            runtime.NumCPU(),
+		Start:             start,
+		End:               end,
+		Config:            cfg,
+		ConfigHash:        configHash(cfg),
+		TotalTime:         end.Sub(start),
+		RequestsPerSecond: requestsPerSecond(summary.Count, end.Sub(start)),
+		Summary:           summary,
+		Memory:            memProfile,
+	}
+}
+
+// WriteManifest writes m as JSON into dir, creating it if needed, along
+// with an HTML report of the same name, and returns the JSON file's path.
+func WriteManifest(dir string, m Manifest) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	base := filepath.Join(dir, runName(m.Pattern, m.Start))
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(base+".json", append(data, '\n'), 0o644); err != nil {
+		return "", err
+	}
+
+	var html bytes.Buffer
+	title := fmt.Sprintf("%s, %s", m.Pattern, m.Start.Format(time.DateTime))
+	if err := reportHTML(&html, title, m.Summary, m.TotalTime, m.Memory); err != nil {
+		return "", err
+	}
+	return base + ".json", os.WriteFile(base+".html", html.Bytes(), 0o644)
+}
+
+// configHash returns a short digest of cfg's JSON encoding.
+func configHash(cfg *config.Config) string {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:6])
+}
+
+// buildVersion returns the VCS revision the binary was built from. Binaries
+// built with go run carry no VCS stamp, so fall back to asking git.
+func buildVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		var revision, modified string
+		for _, s := range info.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				revision = s.Value
+			case "vcs.modified":
+				modified = s.Value
+			}
+		}
+		if revision != "" {
+			if modified == "true" {
+				revision += "-dirty"
+			}
+			return revision
+		}
+	}
+
+	out, err := exec.Command("git", "describe", "--always", "--dirty").Output()
+	if err != nil {
+		return "unknown"
+	}
+	return strings.TrimSpace(string(out))
+}
@@ -0,0 +1,93 @@
+package shared
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"slices"
+	"time"
+)
+
+// metricBuckets are the upper bounds of the exported latency histogram.
+var metricBuckets = []time.Duration{
+	500 * time.Microsecond,
+	time.Millisecond,
+	2500 * time.Microsecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// ServeMetrics exposes the Collector's statistics at addr/metrics in the
+// Prometheus text format, along with net/http/pprof at addr/debug/pprof/,
+// until stop is called.
+func ServeMetrics(addr string, c *Collector) (stop func(), err error) {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		c.WriteMetrics(w)
+	})
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	srv := &http.Server{Handler: mux}
+	go func() {
+		if err := srv.Serve(lis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("serving metrics", "err", err)
+		}
+	}()
+	return func() { srv.Close() }, nil
+}
+
+// WriteMetrics writes the current statistics in the Prometheus text
+// exposition format.
+func (c *Collector) WriteMetrics(w io.Writer) {
+	inFlight := c.InFlight()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	fmt.Fprintf(w, "# HELP gcp_requests_in_flight Requests started but not finished.\n")
+	fmt.Fprintf(w, "# TYPE gcp_requests_in_flight gauge\n")
+	fmt.Fprintf(w, "gcp_requests_in_flight %d\n", inFlight)
+
+	fmt.Fprintf(w, "# HELP gcp_requests_total Requests completed, by status code.\n")
+	fmt.Fprintf(w, "# TYPE gcp_requests_total counter\n")
+	for _, status := range slices.Sorted(maps.Keys(c.statuses)) {
+		fmt.Fprintf(w, "gcp_requests_total{status=\"%d\"} %d\n", status, c.statuses[status])
+	}
+
+	fmt.Fprintf(w, "# HELP gcp_request_failures_total Failed requests, by cause.\n")
+	fmt.Fprintf(w, "# TYPE gcp_request_failures_total counter\n")
+	for _, class := range slices.Sorted(maps.Keys(c.classes)) {
+		fmt.Fprintf(w, "gcp_request_failures_total{class=%q} %d\n", class, c.classes[class])
+	}
+
+	fmt.Fprintf(w, "# HELP gcp_request_duration_seconds Request latency, including retries.\n")
+	fmt.Fprintf(w, "# TYPE gcp_request_duration_seconds histogram\n")
+	for _, le := range metricBuckets {
+		fmt.Fprintf(w, "gcp_request_duration_seconds_bucket{le=\"%g\"} %d\n",
+			le.Seconds(), c.latencies.CountAtOrBelow(int64(le)))
+	}
+	fmt.Fprintf(w, "gcp_request_duration_seconds_bucket{le=\"+Inf\"} %d\n", c.latencies.Count())
+	fmt.Fprintf(w, "gcp_request_duration_seconds_sum %g\n", c.latencies.Sum()/float64(time.Second))
+	fmt.Fprintf(w, "gcp_request_duration_seconds_count %d\n", c.latencies.Count())
+}
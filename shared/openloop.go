@@ -0,0 +1,50 @@
+package shared
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// openLoop issues requests on a fixed schedule, whatever the target's
+// latency, and stamps each Result with its scheduled start.
+type openLoop struct {
+	target   Target
+	interval time.Duration
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+// OpenLoop wraps t so that calls to Do start at a constant rate per
+// second, shared by all callers. When the target stalls, calls queue up
+// behind the schedule instead of silently slowing it down, and the time
+// spent behind it shows up in the corrected latency: Result.Intended is
+// when the request should have started.
+func OpenLoop(t Target, rate float64) Target {
+	return &openLoop{target: t, interval: time.Duration(float64(time.Second) / rate)}
+}
+
+func (o *openLoop) Do(ctx context.Context) (Result, error) {
+	o.mu.Lock()
+	if o.next.IsZero() {
+		o.next = time.Now()
+	}
+	intended := o.next
+	o.next = o.next.Add(o.interval)
+	o.mu.Unlock()
+
+	if wait := time.Until(intended); wait > 0 {
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return Result{Err: ctx.Err(), ErrorClass: classifyError(ctx.Err()), Intended: intended}, ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	r, err := o.target.Do(ctx)
+	r.Intended = intended
+	return r, err
+}
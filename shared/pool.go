@@ -0,0 +1,43 @@
+package shared
+
+import (
+	"bytes"
+	"io"
+	"sync"
+
+	"github.com/aawadall/go-concurrency-patterns/config"
+)
+
+// bodyBuffers and copyBuffers hold the buffers a request reads its
+// response with, for reuse when cfg.Pool is set. The copy buffers are
+// pointers to slices so that putting them back does not allocate.
+var (
+	bodyBuffers = sync.Pool{New: func() any { return new(bytes.Buffer) }}
+	copyBuffers = sync.Pool{New: func() any {
+		b := make([]byte, 32<<10)
+		return &b
+	}}
+)
+
+// bodyBuffer returns an empty buffer for the part of a response body kept
+// for validation, and a func to call once it is no longer needed.
+func bodyBuffer(cfg *config.Config) (*bytes.Buffer, func()) {
+	if !cfg.Pool {
+		return new(bytes.Buffer), func() {}
+	}
+	b := bodyBuffers.Get().(*bytes.Buffer)
+	b.Reset()
+	return b, func() { bodyBuffers.Put(b) }
+}
+
+// copyBody copies a response body to w like io.Copy, which allocates a
+// fresh buffer for each copy, but with a pooled buffer when cfg.Pool is
+// set.
+func copyBody(cfg *config.Config, w io.Writer, body io.Reader) (int64, error) {
+	if !cfg.Pool {
+		return io.Copy(w, body)
+	}
+	buf := copyBuffers.Get().(*[]byte)
+	defer copyBuffers.Put(buf)
+	return io.CopyBuffer(w, body, *buf)
+}
@@ -0,0 +1,64 @@
+package shared
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"time"
+
+	"github.com/aawadall/go-concurrency-patterns/config"
+)
+
+// runName names the files saved for a run of pattern started at start.
+func runName(pattern string, start time.Time) string {
+	return fmt.Sprintf("%s-%s", pattern, start.Format("20060102-150405"))
+}
+
+// StartProfiles starts a CPU profile when cfg.CPUProfile is set. stop ends
+// it and, when cfg.HeapProfile is set, writes a heap profile. Files are
+// saved into cfg.ResultsDir, or the working directory, next to the run's
+// manifest.
+func StartProfiles(cfg *config.Config, pattern string, start time.Time) (stop func() error, err error) {
+	dir := cfg.ResultsDir
+	if dir == "" {
+		dir = "."
+	}
+	if cfg.CPUProfile || cfg.HeapProfile {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+	}
+	base := filepath.Join(dir, runName(pattern, start))
+
+	var cpu *os.File
+	if cfg.CPUProfile {
+		if cpu, err = os.Create(base + ".cpu.pprof"); err != nil {
+			return nil, err
+		}
+		if err := pprof.StartCPUProfile(cpu); err != nil {
+			cpu.Close()
+			return nil, err
+		}
+	}
+
+	return func() error {
+		if cpu != nil {
+			pprof.StopCPUProfile()
+			if err := cpu.Close(); err != nil {
+				return err
+			}
+		}
+		if !cfg.HeapProfile {
+			return nil
+		}
+		heap, err := os.Create(base + ".heap.pprof")
+		if err != nil {
+			return err
+		}
+		defer heap.Close()
+		runtime.GC()
+		return pprof.WriteHeapProfile(heap)
+	}, nil
+}
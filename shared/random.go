@@ -0,0 +1,68 @@
+package shared
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Each consumer of randomness draws from its own stream derived from the
+// run's seed, so that adding draws to one doesn't shift the others.
+const (
+	streamTemplates = iota + 1
+	streamSim
+	streamThinkTime
+	streamEndpoints
+)
+
+// lockedSource makes a rand.Source safe for concurrent use.
+type lockedSource struct {
+	mu  sync.Mutex
+	src rand.Source64
+}
+
+func (s *lockedSource) Int63() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.src.Int63()
+}
+
+func (s *lockedSource) Uint64() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.src.Uint64()
+}
+
+func (s *lockedSource) Seed(seed int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.src.Seed(seed)
+}
+
+// newRand returns a generator, safe for concurrent use, for the given
+// stream of the run seeded with seed.
+func newRand(seed int64, stream int64) *rand.Rand {
+	return rand.New(&lockedSource{src: rand.NewSource(streamSeed(seed, stream)).(rand.Source64)})
+}
+
+// WorkerRand returns a generator of the run seeded with seed for worker i
+// of a pattern, on a stream of its own: the workers' streams start above
+// the shared ones, so a pattern drawing from them doesn't shift targets'
+// draws, or each other's.
+func WorkerRand(seed int64, i int) *rand.Rand {
+	return newRand(seed, int64(i+1)<<32)
+}
+
+func streamSeed(seed, stream int64) int64 {
+	return seed ^ stream*0x5851f42d4c957f2d
+}
+
+// templateRand serves the random template functions.
+var templateRand = newRand(time.Now().UnixNano(), streamTemplates)
+
+// Seed makes the random values in request templates repeat from run to
+// run. Targets and wrappers that take a seed of their own are seeded
+// separately.
+func Seed(seed int64) {
+	templateRand.Seed(streamSeed(seed, streamTemplates))
+}
@@ -0,0 +1,126 @@
+package shared
+
+import (
+	"regexp"
+	"runtime"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/aawadall/go-concurrency-patterns/config"
+)
+
+// draws takes n values from each source of randomness of a run seeded
+// with seed.
+func draws(t *testing.T, seed int64, n int) (sim []any, picks []int, rendered []string) {
+	t.Helper()
+	cfg := config.NewConfig("localhost", 5000)
+	cfg.Seed = seed
+	cfg.SimDistribution = "bimodal"
+	cfg.Endpoints = []config.Endpoint{{Weight: 1}, {Weight: 2}, {Weight: 3}}
+
+	target, err := NewSimTarget(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	picker := NewEndpointPicker(cfg)
+	tmpl, err := NewRequestTemplate(`/item/{{randInt 0 1000}}`, `{"id":"{{uuid}}","name":"{{randString 8}}"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	Seed(seed)
+	for range n {
+		latency, fail := target.sample()
+		sim = append(sim, latency, fail)
+		picks = append(picks, picker.Pick())
+		path, body, err := tmpl.Render(RequestData{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		rendered = append(rendered, path, body)
+	}
+	return sim, picks, rendered
+}
+
+func TestSeedRepeatsRuns(t *testing.T) {
+	sim1, picks1, rendered1 := draws(t, 42, 50)
+	sim2, picks2, rendered2 := draws(t, 42, 50)
+	if !slices.Equal(sim1, sim2) {
+		t.Error("sim latencies differ between runs with the same seed")
+	}
+	if !slices.Equal(picks1, picks2) {
+		t.Error("endpoint picks differ between runs with the same seed")
+	}
+	if !slices.Equal(rendered1, rendered2) {
+		t.Error("templates render differently between runs with the same seed")
+	}
+
+	sim3, picks3, rendered3 := draws(t, 43, 50)
+	if slices.Equal(sim1, sim3) && slices.Equal(picks1, picks3) && slices.Equal(rendered1, rendered3) {
+		t.Error("another seed repeats the same run")
+	}
+}
+
+// each worker's stream repeats with the seed, and differs from the other
+// workers' and from the shared streams
+func TestWorkerRand(t *testing.T) {
+	ints := func(r interface{ Int63() int64 }) []int64 {
+		var v []int64
+		for range 10 {
+			v = append(v, r.Int63())
+		}
+		return v
+	}
+	first := ints(WorkerRand(42, 0))
+	if !slices.Equal(first, ints(WorkerRand(42, 0))) {
+		t.Error("a worker's draws differ between runs with the same seed")
+	}
+	if slices.Equal(first, ints(WorkerRand(42, 1))) {
+		t.Error("two workers draw the same values")
+	}
+	if slices.Equal(first, ints(WorkerRand(43, 0))) {
+		t.Error("another seed repeats a worker's draws")
+	}
+	for _, stream := range []int64{streamTemplates, streamSim, streamThinkTime, streamEndpoints} {
+		if slices.Equal(first, ints(newRand(42, stream))) {
+			t.Errorf("a worker draws the same values as stream %d", stream)
+		}
+	}
+}
+
+// run with -race: workers render the random template functions at once
+func TestTemplateRandConcurrent(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+	tmpl, err := NewRequestTemplate(`/{{randInt 0 100}}`, `{{uuid}} {{randString 4}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uuidRE := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12} `)
+	var mu sync.Mutex
+	seen := make(map[string]bool)
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 200 {
+				_, body, err := tmpl.Render(RequestData{})
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if !uuidRE.MatchString(body) {
+					t.Errorf("%q doesn't start with a version 4 UUID", body)
+					return
+				}
+				mu.Lock()
+				seen[body[:36]] = true
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if len(seen) != 8*200 {
+		t.Errorf("got %d distinct UUIDs from %d renders", len(seen), 8*200)
+	}
+}
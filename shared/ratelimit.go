@@ -0,0 +1,92 @@
+package shared
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// rateLimited holds calls to a target back with a token bucket.
+type rateLimited struct {
+	target  Target
+	limiter *rate.Limiter
+}
+
+// RateLimit wraps t so that calls to Do, shared by all callers, stay
+// under rps per second with bursts of up to burst calls. Unlike OpenLoop
+// it only caps the rate: capacity left unused while the target is slow
+// is not made up later beyond the burst.
+func RateLimit(t Target, rps float64, burst int) Target {
+	return &rateLimited{target: t, limiter: rate.NewLimiter(rate.Limit(rps), burst)}
+}
+
+func (r *rateLimited) Do(ctx context.Context) (Result, error) {
+	if err := r.limiter.Wait(ctx); err != nil {
+		// Wait fails early when ctx's deadline would pass first
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		return Result{Err: err, ErrorClass: classifyError(err)}, err
+	}
+	return r.target.Do(ctx)
+}
+
+// adjustableLimiter is a rate.Limiter whose limit changes while calls
+// wait on it. A reservation keeps the delay of the limit it was made at,
+// so a change wakes the waiters, which give their reservations back and
+// reserve again at the new limit rather than sleeping out the old delay.
+type adjustableLimiter struct {
+	limiter *rate.Limiter
+
+	mu      sync.Mutex
+	changed chan struct{} // closed and replaced on every change
+}
+
+func newAdjustableLimiter(limit rate.Limit, burst int) *adjustableLimiter {
+	return &adjustableLimiter{limiter: rate.NewLimiter(limit, burst), changed: make(chan struct{})}
+}
+
+// SetLimit changes the limit and wakes the calls waiting on the old one.
+func (a *adjustableLimiter) SetLimit(limit rate.Limit) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.limiter.Limit() == limit {
+		return
+	}
+	a.limiter.SetLimit(limit)
+	close(a.changed)
+	a.changed = make(chan struct{})
+}
+
+// Wait waits for a token, or fails with ctx's error if ctx is done first.
+func (a *adjustableLimiter) Wait(ctx context.Context) error {
+	for {
+		a.mu.Lock()
+		changed := a.changed
+		a.mu.Unlock()
+
+		r := a.limiter.Reserve()
+		if !r.OK() {
+			return fmt.Errorf("rate: a burst of %d lets no call through", a.limiter.Burst())
+		}
+		delay := r.Delay()
+		if delay == 0 {
+			return nil
+		}
+		t := time.NewTimer(delay)
+		select {
+		case <-t.C:
+			return nil
+		case <-changed:
+			t.Stop()
+			r.Cancel()
+		case <-ctx.Done():
+			t.Stop()
+			r.Cancel()
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package shared
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+func TestAdjustableLimiterWakesWaitersOnChange(t *testing.T) {
+	l := newAdjustableLimiter(minProfileRate, 1)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	// the burst goes at once, and the next token is 100s off
+	if err := l.Wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- l.Wait(ctx) }()
+
+	time.Sleep(20 * time.Millisecond)
+	l.SetLimit(rate.Limit(1000))
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the waiter kept the delay of the old limit")
+	}
+}
+
+func TestAdjustableLimiterFailsWithContext(t *testing.T) {
+	l := newAdjustableLimiter(minProfileRate, 1)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	l.Wait(ctx)
+	if err := l.Wait(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+}
@@ -0,0 +1,136 @@
+package shared
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"golang.org/x/time/rate"
+
+	"github.com/aawadall/go-concurrency-patterns/watch"
+)
+
+// Settings are what can be changed while a run goes.
+type Settings struct {
+	// Concurrency caps the calls in flight. It can't usefully exceed the
+	// workers the run started with.
+	Concurrency int `json:"concurrency"`
+	// RatePerSecond caps the request rate; 0 means no limit.
+	RatePerSecond float64 `json:"rps"`
+}
+
+func (s Settings) String() string {
+	if s.RatePerSecond == 0 {
+		return fmt.Sprintf("concurrency %d, no rate limit", s.Concurrency)
+	}
+	return fmt.Sprintf("concurrency %d, %v rps", s.Concurrency, s.RatePerSecond)
+}
+
+// Check reports why s can't be used in a run that started maxConcurrency
+// workers.
+func (s Settings) Check(maxConcurrency int) error {
+	var errs []error
+	if s.Concurrency < 1 || s.Concurrency > maxConcurrency {
+		errs = append(errs, fmt.Errorf("concurrency must be between 1 and the run's %d workers, got %d", maxConcurrency, s.Concurrency))
+	}
+	if s.RatePerSecond < 0 {
+		errs = append(errs, fmt.Errorf("rps must not be negative, got %v", s.RatePerSecond))
+	}
+	return errors.Join(errs...)
+}
+
+// Reconfigurable wraps t so that the calls in flight and the request rate
+// follow settings as they change. Each setting of it, from the first, is
+// an epoch of the run recorded in c as a separate stage. The returned
+// stop function stops following the changes.
+func Reconfigurable(t Target, c *Collector, settings *watch.Value[Settings], burst int) (Target, func()) {
+	g := newGate()
+	// a change of rate wakes the calls waiting at the old one
+	limiter := newAdjustableLimiter(rate.Inf, burst)
+	apply := func(s Settings, epoch int) {
+		g.setLimit(max(s.Concurrency, 1))
+		if s.RatePerSecond > 0 {
+			limiter.SetLimit(rate.Limit(s.RatePerSecond))
+		} else {
+			limiter.SetLimit(rate.Inf)
+		}
+		c.StartStage(fmt.Sprintf("%d: %v", epoch, s))
+	}
+
+	// apply the first settings before any call gets through
+	ctx, cancel := context.WithCancel(context.Background())
+	updates := settings.Watch(ctx)
+	apply(<-updates, 1)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for epoch := 2; ; epoch++ {
+			s, ok := <-updates
+			if !ok {
+				return
+			}
+			logger.Info("settings changed", "epoch", epoch, "concurrency", s.Concurrency, "rps", s.RatePerSecond)
+			apply(s, epoch)
+		}
+	}()
+	stop := func() {
+		cancel()
+		<-done
+	}
+
+	return TargetFunc(func(ctx context.Context) (Result, error) {
+		err := g.enter(ctx)
+		if err == nil {
+			defer g.leave()
+			if err = limiter.Wait(ctx); err != nil && ctx.Err() != nil {
+				err = ctx.Err()
+			}
+		}
+		if err != nil {
+			return Result{Err: err, ErrorClass: classifyError(err)}, err
+		}
+		return t.Do(ctx)
+	}), stop
+}
+
+// ServeAdmin serves settings at addr/settings until stop is called: GET
+// returns them as JSON, and PUT or POST a JSON object changes those it
+// names, e.g. {"rps": 200}.
+func ServeAdmin(addr string, settings *watch.Value[Settings], maxConcurrency int) (stop func(), err error) {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /settings", func(w http.ResponseWriter, r *http.Request) {
+		s, _ := settings.Load()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s)
+	})
+	change := func(w http.ResponseWriter, r *http.Request) {
+		s, _ := settings.Load()
+		if err := json.NewDecoder(r.Body).Decode(&s); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := s.Check(maxConcurrency); err != nil {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		settings.Set(s)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s)
+	}
+	mux.HandleFunc("PUT /settings", change)
+	mux.HandleFunc("POST /settings", change)
+	srv := &http.Server{Handler: mux}
+	go func() {
+		if err := srv.Serve(lis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("serving admin endpoint", "err", err)
+		}
+	}()
+	return func() { srv.Close() }, nil
+}
@@ -2,34 +2,294 @@ package shared
 
 import (
 	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/aawadall/go-concurrency-patterns/shared/leakcheck"
 )
 
-func Report(latencies []time.Duration, statuses []int, totalTime time.Duration, memProfile map[string]uint64) {
-	var totalLatency time.Duration
-	statusCount := make(map[int]int)
+// Format selects how Report renders its output.
+type Format string
+
+const (
+	FormatText Format = "text"
+	FormatJSON Format = "json"
+	FormatCSV  Format = "csv"
+	FormatHTML Format = "html"
+)
 
-	for i, latency := range latencies {
-		totalLatency += latency
-		status := statuses[i]
-		statusCount[status]++
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(s); f {
+	case FormatText, FormatJSON, FormatCSV, FormatHTML:
+		return f, nil
+	case "":
+		return FormatText, nil
+	default:
+		return "", fmt.Errorf("unknown output format %q", s)
+	}
+}
+
+// Report writes the statistics gathered by a Collector to w. Text output is
+// meant for terminals; JSON and CSV for jq, spreadsheets and CI jobs; HTML
+// for sharing.
+func Report(w io.Writer, format Format, summary Summary, totalTime time.Duration, memProfile map[string]uint64) error {
+	switch format {
+	case FormatJSON:
+		return reportJSON(w, summary, totalTime, memProfile)
+	case FormatCSV:
+		return reportCSV(w, summary, totalTime, memProfile)
+	case FormatHTML:
+		return reportHTML(w, "Benchmark report", summary, totalTime, memProfile)
+	default:
+		reportText(w, summary, totalTime, memProfile)
+		return nil
+	}
+}
+
+func reportText(w io.Writer, summary Summary, totalTime time.Duration, memProfile map[string]uint64) {
+	if summary.Partial {
+		fmt.Fprintf(w, "\n\nPartial run: interrupted after %d requests\n", summary.Count)
+	}
+	if summary.Count == 0 {
+		fmt.Fprintf(w, "\n\nNo requests completed\n")
+		return
+	}
+	if wu := summary.Warmup; wu != nil {
+		fmt.Fprintf(w, "\n\nWarm-up: %d requests in %v, %d failed, mean %v, p99 %v (not included below)\n",
+			wu.Requests, wu.Duration.Round(time.Millisecond), wu.Failures, wu.Mean, wu.P99)
 	}
 
-	avgLatency := totalLatency / time.Duration(len(latencies))
 	// Yellow color for report
-	fmt.Printf("\033[0;33m")
-	fmt.Printf("\n\nAverage Latency: %v\n", avgLatency)
-	fmt.Printf("Total Time: %v\n", totalTime)
-	fmt.Println("Status Code Counts:")
-	for status, count := range statusCount {
-		fmt.Printf("  %d: %d\n", status, count)
+	fmt.Fprintf(w, "\033[0;33m")
+	fmt.Fprintf(w, "\n\nAverage Latency: %v\n", summary.Mean)
+	fmt.Fprintf(w, "Latency Distribution:\n")
+	fmt.Fprintf(w, "  min: %v\n", summary.Min)
+	fmt.Fprintf(w, "  median: %v\n", summary.Median)
+	for _, p := range summary.Percentiles {
+		fmt.Fprintf(w, "  %s: %v\n", formatPercentile(p.P), p.Latency)
+	}
+	fmt.Fprintf(w, "  max: %v\n", summary.Max)
+	if len(summary.Corrected) > 0 {
+		fmt.Fprintf(w, "Corrected for Coordinated Omission:\n")
+		for _, p := range summary.Corrected {
+			fmt.Fprintf(w, "  %s: %v\n", formatPercentile(p.P), p.Latency)
+		}
+	}
+	reportHistogram(w, summary.Histogram)
+	fmt.Fprintf(w, "Total Time: %v\n", totalTime)
+	if summary.TargetRate > 0 {
+		achieved := requestsPerSecond(summary.Count, totalTime)
+		fmt.Fprintf(w, "Achieved Rate: %.1f req/s of %.1f target (%.1f%%)\n",
+			achieved, summary.TargetRate, 100*achieved/summary.TargetRate)
+	}
+	reportSparkline(w, summary.Timeline)
+	fmt.Fprintln(w, "Status Code Counts:")
+	for status, count := range summary.StatusCounts {
+		fmt.Fprintf(w, "  %d: %d\n", status, count)
+	}
+	if len(summary.ErrorClasses) > 0 {
+		fmt.Fprintln(w, "Failures by Cause:")
+		for class, count := range summary.ErrorClasses {
+			fmt.Fprintf(w, "  %s: %d\n", class, count)
+		}
 	}
-	fmt.Printf("\033[0m")
+	fmt.Fprintf(w, "\033[0m")
 
 	if memProfile != nil {
-		fmt.Printf("\nMemory Profile:\n")
+		fmt.Fprintf(w, "\nMemory Profile:\n")
 		for key, value := range memProfile {
-			fmt.Printf("  %s: %d\n", key, value)
+			fmt.Fprintf(w, "  %s: %d\n", key, value)
+		}
+	}
+
+	reportTransfer(w, summary.Transfer, totalTime)
+	reportWorkers(w, summary.Workers)
+	reportEndpoints(w, summary.Count, summary.Endpoints)
+	reportPhases(w, summary.Phases)
+	reportStages(w, summary.Stages)
+	reportRetries(w, summary.Count, summary.Retries)
+	reportValidation(w, summary.Count, summary.ValidationFailures)
+	reportRuntime(w, summary.Runtime)
+	reportSLO(w, summary.SLO)
+	reportLeaks(w, summary.Leaks)
+}
+
+// reportTransfer prints the response size distribution and the aggregate
+// download throughput. It prints nothing if no body bytes were received.
+func reportTransfer(w io.Writer, t TransferSummary, totalTime time.Duration) {
+	if t.Total == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\nTransfer:\n")
+	fmt.Fprintf(w, "  Total Received: %s\n", formatBytes(t.Total))
+	fmt.Fprintf(w, "  Throughput: %.2f MB/s\n", float64(t.Total)/1e6/totalTime.Seconds())
+	fmt.Fprintf(w, "  Response Size: min %s, mean %s, p50 %s, p90 %s, p99 %s, max %s\n",
+		formatBytes(t.Min), formatBytes(t.Mean), formatBytes(t.P50),
+		formatBytes(t.P90), formatBytes(t.P99), formatBytes(t.Max))
+}
+
+// formatPercentile names a percentile the usual way: 99 is "p99" and
+// 99.9 is "p999".
+func formatPercentile(p float64) string {
+	return "p" + strings.ReplaceAll(strconv.FormatFloat(p, 'f', -1, 64), ".", "")
+}
+
+// formatBytes renders a byte count with a decimal unit.
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1e9:
+		return fmt.Sprintf("%.2f GB", float64(n)/1e9)
+	case n >= 1e6:
+		return fmt.Sprintf("%.2f MB", float64(n)/1e6)
+	case n >= 1e3:
+		return fmt.Sprintf("%.2f KB", float64(n)/1e3)
+	default:
+		return fmt.Sprintf("%d B", n)
+	}
+}
+
+// reportWorkers prints how requests were spread across workers, along
+// with the spread between the busiest and least busy worker. It prints
+// nothing when every result came from the same worker.
+func reportWorkers(w io.Writer, workers []WorkerSummary) {
+	if len(workers) < 2 {
+		return
+	}
+	fmt.Fprintf(w, "\nPer-Worker Breakdown:\n")
+	fmt.Fprintf(w, "  %-8s %10s %14s %14s\n", "Worker", "Requests", "Mean Latency", "Idle")
+	least, most := workers[0].Requests, workers[0].Requests
+	for _, worker := range workers {
+		fmt.Fprintf(w, "  %-8d %10d %14v %14v\n", worker.ID, worker.Requests, worker.Mean, worker.Idle.Round(time.Microsecond))
+		least = min(least, worker.Requests)
+		most = max(most, worker.Requests)
+	}
+	fmt.Fprintf(w, "  Requests per worker: min %d, max %d\n", least, most)
+}
+
+// reportEndpoints prints each endpoint's share of the requests and its
+// latency.
+func reportEndpoints(w io.Writer, count int, endpoints []EndpointSummary) {
+	if len(endpoints) == 0 {
+		return
+	}
+	rejected := slices.ContainsFunc(endpoints, func(e EndpointSummary) bool { return e.Rejected > 0 })
+	fmt.Fprintf(w, "\nPer-Endpoint Breakdown:\n")
+	fmt.Fprintf(w, "  %-36s %10s %7s %10s", "Endpoint", "Requests", "Share", "Failures")
+	if rejected {
+		fmt.Fprintf(w, " %10s", "Rejected")
+	}
+	fmt.Fprintf(w, " %12s %12s %12s\n", "Mean", "p50", "p99")
+	for _, e := range endpoints {
+		fmt.Fprintf(w, "  %-36s %10d %6.1f%% %10d",
+			e.Name, e.Requests, 100*float64(e.Requests)/float64(count), e.Failures)
+		if rejected {
+			fmt.Fprintf(w, " %10d", e.Rejected)
+		}
+		fmt.Fprintf(w, " %12v %12v %12v\n", e.Mean, e.P50, e.P99)
+	}
+}
+
+// reportPhases prints p50/p90/p99 for each request phase.
+func reportPhases(w io.Writer, phases []PhaseSummary) {
+	if len(phases) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\nLatency Breakdown:\n")
+	fmt.Fprintf(w, "  %-10s %12s %12s %12s\n", "Phase", "p50", "p90", "p99")
+	for _, p := range phases {
+		fmt.Fprintf(w, "  %-10s %12v %12v %12v\n", p.Name, p.P50, p.P90, p.P99)
+	}
+}
+
+// reportStages prints the results of each stage of the run, marking the
+// knee of the throughput/latency curve.
+func reportStages(w io.Writer, stages []StageSummary) {
+	if len(stages) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\nStages:\n")
+	fmt.Fprintf(w, "  %-30s %10s %10s %10s %12s %12s %12s\n", "Stage", "Requests", "Req/s", "Failures", "Mean", "p50", "p99")
+	for _, st := range stages {
+		knee := ""
+		if st.Knee {
+			knee = "  <- knee"
+		}
+		fmt.Fprintf(w, "  %-30s %10d %10.1f %10d %12v %12v %12v%s\n",
+			st.Name, st.Requests, st.Throughput, st.Failures, st.Mean, st.P50, st.P99, knee)
+	}
+}
+
+// reportRetries compares first-attempt latency against total latency
+// including retries. It prints nothing if no request was retried.
+func reportRetries(w io.Writer, count int, retries RetrySummary) {
+	if retries.Retried == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\nRetries:\n")
+	fmt.Fprintf(w, "  Requests Retried: %d of %d\n", retries.Retried, count)
+	fmt.Fprintf(w, "  Total Attempts: %d\n", retries.Attempts)
+	fmt.Fprintf(w, "  %-14s %12s %12s\n", "", "p50", "p99")
+	fmt.Fprintf(w, "  %-14s %12v %12v\n", "First Attempt", retries.FirstP50, retries.FirstP99)
+	fmt.Fprintf(w, "  %-14s %12v %12v\n", "Total", retries.TotalP50, retries.TotalP99)
+}
+
+// reportValidation prints how many responses failed validation, grouped by
+// reason. These are counted apart from the status codes above.
+func reportValidation(w io.Writer, count int, failures map[string]int) {
+	failed := 0
+	for _, n := range failures {
+		failed += n
+	}
+	if failed == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "\nValidation Failures: %d of %d\n", failed, count)
+	for reason, n := range failures {
+		fmt.Fprintf(w, "  %s: %d\n", reason, n)
+	}
+}
+
+// reportLeaks prints the stacks of goroutines left running after the run.
+func reportLeaks(w io.Writer, leaks []leakcheck.Leak) {
+	if len(leaks) == 0 {
+		return
+	}
+	total := 0
+	for _, l := range leaks {
+		total += l.Count
+	}
+	fmt.Fprintf(w, "\nSuspected Goroutine Leaks: %d\n", total)
+	for _, l := range leaks {
+		fmt.Fprintf(w, "  %d x [%s]\n", l.Count, l.State)
+		for _, line := range strings.Split(l.Stack, "\n") {
+			fmt.Fprintf(w, "    %s\n", line)
+		}
+	}
+}
+
+// reportSLO prints each objective with the value achieved and the overall
+// verdict.
+func reportSLO(w io.Writer, slo *SLOResult) {
+	if slo == nil {
+		return
+	}
+	verdict := "PASS"
+	if !slo.Pass {
+		verdict = "FAIL"
+	}
+	fmt.Fprintf(w, "\nSLO: %s\n", verdict)
+	if slo.Apdex != nil {
+		fmt.Fprintf(w, "  apdex (T=%v): %.2f\n", slo.ApdexT, *slo.Apdex)
+	}
+	for _, c := range slo.Checks {
+		mark := "ok"
+		if !c.Pass {
+			mark = "FAIL"
 		}
+		fmt.Fprintf(w, "  %s: %s (target %s) %s\n", c.Name, c.Actual, c.Target, mark)
 	}
 }
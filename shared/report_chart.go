@@ -0,0 +1,106 @@
+package shared
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	histogramWidth = 40
+	sparklineWidth = 60
+)
+
+// reportHistogram draws one bar per latency bucket, like hey does.
+func reportHistogram(w io.Writer, buckets []HistogramBucket) {
+	if len(buckets) == 0 {
+		return
+	}
+	var most uint64
+	for _, b := range buckets {
+		most = max(most, b.Count)
+	}
+
+	fmt.Fprintf(w, "Latency Histogram:\n")
+	for _, b := range buckets {
+		bar := 0
+		if most > 0 {
+			bar = int(b.Count * histogramWidth / most)
+		}
+		fmt.Fprintf(w, "  %10v [%6d] |%s\n",
+			time.Duration(b.Upper).Round(time.Microsecond), b.Count, strings.Repeat("■", bar))
+	}
+}
+
+var sparkRunes = []rune("▁▂▃▄▅▆▇█")
+
+// reportSparkline draws the throughput over time as a sparkline.
+func reportSparkline(w io.Writer, timeline Timeline) {
+	if len(timeline.Requests) < 2 {
+		return
+	}
+	rates := timeline.Rates()
+	fmt.Fprintf(w, "Throughput: %s (per %v, peak %.1f req/s)\n",
+		sparkline(rates, sparklineWidth), timeline.Interval, slices.Max(rates))
+	if slices.Max(timeline.Failures) > 0 {
+		failures := make([]float64, len(timeline.Failures))
+		for i, n := range timeline.Failures {
+			failures[i] = float64(n)
+		}
+		fmt.Fprintf(w, "Failures:   %s\n", sparkline(failures, sparklineWidth))
+	}
+}
+
+// sparkline renders values as block characters, averaging neighbouring
+// values when there are more than width of them.
+func sparkline(values []float64, width int) string {
+	step := (len(values) + width - 1) / width
+	var points []float64
+	for i := 0; i < len(values); i += step {
+		chunk := values[i:min(i+step, len(values))]
+		sum := 0.0
+		for _, v := range chunk {
+			sum += v
+		}
+		points = append(points, sum/float64(len(chunk)))
+	}
+
+	lo, hi := slices.Min(points), slices.Max(points)
+	var b strings.Builder
+	for _, p := range points {
+		i := 0
+		if hi > lo {
+			i = int((p - lo) / (hi - lo) * float64(len(sparkRunes)-1))
+		}
+		b.WriteRune(sparkRunes[i])
+	}
+	return b.String()
+}
+
+// reportRuntime prints the Go runtime totals for the run, with sparklines
+// of goroutines, heap and scheduler latency over time.
+func reportRuntime(w io.Writer, rt *RuntimeSummary) {
+	if rt == nil {
+		return
+	}
+	fmt.Fprintf(w, "\nRuntime:\n")
+	fmt.Fprintf(w, "  Peak Goroutines: %d\n", rt.PeakGoroutines)
+	fmt.Fprintf(w, "  GC: %d cycles, %v paused\n", rt.GCCycles, rt.GCPause.Round(time.Microsecond))
+	fmt.Fprintf(w, "  Scheduler Latency p99: %v\n", rt.SchedP99)
+	if len(rt.Samples) < 2 {
+		return
+	}
+
+	series := func(get func(RuntimeSample) float64) string {
+		values := make([]float64, len(rt.Samples))
+		for i, s := range rt.Samples {
+			values[i] = get(s)
+		}
+		return sparkline(values, sparklineWidth)
+	}
+	fmt.Fprintf(w, "  Goroutines: %s\n", series(func(s RuntimeSample) float64 { return float64(s.Goroutines) }))
+	fmt.Fprintf(w, "  Heap:       %s\n", series(func(s RuntimeSample) float64 { return float64(s.HeapBytes) }))
+	fmt.Fprintf(w, "  Sched p99:  %s (per %v)\n", series(func(s RuntimeSample) float64 { return float64(s.SchedP99) }), rt.Interval)
+}
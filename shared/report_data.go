@@ -0,0 +1,90 @@
+package shared
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// reportDocument is the JSON form of a report.
+type reportDocument struct {
+	TotalTime         time.Duration     `json:"total_time_ns"`
+	RequestsPerSecond float64           `json:"requests_per_second"`
+	Summary           Summary           `json:"summary"`
+	Memory            map[string]uint64 `json:"memory,omitempty"`
+}
+
+func reportJSON(w io.Writer, summary Summary, totalTime time.Duration, memProfile map[string]uint64) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(reportDocument{
+		TotalTime:         totalTime,
+		RequestsPerSecond: requestsPerSecond(summary.Count, totalTime),
+		Summary:           summary,
+		Memory:            memProfile,
+	})
+}
+
+// reportCSV writes a header row and a single data row, so the output of
+// several runs can be concatenated (minus headers) into one sheet.
+// Durations are in nanoseconds.
+func reportCSV(w io.Writer, summary Summary, totalTime time.Duration, memProfile map[string]uint64) error {
+	var header, row []string
+	add := func(name string, value any) {
+		header = append(header, name)
+		row = append(row, fmt.Sprint(value))
+	}
+	ns := func(d time.Duration) int64 { return int64(d) }
+
+	add("partial", summary.Partial)
+	add("count", summary.Count)
+	add("errors", summary.Errors)
+	add("total_time_ns", ns(totalTime))
+	add("requests_per_second", strconv.FormatFloat(requestsPerSecond(summary.Count, totalTime), 'f', 2, 64))
+	add("mean_ns", ns(summary.Mean))
+	add("stddev_ns", ns(summary.StdDev))
+	add("min_ns", ns(summary.Min))
+	add("median_ns", ns(summary.Median))
+	for _, p := range summary.Percentiles {
+		add(formatPercentile(p.P)+"_ns", ns(p.Latency))
+	}
+	add("max_ns", ns(summary.Max))
+	for _, p := range summary.Corrected {
+		add("corrected_"+formatPercentile(p.P)+"_ns", ns(p.Latency))
+	}
+	add("bytes_total", summary.Transfer.Total)
+	add("retried", summary.Retries.Retried)
+	for _, status := range slices.Sorted(maps.Keys(summary.StatusCounts)) {
+		add("status_"+strconv.Itoa(status), summary.StatusCounts[status])
+	}
+	for _, class := range slices.Sorted(maps.Keys(summary.ErrorClasses)) {
+		add("error_"+string(class), summary.ErrorClasses[class])
+	}
+	if slo := summary.SLO; slo != nil {
+		add("slo_pass", slo.Pass)
+		if slo.Apdex != nil {
+			add("apdex", strconv.FormatFloat(*slo.Apdex, 'f', 3, 64))
+		}
+	}
+	for _, key := range slices.Sorted(maps.Keys(memProfile)) {
+		add("mem_"+key, memProfile[key])
+	}
+
+	cw := csv.NewWriter(w)
+	cw.Write(header)
+	cw.Write(row)
+	cw.Flush()
+	return cw.Error()
+}
+
+func requestsPerSecond(count int, totalTime time.Duration) float64 {
+	if totalTime <= 0 {
+		return 0
+	}
+	return float64(count) / totalTime.Seconds()
+}
@@ -0,0 +1,194 @@
+package shared
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The HTML report is a single self-contained page: charts are inline SVG,
+// so it can be mailed or attached to a ticket as is.
+
+const (
+	chartWidth  = 640
+	chartHeight = 200
+	chartMargin = 40
+)
+
+type chartBar struct {
+	X, Y, Width, Height float64
+	Label               string
+	Title               string
+}
+
+type chart struct {
+	Title  string
+	Width  int
+	Height int
+	Bars   []chartBar
+	// Line holds polyline points for time series charts.
+	Line string
+	// YMax labels the top of the y axis.
+	YMax string
+}
+
+type htmlReport struct {
+	Title    string
+	Summary  Summary
+	Total    time.Duration
+	RPS      float64
+	Memory   map[string]uint64
+	Charts   []chart
+	Statuses []statusRow
+}
+
+type statusRow struct {
+	Status string
+	Count  int
+}
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"pct":   formatPercentile,
+	"bytes": formatBytes,
+	"add":   func(a, b float64) float64 { return a + b },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 1.5em; }
+td, th { padding: 2px 12px; text-align: right; border-bottom: 1px solid #ddd; }
+th { text-align: left; }
+svg { display: block; margin-bottom: 1.5em; }
+svg text { font-size: 10px; fill: #555; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{if .Summary.Partial}}<p><strong>Partial run: interrupted before it finished.</strong></p>
+{{end}}<table>
+<tr><th>Requests</th><td>{{.Summary.Count}}</td></tr>
+<tr><th>Errors</th><td>{{.Summary.Errors}}</td></tr>
+<tr><th>Total time</th><td>{{.Total}}</td></tr>
+<tr><th>Requests/s</th><td>{{printf "%.2f" .RPS}}</td></tr>
+<tr><th>Mean</th><td>{{.Summary.Mean}}</td></tr>
+<tr><th>Min</th><td>{{.Summary.Min}}</td></tr>
+{{range .Summary.Percentiles}}<tr><th>{{pct .P}}</th><td>{{.Latency}}</td></tr>
+{{end}}<tr><th>Max</th><td>{{.Summary.Max}}</td></tr>
+{{range .Summary.Corrected}}<tr><th>{{pct .P}} corrected</th><td>{{.Latency}}</td></tr>
+{{end}}
+<tr><th>Received</th><td>{{bytes .Summary.Transfer.Total}}</td></tr>
+{{with .Summary.Warmup}}<tr><th>Warm-up (excluded)</th><td>{{.Requests}} requests in {{.Duration}}</td></tr>
+{{end}}</table>
+{{with .Summary.Stages}}<h2>Stages</h2>
+<table>
+<tr><th>Stage</th><th>Requests</th><th>Requests/s</th><th>Failures</th><th>Mean</th><th>p50</th><th>p99</th></tr>
+{{range .}}<tr><th>{{.Name}}{{if .Knee}} (knee){{end}}</th><td>{{.Requests}}</td><td>{{printf "%.1f" .Throughput}}</td><td>{{.Failures}}</td><td>{{.Mean}}</td><td>{{.P50}}</td><td>{{.P99}}</td></tr>
+{{end}}</table>
+{{end}}{{range .Charts}}<h2>{{.Title}}</h2>
+<svg width="{{.Width}}" height="{{.Height}}" xmlns="http://www.w3.org/2000/svg">
+<text x="0" y="10">{{.YMax}}</text>
+{{range .Bars}}<rect x="{{.X}}" y="{{.Y}}" width="{{.Width}}" height="{{.Height}}" fill="#4a7ebb"><title>{{.Title}}</title></rect>
+<text x="{{.X}}" y="{{add .Y .Height | add 12}}">{{.Label}}</text>
+{{end}}{{if .Line}}<polyline points="{{.Line}}" fill="none" stroke="#4a7ebb" stroke-width="2"/>
+{{end}}</svg>
+{{end}}<h2>Status codes</h2>
+<table>
+{{range .Statuses}}<tr><th>{{.Status}}</th><td>{{.Count}}</td></tr>
+{{end}}</table>
+{{with .Memory}}<h2>Memory</h2>
+<table>
+{{range $k, $v := .}}<tr><th>{{$k}}</th><td>{{$v}}</td></tr>
+{{end}}</table>
+{{end}}</body>
+</html>
+`))
+
+func reportHTML(w io.Writer, title string, summary Summary, totalTime time.Duration, memProfile map[string]uint64) error {
+	r := htmlReport{
+		Title:   title,
+		Summary: summary,
+		Total:   totalTime,
+		RPS:     requestsPerSecond(summary.Count, totalTime),
+		Memory:  memProfile,
+	}
+
+	var labels []string
+	var counts []float64
+	for _, b := range summary.Histogram {
+		labels = append(labels, time.Duration(b.Upper).Round(time.Microsecond).String())
+		counts = append(counts, float64(b.Count))
+	}
+	r.Charts = append(r.Charts, barChart("Latency distribution", labels, counts))
+
+	if len(summary.Timeline.Requests) > 1 {
+		r.Charts = append(r.Charts, lineChart(
+			fmt.Sprintf("Requests per second (per %v)", summary.Timeline.Interval),
+			summary.Timeline.Rates()))
+	}
+
+	labels, counts = nil, nil
+	for _, status := range slices.Sorted(maps.Keys(summary.StatusCounts)) {
+		name := strconv.Itoa(status)
+		if status == 0 {
+			name = "no response"
+		}
+		r.Statuses = append(r.Statuses, statusRow{name, summary.StatusCounts[status]})
+		labels = append(labels, name)
+		counts = append(counts, float64(summary.StatusCounts[status]))
+	}
+	r.Charts = append(r.Charts, barChart("Status breakdown", labels, counts))
+
+	return htmlTemplate.Execute(w, r)
+}
+
+func barChart(title string, labels []string, values []float64) chart {
+	c := chart{Title: title, Width: chartWidth, Height: chartHeight}
+	if len(values) == 0 {
+		return c
+	}
+	top := slices.Max(values)
+	c.YMax = strconv.FormatFloat(top, 'f', -1, 64)
+	slot := float64(chartWidth) / float64(len(values))
+	plot := float64(chartHeight - chartMargin)
+	for i, v := range values {
+		h := 0.0
+		if top > 0 {
+			h = v / top * (plot - 12)
+		}
+		c.Bars = append(c.Bars, chartBar{
+			X:      float64(i)*slot + 2,
+			Y:      plot - h,
+			Width:  slot - 4,
+			Height: h,
+			Label:  labels[i],
+			Title:  fmt.Sprintf("%s: %v", labels[i], v),
+		})
+	}
+	return c
+}
+
+func lineChart(title string, values []float64) chart {
+	c := chart{Title: title, Width: chartWidth, Height: chartHeight}
+	top := slices.Max(values)
+	c.YMax = strconv.FormatFloat(top, 'f', 1, 64)
+	plot := float64(chartHeight - chartMargin)
+	step := float64(chartWidth) / float64(len(values)-1)
+	var points []string
+	for i, v := range values {
+		y := plot
+		if top > 0 {
+			y = plot - v/top*(plot-12)
+		}
+		points = append(points, fmt.Sprintf("%.1f,%.1f", float64(i)*step, y))
+	}
+	c.Line = strings.Join(points, " ")
+	return c
+}
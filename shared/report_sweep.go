@@ -0,0 +1,96 @@
+package shared
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+// SweepPoint is the outcome of one run of a concurrency sweep.
+type SweepPoint struct {
+	Concurrency int           `json:"concurrency"`
+	TotalTime   time.Duration `json:"total_time_ns"`
+	Summary     Summary       `json:"summary"`
+}
+
+// RequestsPerSecond is the throughput of the run.
+func (p SweepPoint) RequestsPerSecond() float64 {
+	return requestsPerSecond(p.Summary.Count, p.TotalTime)
+}
+
+// ReportSweep writes one row per concurrency level, as a table for text
+// output, or as JSON or CSV to plot throughput against concurrency.
+func ReportSweep(w io.Writer, format Format, points []SweepPoint) error {
+	switch format {
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(points)
+	case FormatCSV:
+		return reportSweepCSV(w, points)
+	case FormatText:
+		reportSweepText(w, points)
+		return nil
+	default:
+		return fmt.Errorf("%s output isn't supported for sweeps", format)
+	}
+}
+
+func reportSweepText(w io.Writer, points []SweepPoint) {
+	if len(points) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\n\nConcurrency Sweep:\n")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "concurrency\trequests\terrors\treq/s\tmean\t")
+	for _, p := range points[0].Summary.Percentiles {
+		fmt.Fprintf(tw, "%s\t", formatPercentile(p.P))
+	}
+	fmt.Fprintln(tw)
+	rates := make([]float64, len(points))
+	for i, point := range points {
+		s := point.Summary
+		rates[i] = point.RequestsPerSecond()
+		fmt.Fprintf(tw, "%d\t%d\t%d\t%.1f\t%v\t", point.Concurrency, s.Count, s.Errors, rates[i], s.Mean)
+		for _, p := range s.Percentiles {
+			fmt.Fprintf(tw, "%v\t", p.Latency)
+		}
+		fmt.Fprintln(tw)
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "Throughput: %s\n", sparkline(rates, len(rates)))
+}
+
+// reportSweepCSV writes a header row and a row per concurrency level.
+// Durations are in nanoseconds.
+func reportSweepCSV(w io.Writer, points []SweepPoint) error {
+	cw := csv.NewWriter(w)
+	if len(points) > 0 {
+		header := []string{"concurrency", "count", "errors", "total_time_ns", "requests_per_second", "mean_ns"}
+		for _, p := range points[0].Summary.Percentiles {
+			header = append(header, formatPercentile(p.P)+"_ns")
+		}
+		cw.Write(header)
+	}
+	for _, point := range points {
+		s := point.Summary
+		row := []string{
+			strconv.Itoa(point.Concurrency),
+			strconv.Itoa(s.Count),
+			strconv.Itoa(s.Errors),
+			strconv.FormatInt(int64(point.TotalTime), 10),
+			strconv.FormatFloat(point.RequestsPerSecond(), 'f', 2, 64),
+			strconv.FormatInt(int64(s.Mean), 10),
+		}
+		for _, p := range s.Percentiles {
+			row = append(row, strconv.FormatInt(int64(p.Latency), 10))
+		}
+		cw.Write(row)
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,37 @@
+package shared
+
+import "time"
+
+// Result is the outcome of a single request.
+type Result struct {
+	Latency time.Duration
+	// Status is 0 when no response was received.
+	Status int
+	// Err is the error that ended the request, if any, and ErrorClass its
+	// category. Non-2xx responses have ErrorClass set but no Err.
+	Err        error
+	ErrorClass ErrorClass
+	// Bytes is the size of the response body.
+	Bytes int64
+
+	Start    time.Time
+	End      time.Time
+	WorkerID int
+	// Endpoint names the endpoint the request went to, when a run has
+	// several.
+	Endpoint string
+	// Intended is when an open-loop schedule meant the request to start,
+	// or zero in closed-loop runs. Start lags it when the run falls behind.
+	Intended time.Time
+
+	Trace Trace
+
+	// ValidationError describes why the response failed validation, or is
+	// empty if it passed.
+	ValidationError string
+
+	// Attempts is the number of tries made, including retries.
+	// FirstLatency is the latency of the first try alone.
+	Attempts     int
+	FirstLatency time.Duration
+}
@@ -0,0 +1,167 @@
+package shared
+
+import (
+	"math"
+	"runtime/metrics"
+	"slices"
+	"sync"
+	"time"
+)
+
+// RuntimeSample is a reading of the Go runtime over one interval of a run.
+type RuntimeSample struct {
+	Goroutines uint64        `json:"goroutines"`
+	HeapBytes  uint64        `json:"heap_bytes"`
+	GCCycles   uint64        `json:"gc_cycles"`
+	GCPause    time.Duration `json:"gc_pause_ns"`
+	// SchedP99 is the 99th percentile time goroutines spent runnable
+	// before running.
+	SchedP99 time.Duration `json:"sched_p99_ns"`
+}
+
+// RuntimeSummary describes the Go runtime's behaviour over a whole run,
+// with one sample per interval.
+type RuntimeSummary struct {
+	Interval       time.Duration   `json:"interval_ns"`
+	PeakGoroutines uint64          `json:"peak_goroutines"`
+	GCCycles       uint64          `json:"gc_cycles"`
+	GCPause        time.Duration   `json:"gc_pause_ns"`
+	SchedP99       time.Duration   `json:"sched_p99_ns"`
+	Samples        []RuntimeSample `json:"samples"`
+}
+
+var runtimeMetrics = []string{
+	"/sched/goroutines:goroutines",
+	"/memory/classes/heap/objects:bytes",
+	"/gc/cycles/total:gc-cycles",
+	"/sched/pauses/total/gc:seconds",
+	"/sched/latencies:seconds",
+}
+
+// runtimeReading holds the cumulative runtime metrics at one point in time.
+type runtimeReading struct {
+	goroutines, heap, cycles uint64
+	pauses, latencies        *metrics.Float64Histogram
+}
+
+func readRuntime() runtimeReading {
+	samples := make([]metrics.Sample, len(runtimeMetrics))
+	for i, name := range runtimeMetrics {
+		samples[i].Name = name
+	}
+	metrics.Read(samples)
+	return runtimeReading{
+		goroutines: samples[0].Value.Uint64(),
+		heap:       samples[1].Value.Uint64(),
+		cycles:     samples[2].Value.Uint64(),
+		pauses:     samples[3].Value.Float64Histogram(),
+		latencies:  samples[4].Value.Float64Histogram(),
+	}
+}
+
+// SampleRuntime reads the Go runtime metrics every interval until stop is
+// called, adding them to the Summary.
+func (c *Collector) SampleRuntime(interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		first := readRuntime()
+		prev := first
+		// each sample makes a new summary and swaps it in under the lock,
+		// so a Summary taken meanwhile never sees one half written; they
+		// share the samples, which are only ever appended to
+		var samples []RuntimeSample
+		var peak uint64
+		sample := func() {
+			cur := readRuntime()
+			samples = append(samples, RuntimeSample{
+				Goroutines: cur.goroutines,
+				HeapBytes:  cur.heap,
+				GCCycles:   cur.cycles - prev.cycles,
+				GCPause:    histogramSum(cur.pauses, prev.pauses),
+				SchedP99:   histogramPercentile(cur.latencies, prev.latencies, 99),
+			})
+			peak = max(peak, cur.goroutines)
+			summary := &RuntimeSummary{
+				Interval:       interval,
+				PeakGoroutines: peak,
+				GCCycles:       cur.cycles - first.cycles,
+				GCPause:        histogramSum(cur.pauses, first.pauses),
+				SchedP99:       histogramPercentile(cur.latencies, first.latencies, 99),
+				Samples:        slices.Clip(samples),
+			}
+			prev = cur
+
+			c.mu.Lock()
+			c.runtime = summary
+			c.mu.Unlock()
+		}
+
+		for {
+			select {
+			case <-done:
+				sample()
+				return
+			case <-ticker.C:
+				sample()
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		wg.Wait()
+	}
+}
+
+// histogramSum estimates the total of the values added to a runtime
+// histogram since prev, from the bucket midpoints.
+func histogramSum(cur, prev *metrics.Float64Histogram) time.Duration {
+	var sum float64
+	for i, n := range cur.Counts {
+		if n -= prev.Counts[i]; n > 0 {
+			sum += float64(n) * bucketMidpoint(cur.Buckets, i)
+		}
+	}
+	return time.Duration(sum * float64(time.Second))
+}
+
+// histogramPercentile returns the upper bound of the bucket holding the
+// pth percentile of the values added to a runtime histogram since prev.
+func histogramPercentile(cur, prev *metrics.Float64Histogram, p float64) time.Duration {
+	var total uint64
+	for i, n := range cur.Counts {
+		total += n - prev.Counts[i]
+	}
+	if total == 0 {
+		return 0
+	}
+	rank := uint64(math.Ceil(p / 100 * float64(total)))
+	var seen uint64
+	for i, n := range cur.Counts {
+		seen += n - prev.Counts[i]
+		if seen >= rank {
+			upper := cur.Buckets[i+1]
+			if math.IsInf(upper, 1) {
+				upper = cur.Buckets[i]
+			}
+			return time.Duration(upper * float64(time.Second))
+		}
+	}
+	return 0
+}
+
+func bucketMidpoint(buckets []float64, i int) float64 {
+	lo, hi := buckets[i], buckets[i+1]
+	switch {
+	case math.IsInf(lo, -1):
+		return hi
+	case math.IsInf(hi, 1):
+		return lo
+	}
+	return (lo + hi) / 2
+}
@@ -0,0 +1,30 @@
+package shared
+
+import (
+	"runtime"
+	"testing"
+	"time"
+)
+
+// run with -race: Summary reads the runtime summary while the sampler
+// keeps replacing it
+func TestSampleRuntimeWhileSummarizing(t *testing.T) {
+	// on one P the sampler seldom runs between two summaries
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+	c := NewCollector()
+	stop := c.SampleRuntime(time.Millisecond)
+	deadline := time.Now().Add(50 * time.Millisecond)
+	for time.Now().Before(deadline) {
+		if rt := c.Summary().Runtime; rt != nil && len(rt.Samples) == 0 {
+			t.Fatal("runtime summary with no samples")
+		}
+	}
+	stop()
+	rt := c.Summary().Runtime
+	if rt == nil || len(rt.Samples) == 0 {
+		t.Fatalf("got runtime summary %+v, want samples", rt)
+	}
+	if rt.PeakGoroutines == 0 {
+		t.Error("no peak goroutine count")
+	}
+}
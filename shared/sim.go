@@ -0,0 +1,130 @@
+package shared
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/aawadall/go-concurrency-patterns/config"
+)
+
+// SimTarget is an in-process target whose latency follows a configurable
+// distribution. It does no I/O, so results only depend on the pattern. A
+// Batch takes one round trip, and each request in it fails at ErrorRate.
+type SimTarget struct {
+	Distribution string
+	Latency      time.Duration
+	Jitter       time.Duration
+	SlowLatency  time.Duration
+	SlowFraction float64
+	ErrorRate    float64
+	// Quiet suppresses the progress dot printed per request.
+	Quiet bool
+
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+func NewSimTarget(cfg *config.Config) (*SimTarget, error) {
+	switch cfg.SimDistribution {
+	case "constant", "uniform", "normal", "lognormal", "bimodal":
+	default:
+		return nil, fmt.Errorf("unknown latency distribution %q", cfg.SimDistribution)
+	}
+	return &SimTarget{
+		Distribution: cfg.SimDistribution,
+		Latency:      cfg.SimLatency,
+		Jitter:       cfg.SimJitter,
+		SlowLatency:  cfg.SimSlowLatency,
+		SlowFraction: cfg.SimSlowFraction,
+		ErrorRate:    cfg.SimErrorRate,
+		Quiet:        cfg.Live || cfg.Quiet,
+		rng:          newRand(cfg.Seed, streamSim),
+	}, nil
+}
+
+func (t *SimTarget) Do(ctx context.Context) (Result, error) {
+	latency, fail := t.sample()
+
+	start := time.Now()
+	timer := time.NewTimer(latency)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		end := time.Now()
+		return Result{
+			Latency: end.Sub(start), Err: ctx.Err(), ErrorClass: classifyError(ctx.Err()),
+			Start: start, End: end, WorkerID: WorkerID(ctx), Attempts: 1,
+		}, ctx.Err()
+	case <-timer.C:
+	}
+
+	status, class := 200, ClassNone
+	if fail {
+		status, class = 500, ClassNon2xx
+	}
+	if b := BatchFrom(ctx); b != nil {
+		// one round trip, which each request in it can fail on its own
+		status, class = 200, ClassNone
+		b.Results = make([]Result, len(b.IDs))
+		for i := range b.Results {
+			if t.failed() {
+				b.Results[i] = Result{Status: 500, ErrorClass: ClassNon2xx}
+			} else {
+				b.Results[i] = Result{Status: 200}
+			}
+		}
+	}
+	end := time.Now()
+	if !t.Quiet {
+		fmt.Fprint(os.Stderr, ".")
+	}
+	return Result{
+		Latency: end.Sub(start), Status: status, ErrorClass: class,
+		Start: start, End: end, WorkerID: WorkerID(ctx),
+		Attempts: 1, FirstLatency: end.Sub(start),
+	}, nil
+}
+
+// failed draws whether one more request of a batch fails.
+func (t *SimTarget) failed() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.rng.Float64() < t.ErrorRate
+}
+
+// sample draws the latency and failure outcome of the next request.
+func (t *SimTarget) sample() (time.Duration, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	mean, spread := float64(t.Latency), float64(t.Jitter)
+	var d float64
+	switch t.Distribution {
+	case "constant":
+		d = mean
+	case "uniform":
+		d = mean - spread + 2*spread*t.rng.Float64()
+	case "normal":
+		d = mean + spread*t.rng.NormFloat64()
+	case "lognormal":
+		// Parameterised so that the median is Latency and Jitter/Latency
+		// is the shape.
+		sigma := 0.0
+		if mean > 0 {
+			sigma = spread / mean
+		}
+		d = mean * math.Exp(sigma*t.rng.NormFloat64())
+	case "bimodal":
+		if t.rng.Float64() < t.SlowFraction {
+			mean = float64(t.SlowLatency)
+		}
+		d = mean + spread*t.rng.NormFloat64()
+	}
+
+	return time.Duration(max(d, 0)), t.rng.Float64() < t.ErrorRate
+}
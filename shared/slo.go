@@ -0,0 +1,96 @@
+package shared
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/aawadall/go-concurrency-patterns/config"
+)
+
+// SLO holds the service level objectives a run is checked against.
+type SLO struct {
+	// Latency caps the latency at given percentiles.
+	Latency []config.LatencyObjective
+	// MaxErrorRate is the highest fraction of failed requests allowed;
+	// 0 disables the check.
+	MaxErrorRate float64
+	// ApdexT is the Apdex threshold: requests within T are satisfied,
+	// within 4T tolerating. 0 disables Apdex.
+	ApdexT time.Duration
+	// MinApdex fails the run when the Apdex score is below it.
+	MinApdex float64
+}
+
+func NewSLO(cfg *config.Config) SLO {
+	return SLO{
+		Latency:      slices.Clone(cfg.LatencySLO),
+		MaxErrorRate: cfg.MaxErrorRate,
+		ApdexT:       cfg.ApdexT,
+		MinApdex:     cfg.MinApdex,
+	}
+}
+
+func (s SLO) empty() bool {
+	return len(s.Latency) == 0 && s.MaxErrorRate == 0 && s.ApdexT == 0
+}
+
+// SLOResult is the outcome of checking a run against its SLO.
+type SLOResult struct {
+	Pass   bool       `json:"pass"`
+	Checks []SLOCheck `json:"checks"`
+	// Apdex is the score between 0 and 1, present when ApdexT is set.
+	Apdex  *float64      `json:"apdex,omitempty"`
+	ApdexT time.Duration `json:"apdex_t_ns,omitempty"`
+}
+
+// SLOCheck is one objective and the value the run achieved.
+type SLOCheck struct {
+	Name   string `json:"name"`
+	Target string `json:"target"`
+	Actual string `json:"actual"`
+	Pass   bool   `json:"pass"`
+}
+
+// evaluate checks the SLO against the statistics gathered so far. The
+// caller holds c.mu.
+func (s SLO) evaluate(c *Collector) *SLOResult {
+	if s.empty() {
+		return nil
+	}
+	r := &SLOResult{Pass: true}
+	check := func(name, target, actual string, pass bool) {
+		r.Checks = append(r.Checks, SLOCheck{name, target, actual, pass})
+		r.Pass = r.Pass && pass
+	}
+
+	for _, o := range s.Latency {
+		actual := c.latencies.DurationAtPercentile(o.Percentile)
+		check(formatPercentile(o.Percentile), "< "+o.Max.String(), actual.String(), actual < o.Max)
+	}
+	if s.MaxErrorRate > 0 {
+		rate := 0.0
+		if c.count > 0 {
+			rate = float64(c.failures()) / float64(c.count)
+		}
+		check("error rate", fmt.Sprintf("< %.2f%%", s.MaxErrorRate*100), fmt.Sprintf("%.2f%%", rate*100), rate < s.MaxErrorRate)
+	}
+	if s.ApdexT > 0 {
+		score := apdex(c.latencies, s.ApdexT)
+		r.Apdex, r.ApdexT = &score, s.ApdexT
+		if s.MinApdex > 0 {
+			check("apdex", fmt.Sprintf(">= %.2f", s.MinApdex), fmt.Sprintf("%.2f", score), score >= s.MinApdex)
+		}
+	}
+	return r
+}
+
+// apdex scores latencies as (satisfied + tolerating/2) / total.
+func apdex(h *Histogram, t time.Duration) float64 {
+	if h.Count() == 0 {
+		return 0
+	}
+	satisfied := h.CountAtOrBelow(int64(t))
+	tolerating := h.CountAtOrBelow(int64(4*t)) - satisfied
+	return (float64(satisfied) + float64(tolerating)/2) / float64(h.Count())
+}
@@ -0,0 +1,17 @@
+//go:build !sqlite
+
+package shared
+
+import "errors"
+
+// RunStore appends runs to an SQLite database. This build has no SQLite
+// support.
+type RunStore struct{}
+
+func OpenRunStore(path, pattern string) (*RunStore, error) {
+	return nil, errors.New("SQLite support is not compiled in, rebuild with -tags sqlite")
+}
+
+func (s *RunStore) Record(r Result) {}
+
+func (s *RunStore) Close(m Manifest) error { return nil }
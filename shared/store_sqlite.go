@@ -0,0 +1,128 @@
+//go:build sqlite
+
+package shared
+
+import (
+	"database/sql"
+	"encoding/json"
+	"sync"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const storeSchema = `
+CREATE TABLE IF NOT EXISTS runs (
+	id          INTEGER PRIMARY KEY,
+	pattern     TEXT NOT NULL,
+	started     TEXT NOT NULL,
+	ended       TEXT,
+	version     TEXT,
+	config_hash TEXT,
+	count       INTEGER,
+	errors      INTEGER,
+	rps         REAL,
+	mean_ns     INTEGER,
+	median_ns   INTEGER,
+	max_ns      INTEGER,
+	manifest    TEXT
+);
+CREATE TABLE IF NOT EXISTS samples (
+	run_id      INTEGER NOT NULL REFERENCES runs(id),
+	worker      INTEGER,
+	start_ns    INTEGER,
+	latency_ns  INTEGER,
+	status      INTEGER,
+	error_class TEXT,
+	bytes       INTEGER
+);
+CREATE INDEX IF NOT EXISTS samples_run ON samples(run_id);
+`
+
+// RunStore appends runs and their per-request samples to an SQLite
+// database. Samples are written in one transaction, committed by Close.
+type RunStore struct {
+	db    *sql.DB
+	runID int64
+
+	mu   sync.Mutex
+	tx   *sql.Tx
+	stmt *sql.Stmt
+	err  error
+}
+
+// OpenRunStore opens or creates the database at path and starts a run of
+// the given pattern.
+func OpenRunStore(path, pattern string) (*RunStore, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, err
+	}
+	s := &RunStore{db: db}
+	if err := s.begin(pattern); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *RunStore) begin(pattern string) error {
+	if _, err := s.db.Exec(storeSchema); err != nil {
+		return err
+	}
+	res, err := s.db.Exec(`INSERT INTO runs (pattern, started) VALUES (?, ?)`,
+		pattern, time.Now().Format(time.RFC3339Nano))
+	if err != nil {
+		return err
+	}
+	if s.runID, err = res.LastInsertId(); err != nil {
+		return err
+	}
+	if s.tx, err = s.db.Begin(); err != nil {
+		return err
+	}
+	s.stmt, err = s.tx.Prepare(`INSERT INTO samples
+		(run_id, worker, start_ns, latency_ns, status, error_class, bytes)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`)
+	return err
+}
+
+// Record stores one sample. Errors are kept and returned by Close.
+func (s *RunStore) Record(r Result) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return
+	}
+	_, s.err = s.stmt.Exec(s.runID, r.WorkerID, r.Start.UnixNano(), int64(r.Latency),
+		r.Status, string(r.ErrorClass), r.Bytes)
+}
+
+// Close commits the samples, fills in the run's results from m and closes
+// the database.
+func (s *RunStore) Close(m Manifest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.db.Close()
+
+	s.stmt.Close()
+	if s.err != nil {
+		s.tx.Rollback()
+		return s.err
+	}
+	if err := s.tx.Commit(); err != nil {
+		return err
+	}
+
+	manifest, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`UPDATE runs SET ended = ?, version = ?, config_hash = ?, count = ?,
+		errors = ?, rps = ?, mean_ns = ?, median_ns = ?, max_ns = ?, manifest = ?
+		WHERE id = ?`,
+		m.End.Format(time.RFC3339Nano), m.Version, m.ConfigHash, m.Summary.Count,
+		m.Summary.Errors, m.RequestsPerSecond, int64(m.Summary.Mean),
+		int64(m.Summary.Median), int64(m.Summary.Max), string(manifest), s.runID)
+	return err
+}
@@ -0,0 +1,127 @@
+package shared
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aawadall/go-concurrency-patterns/config"
+)
+
+// Target is anything a pattern can send load to. Do performs one logical
+// request; the error is non-nil when the request could not complete.
+type Target interface {
+	Do(ctx context.Context) (Result, error)
+}
+
+// NewTarget returns the Target selected by cfg.Mode, spreading requests
+// over cfg.Endpoints if there are any.
+func NewTarget(cfg *config.Config) (Target, error) {
+	if len(cfg.Endpoints) > 0 {
+		return newWeightedTarget(cfg)
+	}
+	switch cfg.Mode {
+	case "", "http":
+		return &HTTPTarget{Config: cfg}, nil
+	case "grpc":
+		return &GRPCTarget{Config: cfg}, nil
+	case "ws":
+		return &WebSocketTarget{Config: cfg}, nil
+	case "sim":
+		return NewSimTarget(cfg)
+	default:
+		return nil, fmt.Errorf("unknown mode %q", cfg.Mode)
+	}
+}
+
+type workerIDKey struct{}
+
+// WithWorkerID tags ctx with the ID of the worker issuing requests, which is
+// exposed to request templates and used to pick per-worker connections.
+func WithWorkerID(ctx context.Context, workerID int) context.Context {
+	return context.WithValue(ctx, workerIDKey{}, workerID)
+}
+
+// WorkerID returns the worker ID stored in ctx, or 0.
+func WorkerID(ctx context.Context) int {
+	id, _ := ctx.Value(workerIDKey{}).(int)
+	return id
+}
+
+// HTTPTarget sends HTTP requests as described by Config. It makes a
+// Batch's bulk call as one POST to the test server's /batch.
+type HTTPTarget struct {
+	Config *config.Config
+}
+
+func (t *HTTPTarget) Do(ctx context.Context) (Result, error) {
+	if b := BatchFrom(ctx); b != nil {
+		return doBatch(ctx, t.Config, b)
+	}
+	return fetch(ctx, t.Config, WorkerID(ctx), doRequest)
+}
+
+// GRPCTarget makes unary gRPC calls as described by Config.
+type GRPCTarget struct {
+	Config *config.Config
+}
+
+func (t *GRPCTarget) Do(ctx context.Context) (Result, error) {
+	return fetch(ctx, t.Config, WorkerID(ctx), doGRPC)
+}
+
+// WebSocketTarget does echo round trips over one connection per worker.
+type WebSocketTarget struct {
+	Config *config.Config
+}
+
+func (t *WebSocketTarget) Do(ctx context.Context) (Result, error) {
+	return fetch(ctx, t.Config, WorkerID(ctx), doWebSocket)
+}
+
+// SleepTarget does no I/O; every request takes Latency and succeeds, as
+// does every request of a Batch.
+type SleepTarget struct {
+	Latency time.Duration
+}
+
+func (t *SleepTarget) Do(ctx context.Context) (Result, error) {
+	start := time.Now()
+	select {
+	case <-ctx.Done():
+		end := time.Now()
+		return Result{
+			Latency: end.Sub(start), Err: ctx.Err(), ErrorClass: classifyError(ctx.Err()),
+			Start: start, End: end, WorkerID: WorkerID(ctx), Attempts: 1,
+		}, ctx.Err()
+	case <-time.After(t.Latency):
+	}
+	if b := BatchFrom(ctx); b != nil {
+		b.Results = make([]Result, len(b.IDs))
+		for i := range b.Results {
+			b.Results[i] = Result{Status: 200}
+		}
+	}
+	end := time.Now()
+	return Result{
+		Latency: end.Sub(start), Status: 200,
+		Start: start, End: end, WorkerID: WorkerID(ctx),
+		Attempts: 1, FirstLatency: end.Sub(start),
+	}, nil
+}
+
+// WithTimeout wraps t so that each call is canceled after d.
+func WithTimeout(t Target, d time.Duration) Target {
+	return TargetFunc(func(ctx context.Context) (Result, error) {
+		ctx, cancel := context.WithTimeout(ctx, d)
+		defer cancel()
+		return t.Do(ctx)
+	})
+}
+
+// TargetFunc adapts an ordinary function to the Target interface.
+type TargetFunc func(ctx context.Context) (Result, error)
+
+func (f TargetFunc) Do(ctx context.Context) (Result, error) {
+	return f(ctx)
+}
@@ -0,0 +1,73 @@
+package shared
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aawadall/go-concurrency-patterns/config"
+)
+
+func TestNewTarget(t *testing.T) {
+	tests := []struct {
+		mode    string
+		want    string
+		wantErr bool
+	}{
+		{mode: "", want: "*shared.HTTPTarget"},
+		{mode: "http", want: "*shared.HTTPTarget"},
+		{mode: "grpc", want: "*shared.GRPCTarget"},
+		{mode: "ws", want: "*shared.WebSocketTarget"},
+		{mode: "sim", want: "*shared.SimTarget"},
+		{mode: "carrier-pigeon", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			cfg := config.NewConfig("localhost", 5000)
+			cfg.Mode = tt.mode
+			target, err := NewTarget(cfg)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("got %T, want an error", target)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := fmt.Sprintf("%T", target); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSleepTarget(t *testing.T) {
+	target := &SleepTarget{Latency: 5 * time.Millisecond}
+	r, err := target.Do(WithWorkerID(context.Background(), 7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Status != 200 || r.WorkerID != 7 || r.Latency < 5*time.Millisecond {
+		t.Errorf("got %+v, want a 200 from worker 7 taking at least 5ms", r)
+	}
+
+	r, err = WithTimeout(&SleepTarget{Latency: time.Second}, 5*time.Millisecond).Do(context.Background())
+	if !errors.Is(err, context.DeadlineExceeded) || r.ErrorClass == ClassNone {
+		t.Errorf("got %v, class %v, want a timeout", err, r.ErrorClass)
+	}
+}
+
+func TestTargetFunc(t *testing.T) {
+	calls := 0
+	target := TargetFunc(func(ctx context.Context) (Result, error) {
+		calls++
+		return Result{Status: 204, WorkerID: WorkerID(ctx)}, nil
+	})
+	r, err := target.Do(WithWorkerID(context.Background(), 3))
+	if err != nil || r.Status != 204 || r.WorkerID != 3 || calls != 1 {
+		t.Errorf("got %+v, %v after %d calls", r, err, calls)
+	}
+}
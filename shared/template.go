@@ -0,0 +1,99 @@
+package shared
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"text/template"
+)
+
+// RequestData is the data a request template is evaluated against.
+type RequestData struct {
+	RequestID int64
+	WorkerID  int
+}
+
+// RequestTemplate renders the path and body of a request.
+type RequestTemplate struct {
+	path *template.Template
+	body *template.Template
+}
+
+var templateFuncs = template.FuncMap{
+	"randInt":    randInt,
+	"randString": randString,
+	"uuid":       uuid,
+}
+
+func NewRequestTemplate(path, body string) (*RequestTemplate, error) {
+	p, err := template.New("path").Funcs(templateFuncs).Parse(path)
+	if err != nil {
+		return nil, fmt.Errorf("parsing path template: %w", err)
+	}
+	b, err := template.New("body").Funcs(templateFuncs).Parse(body)
+	if err != nil {
+		return nil, fmt.Errorf("parsing body template: %w", err)
+	}
+	return &RequestTemplate{path: p, body: b}, nil
+}
+
+// Render evaluates the path and body templates for a single request.
+func (t *RequestTemplate) Render(data RequestData) (path, body string, err error) {
+	var buf bytes.Buffer
+	if err := t.path.Execute(&buf, data); err != nil {
+		return "", "", fmt.Errorf("rendering path: %w", err)
+	}
+	path = buf.String()
+
+	buf.Reset()
+	if err := t.body.Execute(&buf, data); err != nil {
+		return "", "", fmt.Errorf("rendering body: %w", err)
+	}
+	return path, buf.String(), nil
+}
+
+// templates caches parsed templates by their source so each request
+// doesn't re-parse them.
+var templates sync.Map
+
+func templateFor(path, body string) (*RequestTemplate, error) {
+	key := [2]string{path, body}
+	if t, ok := templates.Load(key); ok {
+		return t.(*RequestTemplate), nil
+	}
+	t, err := NewRequestTemplate(path, body)
+	if err != nil {
+		return nil, err
+	}
+	actual, _ := templates.LoadOrStore(key, t)
+	return actual.(*RequestTemplate), nil
+}
+
+func randInt(min, max int) int {
+	if max <= min {
+		return min
+	}
+	return min + templateRand.Intn(max-min)
+}
+
+const letters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+func randString(n int) string {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = letters[templateRand.Intn(len(letters))]
+	}
+	return string(b)
+}
+
+func uuid() string {
+	// two Uint64s rather than Read, which keeps state of its own in the
+	// Rand, outside the locked source
+	var b [16]byte
+	binary.BigEndian.PutUint64(b[:8], templateRand.Uint64())
+	binary.BigEndian.PutUint64(b[8:], templateRand.Uint64())
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
@@ -0,0 +1,59 @@
+package shared
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// thinking pauses each caller after every call to a target.
+type thinking struct {
+	target       Target
+	mean         time.Duration
+	distribution string
+
+	rng *rand.Rand
+}
+
+// ThinkTime wraps t so that each caller pauses after every call, the way
+// a user reads a page before clicking on. Pauses average mean, and are
+// constant, uniform between 0 and twice mean, or exponential, as the
+// distribution says, drawn from a generator seeded with seed. They come
+// after the result is measured, so they never count towards latency.
+func ThinkTime(t Target, mean time.Duration, distribution string, seed int64) (Target, error) {
+	switch distribution {
+	case "constant", "uniform", "exponential":
+	default:
+		return nil, fmt.Errorf("unknown think time distribution %q", distribution)
+	}
+	return &thinking{
+		target:       t,
+		mean:         mean,
+		distribution: distribution,
+		rng:          newRand(seed, streamThinkTime),
+	}, nil
+}
+
+func (t *thinking) Do(ctx context.Context) (Result, error) {
+	r, err := t.target.Do(ctx)
+	timer := time.NewTimer(t.pause())
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+	return r, err
+}
+
+// pause draws the next think time.
+func (t *thinking) pause() time.Duration {
+	switch t.distribution {
+	case "uniform":
+		return time.Duration(2 * float64(t.mean) * t.rng.Float64())
+	case "exponential":
+		return time.Duration(float64(t.mean) * t.rng.ExpFloat64())
+	default:
+		return t.mean
+	}
+}
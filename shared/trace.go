@@ -0,0 +1,100 @@
+package shared
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Trace holds the per-phase timings of a single HTTP request.
+type Trace struct {
+	DNS      time.Duration
+	Connect  time.Duration
+	TLS      time.Duration
+	TTFB     time.Duration
+	BodyRead time.Duration
+}
+
+// tracer records httptrace callbacks into a Trace. net/http may still
+// call them from a dial running in the background after Do has
+// returned, so they take a lock, and once stop is called they are
+// ignored.
+type tracer struct {
+	mu                     sync.Mutex
+	stopped                bool
+	start                  time.Time
+	dnsStart, connectStart time.Time
+	tlsStart, firstByte    time.Time
+	trace                  Trace
+}
+
+func newTracer(ctx context.Context) (*tracer, context.Context) {
+	t := &tracer{start: time.Now()}
+	ct := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { t.on(func() { t.dnsStart = time.Now() }) },
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.on(func() { t.trace.DNS = time.Since(t.dnsStart) })
+		},
+		ConnectStart: func(string, string) { t.on(func() { t.connectStart = time.Now() }) },
+		ConnectDone: func(string, string, error) {
+			t.on(func() { t.trace.Connect = time.Since(t.connectStart) })
+		},
+		TLSHandshakeStart: func() { t.on(func() { t.tlsStart = time.Now() }) },
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.on(func() { t.trace.TLS = time.Since(t.tlsStart) })
+		},
+		GotFirstResponseByte: func() {
+			t.on(func() {
+				t.firstByte = time.Now()
+				t.trace.TTFB = t.firstByte.Sub(t.start)
+			})
+		},
+	}
+	return t, httptrace.WithClientTrace(ctx, ct)
+}
+
+// on records a callback under the lock, unless the tracer is stopped.
+func (t *tracer) on(f func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.stopped {
+		f()
+	}
+}
+
+// stop ignores the callbacks from now on; it's called once Do returns.
+func (t *tracer) stop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.stopped = true
+}
+
+// bodyDone marks the end of the response body read.
+func (t *tracer) bodyDone() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.firstByte.IsZero() {
+		t.trace.BodyRead = time.Since(t.firstByte)
+	}
+}
+
+// result returns a copy of the trace so far.
+func (t *tracer) result() Trace {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.trace
+}
+
+// tracePhases lists the request phases in report order.
+var tracePhases = [...]struct {
+	name string
+	get  func(Trace) time.Duration
+}{
+	{"DNS", func(t Trace) time.Duration { return t.DNS }},
+	{"Connect", func(t Trace) time.Duration { return t.Connect }},
+	{"TLS", func(t Trace) time.Duration { return t.TLS }},
+	{"TTFB", func(t Trace) time.Duration { return t.TTFB }},
+	{"Body Read", func(t Trace) time.Duration { return t.BodyRead }},
+}
@@ -0,0 +1,150 @@
+package shared
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/aawadall/go-concurrency-patterns/config"
+)
+
+// connKey is what a client or connection depends on in a config. The
+// caches are keyed by it rather than by the config's address, so that
+// copies of a config, like the one each point of a sweep runs with, share
+// one client instead of each leaving one behind.
+type connKey struct {
+	scheme, host                  string
+	port                          int
+	protocol                      string
+	maxIdleConns, maxConnsPerHost int
+	insecureSkipVerify            bool
+	caFile, certFile, keyFile     string
+	unixSocket, resolver          string
+	localPortMin, localPortMax    int
+}
+
+func connKeyOf(cfg *config.Config) connKey {
+	return connKey{
+		scheme:             cfg.Scheme,
+		host:               cfg.Host,
+		port:               cfg.Port,
+		protocol:           cfg.Protocol,
+		maxIdleConns:       cfg.MaxIdleConns,
+		maxConnsPerHost:    cfg.MaxConnsPerHost,
+		insecureSkipVerify: cfg.InsecureSkipVerify,
+		caFile:             cfg.CAFile,
+		certFile:           cfg.CertFile,
+		keyFile:            cfg.KeyFile,
+		unixSocket:         cfg.UnixSocket,
+		resolver:           cfg.Resolver,
+		localPortMin:       cfg.LocalPortMin,
+		localPortMax:       cfg.LocalPortMax,
+	}
+}
+
+// clients caches one HTTP client per connection settings so connections
+// are reused across requests.
+var clients sync.Map
+
+func clientFor(cfg *config.Config) (*http.Client, error) {
+	key := connKeyOf(cfg)
+	if c, ok := clients.Load(key); ok {
+		return c.(*http.Client), nil
+	}
+	transport, err := newTransport(cfg)
+	if err != nil {
+		return nil, err
+	}
+	actual, loaded := clients.LoadOrStore(key, &http.Client{Transport: transport})
+	if loaded {
+		// another call made one first
+		if t, ok := transport.(interface{ CloseIdleConnections() }); ok {
+			t.CloseIdleConnections()
+		}
+	}
+	return actual.(*http.Client), nil
+}
+
+func newTransport(cfg *config.Config) (http.RoundTripper, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if cfg.MaxIdleConns > 0 {
+		transport.MaxIdleConns = cfg.MaxIdleConns
+		transport.MaxIdleConnsPerHost = cfg.MaxIdleConns
+	}
+	transport.MaxConnsPerHost = cfg.MaxConnsPerHost
+
+	dial, err := newDialer(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if dial != nil {
+		transport.DialContext = dial
+	}
+
+	var tlsConfig *tls.Config
+	if cfg.Scheme == "https" {
+		if tlsConfig, err = newTLSConfig(cfg); err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	protocols := new(http.Protocols)
+	switch cfg.Protocol {
+	case "":
+		return transport, nil
+	case "http1":
+		protocols.SetHTTP1(true)
+	case "h2":
+		if cfg.Scheme != "https" {
+			return nil, fmt.Errorf("protocol h2 requires https, use h2c for plain text")
+		}
+		protocols.SetHTTP2(true)
+	case "h2c":
+		protocols.SetUnencryptedHTTP2(true)
+	case "h3":
+		if tlsConfig == nil {
+			return nil, fmt.Errorf("protocol h3 requires https")
+		}
+		return newHTTP3Transport(tlsConfig)
+	default:
+		return nil, fmt.Errorf("unknown protocol %q", cfg.Protocol)
+	}
+	transport.Protocols = protocols
+	return transport, nil
+}
+
+func newTLSConfig(cfg *config.Config) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		ServerName:         cfg.Host,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+	}
+
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading CA file: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", cfg.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if cfg.CertFile != "" || cfg.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
@@ -0,0 +1,14 @@
+//go:build h3
+
+package shared
+
+import (
+	"crypto/tls"
+	"net/http"
+
+	"github.com/quic-go/quic-go/http3"
+)
+
+func newHTTP3Transport(tlsConfig *tls.Config) (http.RoundTripper, error) {
+	return &http3.Transport{TLSClientConfig: tlsConfig}, nil
+}
@@ -0,0 +1,13 @@
+//go:build !h3
+
+package shared
+
+import (
+	"crypto/tls"
+	"errors"
+	"net/http"
+)
+
+func newHTTP3Transport(*tls.Config) (http.RoundTripper, error) {
+	return nil, errors.New("HTTP/3 support is not compiled in, rebuild with -tags h3")
+}
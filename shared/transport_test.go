@@ -0,0 +1,40 @@
+package shared
+
+import (
+	"testing"
+
+	"github.com/aawadall/go-concurrency-patterns/config"
+)
+
+// copies of a config, as a sweep makes, share the cached client and
+// validator; a config with other connection settings gets its own
+func TestCachesKeyedBySettings(t *testing.T) {
+	cfg := &config.Config{Host: "127.0.0.1", Port: 5000, Sweep: []int{1, 2}, BodyContains: "ok"}
+	sweep := *cfg
+	sweep.Concurrency = 8
+	other := *cfg
+	other.MaxIdleConns = 3
+
+	c1, err := clientFor(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c2, _ := clientFor(&sweep)
+	c3, _ := clientFor(&other)
+	if c1 != c2 {
+		t.Error("a copy of the config got a client of its own")
+	}
+	if c1 == c3 {
+		t.Error("other connection settings got the same client")
+	}
+
+	v1, _ := validatorFor(cfg)
+	v2, _ := validatorFor(&sweep)
+	if v1 == nil || v1 != v2 {
+		t.Errorf("got validators %p and %p, want the same one", v1, v2)
+	}
+	other.BodyContains = "fine"
+	if v3, _ := validatorFor(&other); v3 == v1 {
+		t.Error("other expectations got the same validator")
+	}
+}
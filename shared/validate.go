@@ -0,0 +1,102 @@
+package shared
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"slices"
+	"sync"
+
+	"github.com/aawadall/go-concurrency-patterns/config"
+)
+
+// Validator checks responses against the expectations in the config.
+type Validator struct {
+	statuses []int
+	contains []byte
+	pattern  *regexp.Regexp
+	maxSize  int64
+}
+
+// NewValidator returns nil if the config sets no expectations.
+func NewValidator(cfg *config.Config) (*Validator, error) {
+	if len(cfg.ExpectStatus) == 0 && cfg.BodyContains == "" && cfg.BodyPattern == "" && cfg.MaxBodySize <= 0 {
+		return nil, nil
+	}
+	v := &Validator{
+		statuses: cfg.ExpectStatus,
+		contains: []byte(cfg.BodyContains),
+		maxSize:  cfg.MaxBodySize,
+	}
+	if cfg.BodyPattern != "" {
+		re, err := regexp.Compile(cfg.BodyPattern)
+		if err != nil {
+			return nil, fmt.Errorf("compiling body pattern: %w", err)
+		}
+		v.pattern = re
+	}
+	return v, nil
+}
+
+// needsBody reports whether Check looks at the response body. The size
+// limit doesn't: it is checked against the size alone.
+func (v *Validator) needsBody() bool {
+	return len(v.contains) > 0 || v.pattern != nil
+}
+
+// Check returns a description of the first failed expectation, or "".
+// size is the full body size, which may exceed len(body).
+func (v *Validator) Check(status int, body []byte, size int64) string {
+	if len(v.statuses) > 0 && !slices.Contains(v.statuses, status) {
+		return fmt.Sprintf("unexpected status %d", status)
+	}
+	if v.maxSize > 0 && size > v.maxSize {
+		return fmt.Sprintf("body exceeds %d bytes", v.maxSize)
+	}
+	if len(v.contains) > 0 && !bytes.Contains(body, v.contains) {
+		return fmt.Sprintf("body does not contain %q", v.contains)
+	}
+	if v.pattern != nil && !v.pattern.Match(body) {
+		return fmt.Sprintf("body does not match %q", v.pattern)
+	}
+	return ""
+}
+
+// validators caches the compiled validator per set of expectations.
+var validators sync.Map
+
+// validatorKey is what a validator depends on in a config, which is what
+// validators is keyed by, so copies of a config share one.
+type validatorKey struct {
+	expectStatus string
+	contains     string
+	pattern      string
+	maxSize      int64
+}
+
+type validatorEntry struct {
+	v   *Validator
+	err error
+}
+
+func validatorFor(cfg *config.Config) (*Validator, error) {
+	key := validatorKey{fmt.Sprint(cfg.ExpectStatus), cfg.BodyContains, cfg.BodyPattern, cfg.MaxBodySize}
+	if e, ok := validators.Load(key); ok {
+		return e.(validatorEntry).v, e.(validatorEntry).err
+	}
+	v, err := NewValidator(cfg)
+	validators.Store(key, validatorEntry{v, err})
+	return v, err
+}
+
+// validate applies the config's validator to a response, if there is one.
+func validate(cfg *config.Config, status int, body []byte, size int64) string {
+	v, err := validatorFor(cfg)
+	if err != nil {
+		return err.Error()
+	}
+	if v == nil {
+		return ""
+	}
+	return v.Check(status, body, size)
+}
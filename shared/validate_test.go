@@ -0,0 +1,73 @@
+package shared
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/aawadall/go-concurrency-patterns/config"
+)
+
+func TestValidatorCheck(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    config.Config
+		status int
+		body   string
+		want   string // "" for a pass, else a prefix of the failure
+	}{
+		{name: "no expectations", cfg: config.Config{}, status: 500, body: "x"},
+		{name: "expected status", cfg: config.Config{ExpectStatus: []int{200, 204}}, status: 204},
+		{name: "unexpected status", cfg: config.Config{ExpectStatus: []int{200}}, status: 404, want: "unexpected status"},
+		{name: "contains", cfg: config.Config{BodyContains: "ok"}, status: 200, body: "all ok"},
+		{name: "missing substring", cfg: config.Config{BodyContains: "ok"}, status: 200, body: "fail", want: "body does not contain"},
+		{name: "pattern", cfg: config.Config{BodyPattern: `^\d+$`}, status: 200, body: "42"},
+		{name: "no match", cfg: config.Config{BodyPattern: `^\d+$`}, status: 200, body: "x42", want: "body does not match"},
+		{name: "within size", cfg: config.Config{MaxBodySize: 4}, status: 200, body: "1234"},
+		{name: "too big", cfg: config.Config{MaxBodySize: 4}, status: 200, body: "12345", want: "body exceeds"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := NewValidator(&tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := ""
+			if v != nil {
+				got = v.Check(tt.status, []byte(tt.body), int64(len(tt.body)))
+			}
+			if (tt.want == "") != (got == "") || !strings.HasPrefix(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// a size limit alone neither keeps the body nor reads past the limit
+func TestRequestReadsUpToMaxBodySize(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("x", 1<<16)))
+	}))
+	defer srv.Close()
+	host, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+	cfg := &config.Config{Host: host, MaxBodySize: 100}
+	cfg.Port, _ = strconv.Atoi(port)
+
+	if v, _ := validatorFor(cfg); v.needsBody() {
+		t.Error("a size limit alone keeps the body")
+	}
+	r, err := doRequest(context.Background(), cfg, 0, "/", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Bytes != 101 {
+		t.Errorf("read %d bytes, want 101", r.Bytes)
+	}
+	if !strings.HasPrefix(r.ValidationError, "body exceeds") {
+		t.Errorf("got validation error %q", r.ValidationError)
+	}
+}
@@ -0,0 +1,209 @@
+// Package watchdog catches a demo that has stopped making progress. The
+// runtime only notices a deadlock when every goroutine is stuck; a
+// watchdog notices when the ones doing the work are, while timers,
+// signal handlers and the rest keep the process alive: once the progress
+// count it watches has not moved for an interval, it dumps every
+// goroutine, picks out the ones blocked on a channel or a lock as the
+// likely participants, and reports them.
+package watchdog
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aawadall/go-concurrency-patterns/shared/leakcheck"
+)
+
+// blocking lists the goroutine states of waiting on a channel or a lock,
+// which are the ones a deadlock is made of.
+var blocking = []string{
+	"chan receive",
+	"chan send",
+	"select",
+	"sync.Mutex.Lock",
+	"sync.RWMutex.Lock",
+	"sync.RWMutex.RLock",
+	"sync.Cond.Wait",
+	"semacquire",
+}
+
+// Options configures a watchdog.
+type Options struct {
+	// Interval is how long Progress may stay the same before the
+	// watchdog calls it a stall.
+	Interval time.Duration
+	// Progress returns a count that grows while the demo makes
+	// progress, such as Collector.Count.
+	Progress func() int
+	// OnStall is called with the report on a stall, after which the
+	// watchdog stops. If it is nil, the report is written to standard
+	// error and the process exits with status 2.
+	OnStall func(Report)
+}
+
+// Report is what a watchdog found on a stall.
+type Report struct {
+	// Stalled is how long Progress had not moved.
+	Stalled time.Duration
+	// Progress is where it stopped.
+	Progress int
+	// Blocked are the goroutines waiting on a channel or a lock, the
+	// likely participants in a deadlock; Others are the rest.
+	Blocked []Group
+	Others  []Group
+}
+
+// Group is a group of goroutines with the same state and stack.
+type Group struct {
+	Count int
+	State string
+	// Site is the first frame outside the runtime and the standard
+	// sync packages, where the goroutines are waiting.
+	Site  string
+	Stack string
+}
+
+// Start starts a watchdog; stop stops it, and waits for OnStall if it
+// is running.
+func Start(opts Options) (stop func()) {
+	if opts.Interval <= 0 {
+		panic("watchdog: Interval must be positive")
+	}
+	quit := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		// check a few times an interval, so that a stall is caught
+		// soon after it has lasted one
+		ticker := time.NewTicker(max(opts.Interval/4, time.Millisecond))
+		defer ticker.Stop()
+		last, since := opts.Progress(), time.Now()
+		for {
+			select {
+			case <-quit:
+				return
+			case now := <-ticker.C:
+				n := opts.Progress()
+				if n != last {
+					last, since = n, now
+					continue
+				}
+				if now.Sub(since) < opts.Interval {
+					continue
+				}
+				r := take(now.Sub(since), n)
+				if opts.OnStall != nil {
+					opts.OnStall(r)
+					return
+				}
+				r.Write(os.Stderr)
+				os.Exit(2)
+			}
+		}
+	}()
+	return func() {
+		select {
+		case <-quit:
+		default:
+			close(quit)
+		}
+		<-done
+	}
+}
+
+// take dumps the goroutines other than the watchdog's own.
+func take(stalled time.Duration, progress int) Report {
+	r := Report{Stalled: stalled, Progress: progress}
+	for _, g := range group(leakcheck.Goroutines()) {
+		if isBlocked(g.State) {
+			r.Blocked = append(r.Blocked, g)
+		} else {
+			r.Others = append(r.Others, g)
+		}
+	}
+	return r
+}
+
+func isBlocked(state string) bool {
+	for _, s := range blocking {
+		if strings.HasPrefix(state, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// group merges goroutines with the same state and stack, most common
+// first.
+func group(gs []leakcheck.Goroutine) []Group {
+	var groups []Group
+	index := make(map[[2]string]int)
+	for _, g := range gs {
+		key := [2]string{g.State, g.Stack}
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, Group{State: g.State, Site: site(g.Stack), Stack: g.Stack})
+		}
+		groups[i].Count++
+	}
+	slices.SortStableFunc(groups, func(a, b Group) int { return cmp.Compare(b.Count, a.Count) })
+	return groups
+}
+
+// site returns the first frame of stack outside the runtime and the
+// standard sync packages, as function and file:line.
+func site(stack string) string {
+	lines := strings.Split(stack, "\n")
+	for i := 0; i+1 < len(lines); i += 2 {
+		fn := strings.TrimSuffix(lines[i], "(...)")
+		if isInternal(fn) {
+			continue
+		}
+		file := strings.TrimSpace(lines[i+1])
+		file, _, _ = strings.Cut(file, " +0x")
+		return fn + " at " + file
+	}
+	return "unknown"
+}
+
+func isInternal(fn string) bool {
+	for _, prefix := range []string{"runtime.", "sync.", "sync/atomic.", "internal/", "time.", "context."} {
+		if strings.HasPrefix(fn, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Write writes r for a reader: the likely participants with their
+// stacks, then a line for each of the other goroutines.
+func (r Report) Write(w io.Writer) error {
+	blocked := 0
+	for _, g := range r.Blocked {
+		blocked += g.Count
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "watchdog: no progress for %v at %d; likely deadlock among %d goroutines\n",
+		r.Stalled.Round(time.Millisecond), r.Progress, blocked)
+	for _, g := range r.Blocked {
+		fmt.Fprintf(&b, "\n%d × [%s] %s\n", g.Count, g.State, g.Site)
+		for _, line := range strings.Split(g.Stack, "\n") {
+			fmt.Fprintf(&b, "    %s\n", line)
+		}
+	}
+	if len(r.Others) > 0 {
+		fmt.Fprintf(&b, "\nother goroutines:\n")
+		for _, g := range r.Others {
+			fmt.Fprintf(&b, "  %d × [%s] %s\n", g.Count, g.State, g.Site)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
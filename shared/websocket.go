@@ -0,0 +1,141 @@
+package shared
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/aawadall/go-concurrency-patterns/config"
+)
+
+// ConsumeWebSocket sends one message over the worker's persistent
+// connection and waits for the echo, regardless of cfg.Mode.
+func ConsumeWebSocket(cfg *config.Config, workerID int) (latency time.Duration, status int) {
+	r, _ := fetch(context.Background(), cfg, workerID, doWebSocket)
+	return r.Latency, r.Status
+}
+
+// wsKey names a connection by its settings, the path template it was
+// opened at and the worker using it.
+type wsKey struct {
+	conn     connKey
+	path     string
+	workerID int
+}
+
+// wsConn is a long-lived connection, used by one round trip at a time:
+// patterns that don't set a worker ID all share worker 0's.
+type wsConn struct {
+	conn *websocket.Conn
+	turn chan struct{} // holds a token while a round trip uses conn
+}
+
+// wsConns holds one long-lived connection per connection settings, path
+// and worker.
+var wsConns sync.Map
+
+func wsConnFor(cfg *config.Config, workerID int, path string) (*wsConn, error) {
+	key := wsKey{connKeyOf(cfg), cfg.Path, workerID}
+	if c, ok := wsConns.Load(key); ok {
+		return c.(*wsConn), nil
+	}
+
+	scheme := "ws"
+	dialer := *websocket.DefaultDialer
+	dial, err := newDialer(cfg)
+	if err != nil {
+		return nil, err
+	}
+	dialer.NetDialContext = dial
+	if cfg.Scheme == "https" {
+		scheme = "wss"
+		tlsConfig, err := newTLSConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
+		dialer.TLSClientConfig = tlsConfig
+	}
+
+	url := fmt.Sprintf("%s://%s%s", scheme, net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)), path)
+	conn, _, err := dialer.Dial(url, nil)
+	if err != nil {
+		return nil, err
+	}
+	c, loaded := wsConns.LoadOrStore(key, &wsConn{conn: conn, turn: make(chan struct{}, 1)})
+	if loaded {
+		// another call dialed first
+		conn.Close()
+	}
+	return c.(*wsConn), nil
+}
+
+// dropWSConn closes c and forgets it, unless it has been replaced already.
+func dropWSConn(cfg *config.Config, workerID int, c *wsConn) {
+	wsConns.CompareAndDelete(wsKey{connKeyOf(cfg), cfg.Path, workerID}, c)
+	c.conn.Close()
+}
+
+// doWebSocket performs one message round trip. The path template is only
+// used when the connection is first opened.
+func doWebSocket(ctx context.Context, cfg *config.Config, workerID int, path, body string) (Result, error) {
+	start := time.Now()
+	c, err := wsConnFor(cfg, workerID, path)
+	if err != nil {
+		logger.Warn("opening WebSocket", "err", err)
+		return Result{ErrorClass: classifyError(err)}, err
+	}
+	select {
+	case c.turn <- struct{}{}:
+		defer func() { <-c.turn }()
+	case <-ctx.Done():
+		return Result{ErrorClass: classifyError(ctx.Err())}, ctx.Err()
+	}
+	connected := time.Since(start)
+	conn := c.conn
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetReadDeadline(deadline)
+	} else {
+		conn.SetReadDeadline(time.Time{})
+	}
+	conn.SetWriteDeadline(time.Time{})
+	// cancelling ctx cuts the round trip short, which leaves the
+	// connection unusable: it is dropped below
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetReadDeadline(time.Now())
+		conn.SetWriteDeadline(time.Now())
+	})
+	defer stop()
+	fail := func(what string, err error) (Result, error) {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		} else {
+			logger.Warn(what, "err", err)
+		}
+		dropWSConn(cfg, workerID, c)
+		return Result{ErrorClass: classifyError(err)}, err
+	}
+
+	if body == "" {
+		body = "ping"
+	}
+	sent := time.Now()
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(body)); err != nil {
+		return fail("sending message", err)
+	}
+	_, echo, err := conn.ReadMessage()
+	if err != nil {
+		return fail("reading echo", err)
+	}
+
+	return Result{
+		Status:          200,
+		Bytes:           int64(len(echo)),
+		Trace:           Trace{Connect: connected, TTFB: time.Since(sent)},
+		ValidationError: validate(cfg, 200, echo, int64(len(echo))),
+	}, nil
+}
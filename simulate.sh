@@ -11,21 +11,14 @@ cleanup() {
     echo "Cleaning up..."
     [ -n "${TAIL_PID:-}" ] && kill "$TAIL_PID" 2>/dev/null || true
     [ -n "${SERVER_PID:-}" ] && kill "$SERVER_PID" 2>/dev/null || true
-    deactivate 2>/dev/null || true
-    rm -rf venv
 }
 trap cleanup EXIT
 
-# start venv
-python3 -m venv venv
-source venv/bin/activate
-
-# install server deps
-pip install -r ./server/requirements.txt
-
-# start python server and log output
+# build and start the test server, logging its output; built first so
+# that SERVER_PID is the server's own and not go run's
+go build -o bin/server ./cmd/server
 mkdir -p logs
-python3 ./server/server.py > logs/server.log 2>&1 &
+./bin/server > logs/server.log 2>&1 &
 SERVER_PID=$!
 
 
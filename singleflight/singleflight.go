@@ -0,0 +1,49 @@
+// Package singleflight coalesces concurrent calls for the same key into
+// one: while a call for a key is in flight, later callers wait for it and
+// share its result instead of making their own. It is a generic take on
+// golang.org/x/sync/singleflight, without the panic and Forget handling.
+package singleflight
+
+import "sync"
+
+// call is one call in flight and the callers waiting on it.
+type call[V any] struct {
+	wg   sync.WaitGroup
+	val  V
+	err  error
+	dups int
+}
+
+// Group coalesces calls by key. The zero value is ready to use.
+type Group[K comparable, V any] struct {
+	mu    sync.Mutex
+	calls map[K]*call[V]
+}
+
+// Do calls fn for key, unless a call for key is already in flight, in
+// which case it waits for that call instead. shared reports whether the
+// result went to more than one caller.
+func (g *Group[K, V]) Do(key K, fn func() (V, error)) (v V, err error, shared bool) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[K]*call[V])
+	}
+	if c, ok := g.calls[key]; ok {
+		c.dups++
+		g.mu.Unlock()
+		c.wg.Wait()
+		return c.val, c.err, true
+	}
+	c := &call[V]{}
+	c.wg.Add(1)
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	c.val, c.err = fn()
+
+	g.mu.Lock()
+	delete(g.calls, key)
+	g.mu.Unlock()
+	c.wg.Done()
+	return c.val, c.err, c.dups > 0
+}
@@ -0,0 +1,252 @@
+// Package supervisor runs goroutines under a supervisor that restarts
+// them when they fail, in the style of Erlang/OTP supervision trees. A
+// child that returns an error or panics is restarted, either alone or
+// together with its siblings, until the children fail too often, when
+// the supervisor gives up. A child that returns nil has finished and is
+// left alone. Since a supervisor's Run is itself a child function,
+// supervisors nest into trees.
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrTooManyRestarts is returned by Run when the children failed more
+// often than the restart limit allows.
+var ErrTooManyRestarts = errors.New("supervisor: too many restarts")
+
+// Strategy says which children are restarted when one fails.
+type Strategy int
+
+const (
+	// OneForOne restarts only the child that failed.
+	OneForOne Strategy = iota
+	// OneForAll stops every other running child and restarts them all,
+	// for children that depend on each other.
+	OneForAll
+)
+
+func (s Strategy) String() string {
+	switch s {
+	case OneForOne:
+		return "one-for-one"
+	case OneForAll:
+		return "one-for-all"
+	default:
+		return "unknown"
+	}
+}
+
+// Child is a supervised function. It should return when ctx is done.
+type Child struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// Options configure a supervisor. It gives up once more than MaxRestarts
+// restarts happen within Within; a MaxRestarts of 0 allows none.
+type Options struct {
+	Strategy    Strategy
+	MaxRestarts int
+	Within      time.Duration
+}
+
+// State is the state of a child.
+type State int
+
+const (
+	Running State = iota
+	// Finished children returned nil.
+	Finished
+	// Stopped children were stopped by the supervisor stopping.
+	Stopped
+	// Failed children failed and were not restarted, because the
+	// supervisor gave up.
+	Failed
+)
+
+func (s State) String() string {
+	switch s {
+	case Running:
+		return "running"
+	case Finished:
+		return "finished"
+	case Stopped:
+		return "stopped"
+	case Failed:
+		return "failed"
+	default:
+		return "unknown"
+	}
+}
+
+// Status describes a child.
+type Status struct {
+	Name     string
+	State    State
+	Restarts int
+	// LastErr is the child's last failure, nil if it never failed.
+	LastErr error
+}
+
+// child is a Child and its bookkeeping. gen counts its starts, so that
+// the exit of an instance the supervisor stopped can be told apart.
+type child struct {
+	Child
+	status Status
+	gen    int
+	cancel context.CancelFunc
+}
+
+// exit reports that an instance of a child returned.
+type exit struct {
+	index int
+	gen   int
+	err   error
+}
+
+// Supervisor restarts its children when they fail. It is safe to call
+// Status while Run is running.
+type Supervisor struct {
+	opts Options
+
+	mu       sync.Mutex
+	children []*child
+	restarts []time.Time
+}
+
+func New(opts Options, children ...Child) *Supervisor {
+	s := &Supervisor{opts: opts}
+	for _, c := range children {
+		s.children = append(s.children, &child{Child: c, status: Status{Name: c.Name}})
+	}
+	return s
+}
+
+// Run runs the children until they have all finished, ctx is done, or
+// they fail too often, when it stops the rest and returns
+// ErrTooManyRestarts with the last failure.
+func (s *Supervisor) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	exits := make(chan exit)
+	running := 0
+
+	start := func(i int) {
+		c := s.children[i]
+		cctx, ccancel := context.WithCancel(ctx)
+		c.gen++
+		c.cancel = ccancel
+		c.status.State = Running
+		running++
+		go func(gen int) {
+			exits <- exit{i, gen, safely(cctx, c.Run)}
+		}(c.gen)
+	}
+
+	s.mu.Lock()
+	for i := range s.children {
+		start(i)
+	}
+	s.mu.Unlock()
+
+	var result error
+	for running > 0 {
+		e := <-exits
+		running--
+
+		s.mu.Lock()
+		c := s.children[e.index]
+		c.cancel()
+		switch {
+		case e.gen != c.gen:
+			// stopped for a one-for-all restart
+		case e.err == nil:
+			c.status.State = Finished
+		case ctx.Err() != nil:
+			c.status.State = Stopped
+		default:
+			c.status.LastErr = e.err
+			if !s.allowRestart() {
+				c.status.State = Failed
+				result = fmt.Errorf("%w: %s: %w", ErrTooManyRestarts, c.Name, e.err)
+				cancel()
+				break
+			}
+			c.status.Restarts++
+			if s.opts.Strategy == OneForOne {
+				start(e.index)
+				break
+			}
+			// stop the siblings, wait for them, then start everyone
+			// who hadn't finished
+			stopping := 0
+			for _, sib := range s.children {
+				if sib != c && sib.status.State == Running {
+					sib.gen++
+					sib.cancel()
+					stopping++
+				}
+			}
+			s.mu.Unlock()
+			for range stopping {
+				<-exits
+				running--
+			}
+			s.mu.Lock()
+			for i, sib := range s.children {
+				if sib.status.State == Running || sib == c {
+					if sib != c {
+						sib.status.Restarts++
+					}
+					start(i)
+				}
+			}
+		}
+		s.mu.Unlock()
+	}
+	if result == nil {
+		result = ctx.Err()
+	}
+	return result
+}
+
+// allowRestart records a restart, reporting whether it is within the
+// limit. s.mu must be held.
+func (s *Supervisor) allowRestart() bool {
+	now := time.Now()
+	s.restarts = append(s.restarts, now)
+	recent := s.restarts[:0]
+	for _, t := range s.restarts {
+		if now.Sub(t) <= s.opts.Within {
+			recent = append(recent, t)
+		}
+	}
+	s.restarts = recent
+	return len(s.restarts) <= s.opts.MaxRestarts
+}
+
+// safely calls fn, turning a panic into an error.
+func safely(ctx context.Context, fn func(context.Context) error) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+	return fn(ctx)
+}
+
+// Status returns the status of every child, in order.
+func (s *Supervisor) Status() []Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]Status, len(s.children))
+	for i, c := range s.children {
+		out[i] = c.status
+	}
+	return out
+}
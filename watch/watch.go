@@ -0,0 +1,80 @@
+// Package watch is a value that one goroutine updates and any number of
+// others watch: every watcher sees the latest value, but one that falls
+// behind skips the updates it missed rather than holding up the writer,
+// like a broadcast channel that only ever buffers the newest value.
+package watch
+
+import (
+	"context"
+	"sync"
+)
+
+// Value is a watched value. The zero value is not ready to use; create one
+// with New.
+type Value[T any] struct {
+	mu      sync.Mutex
+	val     T
+	version uint64
+	changed chan struct{} // closed by the next Set
+}
+
+// New returns a Value set to v.
+func New[T any](v T) *Value[T] {
+	return &Value[T]{val: v, changed: make(chan struct{})}
+}
+
+// Set updates the value, waking everyone waiting for a change.
+func (v *Value[T]) Set(val T) {
+	v.mu.Lock()
+	v.val = val
+	v.version++
+	close(v.changed)
+	v.changed = make(chan struct{})
+	v.mu.Unlock()
+}
+
+// Load returns the value and its version, which goes up by one with
+// every Set.
+func (v *Value[T]) Load() (T, uint64) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.val, v.version
+}
+
+// Changed returns a channel that is closed by the next Set, for waiting
+// on a change in a select.
+func (v *Value[T]) Changed() <-chan struct{} {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.changed
+}
+
+// Watch returns a channel with the current value and then each new one
+// until ctx is done, when it is closed. It holds only the latest value: a
+// watcher that is busy when several updates land receives the last of
+// them.
+func (v *Value[T]) Watch(ctx context.Context) <-chan T {
+	out := make(chan T, 1)
+	go func() {
+		defer close(out)
+		for {
+			v.mu.Lock()
+			val, changed := v.val, v.changed
+			v.mu.Unlock()
+
+			// replace a value the watcher hasn't taken yet
+			select {
+			case <-out:
+			default:
+			}
+			out <- val
+
+			select {
+			case <-changed:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}